@@ -54,6 +54,7 @@ func TestMSAN(t *testing.T) {
 		{src: "msan6.go"},
 		{src: "msan7.go"},
 		{src: "msan8.go"},
+		{src: "msan9.go"},
 		{src: "msan_fail.go", wantErr: true},
 		// This may not always fail specifically due to MSAN. It may sometimes
 		// fail because of a fault. However, we don't care what kind of error we
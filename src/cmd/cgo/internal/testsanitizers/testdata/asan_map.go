@@ -0,0 +1,71 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+// Map keys and elements are written by the runtime, which reports
+// those writes to asan. Assigning, deleting and reinserting entries
+// must not be reported.
+
+import "fmt"
+
+type key struct {
+	s string
+	n [3]int64
+}
+
+type elem [200]byte
+
+func fill(b byte) elem {
+	var e elem
+	for i := range e {
+		e[i] = b
+	}
+	return e
+}
+
+func main() {
+	// Small maps and maps with tables.
+	for _, n := range []int{4, 1000} {
+		m := make(map[key]elem)
+
+		// Assign, then read.
+		for i := 0; i < n; i++ {
+			m[key{n: [3]int64{int64(i)}}] = fill(byte(i))
+		}
+		for i := 0; i < n; i++ {
+			if e := m[key{n: [3]int64{int64(i)}}]; e != fill(byte(i)) {
+				panic(fmt.Sprintf("m[%d] = %v, want %d", i, e[0], byte(i)))
+			}
+		}
+
+		// Delete, then reinsert into the freed slots.
+		for i := 0; i < n; i += 2 {
+			delete(m, key{n: [3]int64{int64(i)}})
+		}
+		for i := 0; i < n; i += 2 {
+			k := key{n: [3]int64{int64(i)}}
+			if e := m[k]; e != (elem{}) {
+				panic(fmt.Sprintf("m[%d] = %v after delete, want 0", i, e[0]))
+			}
+			m[k] = fill(byte(i + 1))
+		}
+		for k, e := range m {
+			i := k.n[0]
+			want := byte(i)
+			if i%2 == 0 {
+				want = byte(i + 1)
+			}
+			if e != fill(want) {
+				panic(fmt.Sprintf("m[%d] = %v, want %d", i, e[0], want))
+			}
+		}
+
+		clear(m)
+		m[key{s: "x"}] = fill(7)
+		if e := m[key{s: "x"}]; e != fill(7) {
+			panic(fmt.Sprintf("m[x] = %v after clear, want 7", e[0]))
+		}
+	}
+}
@@ -0,0 +1,34 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"unsafe"
+)
+
+func main() {
+	// Enough entries that the map has tables, whose slots are poisoned
+	// when they are emptied.
+	m := make(map[int]int)
+	for i := range 100 {
+		m[i] = i
+	}
+	p := elemPointer(m, 42)
+	delete(m, 42)
+	fmt.Println(*p) // BOOM
+}
+
+// elemPointer returns a pointer to the element of key k in m, as a
+// compiled m[k] uses it before copying the element out.
+func elemPointer(m map[int]int, k int) *int {
+	var i any = m
+	typ := (*[2]unsafe.Pointer)(unsafe.Pointer(&i))[0]
+	p, _ := mapaccess2(typ, *(*unsafe.Pointer)(unsafe.Pointer(&m)), unsafe.Pointer(&k))
+	return (*int)(p)
+}
+
+//go:linkname mapaccess2 runtime.mapaccess2
+func mapaccess2(t, m, key unsafe.Pointer) (unsafe.Pointer, bool)
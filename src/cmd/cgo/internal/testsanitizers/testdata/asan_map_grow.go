@@ -0,0 +1,48 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+// An iteration started before a map grows keeps reading keys and
+// elements from the groups of the tables the growth replaced, and hashes
+// and compares the keys with the compiler-generated functions of this
+// package, which asan instruments. Those reads must not be reported.
+
+import "fmt"
+
+type key struct {
+	s string
+	i int
+}
+
+func main() {
+	m := make(map[key]int)
+	for i := range 2000 {
+		m[key{fmt.Sprint(i), i}] = i
+	}
+
+	seen := 0
+	next := 2000
+	for k, v := range m {
+		if k.i != v {
+			panic(fmt.Sprintf("m[%v] = %d, want %d", k, v, k.i))
+		}
+		seen++
+		// Grow to 20000 entries, splitting and resizing the tables
+		// the iteration has yet to visit.
+		for range 18 {
+			if next == 20000 {
+				break
+			}
+			m[key{fmt.Sprint(next), next}] = next
+			next++
+		}
+	}
+	if seen < 2000 {
+		panic(fmt.Sprintf("saw %d entries, want at least 2000", seen))
+	}
+	if len(m) != next {
+		panic(fmt.Sprintf("len(m) = %d, want %d", len(m), next))
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+// Map keys and elements are written by the runtime, whose stores are
+// not instrumented. Reading them back must not be reported by msan,
+// including after deleting entries and reusing their slots.
+
+/*
+#include <stdlib.h>
+
+void check(unsigned char* p, int n, unsigned char want) {
+	int i;
+
+	for (i = 0; i < n; i++) {
+		if (p[i] != want) {
+			abort();
+		}
+	}
+}
+*/
+import "C"
+
+import "unsafe"
+
+type key struct {
+	s string
+	n [3]int64
+}
+
+type elem [200]byte
+
+func check(e *elem, want byte) {
+	C.check((*C.uchar)(unsafe.Pointer(&e[0])), C.int(len(e)), C.uchar(want))
+}
+
+func fill(b byte) elem {
+	var e elem
+	for i := range e {
+		e[i] = b
+	}
+	return e
+}
+
+func main() {
+	// Small maps and maps with tables.
+	for _, n := range []int{4, 1000} {
+		m := make(map[key]elem)
+
+		// Assign, then read.
+		for i := 0; i < n; i++ {
+			m[key{n: [3]int64{int64(i)}}] = fill(byte(i))
+		}
+		for i := 0; i < n; i++ {
+			e := m[key{n: [3]int64{int64(i)}}]
+			check(&e, byte(i))
+		}
+
+		// Delete, then reinsert into the freed slots.
+		for i := 0; i < n; i += 2 {
+			delete(m, key{n: [3]int64{int64(i)}})
+		}
+		for i := 0; i < n; i += 2 {
+			k := key{n: [3]int64{int64(i)}}
+			e := m[k]
+			check(&e, 0)
+			m[k] = fill(byte(i + 1))
+		}
+		for k, e := range m {
+			i := k.n[0]
+			want := byte(i)
+			if i%2 == 0 {
+				want = byte(i + 1)
+			}
+			check(&e, want)
+		}
+
+		clear(m)
+		m[key{s: "x"}] = fill(7)
+		e := m[key{s: "x"}]
+		check(&e, 7)
+	}
+}
@@ -12,9 +12,21 @@ import (
 	"cmd/internal/obj"
 	"cmd/internal/objabi"
 	"cmd/internal/src"
+	"cmd/internal/sys"
 	"internal/abi"
 )
 
+// SwissMapGroupSlots returns the number of slots in a map group on the
+// target architecture. It must match internal/abi.SwissMapGroupSlots as
+// compiled for GOARCH, which the compiler cannot use directly as it
+// describes the host.
+func SwissMapGroupSlots() int64 {
+	if base.Ctxt.Arch.Family == sys.AMD64 {
+		return 16
+	}
+	return 8
+}
+
 // SwissMapGroupType makes the map slot group type given the type of the map.
 func SwissMapGroupType(t *types.Type) *types.Type {
 	if t.MapType().SwissGroup != nil {
@@ -28,7 +40,7 @@ func SwissMapGroupType(t *types.Type) *types.Type {
 	// Make sure this stays in sync with internal/runtime/maps/group.go.
 	//
 	// type group struct {
-	//     ctrl uint64
+	//     ctrl  [abi.SwissMapCtrlWords]uint64
 	//     slots [abi.SwissMapGroupSlots]struct {
 	//         key  keyType
 	//         elem elemType
@@ -46,20 +58,36 @@ func SwissMapGroupType(t *types.Type) *types.Type {
 		elemtype = types.NewPtr(elemtype)
 	}
 
+	// A struct ending in a zero-size field is padded, so that a pointer
+	// to the field doesn't point past the struct. Put a zero-size elem
+	// before the key, where it needs no padding. Its address is then
+	// the key's, which internal/runtime/maps never writes through.
 	slotFields := []*types.Field{
 		makefield("key", keytype),
 		makefield("elem", elemtype),
 	}
+	if elemtype.Size() == 0 {
+		slotFields[0], slotFields[1] = slotFields[1], slotFields[0]
+	}
 	slot := types.NewStruct(slotFields)
 	slot.SetNoalg(true)
 
-	slotArr := types.NewArray(slot, abi.SwissMapGroupSlots)
+	slots := SwissMapGroupSlots()
+	slotArr := types.NewArray(slot, slots)
 	slotArr.SetNoalg(true)
 
+	ctrlArr := types.NewArray(types.Types[types.TUINT64], slots/8)
+
+	// Likewise, if the slots are zero-size, put them before the control
+	// word. internal/runtime/maps points all of them at the start of
+	// the group.
 	fields := []*types.Field{
-		makefield("ctrl", types.Types[types.TUINT64]),
+		makefield("ctrl", ctrlArr),
 		makefield("slots", slotArr),
 	}
+	if slot.Size() == 0 {
+		fields[0], fields[1] = fields[1], fields[0]
+	}
 
 	group := types.NewStruct(fields)
 	group.SetNoalg(true)
@@ -69,11 +97,7 @@ func SwissMapGroupType(t *types.Type) *types.Type {
 	if !types.IsComparable(t.Key()) {
 		base.Fatalf("unsupported map key type for %v", t)
 	}
-	if group.Size() <= 8 {
-		// internal/runtime/maps creates pointers to slots, even if
-		// both key and elem are size zero. In this case, each slot is
-		// size 0, but group should still reserve a word of padding at
-		// the end to ensure pointers are valid.
+	if group.Size() != ctrlArr.Size()+slotArr.Size() {
 		base.Fatalf("bad group size for %v", t)
 	}
 	if t.Key().Size() > abi.SwissMapMaxKeyBytes && !keytype.IsPtr() {
@@ -88,6 +112,19 @@ func SwissMapGroupType(t *types.Type) *types.Type {
 	return group
 }
 
+// SwissMapGroupField returns the field called name of a group type made
+// by SwissMapGroupType, or of its slot type. The order of the fields
+// depends on which are zero-size.
+func SwissMapGroupField(t *types.Type, name string) *types.Field {
+	for _, f := range t.Fields() {
+		if f.Sym.Name == name {
+			return f
+		}
+	}
+	base.Fatalf("no field %s in %v", name, t)
+	return nil
+}
+
 var cachedSwissTableType *types.Type
 
 // swissTableType returns a type interchangeable with internal/runtime/maps.table.
@@ -155,6 +192,11 @@ func SwissMapType() *types.Type {
 	//     dirPtr unsafe.Pointer
 	//     dirLen int
 	//
+	//     groups struct {
+	//         data       unsafe.Pointer
+	//         lengthMask uint64
+	//     }
+	//
 	//     globalDepth uint8
 	//     globalShift uint8
 	//
@@ -164,11 +206,16 @@ func SwissMapType() *types.Type {
 	//     clearSeq uint64
 	// }
 	// must match internal/runtime/maps/map.go:Map.
+	groups := types.NewStruct([]*types.Field{
+		makefield("data", types.Types[types.TUNSAFEPTR]),
+		makefield("lengthMask", types.Types[types.TUINT64]),
+	})
 	fields := []*types.Field{
 		makefield("used", types.Types[types.TUINT64]),
 		makefield("seed", types.Types[types.TUINTPTR]),
 		makefield("dirPtr", types.Types[types.TUNSAFEPTR]),
 		makefield("dirLen", types.Types[types.TINT]),
+		makefield("groups", groups),
 		makefield("globalDepth", types.Types[types.TUINT8]),
 		makefield("globalShift", types.Types[types.TUINT8]),
 		makefield("writing", types.Types[types.TUINT8]),
@@ -183,9 +230,9 @@ func SwissMapType() *types.Type {
 	m.SetUnderlying(types.NewStruct(fields))
 	types.CalcSize(m)
 
-	// The size of Map should be 48 bytes on 64 bit
-	// and 32 bytes on 32 bit platforms.
-	if size := int64(2*8 + 4*types.PtrSize /* one extra for globalDepth/globalShift/writing + padding */); m.Size() != size {
+	// The size of Map should be 64 bytes on 64 bit
+	// and 44 bytes on 32 bit platforms.
+	if size := int64(3*8 + 5*types.PtrSize /* one extra for globalDepth/globalShift/writing + padding */); m.Size() != size {
 		base.Fatalf("internal/runtime/maps.Map size not correct: got %d, want %d", m.Size(), size)
 	}
 
@@ -214,6 +261,7 @@ func SwissMapIterType() *types.Type {
 	//    clearSeq uint64
 	//
 	//    globalDepth uint8
+	//    keysOnly    bool
 	//    // N.B. padding
 	//
 	//    dirIdx int
@@ -234,6 +282,7 @@ func SwissMapIterType() *types.Type {
 		makefield("dirOffset", types.Types[types.TUINT64]),
 		makefield("clearSeq", types.Types[types.TUINT64]),
 		makefield("globalDepth", types.Types[types.TUINT8]),
+		makefield("keysOnly", types.Types[types.TBOOL]),
 		makefield("dirIdx", types.Types[types.TINT]),
 		makefield("tab", types.NewPtr(swissTableType())),
 		makefield("group", types.Types[types.TUNSAFEPTR]),
@@ -251,7 +300,7 @@ func SwissMapIterType() *types.Type {
 
 	// The size of Iter should be 96 bytes on 64 bit
 	// and 64 bytes on 32 bit platforms.
-	if size := 8*types.PtrSize /* one extra for globalDepth + padding */ + 4*8; iter.Size() != int64(size) {
+	if size := 8*types.PtrSize /* one extra for globalDepth, keysOnly + padding */ + 4*8; iter.Size() != int64(size) {
 		base.Fatalf("internal/runtime/maps.Iter size not correct: got %d, want %d", iter.Size(), size)
 	}
 
@@ -267,14 +316,8 @@ func writeSwissMapType(t *types.Type, lsym *obj.LSym, c rttype.Cursor) {
 	s3 := writeType(gtyp)
 	hasher := genhash(t.Key())
 
-	slotTyp := gtyp.Field(1).Type.Elem()
-	elemOff := slotTyp.Field(1).Offset
-	if AlgType(t.Key()) == types.AMEM64 && elemOff != 8 {
-		base.Fatalf("runtime assumes elemOff for 8-byte keys is 8, got %d", elemOff)
-	}
-	if AlgType(t.Key()) == types.ASTRING && elemOff != int64(2*types.PtrSize) {
-		base.Fatalf("runtime assumes elemOff for string keys is %d, got %d", 2*types.PtrSize, elemOff)
-	}
+	slotTyp := SwissMapGroupField(gtyp, "slots").Type.Elem()
+	elemOff := SwissMapGroupField(slotTyp, "elem").Offset
 
 	c.Field("Key").WritePtr(s1)
 	c.Field("Elem").WritePtr(s2)
@@ -293,7 +336,7 @@ func writeSwissMapType(t *types.Type, lsym *obj.LSym, c rttype.Cursor) {
 	if t.Key().Size() > abi.SwissMapMaxKeyBytes {
 		flags |= abi.SwissMapIndirectKey
 	}
-	if t.Elem().Size() > abi.SwissMapMaxKeyBytes {
+	if t.Elem().Size() > abi.SwissMapMaxElemBytes {
 		flags |= abi.SwissMapIndirectElem
 	}
 	c.Field("Flags").WriteUint32(flags)
@@ -1137,9 +1137,10 @@ func init() {
 
 		// Unpack bytes, low 64-bits.
 		//
-		// Input/output registers treated as [8]uint8.
+		// Input registers treated as [8]uint8 (the low 64-bits), output
+		// register treated as [16]uint8.
 		//
-		// output = {in1[0], in2[0], in1[1], in2[1], in1[2], in2[2], in1[3], in2[3]}
+		// output = {in1[0], in2[0], in1[1], in2[1], ..., in1[7], in2[7]}
 		{name: "PUNPCKLBW", argLength: 2, reg: fp21, resultInArg0: true, asm: "PUNPCKLBW"},
 
 		// Shuffle 16-bit words, low 64-bits.
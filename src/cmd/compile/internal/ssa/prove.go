@@ -341,6 +341,20 @@ func (l limit) exp2(b uint) limit {
 	return r
 }
 
+// Similar to add, but computes l >> l2 (unsigned) for a value of bitsize b.
+func (l limit) rshU(l2 limit, b uint) limit {
+	r := noLimit
+	if l2.umin < uint64(b) {
+		r.umax = l.umax >> l2.umin
+	} else {
+		r.umax = 0
+	}
+	if l2.umax < uint64(b) {
+		r.umin = l.umin >> l2.umax
+	}
+	return r
+}
+
 // Similar to add, but computes the complement of the limit for bitsize b.
 func (l limit) com(b uint) limit {
 	switch b {
@@ -438,6 +452,9 @@ type factsTable struct {
 	// more than one len(s) for a slice. We could keep a list if necessary.
 	lens map[ID]*Value
 	caps map[ID]*Value
+
+	// For each value k, a 1<<k value (if any).
+	pow2s map[ID]*Value
 }
 
 // checkpointBound is an invalid value used for checkpointing
@@ -1474,6 +1491,16 @@ func prove(f *Func) {
 				} else {
 					ft.caps[v.Args[0].ID] = v
 				}
+			case OpLsh64x64, OpLsh64x32, OpLsh64x16, OpLsh64x8,
+				OpLsh32x64, OpLsh32x32, OpLsh32x16, OpLsh32x8:
+				// Remember 1<<k so that right shifts extracting
+				// the top k bits can be related to it (see addLocalFactsRsh).
+				if c := v.Args[0]; c.isGenericIntConst() && c.AuxInt == 1 {
+					if ft.pow2s == nil {
+						ft.pow2s = map[ID]*Value{}
+					}
+					ft.pow2s[v.Args[1].ID] = v
+				}
 			}
 		}
 	}
@@ -1843,6 +1870,22 @@ func (ft *factsTable) flowLimit(v *Value) bool {
 		a := ft.limits[v.Args[0].ID]
 		b := ft.limits[v.Args[1].ID]
 		return ft.newLimit(v, a.mul(b.exp2(8), 8))
+	case OpRsh64Ux64, OpRsh64Ux32, OpRsh64Ux16, OpRsh64Ux8:
+		a := ft.limits[v.Args[0].ID]
+		b := ft.limits[v.Args[1].ID]
+		return ft.newLimit(v, a.rshU(b, 64))
+	case OpRsh32Ux64, OpRsh32Ux32, OpRsh32Ux16, OpRsh32Ux8:
+		a := ft.limits[v.Args[0].ID]
+		b := ft.limits[v.Args[1].ID]
+		return ft.newLimit(v, a.rshU(b, 32))
+	case OpRsh16Ux64, OpRsh16Ux32, OpRsh16Ux16, OpRsh16Ux8:
+		a := ft.limits[v.Args[0].ID]
+		b := ft.limits[v.Args[1].ID]
+		return ft.newLimit(v, a.rshU(b, 16))
+	case OpRsh8Ux64, OpRsh8Ux32, OpRsh8Ux16, OpRsh8Ux8:
+		a := ft.limits[v.Args[0].ID]
+		b := ft.limits[v.Args[1].ID]
+		return ft.newLimit(v, a.rshU(b, 8))
 	case OpMod64, OpMod32, OpMod16, OpMod8:
 		a := ft.limits[v.Args[0].ID]
 		b := ft.limits[v.Args[1].ID]
@@ -2025,12 +2068,14 @@ func addLocalFacts(ft *factsTable, b *Block) {
 			// TODO: investigate how to always add facts without much slowdown, see issue #57959
 			//ft.update(b, v, v.Args[0], unsigned, gt|eq)
 			//ft.update(b, v, v.Args[1], unsigned, gt|eq)
-		case OpDiv64u, OpDiv32u, OpDiv16u, OpDiv8u,
-			OpRsh8Ux64, OpRsh8Ux32, OpRsh8Ux16, OpRsh8Ux8,
+		case OpDiv64u, OpDiv32u, OpDiv16u, OpDiv8u:
+			ft.update(b, v, v.Args[0], unsigned, lt|eq)
+		case OpRsh8Ux64, OpRsh8Ux32, OpRsh8Ux16, OpRsh8Ux8,
 			OpRsh16Ux64, OpRsh16Ux32, OpRsh16Ux16, OpRsh16Ux8,
 			OpRsh32Ux64, OpRsh32Ux32, OpRsh32Ux16, OpRsh32Ux8,
 			OpRsh64Ux64, OpRsh64Ux32, OpRsh64Ux16, OpRsh64Ux8:
 			ft.update(b, v, v.Args[0], unsigned, lt|eq)
+			addLocalFactsRsh(ft, b, v)
 		case OpMod64u, OpMod32u, OpMod16u, OpMod8u:
 			ft.update(b, v, v.Args[0], unsigned, lt|eq)
 			// Note: we have to be careful that this doesn't imply
@@ -2052,6 +2097,53 @@ func addLocalFacts(ft *factsTable, b *Block) {
 	}
 }
 
+// addLocalFactsRsh adds facts for an unsigned right shift v that
+// extracts the top k bits of its argument, i.e.
+//
+//	v = x >> (bits - k)   with 0 <= k < bits
+//
+// Such a v is strictly less than 1<<k. This is the indexing pattern
+// used by extendible hashing, where a directory of length 1<<k is
+// indexed by the top k bits of a hash:
+//
+//	dir[hash>>(64-k)]                      // with len(dir) == 1<<k
+//	dir[hash>>(64-bits.TrailingZeros(n))]  // with n == len(dir) > 0
+//
+// If 1<<k is available as a value, we learn v < 1<<k, which the
+// poset can then relate to len(dir) through a preceding check.
+// If k is the trailing zero count of a nonzero n, we learn v < n,
+// because 1<<TrailingZeros(n) <= n.
+func addLocalFactsRsh(ft *factsTable, b *Block, v *Value) {
+	size := v.Type.Size() * 8
+	s := v.Args[1]
+	switch s.Op {
+	case OpSub64, OpSub32, OpSub16, OpSub8:
+	default:
+		return
+	}
+	if c := s.Args[0]; !c.isGenericIntConst() || c.AuxInt != size {
+		return
+	}
+	k := s.Args[1]
+	if ft.limits[k.ID].umax >= uint64(size) {
+		// k might be out of range: bits-k could wrap or be zero.
+		return
+	}
+	var w *Value
+	switch {
+	case ft.pow2s[k.ID] != nil && ft.pow2s[k.ID].Type.Size() == v.Type.Size():
+		w = ft.pow2s[k.ID]
+	case (k.Op == OpCtz64 || k.Op == OpCtz32) && k.Args[0].Type.Size() == v.Type.Size() && ft.limits[k.Args[0].ID].nonzero():
+		w = k.Args[0]
+	default:
+		return
+	}
+	ft.update(b, v, w, unsigned, lt)
+	if ft.isNonNegative(v) && ft.isNonNegative(w) {
+		ft.update(b, v, w, signed, lt)
+	}
+}
+
 func addLocalFactsPhi(ft *factsTable, v *Value) {
 	// Look for phis that implement min/max.
 	//   z:
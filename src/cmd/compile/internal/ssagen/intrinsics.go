@@ -1263,136 +1263,85 @@ func initIntrinsics(cfg *intrinsicBuildConfig) {
 
 	/******** internal/runtime/maps ********/
 
-	// Important: The intrinsic implementations below return a packed
-	// bitset, while the portable Go implementation uses an unpacked
-	// representation (one bit set in each byte).
-	//
-	// Thus we must replace most bitset methods with implementations that
-	// work with the packed representation.
-	//
-	// TODO(prattmic): The bitset implementations don't use SIMD, so they
-	// could be handled with build tags (though that would break
-	// -d=ssa/intrinsics/off=1).
-
-	// With a packed representation we no longer need to shift the result
-	// of TrailingZeros64.
-	alias("internal/runtime/maps", "bitsetFirst", "internal/runtime/sys", "TrailingZeros64", sys.ArchAMD64)
-
-	addF("internal/runtime/maps", "bitsetRemoveBelow",
-		func(s *state, n *ir.CallExpr, args []*ssa.Value) *ssa.Value {
-			b := args[0]
-			i := args[1]
-
-			// Clear the lower i bits in b.
-			//
-			// out = b &^ ((1 << i) - 1)
-
-			one := s.constInt64(types.Types[types.TUINT64], 1)
-
-			mask := s.newValue2(ssa.OpLsh8x8, types.Types[types.TUINT64], one, i)
-			mask = s.newValue2(ssa.OpSub64, types.Types[types.TUINT64], mask, one)
-			mask = s.newValue1(ssa.OpCom64, types.Types[types.TUINT64], mask)
-
-			return s.newValue2(ssa.OpAnd64, types.Types[types.TUINT64], b, mask)
-		},
-		sys.AMD64)
-
-	addF("internal/runtime/maps", "bitsetLowestSet",
-		func(s *state, n *ir.CallExpr, args []*ssa.Value) *ssa.Value {
-			b := args[0]
+	// On AMD64, a group has 16 slots, so its 16 control bytes fill an XMM
+	// register. The intrinsics below return a packed bitset (bit N set
+	// means slot N matched), which is also what the portable Go
+	// implementations return for 16-slot groups. See the note on
+	// internal/runtime/maps.bitset.
+
+	// loadCtrlGroup loads the control bytes of the group at p.
+	loadCtrlGroup := func(s *state, p *ssa.Value) *ssa.Value {
+		return s.load(types.TypeInt128, p)
+	}
 
-			// Test the lowest bit in b.
+	// broadcastByte copies the low byte of the integer b into each byte
+	// of an XMM register.
+	broadcastByte := func(s *state, b *ssa.Value) *ssa.Value {
+		if buildcfg.GOAMD64 >= 4 {
+			// VPBROADCASTB saves 1 instruction vs PSHUFB
+			// because the input can come from a GP
+			// register, while PSHUFB requires moving into
+			// an FP register first.
 			//
-			// out = (b & 1) == 1
-
-			one := s.constInt64(types.Types[types.TUINT64], 1)
-			and := s.newValue2(ssa.OpAnd64, types.Types[types.TUINT64], b, one)
-			return s.newValue2(ssa.OpEq64, types.Types[types.TBOOL], and, one)
-		},
-		sys.AMD64)
+			// Nominally PSHUFB would require a second
+			// additional instruction to load the control
+			// mask into a FP register. But broadcast uses
+			// a control mask of 0, and the register ABI
+			// already defines X15 as a zero register.
+			return s.newValue1(ssa.OpAMD64VPBROADCASTB, types.TypeInt128, b) // use gp copy of b
+		}
 
-	addF("internal/runtime/maps", "bitsetShiftOutLowest",
-		func(s *state, n *ir.CallExpr, args []*ssa.Value) *ssa.Value {
-			b := args[0]
+		// Explicit copy to fp register. See
+		// https://go.dev/issue/70451.
+		bfp := s.newValue1(ssa.OpAMD64MOVQi2f, types.TypeInt128, b)
 
-			// Right shift out the lowest bit in b.
-			//
-			// out = b >> 1
+		if buildcfg.GOAMD64 >= 2 {
+			// PSHUFB performs a byte broadcast when given
+			// a control input of 0.
+			return s.newValue1(ssa.OpAMD64PSHUFBbroadcast, types.TypeInt128, bfp)
+		}
 
-			one := s.constInt64(types.Types[types.TUINT64], 1)
-			return s.newValue2(ssa.OpRsh64Ux64, types.Types[types.TUINT64], b, one)
-		},
-		sys.AMD64)
+		// No direct byte broadcast. First we must
+		// duplicate the lower byte and then do a
+		// 16-bit broadcast.
+
+		// "Unpack" b with itself. This duplicates the
+		// input, resulting in b in the lower two
+		// bytes.
+		unpack := s.newValue2(ssa.OpAMD64PUNPCKLBW, types.TypeInt128, bfp, bfp)
+
+		// Copy the lower 16-bits of unpack into every
+		// 16-bit slot in the lower 64-bits of the
+		// output register. Note that immediate 0
+		// selects the low word as the source for every
+		// destination slot.
+		low := s.newValue1I(ssa.OpAMD64PSHUFLW, types.TypeInt128, 0, unpack)
+
+		// Unpack the lower 64-bits with themselves once
+		// more to fill all 128 bits.
+		return s.newValue2(ssa.OpAMD64PUNPCKLBW, types.TypeInt128, low, low)
+	}
 
 	addF("internal/runtime/maps", "ctrlGroupMatchH2",
 		func(s *state, n *ir.CallExpr, args []*ssa.Value) *ssa.Value {
-			g := args[0]
+			g := loadCtrlGroup(s, args[0])
 			h := args[1]
 
-			// Explicit copies to fp registers. See
-			// https://go.dev/issue/70451.
-			gfp := s.newValue1(ssa.OpAMD64MOVQi2f, types.TypeInt128, g)
-			hfp := s.newValue1(ssa.OpAMD64MOVQi2f, types.TypeInt128, h)
-
-			// Broadcast h2 into each byte of a word.
-			var broadcast *ssa.Value
-			if buildcfg.GOAMD64 >= 4 {
-				// VPBROADCASTB saves 1 instruction vs PSHUFB
-				// because the input can come from a GP
-				// register, while PSHUFB requires moving into
-				// an FP register first.
-				//
-				// Nominally PSHUFB would require a second
-				// additional instruction to load the control
-				// mask into a FP register. But broadcast uses
-				// a control mask of 0, and the register ABI
-				// already defines X15 as a zero register.
-				broadcast = s.newValue1(ssa.OpAMD64VPBROADCASTB, types.TypeInt128, h) // use gp copy of h
-			} else if buildcfg.GOAMD64 >= 2 {
-				// PSHUFB performs a byte broadcast when given
-				// a control input of 0.
-				broadcast = s.newValue1(ssa.OpAMD64PSHUFBbroadcast, types.TypeInt128, hfp)
-			} else {
-				// No direct byte broadcast. First we must
-				// duplicate the lower byte and then do a
-				// 16-bit broadcast.
-
-				// "Unpack" h2 with itself. This duplicates the
-				// input, resulting in h2 in the lower two
-				// bytes.
-				unpack := s.newValue2(ssa.OpAMD64PUNPCKLBW, types.TypeInt128, hfp, hfp)
-
-				// Copy the lower 16-bits of unpack into every
-				// 16-bit slot in the lower 64-bits of the
-				// output register. Note that immediate 0
-				// selects the low word as the source for every
-				// destination slot.
-				broadcast = s.newValue1I(ssa.OpAMD64PSHUFLW, types.TypeInt128, 0, unpack)
-
-				// No need to broadcast into the upper 64-bits,
-				// as we don't use those.
-			}
+			// Broadcast h2 into each byte of a register.
+			broadcast := broadcastByte(s, h)
 
-			// Compare each byte of the control word with h2. Each
+			// Compare each control byte with h2. Each
 			// matching byte has every bit set.
-			eq := s.newValue2(ssa.OpAMD64PCMPEQB, types.TypeInt128, broadcast, gfp)
+			eq := s.newValue2(ssa.OpAMD64PCMPEQB, types.TypeInt128, broadcast, g)
 
 			// Construct a "byte mask": each output bit is equal to
 			// the sign bit each input byte.
 			//
 			// This results in a packed output (bit N set means
-			// byte N matched).
-			//
-			// NOTE: See comment above on bitsetFirst.
-			out := s.newValue1(ssa.OpAMD64PMOVMSKB, types.Types[types.TUINT16], eq)
-
-			// g is only 64-bits so the upper 64-bits of the
-			// 128-bit register will be zero. If h2 is also zero,
-			// then we'll get matches on those bytes. Truncate the
-			// upper bits to ignore such matches.
-			ret := s.newValue1(ssa.OpZeroExt8to64, types.Types[types.TUINT64], out)
-
-			return ret
+			// byte N matched). All 16 bytes are control bytes,
+			// so there is nothing to truncate, and PMOVMSKB
+			// zeroes the upper bits of the output register.
+			return s.newValue1(ssa.OpAMD64PMOVMSKB, types.Types[types.TUINT16], eq)
 		},
 		sys.AMD64)
 
@@ -1402,11 +1351,7 @@ func initIntrinsics(cfg *intrinsicBuildConfig) {
 			// A deleted slot is  1111 1110
 			// A full slot is     0??? ????
 
-			g := args[0]
-
-			// Explicit copy to fp register. See
-			// https://go.dev/issue/70451.
-			gfp := s.newValue1(ssa.OpAMD64MOVQi2f, types.TypeInt128, g)
+			g := loadCtrlGroup(s, args[0])
 
 			if buildcfg.GOAMD64 >= 2 {
 				// "PSIGNB negates each data element of the
@@ -1422,7 +1367,7 @@ func initIntrinsics(cfg *intrinsicBuildConfig) {
 				// corresponding data element in the
 				// destination operand is set to zero" - Intel SDM
 				//
-				// If we pass the group control word as both
+				// If we pass the group control bytes as both
 				// arguments:
 				// - Full slots are unchanged.
 				// - Deleted slots are negated, becoming
@@ -1433,55 +1378,33 @@ func initIntrinsics(cfg *intrinsicBuildConfig) {
 				// The result is that only empty slots have the
 				// sign bit set. We then use PMOVMSKB to
 				// extract the sign bits.
-				sign := s.newValue2(ssa.OpAMD64PSIGNB, types.TypeInt128, gfp, gfp)
+				sign := s.newValue2(ssa.OpAMD64PSIGNB, types.TypeInt128, g, g)
 
 				// Construct a "byte mask": each output bit is
 				// equal to the sign bit each input byte. The
-				// sign bit is only set for empty or deleted
-				// slots.
+				// sign bit is only set for empty slots.
 				//
 				// This results in a packed output (bit N set
 				// means byte N matched).
-				//
-				// NOTE: See comment above on bitsetFirst.
-				ret := s.newValue1(ssa.OpAMD64PMOVMSKB, types.Types[types.TUINT16], sign)
-
-				// g is only 64-bits so the upper 64-bits of
-				// the 128-bit register will be zero. PSIGNB
-				// will keep all of these bytes zero, so no
-				// need to truncate.
-
-				return ret
+				return s.newValue1(ssa.OpAMD64PMOVMSKB, types.Types[types.TUINT16], sign)
 			}
 
 			// No PSIGNB, simply do byte equality with ctrlEmpty.
 
-			// Load ctrlEmpty into each byte of a control word.
-			var ctrlsEmpty uint64 = abi.SwissMapCtrlEmpty
-			e := s.constInt64(types.Types[types.TUINT64], int64(ctrlsEmpty))
-			// Explicit copy to fp register. See
-			// https://go.dev/issue/70451.
-			efp := s.newValue1(ssa.OpAMD64MOVQi2f, types.TypeInt128, e)
+			// Load ctrlEmpty into each byte of a register.
+			e := s.constInt64(types.Types[types.TUINT64], int64(abi.SwissMapCtrlEmpty&0xff))
+			efp := broadcastByte(s, e)
 
-			// Compare each byte of the control word with ctrlEmpty. Each
+			// Compare each control byte with ctrlEmpty. Each
 			// matching byte has every bit set.
-			eq := s.newValue2(ssa.OpAMD64PCMPEQB, types.TypeInt128, efp, gfp)
+			eq := s.newValue2(ssa.OpAMD64PCMPEQB, types.TypeInt128, efp, g)
 
 			// Construct a "byte mask": each output bit is equal to
 			// the sign bit each input byte.
 			//
 			// This results in a packed output (bit N set means
 			// byte N matched).
-			//
-			// NOTE: See comment above on bitsetFirst.
-			out := s.newValue1(ssa.OpAMD64PMOVMSKB, types.Types[types.TUINT16], eq)
-
-			// g is only 64-bits so the upper 64-bits of the
-			// 128-bit register will be zero. The upper 64-bits of
-			// efp are also zero, so we'll get matches on those
-			// bytes. Truncate the upper bits to ignore such
-			// matches.
-			return s.newValue1(ssa.OpZeroExt8to64, types.Types[types.TUINT64], out)
+			return s.newValue1(ssa.OpAMD64PMOVMSKB, types.Types[types.TUINT16], eq)
 		},
 		sys.AMD64)
 
@@ -1494,11 +1417,7 @@ func initIntrinsics(cfg *intrinsicBuildConfig) {
 			// A slot is empty or deleted iff bit 7 (sign bit) is
 			// set.
 
-			g := args[0]
-
-			// Explicit copy to fp register. See
-			// https://go.dev/issue/70451.
-			gfp := s.newValue1(ssa.OpAMD64MOVQi2f, types.TypeInt128, g)
+			g := loadCtrlGroup(s, args[0])
 
 			// Construct a "byte mask": each output bit is equal to
 			// the sign bit each input byte. The sign bit is only
@@ -1506,15 +1425,7 @@ func initIntrinsics(cfg *intrinsicBuildConfig) {
 			//
 			// This results in a packed output (bit N set means
 			// byte N matched).
-			//
-			// NOTE: See comment above on bitsetFirst.
-			ret := s.newValue1(ssa.OpAMD64PMOVMSKB, types.Types[types.TUINT16], gfp)
-
-			// g is only 64-bits so the upper 64-bits of the
-			// 128-bit register will be zero. Zero will never match
-			// ctrlEmpty or ctrlDeleted, so no need to truncate.
-
-			return ret
+			return s.newValue1(ssa.OpAMD64PMOVMSKB, types.Types[types.TUINT16], g)
 		},
 		sys.AMD64)
 
@@ -1526,11 +1437,7 @@ func initIntrinsics(cfg *intrinsicBuildConfig) {
 			//
 			// A slot is full iff bit 7 (sign bit) is unset.
 
-			g := args[0]
-
-			// Explicit copy to fp register. See
-			// https://go.dev/issue/70451.
-			gfp := s.newValue1(ssa.OpAMD64MOVQi2f, types.TypeInt128, g)
+			g := loadCtrlGroup(s, args[0])
 
 			// Construct a "byte mask": each output bit is equal to
 			// the sign bit each input byte. The sign bit is only
@@ -1538,17 +1445,14 @@ func initIntrinsics(cfg *intrinsicBuildConfig) {
 			//
 			// This results in a packed output (bit N set means
 			// byte N matched).
-			//
-			// NOTE: See comment above on bitsetFirst.
-			mask := s.newValue1(ssa.OpAMD64PMOVMSKB, types.Types[types.TUINT16], gfp)
+			mask := s.newValue1(ssa.OpAMD64PMOVMSKB, types.Types[types.TUINT16], g)
 
 			// Invert the mask to set the bits for the full slots.
 			out := s.newValue1(ssa.OpCom16, types.Types[types.TUINT16], mask)
 
-			// g is only 64-bits so the upper 64-bits of the
-			// 128-bit register will be zero, with bit 7 unset.
-			// Truncate the upper bits to ignore these.
-			return s.newValue1(ssa.OpZeroExt8to64, types.Types[types.TUINT64], out)
+			// The inversion also sets the bits above the 16
+			// slots. Truncate them.
+			return s.newValue1(ssa.OpZeroExt16to64, types.Types[types.TUINT64], out)
 		},
 		sys.AMD64)
 }
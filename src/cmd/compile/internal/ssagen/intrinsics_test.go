@@ -88,10 +88,6 @@ var wantIntrinsics = map[testIntrinsicKey]struct{}{
 	{"amd64", "internal/runtime/atomic", "Xchgint32"}:                  struct{}{},
 	{"amd64", "internal/runtime/atomic", "Xchgint64"}:                  struct{}{},
 	{"amd64", "internal/runtime/atomic", "Xchguintptr"}:                struct{}{},
-	{"amd64", "internal/runtime/maps", "bitsetFirst"}:                  struct{}{},
-	{"amd64", "internal/runtime/maps", "bitsetRemoveBelow"}:            struct{}{},
-	{"amd64", "internal/runtime/maps", "bitsetLowestSet"}:              struct{}{},
-	{"amd64", "internal/runtime/maps", "bitsetShiftOutLowest"}:         struct{}{},
 	{"amd64", "internal/runtime/maps", "ctrlGroupMatchH2"}:             struct{}{},
 	{"amd64", "internal/runtime/maps", "ctrlGroupMatchEmpty"}:          struct{}{},
 	{"amd64", "internal/runtime/maps", "ctrlGroupMatchEmptyOrDeleted"}: struct{}{},
@@ -0,0 +1,62 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package test
+
+import (
+	"internal/buildcfg"
+	"internal/testenv"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+// TestMapRangeKeysOnly checks that range loops over a map that don't use
+// the element start the iteration with mapiterinitkeys, and those that
+// do with mapiterinit.
+func TestMapRangeKeysOnly(t *testing.T) {
+	if !buildcfg.Experiment.SwissMap {
+		t.Skip("keys-only iteration requires swiss maps")
+	}
+	testenv.MustHaveGoBuild(t)
+	t.Parallel()
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "x.go")
+	const prog = `
+package x
+
+func none(m map[int]string) (n int) { for range m { n++ }; return }
+func keys(m map[int]string) (n int) { for k := range m { n += k }; return }
+func blank(m map[int]string) (n int) { for k, _ := range m { n += k }; return }
+func elems(m map[int]string) (n int) { for _, v := range m { n += len(v) }; return }
+func both(m map[int]string) (n int) { for k, v := range m { n += k + len(v) }; return }
+`
+	if err := os.WriteFile(src, []byte(prog), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := testenv.Command(t, testenv.GoToolPath(t), "tool", "compile", "-p=x", "-S", "-o", filepath.Join(dir, "x.o"), src)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("%v\n%s", err, out)
+	}
+	calls := firstCalls(out, mapIterInitRE)
+	for fn, want := range map[string]string{
+		"none":  "mapiterinitkeys",
+		"keys":  "mapiterinitkeys",
+		"blank": "mapiterinitkeys",
+		"elems": "mapiterinit",
+		"both":  "mapiterinit",
+	} {
+		if got, ok := calls[fn]; !ok {
+			t.Errorf("%s calls no map iterator init function", fn)
+		} else if got != want {
+			t.Errorf("%s calls runtime.%s, want runtime.%s", fn, got, want)
+		}
+	}
+}
+
+var mapIterInitRE = regexp.MustCompile(`CALL\s+runtime\.(mapiterinit\w*)\(SB\)`)
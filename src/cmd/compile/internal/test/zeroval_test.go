@@ -0,0 +1,81 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package test
+
+import (
+	"fmt"
+	"internal/abi"
+	"internal/testenv"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// TestMapZeroValThreshold checks that the compiler calls the map
+// lookup functions that may return abi.ZeroVal exactly for elements
+// of at most abi.ZeroValSize bytes, and the _fat variants, which take
+// a zero value from the caller, for larger ones.
+func TestMapZeroValThreshold(t *testing.T) {
+	testenv.MustHaveGoBuild(t)
+	t.Parallel()
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "x.go")
+	prog := fmt.Sprintf(`
+package x
+
+type fits [%d]byte
+type big [%d]byte
+
+func fits1(m map[string]fits, k string) fits { return m[k] }
+func fits2(m map[string]fits, k string) (fits, bool) { v, ok := m[k]; return v, ok }
+func big1(m map[string]big, k string) big { return m[k] }
+func big2(m map[string]big, k string) (big, bool) { v, ok := m[k]; return v, ok }
+`, abi.ZeroValSize, abi.ZeroValSize+1)
+	if err := os.WriteFile(src, []byte(prog), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := testenv.Command(t, testenv.GoToolPath(t), "tool", "compile", "-p=x", "-S", "-o", filepath.Join(dir, "x.o"), src)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("%v\n%s", err, out)
+	}
+	calls := firstCalls(out, mapAccessRE)
+	for fn, fat := range map[string]bool{"fits1": false, "fits2": false, "big1": true, "big2": true} {
+		call, ok := calls[fn]
+		if !ok {
+			t.Errorf("%s calls no map lookup function", fn)
+			continue
+		}
+		if isFat := strings.HasSuffix(call, "_fat"); isFat != fat {
+			t.Errorf("%s calls runtime.%s, want _fat variant: %v", fn, call, fat)
+		}
+	}
+}
+
+var (
+	textRE      = regexp.MustCompile(`(?m)^x\.(\w+) STEXT`)
+	mapAccessRE = regexp.MustCompile(`CALL\s+runtime\.(mapaccess[12]\w*)\(SB\)`)
+)
+
+// firstCalls returns, for each function in the -S output out, the first
+// call in it that matches call, whose first submatch is the callee.
+func firstCalls(out []byte, call *regexp.Regexp) map[string]string {
+	calls := make(map[string]string)
+	texts := textRE.FindAllSubmatchIndex(out, -1)
+	for i, m := range texts {
+		end := len(out)
+		if i+1 < len(texts) {
+			end = texts[i+1][0]
+		}
+		if c := call.FindSubmatch(out[m[1]:end]); c != nil {
+			calls[string(out[m[2]:m[3]])] = string(c[1])
+		}
+	}
+	return calls
+}
@@ -322,6 +322,7 @@ func walkMakeSwissMap(n *ir.MakeExpr, init *ir.Nodes) ir.Node {
 	t := n.Type()
 	mapType := reflectdata.SwissMapType()
 	hint := n.Len
+	groupSlots := reflectdata.SwissMapGroupSlots()
 
 	// var m *Map
 	var m ir.Node
@@ -336,9 +337,10 @@ func walkMakeSwissMap(n *ir.MakeExpr, init *ir.Nodes) ir.Node {
 		// is not larger than SwissMapGroupSlots. In case hint is
 		// larger, runtime.makemap will allocate on the heap.
 		// Maximum key and elem size is 128 bytes, larger objects
-		// are stored with an indirection. So max bucket size is 2048+eps.
+		// are stored with an indirection. So max bucket size is
+		// 4096+eps with 16 slots per group.
 		if !ir.IsConst(hint, constant.Int) ||
-			constant.Compare(hint.Val(), token.LEQ, constant.MakeInt64(abi.SwissMapGroupSlots)) {
+			constant.Compare(hint.Val(), token.LEQ, constant.MakeInt64(groupSlots)) {
 
 			// In case hint is larger than SwissMapGroupSlots
 			// runtime.makemap will allocate on the heap, see
@@ -347,11 +349,13 @@ func walkMakeSwissMap(n *ir.MakeExpr, init *ir.Nodes) ir.Node {
 			// if hint <= abi.SwissMapGroupSlots {
 			//     var gv group
 			//     g = &gv
-			//     g.ctrl = abi.SwissMapCtrlEmpty
+			//     for i := range g.ctrl {
+			//         g.ctrl[i] = abi.SwissMapCtrlEmpty
+			//     }
 			//     m.dirPtr = g
 			// }
 
-			nif := ir.NewIfStmt(base.Pos, ir.NewBinaryExpr(base.Pos, ir.OLE, hint, ir.NewInt(base.Pos, abi.SwissMapGroupSlots)), nil, nil)
+			nif := ir.NewIfStmt(base.Pos, ir.NewBinaryExpr(base.Pos, ir.OLE, hint, ir.NewInt(base.Pos, groupSlots)), nil, nil)
 			nif.Likely = true
 
 			groupType := reflectdata.SwissMapGroupType(t)
@@ -364,10 +368,13 @@ func walkMakeSwissMap(n *ir.MakeExpr, init *ir.Nodes) ir.Node {
 			// makes conversion to uint64 upset.
 			empty := ir.NewBasicLit(base.Pos, types.UntypedInt, constant.MakeUint64(abi.SwissMapCtrlEmpty))
 
-			// g.ctrl = abi.SwissMapCtrlEmpty
-			csym := groupType.Field(0).Sym // g.ctrl see reflectdata/map_swiss.go
-			ca := ir.NewAssignStmt(base.Pos, ir.NewSelectorExpr(base.Pos, ir.ODOT, g, csym), empty)
-			nif.Body.Append(ca)
+			// g.ctrl[i] = abi.SwissMapCtrlEmpty
+			cfield := reflectdata.SwissMapGroupField(groupType, "ctrl") // g.ctrl see reflectdata/map_swiss.go
+			csym := cfield.Sym
+			for i := range cfield.Type.NumElem() {
+				ctrl := ir.NewIndexExpr(base.Pos, ir.NewSelectorExpr(base.Pos, ir.ODOT, g, csym), ir.NewInt(base.Pos, i))
+				nif.Body.Append(ir.NewAssignStmt(base.Pos, ctrl, empty))
+			}
 
 			// m.dirPtr = g
 			dsym := mapType.Field(2).Sym // m.dirPtr see reflectdata/map_swiss.go
@@ -377,7 +384,7 @@ func walkMakeSwissMap(n *ir.MakeExpr, init *ir.Nodes) ir.Node {
 		}
 	}
 
-	if ir.IsConst(hint, constant.Int) && constant.Compare(hint.Val(), token.LEQ, constant.MakeInt64(abi.SwissMapGroupSlots)) {
+	if ir.IsConst(hint, constant.Int) && constant.Compare(hint.Val(), token.LEQ, constant.MakeInt64(groupSlots)) {
 		// Handling make(map[any]any) and
 		// make(map[any]any, hint) where hint <= abi.SwissMapGroupSlots
 		// specially allows for faster map initialization and
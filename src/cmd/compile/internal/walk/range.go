@@ -252,7 +252,13 @@ func walkRange(nrange *ir.RangeStmt) ir.Node {
 			elemsym = th.Field(1).Sym // ditto
 		}
 
-		fn := typecheck.LookupRuntime("mapiterinit", t.Key(), t.Elem(), th)
+		iterinit := "mapiterinit"
+		if buildcfg.Experiment.SwissMap && v2 == nil {
+			// The element is unused, so the iteration need not
+			// look it up.
+			iterinit = "mapiterinitkeys"
+		}
+		fn := typecheck.LookupRuntime(iterinit, t.Key(), t.Elem(), th)
 		init = append(init, mkcallstmt1(fn, reflectdata.RangeMapRType(base.Pos, nrange), ha, typecheck.NodAddr(hit)))
 		nfor.Cond = ir.NewBinaryExpr(base.Pos, ir.ONE, ir.NewSelectorExpr(base.Pos, ir.ODOT, hit, keysym), typecheck.NodNil())
 
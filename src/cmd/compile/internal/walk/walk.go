@@ -184,6 +184,14 @@ var mapaccess2 = mkmapnames("mapaccess2", "")
 var mapassign = mkmapnames("mapassign", "ptr")
 var mapdelete = mkmapnames("mapdelete", "")
 
+// mapElemFitsZeroVal reports whether the elements of map type t fit
+// in abi.ZeroVal, which the runtime's lookup functions return for
+// missing keys. Lookups of larger elements must call the _fat
+// variants, passing a zero value of the element type.
+func mapElemFitsZeroVal(t *types.Type) bool {
+	return t.Elem().Size() <= abi.ZeroValSize
+}
+
 func mapfast(t *types.Type) int {
 	if buildcfg.Experiment.SwissMap {
 		return mapfastSwiss(t)
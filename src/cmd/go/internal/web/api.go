@@ -57,7 +57,7 @@ func (e *HTTPError) Error() string {
 	if eErr := e.Err; eErr != nil {
 		if pErr, ok := e.Err.(*fs.PathError); ok {
 			if u, err := url.Parse(e.URL); err == nil {
-				if fp, err := urlToFilePath(u); err == nil && pErr.Path == fp {
+				if fp, err := u.ToFilePath(); err == nil && pErr.Path == fp {
 					// Remove the redundant copy of the path.
 					eErr = pErr.Err
 				}
@@ -7,6 +7,7 @@ package web
 import (
 	"errors"
 	"io/fs"
+	"net/url"
 	"os"
 	"path/filepath"
 	"testing"
@@ -28,7 +29,7 @@ func TestGetFileURL(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	u, err := urlFromFilePath(f.Name())
+	u, err := url.FromFilePath(f.Name())
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -48,7 +49,7 @@ func TestGetNonexistentFile(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	u, err := urlFromFilePath(path)
+	u, err := url.FromFilePath(path)
 	if err != nil {
 		t.Fatal(err)
 	}
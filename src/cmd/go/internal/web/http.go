@@ -292,7 +292,7 @@ func get(security SecurityMode, url *urlpkg.URL) (*Response, error) {
 }
 
 func getFile(u *urlpkg.URL) (*Response, error) {
-	path, err := urlToFilePath(u)
+	path, err := u.ToFilePath()
 	if err != nil {
 		return nil, err
 	}
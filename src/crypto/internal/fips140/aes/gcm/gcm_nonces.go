@@ -42,6 +42,17 @@ func SealWithRandomNonce(g *GCM, nonce, out, plaintext, additionalData []byte) {
 	seal(out, g, nonce, plaintext, additionalData)
 }
 
+// nonceFixedField returns the 32-bit fixed field of a 96-bit nonce that
+// is made of a fixed field followed by a 64-bit counter.
+func nonceFixedField(nonce []byte) uint32 {
+	return byteorder.BEUint32(nonce[:gcmStandardNonceSize-8])
+}
+
+// nonceCounter returns the 64-bit counter at the end of a 96-bit nonce.
+func nonceCounter(nonce []byte) uint64 {
+	return byteorder.BEUint64(nonce[gcmStandardNonceSize-8:])
+}
+
 // NewGCMWithCounterNonce returns a new AEAD that works like GCM, but enforces
 // the construction of deterministic nonces. The nonce must be 96 bits, the
 // first 32 bits must be an encoding of the module name, and the last 64 bits
@@ -73,14 +84,14 @@ func (g *GCMWithCounterNonce) Seal(dst, nonce, plaintext, data []byte) []byte {
 		panic("crypto/cipher: incorrect nonce length given to GCM")
 	}
 
-	counter := byteorder.BEUint64(nonce[len(nonce)-8:])
+	counter := nonceCounter(nonce)
 	if !g.ready {
 		// The first invocation sets the fixed name encoding and start counter.
 		g.ready = true
 		g.start = counter
-		g.fixedName = byteorder.BEUint32(nonce[:4])
+		g.fixedName = nonceFixedField(nonce)
 	}
-	if g.fixedName != byteorder.BEUint32(nonce[:4]) {
+	if g.fixedName != nonceFixedField(nonce) {
 		panic("crypto/cipher: incorrect module name given to GCMWithCounterNonce")
 	}
 	counter -= g.start
@@ -130,7 +141,7 @@ func (g *GCMForTLS12) Seal(dst, nonce, plaintext, data []byte) []byte {
 		panic("crypto/cipher: incorrect nonce length given to GCM")
 	}
 
-	counter := byteorder.BEUint64(nonce[len(nonce)-8:])
+	counter := nonceCounter(nonce)
 
 	// Ensure the counter is monotonically increasing.
 	if counter == math.MaxUint64 {
@@ -176,7 +187,7 @@ func (g *GCMForTLS13) Seal(dst, nonce, plaintext, data []byte) []byte {
 		panic("crypto/cipher: incorrect nonce length given to GCM")
 	}
 
-	counter := byteorder.BEUint64(nonce[len(nonce)-8:])
+	counter := nonceCounter(nonce)
 	if !g.ready {
 		// In the first call, the counter is zero, so we learn the XOR mask.
 		g.ready = true
@@ -230,7 +241,7 @@ func (g *GCMForSSH) Seal(dst, nonce, plaintext, data []byte) []byte {
 		panic("crypto/cipher: incorrect nonce length given to GCM")
 	}
 
-	counter := byteorder.BEUint64(nonce[len(nonce)-8:])
+	counter := nonceCounter(nonce)
 	if !g.ready {
 		// In the first call we learn the start value.
 		g.ready = true
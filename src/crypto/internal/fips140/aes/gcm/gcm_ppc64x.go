@@ -166,22 +166,8 @@ func open(out []byte, g *GCM, nonce, ciphertext, data []byte) error {
 }
 
 func gcmLengths(len0, len1 uint64) [16]byte {
-	return [16]byte{
-		byte(len0 >> 56),
-		byte(len0 >> 48),
-		byte(len0 >> 40),
-		byte(len0 >> 32),
-		byte(len0 >> 24),
-		byte(len0 >> 16),
-		byte(len0 >> 8),
-		byte(len0),
-		byte(len1 >> 56),
-		byte(len1 >> 48),
-		byte(len1 >> 40),
-		byte(len1 >> 32),
-		byte(len1 >> 24),
-		byte(len1 >> 16),
-		byte(len1 >> 8),
-		byte(len1),
-	}
+	var b [16]byte
+	byteorder.BEPutUint64(b[:8], len0)
+	byteorder.BEPutUint64(b[8:], len1)
+	return b
 }
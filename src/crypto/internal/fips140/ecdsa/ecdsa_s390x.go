@@ -24,7 +24,9 @@ import (
 //go:noescape
 func kdsa(fc uint64, params *[4096]byte) (errn uint64)
 
-var supportsKDSA = cpu.S390XHasECDSA
+// KDSA is part of message-security-assist extension 9. Checking for the
+// extension as well lets GODEBUG=cpu.msa9=off disable this path.
+var supportsKDSA = cpu.S390XHasMSA9 && cpu.S390XHasECDSA
 
 func init() {
 	// CP Assist for Cryptographic Functions (CPACF)
@@ -25,6 +25,7 @@ var S390XHasAESCTR = cpu.S390X.HasAESCTR
 var S390XHasAESGCM = cpu.S390X.HasAESGCM
 var S390XHasECDSA = cpu.S390X.HasECDSA
 var S390XHasGHASH = cpu.S390X.HasGHASH
+var S390XHasMSA9 = cpu.S390X.HasMSA9
 var S390XHasSHA256 = cpu.S390X.HasSHA256
 var S390XHasSHA3 = cpu.S390X.HasSHA3
 var S390XHasSHA512 = cpu.S390X.HasSHA512
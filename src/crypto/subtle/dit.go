@@ -7,6 +7,7 @@ package subtle
 import (
 	"internal/runtime/sys"
 	"runtime"
+	_ "unsafe" // for linkname
 )
 
 // WithDataIndependentTiming enables architecture specific features which ensure
@@ -18,7 +19,9 @@ import (
 // variable-time code constant-time.
 //
 // WithDataIndependentTiming may lock the current goroutine to the OS thread for
-// the duration of f. Calls to WithDataIndependentTiming may be nested.
+// the duration of f. With GODEBUG=ditgoroutine=1 the runtime instead tracks the
+// mode for the goroutine and applies it to whichever thread runs it.
+// Calls to WithDataIndependentTiming may be nested.
 //
 // On Arm64 processors with FEAT_DIT, WithDataIndependentTiming enables
 // PSTATE.DIT. See https://developer.arm.com/documentation/ka005181/1-0/?lang=en.
@@ -33,6 +36,12 @@ func WithDataIndependentTiming(f func()) {
 		return
 	}
 
+	if old, ok := setDITWanted(true); ok {
+		defer setDITWanted(old)
+		f()
+		return
+	}
+
 	runtime.LockOSThread()
 	defer runtime.UnlockOSThread()
 
@@ -48,3 +57,8 @@ func WithDataIndependentTiming(f func()) {
 
 	f()
 }
+
+// setDITWanted is provided by package runtime. See the comment there.
+//
+//go:linkname setDITWanted
+func setDITWanted(want bool) (old, ok bool)
@@ -7,6 +7,11 @@ package subtle
 import (
 	"internal/cpu"
 	"internal/runtime/sys"
+	"internal/testenv"
+	"os"
+	"runtime"
+	"sync"
+	"sync/atomic"
 	"testing"
 )
 
@@ -63,3 +68,73 @@ func TestDITPanic(t *testing.T) {
 		panic("bad")
 	})
 }
+
+func TestDITGoroutineMigration(t *testing.T) {
+	if !cpu.ARM64.HasDIT {
+		t.Skip("CPU does not support DIT")
+	}
+	testenv.MustHaveExec(t)
+
+	cmd := testenv.Command(t, testenv.Executable(t), "-test.run=^TestDITGoroutineMigrationChild$")
+	cmd.Env = append(cmd.Environ(), "GO_WANT_DIT_CHILD=1", "GODEBUG=ditgoroutine=1")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("%v\n%s", err, out)
+	}
+}
+
+func TestDITGoroutineMigrationChild(t *testing.T) {
+	if os.Getenv("GO_WANT_DIT_CHILD") != "1" {
+		t.Skip("not running as child process")
+	}
+	if sys.DITEnabled() {
+		t.Fatal("dit enabled on entry")
+	}
+
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+
+	// Keep other goroutines busy so the DIT goroutine gets preempted
+	// and moved between threads, and check that none of them ever
+	// observes the mode.
+	var leaked atomic.Bool
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				if sys.DITEnabled() {
+					leaked.Store(true)
+				}
+				runtime.Gosched()
+			}
+		}()
+	}
+
+	WithDataIndependentTiming(func() {
+		for i := 0; i < 1000; i++ {
+			if i%100 == 0 {
+				runtime.GC()
+			}
+			runtime.Gosched()
+			if !sys.DITEnabled() {
+				t.Fatalf("dit not enabled after reschedule %d", i)
+			}
+		}
+	})
+	close(stop)
+	wg.Wait()
+
+	if sys.DITEnabled() {
+		t.Error("dit still enabled after WithDataIndependentTiming returned")
+	}
+	if leaked.Load() {
+		t.Error("dit enabled on a goroutine that did not request it")
+	}
+}
@@ -11,11 +11,16 @@ import (
 // Map constants common to several packages
 // runtime/runtime-gdb.py:MapTypePrinter contains its own copy
 const (
-	// Number of bits in the group.slot count.
-	SwissMapGroupSlotsBits = 3
+	// Number of slots in a group. SwissMapGroupSlotsBits is defined
+	// per architecture: groups have 16 slots on amd64, where the
+	// control bytes are matched with SIMD instructions, and 8 slots
+	// elsewhere. cmd/compile/internal/reflectdata.SwissMapGroupSlots
+	// contains its own copy.
+	SwissMapGroupSlots = 1 << SwissMapGroupSlotsBits
 
-	// Number of slots in a group.
-	SwissMapGroupSlots = 1 << SwissMapGroupSlotsBits // 8
+	// Number of uint64 control words in a group, each holding the
+	// control bytes of 8 slots.
+	SwissMapCtrlWords = SwissMapGroupSlots / 8
 
 	// Maximum key or elem size to keep inline (instead of mallocing per element).
 	// Must fit in a uint8.
@@ -25,7 +30,7 @@ const (
 	ctrlEmpty = 0b10000000
 	bitsetLSB = 0x0101010101010101
 
-	// Value of control word with all empty slots.
+	// Value of a control word with all empty slots.
 	SwissMapCtrlEmpty = bitsetLSB * uint64(ctrlEmpty)
 )
 
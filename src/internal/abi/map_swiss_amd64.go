@@ -0,0 +1,11 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package abi
+
+// Number of bits in the group.slot count.
+//
+// SSE2 is always available on amd64, so the 16 control bytes of a
+// group are matched at once.
+const SwissMapGroupSlotsBits = 4 // 16 slots
@@ -0,0 +1,10 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !amd64
+
+package abi
+
+// Number of bits in the group.slot count.
+const SwissMapGroupSlotsBits = 3 // 8 slots
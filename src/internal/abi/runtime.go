@@ -4,5 +4,18 @@
 
 package abi
 
-// ZeroValSize is the size in bytes of runtime.zeroVal.
+import _ "unsafe" // for go:linkname
+
+// ZeroValSize is the size in bytes of ZeroVal. The compiler passes
+// its own zero value to the mapaccess*_fat runtime functions for map
+// elements larger than this, and calls the other lookup functions,
+// which may return &ZeroVal[0], only for elements that fit.
 const ZeroValSize = 1024
+
+// ZeroVal is a block of zero bytes, shared by the runtime, the map
+// implementation and reflect, for zero values of types of at most
+// ZeroValSize bytes. It must not be written. Its symbol is
+// runtime.zeroVal, which some packages refer to by linkname.
+//
+//go:linkname ZeroVal runtime.zeroVal
+var ZeroVal [ZeroValSize]byte
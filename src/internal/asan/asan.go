@@ -17,3 +17,9 @@ func Read(addr unsafe.Pointer, len uintptr)
 
 //go:linkname Write runtime.asanwrite
 func Write(addr unsafe.Pointer, len uintptr)
+
+//go:linkname Poison runtime.asanpoison
+func Poison(addr unsafe.Pointer, len uintptr)
+
+//go:linkname Unpoison runtime.asanunpoison
+func Unpoison(addr unsafe.Pointer, len uintptr)
@@ -15,3 +15,7 @@ const Enabled = false
 func Read(addr unsafe.Pointer, len uintptr) {}
 
 func Write(addr unsafe.Pointer, len uintptr) {}
+
+func Poison(addr unsafe.Pointer, len uintptr) {}
+
+func Unpoison(addr unsafe.Pointer, len uintptr) {}
@@ -18,6 +18,8 @@ const (
 	offsetS390xHasVX = unsafe.Offsetof(cpu.S390X.HasVX)
 
 	offsetPPC64HasPOWER9 = unsafe.Offsetof(cpu.PPC64.IsPOWER9)
+
+	offsetRISCV64HasV = unsafe.Offsetof(cpu.RISCV64.HasV)
 )
 
 // MaxLen is the maximum length of the string to be searched for (argument b) in Index.
@@ -74,6 +74,9 @@ TEXT indexByteBig<>(SB),NOSPLIT|NOFRAME,$0
 	// On exit
 	// X10 = index of first instance of sought byte, if found, or -1 otherwise
 
+	MOVBU	internal∕cpu·RISCV64+const_offsetRISCV64HasV(SB), X5
+	BNEZ	X5, vector
+
 	// Process the first few bytes until we get to an 8 byte boundary
 	// No need to check for end here as we have at least 16 bytes in
 	// the buffer.
@@ -137,3 +140,20 @@ found:
 notfound:
 	MOV	$-1, X10
 	RET
+
+vector:
+	// The assembler does not yet encode vector instructions,
+	// so they are spelled out as WORDs.
+	SUB	X10, X11, X5		// X5 = bytes left
+	WORD	$0x0c32f357		// VSETVLI X5, E8, M8, TA, MA, X6
+	WORD	$0x02050407		// VLE8V (X10), V8
+	WORD	$0x62864057		// VMSEQVX X12, V8, V0
+	WORD	$0x4208a3d7		// VFIRSTM V0, X7
+	BGEZ	X7, vectorfound
+	ADD	X6, X10
+	BNE	X10, X11, vector
+	JMP	notfound
+
+vectorfound:
+	ADD	X7, X10
+	JMP	found
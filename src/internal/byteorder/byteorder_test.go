@@ -0,0 +1,157 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package byteorder_test
+
+import (
+	"bytes"
+	"fmt"
+	. "internal/byteorder"
+	"testing"
+)
+
+// An order is the set of functions for one byte order and width, with
+// the values widened to uint64.
+type order struct {
+	name   string
+	size   int
+	big    bool
+	get    func([]byte) uint64
+	put    func([]byte, uint64)
+	append func([]byte, uint64) []byte
+}
+
+var orders = []order{
+	{"LE16", 2, false,
+		func(b []byte) uint64 { return uint64(LEUint16(b)) },
+		func(b []byte, v uint64) { LEPutUint16(b, uint16(v)) },
+		func(b []byte, v uint64) []byte { return LEAppendUint16(b, uint16(v)) }},
+	{"LE32", 4, false,
+		func(b []byte) uint64 { return uint64(LEUint32(b)) },
+		func(b []byte, v uint64) { LEPutUint32(b, uint32(v)) },
+		func(b []byte, v uint64) []byte { return LEAppendUint32(b, uint32(v)) }},
+	{"LE48", 6, false, LEUint48, LEPutUint48, LEAppendUint48},
+	{"LE64", 8, false, LEUint64, LEPutUint64, LEAppendUint64},
+	{"BE16", 2, true,
+		func(b []byte) uint64 { return uint64(BEUint16(b)) },
+		func(b []byte, v uint64) { BEPutUint16(b, uint16(v)) },
+		func(b []byte, v uint64) []byte { return BEAppendUint16(b, uint16(v)) }},
+	{"BE32", 4, true,
+		func(b []byte) uint64 { return uint64(BEUint32(b)) },
+		func(b []byte, v uint64) { BEPutUint32(b, uint32(v)) },
+		func(b []byte, v uint64) []byte { return BEAppendUint32(b, uint32(v)) }},
+	{"BE48", 6, true, BEUint48, BEPutUint48, BEAppendUint48},
+	{"BE64", 8, true, BEUint64, BEPutUint64, BEAppendUint64},
+}
+
+// encode returns the encoding of the low o.size bytes of v.
+func (o order) encode(v uint64) []byte {
+	b := make([]byte, o.size)
+	for i := range b {
+		shift := 8 * i
+		if o.big {
+			shift = 8 * (o.size - 1 - i)
+		}
+		b[i] = byte(v >> shift)
+	}
+	return b
+}
+
+var values = []uint64{
+	0,
+	1,
+	0x80,
+	0xff,
+	0x0102030405060708,
+	0x7fff,
+	0x8000,
+	0xffff,
+	0x7fffffff,
+	0x80000000,
+	0xffffffff,
+	0x7fffffffffff,
+	0x800000000000,
+	0xffffffffffff,
+	0x1000000000000,
+	0x7fffffffffffffff,
+	0x8000000000000000,
+	0xffffffffffffffff,
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, o := range orders {
+		mask := uint64(1)<<(8*o.size) - 1
+		if o.size == 8 {
+			mask = ^uint64(0)
+		}
+		for _, v := range values {
+			want := o.encode(v)
+
+			b := make([]byte, o.size+1)
+			b[o.size] = 0xaa
+			o.put(b, v)
+			if !bytes.Equal(b[:o.size], want) || b[o.size] != 0xaa {
+				t.Errorf("%sPut(%#x) = %x, want %x followed by aa", o.name, v, b, want)
+			}
+			if got := o.get(b); got != v&mask {
+				t.Errorf("%s(%x) = %#x, want %#x", o.name, b[:o.size], got, v&mask)
+			}
+
+			prefix := []byte{1, 2, 3}
+			b = o.append(prefix[:len(prefix):len(prefix)], v)
+			if !bytes.Equal(b[:len(prefix)], prefix) || !bytes.Equal(b[len(prefix):], want) {
+				t.Errorf("%sAppend(%x, %#x) = %x, want %x%x", o.name, prefix, v, b, prefix, want)
+			}
+		}
+	}
+}
+
+// TestAllBytes checks that every byte value decodes at every position.
+func TestAllBytes(t *testing.T) {
+	for _, o := range orders {
+		for i := range o.size {
+			for c := range 256 {
+				b := make([]byte, o.size)
+				b[i] = byte(c)
+				shift := 8 * i
+				if o.big {
+					shift = 8 * (o.size - 1 - i)
+				}
+				want := uint64(c) << shift
+				if got := o.get(b); got != want {
+					t.Fatalf("%s(%x) = %#x, want %#x", o.name, b, got, want)
+				}
+				if got := o.append(nil, want); !bytes.Equal(got, b) {
+					t.Fatalf("%sAppend(nil, %#x) = %x, want %x", o.name, want, got, b)
+				}
+			}
+		}
+	}
+}
+
+func TestShortBuffer(t *testing.T) {
+	for _, o := range orders {
+		for n := range o.size {
+			b := make([]byte, n)
+			name := fmt.Sprintf("%s(len %d)", o.name, n)
+			mustPanic(t, name, func() { o.get(b) })
+			mustPanic(t, name+" put", func() { o.put(b, ^uint64(0)) })
+			for i, c := range b {
+				if c != 0 {
+					t.Errorf("%s put wrote %#x at %d before panicking", name, c, i)
+				}
+			}
+		}
+	}
+}
+
+func mustPanic(t *testing.T, name string, f func()) {
+	t.Helper()
+	defer func() {
+		if recover() == nil {
+			t.Errorf("%s did not panic", name)
+		}
+	}()
+	f()
+}
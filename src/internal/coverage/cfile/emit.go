@@ -15,6 +15,7 @@ import (
 	"internal/coverage/encodecounter"
 	"internal/coverage/encodemeta"
 	"internal/coverage/rtcov"
+	"internal/runtime/exithook"
 	"io"
 	"os"
 	"path/filepath"
@@ -121,9 +122,8 @@ func emitMetaData() {
 	ml, err := prepareForMetaEmit()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error: coverage meta-data prep failed: %v\n", err)
-		if os.Getenv("GOCOVERDEBUG") != "" {
-			panic("meta-data write failure")
-		}
+		emitFailed("meta-data write failure")
+		return
 	}
 	if len(ml) == 0 {
 		fmt.Fprintf(os.Stderr, "program not built with -cover\n")
@@ -138,9 +138,20 @@ func emitMetaData() {
 
 	if err := emitMetaDataToDirectory(goCoverDir, ml); err != nil {
 		fmt.Fprintf(os.Stderr, "error: coverage meta-data emit failed: %v\n", err)
-		if os.Getenv("GOCOVERDEBUG") != "" {
-			panic("meta-data write failure")
-		}
+		emitFailed("meta-data write failure")
+	}
+}
+
+// emitFailed is called after a failure to write coverage data has
+// been reported. With GOCOVERDEBUG set, the failure also makes the
+// program fail: when called from an exit hook, by forcing a non-zero
+// exit code, and otherwise by panicking.
+func emitFailed(msg string) {
+	if os.Getenv("GOCOVERDEBUG") == "" {
+		return
+	}
+	if !exithook.SetExitCode(1) {
+		panic(msg)
 	}
 }
 
@@ -278,9 +289,7 @@ func emitCounterData() {
 	}
 	if err := emitCounterDataToDirectory(goCoverDir); err != nil {
 		fmt.Fprintf(os.Stderr, "error: coverage counter data emit failed: %v\n", err)
-		if os.Getenv("GOCOVERDEBUG") != "" {
-			panic("counter-data write failure")
-		}
+		emitFailed("counter-data write failure")
 	}
 }
 
@@ -28,10 +28,12 @@ import "internal/runtime/exithook"
 func InitHook(istest bool) {
 	// Note: hooks are run in reverse registration order, so
 	// register the counter data hook before the meta-data hook
-	// (in the case where two hooks are needed).
-	exithook.Add(exithook.Hook{F: emitCounterData, RunOnFailure: true})
+	// (in the case where two hooks are needed). Both run in the
+	// first phase, so that coverage data is written before user
+	// exit hooks run.
+	exithook.Add(exithook.Hook{F: emitCounterData, RunOnFailure: true, Phase: exithook.PhaseFirst})
 	if istest {
-		exithook.Add(exithook.Hook{F: emitMetaData, RunOnFailure: true})
+		exithook.Add(exithook.Hook{F: emitMetaData, RunOnFailure: true, Phase: exithook.PhaseFirst})
 	} else {
 		emitMetaData()
 	}
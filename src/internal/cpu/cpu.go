@@ -4,6 +4,18 @@
 
 // Package cpu implements processor feature detection
 // used by the Go standard library.
+//
+// Detected features can be turned off with GODEBUG=cpu.<feature>=off.
+// Some features, typically instruction set extensions that need no
+// operating system support, can also be forced on with
+// GODEBUG=cpu.<feature>=on when detection misses them, for example in
+// virtual machines that mask CPUID bits. This is at the user's own risk:
+// if the processor does not in fact implement the feature, the program
+// will crash with an illegal instruction, possibly long after startup.
+// Features that need operating system support (such as wider vector
+// register state) or whose detection changes how the runtime itself
+// initializes (such as atomics on arm64) cannot be forced on.
+// cpu.all=on never forces features on.
 package cpu
 
 import _ "unsafe" // for linkname
@@ -16,39 +28,88 @@ var DebugOptions bool
 // CacheLinePad is used to pad structs to avoid false sharing.
 type CacheLinePad struct{ _ [CacheLinePadSize]byte }
 
-// CacheLineSize is the CPU's assumed cache line size.
-// There is currently no runtime detection of the real cache line size
-// so we use the constant per GOARCH CacheLinePadSize as an approximation.
+// CacheLineSize is the CPU's data cache line size.
+// It starts out as the constant per GOARCH CacheLinePadSize and is
+// replaced during Initialize by the size reported by the processor or
+// operating system, where that is available. Static padding must keep
+// using CacheLinePadSize; CacheLineSize is meant for memory that is
+// aligned at run time.
 var CacheLineSize uintptr = CacheLinePadSize
 
+// A Cache describes a data or unified cache of the processor.
+type Cache struct {
+	Level    int // 1 for the cache closest to the core
+	LineSize int // in bytes
+	Size     int // in bytes
+	SharedBy int // maximum number of logical processors sharing the cache
+}
+
+// CacheTopology lists the data and unified caches of the processor,
+// in order of increasing level. It is empty if the cache geometry
+// could not be detected.
+var CacheTopology []Cache
+
+// cacheLineSizeSet reports whether setCacheLineSize replaced the
+// default CacheLineSize.
+var cacheLineSizeSet bool
+
+// setCacheLineSize sets CacheLineSize to size if size is a plausible
+// cache line size, that is a power of two between 16 and 1024 bytes.
+func setCacheLineSize(size uintptr) {
+	if size < 16 || size > 1024 || size&(size-1) != 0 {
+		return
+	}
+	CacheLineSize = size
+	cacheLineSizeSet = true
+}
+
+// SetOSCacheLineSize sets CacheLineSize to size, the data cache line
+// size reported by the operating system, if Initialize could not get it
+// from the processor. A size of 0 means the operating system does not
+// report it either. It is called by the runtime after Initialize.
+func SetOSCacheLineSize(size uintptr) {
+	if !cacheLineSizeSet {
+		setCacheLineSize(size)
+	}
+}
+
 // The booleans in X86 contain the correspondingly named cpuid feature bit.
 // HasAVX and HasAVX2 are only set if the OS does support XMM and YMM registers
 // in addition to the cpuid feature bit being set.
+// The HasAMX bits are only set on amd64 if the OS supports the tile state,
+// and the tile instructions must not be used before EnableAMX returns true.
 // The struct is padded to avoid false sharing.
 var X86 struct {
-	_            CacheLinePad
-	HasAES       bool
-	HasADX       bool
-	HasAVX       bool
-	HasAVX2      bool
-	HasAVX512F   bool
-	HasAVX512BW  bool
-	HasAVX512VL  bool
-	HasBMI1      bool
-	HasBMI2      bool
-	HasERMS      bool
-	HasFSRM      bool
-	HasFMA       bool
-	HasOSXSAVE   bool
-	HasPCLMULQDQ bool
-	HasPOPCNT    bool
-	HasRDTSCP    bool
-	HasSHA       bool
-	HasSSE3      bool
-	HasSSSE3     bool
-	HasSSE41     bool
-	HasSSE42     bool
-	_            CacheLinePad
+	_                  CacheLinePad
+	HasAES             bool
+	HasADX             bool
+	HasAMXBF16         bool
+	HasAMXInt8         bool
+	HasAMXTile         bool
+	HasAVX             bool
+	HasAVX2            bool
+	HasAVX512F         bool
+	HasAVX512BW        bool
+	HasAVX512VL        bool
+	HasAVX512VNNI      bool
+	HasAVX512BF16      bool
+	HasAVX512FP16      bool
+	HasAVX512VPOPCNTDQ bool
+	HasBMI1            bool
+	HasBMI2            bool
+	HasERMS            bool
+	HasFSRM            bool
+	HasFMA             bool
+	HasOSXSAVE         bool
+	HasPCLMULQDQ       bool
+	HasPOPCNT          bool
+	HasRDTSCP          bool
+	HasSHA             bool
+	HasSSE3            bool
+	HasSSSE3           bool
+	HasSSE41           bool
+	HasSSE42           bool
+	_                  CacheLinePad
 }
 
 // The booleans in ARM contain the correspondingly named cpu feature bit.
@@ -62,20 +123,60 @@ var ARM struct {
 }
 
 // The booleans in ARM64 contain the correspondingly named cpu feature bit.
+// SVEVectorLength is the SVE vector length in bytes; it is only
+// meaningful if HasSVE is set. Microarch identifies the core, and
+// IsNeoverse is set for Neoverse cores unless turned off with
+// GODEBUG=cpu.isNeoverse=off.
 // The struct is padded to avoid false sharing.
 var ARM64 struct {
-	_          CacheLinePad
-	HasAES     bool
-	HasPMULL   bool
-	HasSHA1    bool
-	HasSHA2    bool
-	HasSHA512  bool
-	HasCRC32   bool
-	HasATOMICS bool
-	HasCPUID   bool
-	HasDIT     bool
-	IsNeoverse bool
-	_          CacheLinePad
+	_               CacheLinePad
+	HasAES          bool
+	HasPMULL        bool
+	HasSHA1         bool
+	HasSHA2         bool
+	HasSHA512       bool
+	HasSHA3         bool
+	HasSM3          bool
+	HasSM4          bool
+	HasCRC32        bool
+	HasATOMICS      bool
+	HasCPUID        bool
+	HasDIT          bool
+	HasSVE          bool
+	HasSVE2         bool
+	IsNeoverse      bool
+	Microarch       Microarch
+	SVEVectorLength int
+	_               CacheLinePad
+}
+
+// A Microarch identifies an arm64 core design, for tuning code to it.
+// Cores that are not recognized, or whose identity is not available
+// to user space, are reported as MicroarchUnknown.
+type Microarch uint8
+
+const (
+	MicroarchUnknown Microarch = iota
+	NeoverseN1
+	NeoverseV1
+	NeoverseN2
+	NeoverseV2
+	NeoverseN3
+	NeoverseV3
+	AppleM1
+	AppleM2
+	AppleM3
+	AppleM4
+)
+
+// IsNeoverse reports whether m is an Arm Neoverse core.
+func (m Microarch) IsNeoverse() bool {
+	return m >= NeoverseN1 && m <= NeoverseV3
+}
+
+// IsApple reports whether m is an Apple M-class core.
+func (m Microarch) IsApple() bool {
+	return m >= AppleM1 && m <= AppleM4
 }
 
 // The booleans in Loong64 contain the correspondingly named cpu feature bit.
@@ -83,6 +184,7 @@ var ARM64 struct {
 var Loong64 struct {
 	_         CacheLinePad
 	HasLSX    bool // support 128-bit vector extension
+	HasLASX   bool // support 256-bit vector extension
 	HasCRC32  bool // support CRC instruction
 	HasLAMCAS bool // support AMCAS[_DB].{B/H/W/D}
 	HasLAM_BH bool // support AM{SWAP/ADD}[_DB].{B/H} instruction
@@ -110,6 +212,20 @@ var PPC64 struct {
 	_         CacheLinePad
 }
 
+// The booleans in RISCV64 indicate the presence of the correspondingly named
+// RISC-V extensions. They are only set if the kernel reports that the
+// extension may be used in user space.
+// The struct is padded to avoid false sharing.
+var RISCV64 struct {
+	_              CacheLinePad
+	HasV           bool // vector extension
+	HasZba         bool // address generation
+	HasZbb         bool // basic bit manipulation
+	HasZbs         bool // single-bit instructions
+	CacheBlockSize int  // size in bytes of the blocks zeroed by CBO.ZERO, 0 if unknown
+	_              CacheLinePad
+}
+
 var S390X struct {
 	_         CacheLinePad
 	HasZARCH  bool // z architecture mode is active [mandatory]
@@ -119,6 +235,7 @@ var S390X struct {
 	HasDFP    bool // decimal floating point
 	HasETF3EH bool // ETF-3 enhanced
 	HasMSA    bool // message security assist (CPACF)
+	HasMSA9   bool // message security assist extension 9
 	HasAES    bool // KM-AES{128,192,256} functions
 	HasAESCBC bool // KMC-AES{128,192,256} functions
 	HasAESCTR bool // KMCTR-AES{128,192,256} functions
@@ -130,6 +247,8 @@ var S390X struct {
 	HasSHA3   bool // K{I,L}MD-SHA3-{224,256,384,512} and K{I,L}MD-SHAKE-{128,256} functions
 	HasVX     bool // vector facility. Note: the runtime sets this when it processes auxv records.
 	HasVXE    bool // vector-enhancements facility 1
+	HasVXE2   bool // vector-enhancements facility 2
+	HasNNPA   bool // neural-network-processing-assist facility
 	HasKDSA   bool // elliptic curve functions
 	HasECDSA  bool // NIST curves
 	HasEDDSA  bool // Edwards curves
@@ -143,6 +262,7 @@ var S390X struct {
 //go:linkname Loong64
 //go:linkname MIPS64X
 //go:linkname PPC64
+//go:linkname RISCV64
 //go:linkname S390X
 
 // Initialize examines the processor and sets the relevant variables above.
@@ -152,6 +272,8 @@ var S390X struct {
 func Initialize(env string) {
 	doinit()
 	processOptions(env)
+	afterOptions()
+	collectFeatures()
 }
 
 // options contains the cpu debug options that can be used in GODEBUG.
@@ -160,12 +282,20 @@ func Initialize(env string) {
 // (e.g. SSE2 on amd64).
 var options []option
 
+// baseline contains the features that are mandatory for the specific GOARCH
+// and its configured microarchitecture level and so are not in options
+// (e.g. POPCNT with GOAMD64=v2). They are only used to report features.
+var baseline []option
+
 // Option names should be lower case. e.g. avx instead of AVX.
 type option struct {
 	Name      string
 	Feature   *bool
+	Forceable bool // whether feature may be forced on without detected support
 	Specified bool // whether feature value was specified in GODEBUG
 	Enable    bool // whether feature should be enabled
+	Force     bool // whether feature was set to on by name in GODEBUG
+	Disabled  bool // whether feature was detected but turned off in GODEBUG
 }
 
 // processOptions enables or disables CPU feature values based on the parsed env string.
@@ -173,7 +303,9 @@ type option struct {
 // where feature names is one of the architecture specific list stored in the
 // cpu packages options variable and values are either 'on' or 'off'.
 // If env contains cpu.all=off then all cpu features referenced through the options
-// variable are disabled. Other feature names and values result in warning messages.
+// variable are disabled. Features that are not detected are only enabled if they
+// are named explicitly and marked Forceable, with a warning.
+// Other feature names and values result in warning messages.
 func processOptions(env string) {
 field:
 	for env != "" {
@@ -217,6 +349,7 @@ field:
 			if options[i].Name == key {
 				options[i].Specified = true
 				options[i].Enable = enable
+				options[i].Force = enable
 				continue field
 			}
 		}
@@ -224,20 +357,78 @@ field:
 		print("GODEBUG: unknown cpu feature \"", key, "\"\n")
 	}
 
-	for _, o := range options {
+	for i := range options {
+		o := &options[i]
 		if !o.Specified {
 			continue
 		}
 
 		if o.Enable && !*o.Feature {
-			print("GODEBUG: can not enable \"", o.Name, "\", missing CPU support\n")
-			continue
+			if !o.Forceable || !o.Force {
+				print("GODEBUG: can not enable \"", o.Name, "\", missing CPU support\n")
+				continue
+			}
+			print("GODEBUG: WARNING: forcing on \"", o.Name, "\" without detected CPU support; the program will crash if the CPU lacks it\n")
 		}
+		o.Disabled = !o.Enable && *o.Feature
 
 		*o.Feature = o.Enable
 	}
 }
 
+// A Feature describes the state of a CPU feature.
+type Feature struct {
+	Name      string // lower case name as used in GODEBUG, e.g. "avx2"
+	Enabled   bool   // whether the feature is detected and in use
+	ForcedOff bool   // whether the feature was detected but turned off in GODEBUG
+}
+
+// features is the result of collectFeatures.
+var features []Feature
+
+// collectFeatures records the final state of the baseline and optional
+// features for Features and Summary.
+func collectFeatures() {
+	features = make([]Feature, 0, len(baseline)+len(options))
+	for _, list := range [][]option{baseline, options} {
+		for _, o := range list {
+			features = append(features, Feature{
+				Name:      o.Name,
+				Enabled:   *o.Feature,
+				ForcedOff: o.Disabled,
+			})
+		}
+	}
+}
+
+// Features returns the baseline and optional CPU features known for
+// the running architecture, in a fixed order, as of Initialize.
+// The result must not be modified.
+func Features() []Feature {
+	return features
+}
+
+// Summary returns a single line listing the names of the enabled CPU
+// features, separated by spaces. Features that were detected but turned
+// off in GODEBUG are listed with a leading '-'. Features that were not
+// detected are omitted.
+func Summary() string {
+	var b []byte
+	for _, f := range features {
+		if !f.Enabled && !f.ForcedOff {
+			continue
+		}
+		if len(b) > 0 {
+			b = append(b, ' ')
+		}
+		if f.ForcedOff {
+			b = append(b, '-')
+		}
+		b = append(b, f.Name...)
+	}
+	return string(b)
+}
+
 // indexByte returns the index of the first instance of c in s,
 // or -1 if c is not present in s.
 // indexByte is semantically the same as [strings.IndexByte].
@@ -0,0 +1,21 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cpu
+
+// requestAMX is provided by package runtime. It asks the OS for
+// permission to use the AMX tile data state, once per process.
+func requestAMX() bool
+
+// EnableAMX prepares the process to use AMX tile instructions and
+// reports whether they may be used. It returns false if X86.HasAMXTile
+// is false, including when AMX was disabled with GODEBUG=cpu.amxtile=off,
+// or if the OS denied the request. It is safe to call EnableAMX
+// concurrently and repeatedly; only the first call asks the OS.
+func EnableAMX() bool {
+	if !X86.HasAMXTile {
+		return false
+	}
+	return requestAMX()
+}
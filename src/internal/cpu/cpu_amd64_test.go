@@ -0,0 +1,58 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cpu_test
+
+import (
+	. "internal/cpu"
+	"internal/godebug"
+	"runtime"
+	"testing"
+)
+
+func TestAMXImpliesTile(t *testing.T) {
+	if (X86.HasAMXInt8 || X86.HasAMXBF16) && !X86.HasAMXTile {
+		t.Fatalf("HasAMXTile expected true when HasAMXInt8 or HasAMXBF16 is true, got false")
+	}
+}
+
+func TestEnableAMX(t *testing.T) {
+	ok := EnableAMX()
+	if !X86.HasAMXTile {
+		if ok {
+			t.Fatalf("EnableAMX() = true without AMX support")
+		}
+		t.Skip("skipping test: AMX not supported")
+	}
+	if !ok && runtime.GOOS == "linux" {
+		// Every Linux kernel that enables the tile state in XCR0
+		// also implements the permission request.
+		t.Fatalf("EnableAMX() = false, want true")
+	}
+	if again := EnableAMX(); again != ok {
+		t.Fatalf("second EnableAMX() = %v, first returned %v", again, ok)
+	}
+}
+
+func TestDisableAMX(t *testing.T) {
+	if !X86.HasAMXTile {
+		t.Skip("skipping test: AMX not supported")
+	}
+	runDebugOptionsTest(t, "TestAMXDebugOption", "cpu.amxtile=off")
+}
+
+func TestAMXDebugOption(t *testing.T) {
+	MustHaveDebugOptionsSupport(t)
+
+	if godebug.New("#cpu.amxtile").Value() != "off" {
+		t.Skipf("skipping test: GODEBUG=cpu.amxtile=off not set")
+	}
+
+	if X86.HasAMXTile {
+		t.Errorf("X86.HasAMXTile expected false, got true")
+	}
+	if EnableAMX() {
+		t.Errorf("EnableAMX() = true with GODEBUG=cpu.amxtile=off")
+	}
+}
@@ -10,33 +10,127 @@ package cpu
 const CacheLinePadSize = 128
 
 func doinit() {
+	// Atomics, CPUID and SVE are acted on while detecting features or
+	// initializing the runtime, so they cannot be forced on.
 	options = []option{
-		{Name: "aes", Feature: &ARM64.HasAES},
-		{Name: "pmull", Feature: &ARM64.HasPMULL},
-		{Name: "sha1", Feature: &ARM64.HasSHA1},
-		{Name: "sha2", Feature: &ARM64.HasSHA2},
-		{Name: "sha512", Feature: &ARM64.HasSHA512},
-		{Name: "crc32", Feature: &ARM64.HasCRC32},
+		{Name: "aes", Feature: &ARM64.HasAES, Forceable: true},
+		{Name: "pmull", Feature: &ARM64.HasPMULL, Forceable: true},
+		{Name: "sha1", Feature: &ARM64.HasSHA1, Forceable: true},
+		{Name: "sha2", Feature: &ARM64.HasSHA2, Forceable: true},
+		{Name: "sha512", Feature: &ARM64.HasSHA512, Forceable: true},
+		{Name: "sha3", Feature: &ARM64.HasSHA3, Forceable: true},
+		{Name: "sm3", Feature: &ARM64.HasSM3, Forceable: true},
+		{Name: "sm4", Feature: &ARM64.HasSM4, Forceable: true},
+		{Name: "crc32", Feature: &ARM64.HasCRC32, Forceable: true},
 		{Name: "atomics", Feature: &ARM64.HasATOMICS},
 		{Name: "cpuid", Feature: &ARM64.HasCPUID},
+		{Name: "sve", Feature: &ARM64.HasSVE},
+		{Name: "sve2", Feature: &ARM64.HasSVE2},
 		{Name: "isNeoverse", Feature: &ARM64.IsNeoverse},
 	}
 
 	// arm64 uses different ways to detect CPU features at runtime depending on the operating system.
 	osInit()
+
+	ARM64.IsNeoverse = ARM64.Microarch.IsNeoverse()
+}
+
+// afterOptions adjusts the values that depend on features GODEBUG may
+// have turned off.
+func afterOptions() {
+	if !ARM64.HasSVE {
+		// The vector length is only meaningful with SVE.
+		ARM64.SVEVectorLength = 0
+	}
 }
 
 func getisar0() uint64
 
 func getpfr0() uint64
 
+func getzfr0() uint64
+
 func getMIDR() uint64
 
+// getCTR returns CTR_EL0. It must only be called if the operating
+// system allows user space to read the register.
+func getCTR() uint64
+
+// getSVEVectorLength returns the current SVE vector length in bytes.
+// It must only be called if the CPU supports SVE.
+func getSVEVectorLength() int
+
+// sveCNTB returns the SVE vector length in bytes as counted by CNTB.
+// It is used by tests to cross-check getSVEVectorLength.
+func sveCNTB() int
+
+// pfr0HasSVE reports whether ID_AA64PFR0_EL1 reports SVE. The SVE
+// registers and instructions, such as ID_AA64ZFR0_EL1 and RDVL, must
+// only be used if it does.
+func pfr0HasSVE(pfr0 uint64) bool {
+	return extractBits(pfr0, 32, 35) >= 1
+}
+
 func extractBits(data uint64, start, end uint) uint {
 	return (uint)(data>>start) & ((1 << (end - start + 1)) - 1)
 }
 
-func parseARM64SystemRegisters(isar0, pfr0 uint64) {
+// midrMicroarch decodes the implementer and part number fields of
+// MIDR_EL1. The values are from the Arm and Linux kernel part lists.
+func midrMicroarch(midr uint64) Microarch {
+	implementer := extractBits(midr, 24, 31)
+	part := extractBits(midr, 4, 15)
+	switch implementer {
+	case 'A': // Arm
+		switch part {
+		case 0xd0c:
+			return NeoverseN1
+		case 0xd40:
+			return NeoverseV1
+		case 0xd49:
+			return NeoverseN2
+		case 0xd4f:
+			return NeoverseV2
+		case 0xd8e:
+			return NeoverseN3
+		case 0xd84:
+			return NeoverseV3
+		}
+	case 'a': // Apple, as seen by Linux on Apple Silicon
+		switch part {
+		case 0x022, 0x023, 0x024, 0x025, 0x028, 0x029:
+			return AppleM1
+		case 0x032, 0x033, 0x034, 0x035, 0x038, 0x039:
+			return AppleM2
+		}
+	}
+	return MicroarchUnknown
+}
+
+// cpufamilyMicroarch decodes the hw.cpufamily value of darwin, which
+// identifies the core generation where MIDR_EL1 cannot be read.
+// The values are CPUFAMILY_ARM_* from the darwin mach/machine.h.
+func cpufamilyMicroarch(family uint32) Microarch {
+	switch family {
+	case 0x1b588bb3: // FIRESTORM_ICESTORM
+		return AppleM1
+	case 0xda33d83d: // BLIZZARD_AVALANCHE
+		return AppleM2
+	case 0xfa33415e, 0x5f4dea93, 0x72015832: // IBIZA, LOBOS, PALMA
+		return AppleM3
+	case 0x6f5129ac, 0x17d5b93a: // DONAN, BRAVA
+		return AppleM4
+	}
+	return MicroarchUnknown
+}
+
+// parseCTR sets CacheLineSize from CTR_EL0.DminLine, the log2 of
+// the number of words in the smallest data cache line.
+func parseCTR(ctr uint64) {
+	setCacheLineSize(4 << extractBits(ctr, 16, 19))
+}
+
+func parseARM64SystemRegisters(isar0, pfr0, zfr0 uint64) {
 	// ID_AA64ISAR0_EL1
 	switch extractBits(isar0, 4, 7) {
 	case 1:
@@ -69,8 +163,34 @@ func parseARM64SystemRegisters(isar0, pfr0 uint64) {
 		ARM64.HasATOMICS = true
 	}
 
+	switch extractBits(isar0, 32, 35) {
+	case 1:
+		ARM64.HasSHA3 = true
+	}
+
+	switch extractBits(isar0, 36, 39) {
+	case 1:
+		ARM64.HasSM3 = true
+	}
+
+	switch extractBits(isar0, 40, 43) {
+	case 1:
+		ARM64.HasSM4 = true
+	}
+
 	switch extractBits(pfr0, 48, 51) {
 	case 1:
 		ARM64.HasDIT = true
 	}
+
+	// ID_AA64PFR0_EL1.SVE
+	if pfr0HasSVE(pfr0) {
+		ARM64.HasSVE = true
+		ARM64.SVEVectorLength = getSVEVectorLength()
+
+		// ID_AA64ZFR0_EL1.SVEver
+		if extractBits(zfr0, 0, 3) >= 1 {
+			ARM64.HasSVE2 = true
+		}
+	}
 }
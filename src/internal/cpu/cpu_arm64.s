@@ -18,8 +18,34 @@ TEXT ·getpfr0(SB),NOSPLIT,$0-8
 	MOVD R0, ret+0(FP)
 	RET
 
+// func getzfr0() uint64
+TEXT ·getzfr0(SB),NOSPLIT,$0-8
+	// get SVE Feature ID Register 0 into R0
+	MRS	ID_AA64ZFR0_EL1, R0
+	MOVD	R0, ret+0(FP)
+	RET
+
 // func getMIDR() uint64
 TEXT ·getMIDR(SB), NOSPLIT, $0-8
 	MRS	MIDR_EL1, R0
 	MOVD	R0, ret+0(FP)
 	RET
+
+// func getCTR() uint64
+TEXT ·getCTR(SB),NOSPLIT,$0-8
+	// get Cache Type Register into R0
+	MRS	CTR_EL0, R0
+	MOVD	R0, ret+0(FP)
+	RET
+
+// func getSVEVectorLength() int
+TEXT ·getSVEVectorLength(SB),NOSPLIT,$0-8
+	WORD	$0x04bf5020	// RDVL	R0, #1
+	MOVD	R0, ret+0(FP)
+	RET
+
+// func sveCNTB() int
+TEXT ·sveCNTB(SB),NOSPLIT,$0-8
+	WORD	$0x0420e3e0	// CNTB	R0, ALL, MUL #1
+	MOVD	R0, ret+0(FP)
+	RET
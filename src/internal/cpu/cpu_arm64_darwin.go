@@ -8,20 +8,88 @@ package cpu
 
 import _ "unsafe" // for linkname
 
+// sysctlNames are the hw.optional names osInit reads. Their values
+// are read once, before any other goroutine runs, and kept in
+// sysctlValues, so that neither osInit nor sysctlEnabled asks the
+// kernel for them again.
+var sysctlNames = [...]string{
+	"hw.optional.arm.FEAT_AES",
+	"hw.optional.arm.FEAT_PMULL",
+	"hw.optional.arm.FEAT_SHA1",
+	"hw.optional.arm.FEAT_SHA256",
+	"hw.optional.arm.FEAT_SHA512",
+	"hw.optional.armv8_2_sha512",
+	"hw.optional.arm.FEAT_SHA3",
+	"hw.optional.armv8_2_sha3",
+	"hw.optional.arm.FEAT_CRC32",
+	"hw.optional.armv8_crc32",
+	"hw.optional.arm.FEAT_LSE",
+	"hw.optional.armv8_1_atomics",
+	"hw.optional.arm.FEAT_DIT",
+}
+
+// sysctlValues holds the values of sysctlNames, or -1 for the names
+// the kernel does not know.
+var sysctlValues [len(sysctlNames)]int32
+
 func osInit() {
-	ARM64.HasATOMICS = sysctlEnabled([]byte("hw.optional.armv8_1_atomics\x00"))
-	ARM64.HasCRC32 = sysctlEnabled([]byte("hw.optional.armv8_crc32\x00"))
-	ARM64.HasSHA512 = sysctlEnabled([]byte("hw.optional.armv8_2_sha512\x00"))
-	ARM64.HasDIT = sysctlEnabled([]byte("hw.optional.arm.FEAT_DIT\x00"))
-
-	// There are no hw.optional sysctl values for the below features on Mac OS 11.0
-	// to detect their supported state dynamically. Assume the CPU features that
-	// Apple Silicon M1 supports to be available as a minimal set of features
-	// to all Go programs running on darwin/arm64.
-	ARM64.HasAES = true
-	ARM64.HasPMULL = true
-	ARM64.HasSHA1 = true
-	ARM64.HasSHA2 = true
+	for i, name := range sysctlNames {
+		var buf [32]byte
+		n := copy(buf[:len(buf)-1], name)
+		ret, value := getsysctlbyname(buf[:n+1])
+		if ret < 0 {
+			value = -1
+		}
+		sysctlValues[i] = value
+	}
+
+	// Query the hw.optional.arm.FEAT_* names, which describe the
+	// running core. Older kernels lack some of them; fall back to the
+	// older hw.optional names, or else assume the feature set of Apple
+	// Silicon M1, the minimal set available to all Go programs running
+	// on darwin/arm64.
+	ARM64.HasAES = sysctlFeature("hw.optional.arm.FEAT_AES", "", true)
+	ARM64.HasPMULL = sysctlFeature("hw.optional.arm.FEAT_PMULL", "", true)
+	ARM64.HasSHA1 = sysctlFeature("hw.optional.arm.FEAT_SHA1", "", true)
+	ARM64.HasSHA2 = sysctlFeature("hw.optional.arm.FEAT_SHA256", "", true)
+	ARM64.HasSHA512 = sysctlFeature("hw.optional.arm.FEAT_SHA512", "hw.optional.armv8_2_sha512", false)
+	ARM64.HasSHA3 = sysctlFeature("hw.optional.arm.FEAT_SHA3", "hw.optional.armv8_2_sha3", false)
+	ARM64.HasCRC32 = sysctlFeature("hw.optional.arm.FEAT_CRC32", "hw.optional.armv8_crc32", false)
+	ARM64.HasATOMICS = sysctlFeature("hw.optional.arm.FEAT_LSE", "hw.optional.armv8_1_atomics", false)
+	ARM64.HasDIT = sysctlFeature("hw.optional.arm.FEAT_DIT", "", false)
+
+	if ret, family := getsysctlbyname([]byte("hw.cpufamily\x00")); ret >= 0 {
+		ARM64.Microarch = cpufamilyMicroarch(uint32(family))
+	}
+
+	// Apple cores implement neither SM3/SM4 nor SVE outside of SME
+	// streaming mode, and the kernel has no names for them.
+}
+
+// sysctlValue returns the value osInit read for name, which must be
+// one of sysctlNames, or -1 if the kernel does not know name.
+func sysctlValue(name string) int32 {
+	for i, n := range sysctlNames {
+		if n == name {
+			return sysctlValues[i]
+		}
+	}
+	panic("internal/cpu: sysctl name missing from sysctlNames")
+}
+
+// sysctlFeature reports whether the hw.optional feature name is
+// present. If the kernel does not know name, it reports whether the
+// older name fallback is present, or def if there is no fallback or the
+// kernel does not know it either.
+func sysctlFeature(name, fallback string, def bool) bool {
+	value := sysctlValue(name)
+	if value < 0 && fallback != "" {
+		value = sysctlValue(fallback)
+	}
+	if value < 0 {
+		return def
+	}
+	return value > 0
 }
 
 //go:noescape
@@ -38,6 +106,13 @@ func getsysctlbyname(name []byte) (int32, int32)
 //
 //go:linkname sysctlEnabled
 func sysctlEnabled(name []byte) bool {
+	if len(name) > 0 {
+		for i, n := range sysctlNames {
+			if n == string(name[:len(name)-1]) {
+				return sysctlValues[i] > 0
+			}
+		}
+	}
 	ret, value := getsysctlbyname(name)
 	if ret < 0 {
 		return false
@@ -0,0 +1,44 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build arm64 && darwin && !ios
+
+package cpu_test
+
+import (
+	. "internal/cpu"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestDarwinSysctlFeatures(t *testing.T) {
+	if strings.Contains(os.Getenv("GODEBUG"), "cpu.") {
+		t.Skip("skipping test: cpu options set in GODEBUG")
+	}
+	for _, tt := range []struct {
+		name string
+		has  bool
+	}{
+		{"hw.optional.arm.FEAT_AES", ARM64.HasAES},
+		{"hw.optional.arm.FEAT_PMULL", ARM64.HasPMULL},
+		{"hw.optional.arm.FEAT_SHA1", ARM64.HasSHA1},
+		{"hw.optional.arm.FEAT_SHA256", ARM64.HasSHA2},
+		{"hw.optional.arm.FEAT_SHA512", ARM64.HasSHA512},
+		{"hw.optional.arm.FEAT_SHA3", ARM64.HasSHA3},
+		{"hw.optional.arm.FEAT_CRC32", ARM64.HasCRC32},
+		{"hw.optional.arm.FEAT_LSE", ARM64.HasATOMICS},
+		{"hw.optional.arm.FEAT_DIT", ARM64.HasDIT},
+	} {
+		v, err := syscall.SysctlUint32(tt.name)
+		if err != nil {
+			t.Logf("%s: %v", tt.name, err)
+			continue
+		}
+		if want := v != 0; tt.has != want {
+			t.Errorf("%s = %d, but internal/cpu reports %v", tt.name, v, tt.has)
+		}
+	}
+}
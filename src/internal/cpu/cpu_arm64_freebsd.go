@@ -7,9 +7,17 @@
 package cpu
 
 func osInit() {
-	// Retrieve info from system register ID_AA64ISAR0_EL1.
+	// Retrieve info from system registers ID_AA64ISAR0_EL1,
+	// ID_AA64PFR0_EL1 and, on CPUs with SVE, ID_AA64ZFR0_EL1.
 	isar0 := getisar0()
 	prf0 := getpfr0()
+	var zfr0 uint64
+	if pfr0HasSVE(prf0) {
+		zfr0 = getzfr0()
+	}
 
-	parseARM64SystemRegisters(isar0, prf0)
+	parseARM64SystemRegisters(isar0, prf0, zfr0)
+
+	// FreeBSD lets user space read CTR_EL0.
+	parseCTR(getCTR())
 }
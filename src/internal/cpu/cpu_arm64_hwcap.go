@@ -21,6 +21,10 @@ import _ "unsafe" // for linkname
 //go:linkname HWCap
 var HWCap uint
 
+// HWCap2 may be initialized by archauxv and
+// should not be changed after it was initialized.
+var HWCap2 uint
+
 // HWCAP bits. These are exposed by Linux.
 const (
 	hwcap_AES     = 1 << 3
@@ -30,10 +34,19 @@ const (
 	hwcap_CRC32   = 1 << 7
 	hwcap_ATOMICS = 1 << 8
 	hwcap_CPUID   = 1 << 11
+	hwcap_SHA3    = 1 << 17
+	hwcap_SM3     = 1 << 18
+	hwcap_SM4     = 1 << 19
 	hwcap_SHA512  = 1 << 21
+	hwcap_SVE     = 1 << 22
 	hwcap_DIT     = 1 << 24
 )
 
+// HWCAP2 bits. These are exposed by Linux.
+const (
+	hwcap2_SVE2 = 1 << 1
+)
+
 func hwcapInit(os string) {
 	// HWCap was populated by the runtime from the auxiliary vector.
 	// Use HWCap information since reading aarch64 system registers
@@ -45,32 +58,33 @@ func hwcapInit(os string) {
 	ARM64.HasCRC32 = isSet(HWCap, hwcap_CRC32)
 	ARM64.HasCPUID = isSet(HWCap, hwcap_CPUID)
 	ARM64.HasSHA512 = isSet(HWCap, hwcap_SHA512)
+	ARM64.HasSHA3 = isSet(HWCap, hwcap_SHA3)
+	ARM64.HasSM3 = isSet(HWCap, hwcap_SM3)
+	ARM64.HasSM4 = isSet(HWCap, hwcap_SM4)
 	ARM64.HasDIT = isSet(HWCap, hwcap_DIT)
+	ARM64.HasSVE = isSet(HWCap, hwcap_SVE)
+	ARM64.HasSVE2 = ARM64.HasSVE && isSet(HWCap2, hwcap2_SVE2)
+	if ARM64.HasSVE {
+		ARM64.SVEVectorLength = getSVEVectorLength()
+	}
 
 	// The Samsung S9+ kernel reports support for atomics, but not all cores
 	// actually support them, resulting in SIGILL. See issue #28431.
 	// TODO(elias.naur): Only disable the optimization on bad chipsets on android.
 	ARM64.HasATOMICS = isSet(HWCap, hwcap_ATOMICS) && os != "android"
 
-	// Check to see if executing on a Neoverse core and in order to do that,
-	// check the AUXV for the CPUID bit. The getMIDR function executes an
-	// instruction which would normally be an illegal instruction, but it's
-	// trapped by the kernel, the value sanitized and then returned.
+	// Linux lets user space read CTR_EL0, emulating it on cores with
+	// errata that require it to be trapped.
+	parseCTR(getCTR())
+
+	// Identify the core for tuning. To do that, check the AUXV for the
+	// CPUID bit. The getMIDR function executes an instruction which would
+	// normally be an illegal instruction, but it's trapped by the kernel,
+	// the value sanitized and then returned.
 	// Without the CPUID bit the kernel will not trap the instruction and the
 	// process will be terminated with SIGILL.
 	if ARM64.HasCPUID {
-		midr := getMIDR()
-		part_num := uint16((midr >> 4) & 0xfff)
-		implementer := byte((midr >> 24) & 0xff)
-
-		// d0c - NeoverseN1
-		// d40 - NeoverseV1
-		// d49 - NeoverseN2
-		// d4f - NeoverseV2
-		if implementer == 'A' && (part_num == 0xd0c || part_num == 0xd40 ||
-			part_num == 0xd49 || part_num == 0xd4f) {
-			ARM64.IsNeoverse = true
-		}
+		ARM64.Microarch = midrMicroarch(getMIDR())
 	}
 }
 
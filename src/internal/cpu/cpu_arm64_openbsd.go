@@ -14,6 +14,7 @@ const (
 	_CPU_ID_AA64ISAR0 = 2
 	_CPU_ID_AA64ISAR1 = 3
 	_CPU_ID_AA64PFR0  = 8
+	_CPU_ID_AA64ZFR0  = 11
 )
 
 //go:noescape
@@ -31,5 +32,9 @@ func osInit() {
 		return
 	}
 
-	parseARM64SystemRegisters(isar0, pfr0)
+	// Get ID_AA64ZFR0 from sysctl. Older kernels do not provide it,
+	// in which case SVE2 is reported as unsupported.
+	zfr0, _ := sysctlUint64([]uint32{_CTL_MACHDEP, _CPU_ID_AA64ZFR0})
+
+	parseARM64SystemRegisters(isar0, pfr0, zfr0)
 }
@@ -0,0 +1,156 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build arm64
+
+package cpu_test
+
+import (
+	. "internal/cpu"
+	"internal/godebug"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestARM64ifSVE2hasSVE(t *testing.T) {
+	if ARM64.HasSVE2 && !ARM64.HasSVE {
+		t.Fatalf("HasSVE expected true when HasSVE2 is true, got false")
+	}
+}
+
+func TestSVEVectorLength(t *testing.T) {
+	if !ARM64.HasSVE {
+		t.Skip("skipping test: SVE not supported")
+	}
+	vl := ARM64.SVEVectorLength
+	// The architecture allows vector lengths from 128 to 2048 bits
+	// in multiples of 128 bits.
+	if vl < 16 || vl > 256 || vl%16 != 0 {
+		t.Fatalf("ARM64.SVEVectorLength = %d, want a multiple of 16 in [16, 256]", vl)
+	}
+	if got := SVECNTB(); got != vl {
+		t.Errorf("CNTB = %d, want ARM64.SVEVectorLength = %d", got, vl)
+	}
+}
+
+func TestDisableSVE(t *testing.T) {
+	if !ARM64.HasSVE {
+		t.Skip("skipping test: SVE not supported")
+	}
+	runDebugOptionsTest(t, "TestSVEDebugOption", "cpu.sve=off")
+}
+
+func TestSVEDebugOption(t *testing.T) {
+	MustHaveDebugOptionsSupport(t)
+
+	if godebug.New("#cpu.sve").Value() != "off" {
+		t.Skipf("skipping test: GODEBUG=cpu.sve=off not set")
+	}
+
+	want := false
+	if got := ARM64.HasSVE; got != want {
+		t.Errorf("ARM64.HasSVE expected %v, got %v", want, got)
+	}
+	if got := ARM64.SVEVectorLength; got != 0 {
+		t.Errorf("ARM64.SVEVectorLength = %d, want 0 with SVE off", got)
+	}
+}
+
+func TestARM64HWCapMatchesRegisters(t *testing.T) {
+	if runtime.GOOS != "linux" && runtime.GOOS != "android" {
+		t.Skip("skipping test: HWCAP only available on Linux")
+	}
+	if !ARM64.HasCPUID {
+		t.Skip("skipping test: ID registers not readable")
+	}
+	if strings.Contains(os.Getenv("GODEBUG"), "cpu.") {
+		t.Skip("skipping test: cpu options set in GODEBUG")
+	}
+
+	type features struct {
+		AES, PMULL, SHA1, SHA2, SHA512, SHA3, SM3, SM4, CRC32 bool
+	}
+	read := func() features {
+		return features{
+			AES:    ARM64.HasAES,
+			PMULL:  ARM64.HasPMULL,
+			SHA1:   ARM64.HasSHA1,
+			SHA2:   ARM64.HasSHA2,
+			SHA512: ARM64.HasSHA512,
+			SHA3:   ARM64.HasSHA3,
+			SM3:    ARM64.HasSM3,
+			SM4:    ARM64.HasSM4,
+			CRC32:  ARM64.HasCRC32,
+		}
+	}
+	hwcap := read()
+	var regs features
+	WithARM64FromRegisters(func() { regs = read() })
+	if hwcap != regs {
+		t.Errorf("features from HWCAP %+v do not match ID registers %+v", hwcap, regs)
+	}
+}
+
+func TestMIDRMicroarch(t *testing.T) {
+	tests := []struct {
+		midr uint64
+		want Microarch
+	}{
+		{0x413fd0c1, NeoverseN1}, // Ampere Altra, AWS Graviton2
+		{0x411fd401, NeoverseV1}, // AWS Graviton3
+		{0x410fd490, NeoverseN2},
+		{0x410fd4f0, NeoverseV2}, // NVIDIA Grace, AWS Graviton4
+		{0x410fd8e0, NeoverseN3},
+		{0x410fd840, NeoverseV3},
+		{0x611f0221, AppleM1},          // Icestorm
+		{0x611f0231, AppleM1},          // Firestorm
+		{0x611f0291, AppleM1},          // Firestorm Max
+		{0x611f0320, AppleM2},          // Blizzard
+		{0x611f0390, AppleM2},          // Avalanche Max
+		{0x410fd083, MicroarchUnknown}, // Cortex-A72
+		{0x510fd0c0, MicroarchUnknown}, // N1 part number from another implementer
+		{0, MicroarchUnknown},
+	}
+	for _, tt := range tests {
+		got := MIDRMicroarch(tt.midr)
+		if got != tt.want {
+			t.Errorf("MIDRMicroarch(%#x) = %d, want %d", tt.midr, got, tt.want)
+		}
+		if got.IsNeoverse() && got.IsApple() {
+			t.Errorf("MIDRMicroarch(%#x) = %d is both Neoverse and Apple", tt.midr, got)
+		}
+	}
+}
+
+func TestCPUFamilyMicroarch(t *testing.T) {
+	tests := []struct {
+		family uint32
+		want   Microarch
+	}{
+		{0x1b588bb3, AppleM1},
+		{0xda33d83d, AppleM2},
+		{0xfa33415e, AppleM3},
+		{0x72015832, AppleM3},
+		{0x6f5129ac, AppleM4},
+		{0x17d5b93a, AppleM4},
+		{0x8765edea, MicroarchUnknown}, // A16, not an M-class core
+		{0, MicroarchUnknown},
+	}
+	for _, tt := range tests {
+		if got := CPUFamilyMicroarch(tt.family); got != tt.want {
+			t.Errorf("CPUFamilyMicroarch(%#x) = %d, want %d", tt.family, got, tt.want)
+		}
+	}
+}
+
+func TestIsNeoverseMatchesMicroarch(t *testing.T) {
+	if strings.Contains(os.Getenv("GODEBUG"), "cpu.") {
+		t.Skip("skipping test: cpu options set in GODEBUG")
+	}
+	if ARM64.IsNeoverse != ARM64.Microarch.IsNeoverse() {
+		t.Errorf("ARM64.IsNeoverse = %v, want %v for Microarch %d", ARM64.IsNeoverse, !ARM64.IsNeoverse, ARM64.Microarch)
+	}
+}
@@ -0,0 +1,50 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build arm64
+
+package cpu
+
+// These are initialized by the runtime before Initialize is called
+// and should not be changed after that.
+var (
+	// WindowsProcessorFeatures has bit n set if
+	// IsProcessorFeaturePresent(n) reports true.
+	WindowsProcessorFeatures uint64
+
+	// WindowsIDRegisters reports whether WindowsISAR0 and WindowsPFR0
+	// were read from the registry. WindowsZFR0 is zero if not present.
+	WindowsIDRegisters                     bool
+	WindowsISAR0, WindowsPFR0, WindowsZFR0 uint64
+)
+
+// Processor feature numbers for IsProcessorFeaturePresent.
+const (
+	pf_ARM_V8_CRYPTO_INSTRUCTIONS_AVAILABLE  = 30
+	pf_ARM_V8_CRC32_INSTRUCTIONS_AVAILABLE   = 31
+	pf_ARM_V81_ATOMIC_INSTRUCTIONS_AVAILABLE = 34
+)
+
+func osInit() {
+	if WindowsIDRegisters {
+		// Windows does not enable SVE for user mode,
+		// so ignore what the hardware supports.
+		pfr0 := WindowsPFR0 &^ (0xf << 32)
+		parseARM64SystemRegisters(WindowsISAR0, pfr0, WindowsZFR0)
+	}
+
+	// IsProcessorFeaturePresent is documented, so it is authoritative
+	// for the features it knows about.
+	crypto := pfIsSet(pf_ARM_V8_CRYPTO_INSTRUCTIONS_AVAILABLE)
+	ARM64.HasAES = crypto
+	ARM64.HasPMULL = crypto
+	ARM64.HasSHA1 = crypto
+	ARM64.HasSHA2 = crypto
+	ARM64.HasCRC32 = pfIsSet(pf_ARM_V8_CRC32_INSTRUCTIONS_AVAILABLE)
+	ARM64.HasATOMICS = pfIsSet(pf_ARM_V81_ATOMIC_INSTRUCTIONS_AVAILABLE)
+}
+
+func pfIsSet(pf uint) bool {
+	return WindowsProcessorFeatures&(1<<pf) != 0
+}
@@ -0,0 +1,66 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build arm64
+
+package cpu_test
+
+import (
+	. "internal/cpu"
+	"internal/godebug"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestWindowsProcessorFeatures(t *testing.T) {
+	if os.Getenv("GODEBUG") != "" {
+		t.Skip("skipping test: GODEBUG may change feature bits")
+	}
+	isProcessorFeaturePresent := syscall.NewLazyDLL("kernel32.dll").NewProc("IsProcessorFeaturePresent")
+	present := func(pf uintptr) bool {
+		r, _, _ := isProcessorFeaturePresent.Call(pf)
+		return r&0xff != 0
+	}
+	for _, tt := range []struct {
+		name string
+		has  bool
+		pf   uintptr
+	}{
+		{"AES", ARM64.HasAES, 30},
+		{"PMULL", ARM64.HasPMULL, 30},
+		{"SHA1", ARM64.HasSHA1, 30},
+		{"SHA2", ARM64.HasSHA2, 30},
+		{"CRC32", ARM64.HasCRC32, 31},
+		{"ATOMICS", ARM64.HasATOMICS, 34},
+	} {
+		if want := present(tt.pf); tt.has != want {
+			t.Errorf("Has%s = %v, IsProcessorFeaturePresent(%d) = %v", tt.name, tt.has, tt.pf, want)
+		}
+	}
+	// Every Windows on Arm device has the Armv8.0 CRC32 instructions.
+	if !ARM64.HasCRC32 {
+		t.Errorf("HasCRC32 = false, want true")
+	}
+}
+
+func TestDisableAES(t *testing.T) {
+	if !ARM64.HasAES {
+		t.Skip("skipping test: AES not supported")
+	}
+	runDebugOptionsTest(t, "TestAESDebugOption", "cpu.aes=off")
+}
+
+func TestAESDebugOption(t *testing.T) {
+	MustHaveDebugOptionsSupport(t)
+
+	if godebug.New("#cpu.aes").Value() != "off" {
+		t.Skipf("skipping test: GODEBUG=cpu.aes=off not set")
+	}
+
+	want := false
+	if got := ARM64.HasAES; got != want {
+		t.Errorf("ARM64.HasAES expected %v, got %v", want, got)
+	}
+}
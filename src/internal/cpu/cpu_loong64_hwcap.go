@@ -12,13 +12,15 @@ var HWCap uint
 
 // HWCAP bits. These are exposed by the Linux kernel.
 const (
-	hwcap_LOONGARCH_LSX = 1 << 4
+	hwcap_LOONGARCH_LSX  = 1 << 4
+	hwcap_LOONGARCH_LASX = 1 << 5
 )
 
 func hwcapInit() {
-	// TODO: Features that require kernel support like LSX and LASX can
-	// be detected here once needed in std library or by the compiler.
+	// Features that require kernel support like LSX and LASX are
+	// detected here. The kernel only reports LASX along with LSX.
 	Loong64.HasLSX = hwcIsSet(HWCap, hwcap_LOONGARCH_LSX)
+	Loong64.HasLASX = hwcIsSet(HWCap, hwcap_LOONGARCH_LASX)
 }
 
 func hwcIsSet(hwc uint, val uint) bool {
@@ -0,0 +1,81 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build loong64 && linux
+
+package cpu_test
+
+import (
+	. "internal/cpu"
+	"internal/godebug"
+	"os"
+	"strings"
+	"testing"
+)
+
+// cpuinfoFeatures returns the feature flags the kernel lists
+// in /proc/cpuinfo for the first CPU.
+func cpuinfoFeatures(t *testing.T) map[string]bool {
+	data, err := os.ReadFile("/proc/cpuinfo")
+	if err != nil {
+		t.Skipf("skipping test: %v", err)
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok || strings.TrimSpace(key) != "Features" {
+			continue
+		}
+		features := make(map[string]bool)
+		for _, f := range strings.Fields(value) {
+			features[f] = true
+		}
+		return features
+	}
+	t.Skip("skipping test: no Features line in /proc/cpuinfo")
+	return nil
+}
+
+func TestLoong64FeaturesMatchCPUInfo(t *testing.T) {
+	if godebug.New("#cpu.all").Value() != "" || godebug.New("#cpu.lsx").Value() != "" || godebug.New("#cpu.lasx").Value() != "" {
+		t.Skip("skipping test: cpu options set in GODEBUG")
+	}
+	features := cpuinfoFeatures(t)
+	for _, tt := range []struct {
+		name string
+		has  bool
+	}{
+		{"lsx", Loong64.HasLSX},
+		{"lasx", Loong64.HasLASX},
+	} {
+		if want := features[tt.name]; tt.has != want {
+			t.Errorf("Has%s = %v, but /proc/cpuinfo reports %v", strings.ToUpper(tt.name), tt.has, want)
+		}
+	}
+}
+
+func TestLoong64ifLASXhasLSX(t *testing.T) {
+	if Loong64.HasLASX && !Loong64.HasLSX {
+		t.Fatalf("HasLSX expected true when HasLASX is true, got false")
+	}
+}
+
+func TestDisableLSX(t *testing.T) {
+	if !Loong64.HasLSX {
+		t.Skip("skipping test: LSX not supported")
+	}
+	runDebugOptionsTest(t, "TestLSXDebugOption", "cpu.lsx=off")
+}
+
+func TestLSXDebugOption(t *testing.T) {
+	MustHaveDebugOptionsSupport(t)
+
+	if godebug.New("#cpu.lsx").Value() != "off" {
+		t.Skipf("skipping test: GODEBUG=cpu.lsx=off not set")
+	}
+
+	want := false
+	if got := Loong64.HasLSX; got != want {
+		t.Errorf("Loong64.HasLSX expected %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,11 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !arm64
+
+package cpu
+
+// afterOptions adjusts the values that depend on features GODEBUG may
+// have turned off. No such values exist on this architecture.
+func afterOptions() {}
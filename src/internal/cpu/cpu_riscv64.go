@@ -7,4 +7,12 @@ package cpu
 const CacheLinePadSize = 64
 
 func doinit() {
+	options = []option{
+		{Name: "v", Feature: &RISCV64.HasV},
+		{Name: "zba", Feature: &RISCV64.HasZba},
+		{Name: "zbb", Feature: &RISCV64.HasZbb},
+		{Name: "zbs", Feature: &RISCV64.HasZbs},
+	}
+
+	osInit()
 }
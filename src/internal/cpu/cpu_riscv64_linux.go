@@ -0,0 +1,56 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build riscv64 && linux
+
+package cpu
+
+// HWProbePair is a key/value pair as exchanged with the Linux
+// riscv_hwprobe system call.
+type HWProbePair struct {
+	Key   int64
+	Value uint64
+}
+
+// Keys and values for riscv_hwprobe. These are exposed by Linux.
+const (
+	hwprobe_KEY_IMA_EXT_0         = 4
+	hwprobe_KEY_ZICBOZ_BLOCK_SIZE = 6
+
+	hwprobe_IMA_V      = 1 << 2
+	hwprobe_EXT_ZBA    = 1 << 3
+	hwprobe_EXT_ZBB    = 1 << 4
+	hwprobe_EXT_ZBS    = 1 << 5
+	hwprobe_EXT_ZICBOZ = 1 << 6
+)
+
+// HWProbe is passed to riscv_hwprobe by the runtime before Initialize
+// is called and should not be changed after that. The kernel sets the
+// key of any pair it does not know to -1. If the system call is not
+// available (before Linux 6.4) the values are left zero and no
+// extensions are reported.
+var HWProbe = [...]HWProbePair{
+	{Key: hwprobe_KEY_IMA_EXT_0},
+	{Key: hwprobe_KEY_ZICBOZ_BLOCK_SIZE},
+}
+
+func osInit() {
+	var ext, blockSize uint64
+	for _, p := range HWProbe {
+		switch p.Key {
+		case hwprobe_KEY_IMA_EXT_0:
+			ext = p.Value
+		case hwprobe_KEY_ZICBOZ_BLOCK_SIZE:
+			blockSize = p.Value
+		}
+	}
+
+	RISCV64.HasV = ext&hwprobe_IMA_V != 0
+	RISCV64.HasZba = ext&hwprobe_EXT_ZBA != 0
+	RISCV64.HasZbb = ext&hwprobe_EXT_ZBB != 0
+	RISCV64.HasZbs = ext&hwprobe_EXT_ZBS != 0
+	if ext&hwprobe_EXT_ZICBOZ != 0 {
+		RISCV64.CacheBlockSize = int(blockSize)
+	}
+}
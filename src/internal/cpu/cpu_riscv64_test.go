@@ -0,0 +1,37 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cpu_test
+
+import (
+	. "internal/cpu"
+	"internal/godebug"
+	"testing"
+)
+
+func TestDisableV(t *testing.T) {
+	if !RISCV64.HasV {
+		t.Skip("skipping test: V extension not supported")
+	}
+	runDebugOptionsTest(t, "TestVDebugOption", "cpu.v=off")
+}
+
+func TestVDebugOption(t *testing.T) {
+	MustHaveDebugOptionsSupport(t)
+
+	if godebug.New("#cpu.v").Value() != "off" {
+		t.Skipf("skipping test: GODEBUG=cpu.v=off not set")
+	}
+
+	want := false
+	if got := RISCV64.HasV; got != want {
+		t.Errorf("RISCV64.HasV expected %v, got %v", want, got)
+	}
+}
+
+func TestCacheBlockSize(t *testing.T) {
+	if n := RISCV64.CacheBlockSize; n&(n-1) != 0 {
+		t.Errorf("RISCV64.CacheBlockSize = %d, want a power of two or 0", n)
+	}
+}
@@ -95,12 +95,15 @@ const (
 	msa9 facility = 155 // message-security-assist extension 9
 
 	// vector facilities
-	vxe facility = 135 // vector-enhancements 1
+	vxe  facility = 135 // vector-enhancements 1
+	vxe2 facility = 148 // vector-enhancements 2
+	nnpa facility = 165 // neural-network-processing-assist
 
-	// Note: vx requires kernel support
-	// and so must be fetched from HWCAP.
+	// Note: vx, and therefore the facilities built on it,
+	// require kernel support and so must be fetched from HWCAP.
 
-	hwcap_VX = 1 << 11 // vector facility
+	hwcap_VX   = 1 << 11 // vector facility
+	hwcap_NNPA = 1 << 20 // neural-network-processing-assist
 )
 
 // facilityList contains the result of an STFLE call.
@@ -145,6 +148,9 @@ func doinit() {
 		{Name: "etf3eh", Feature: &S390X.HasETF3EH},
 		{Name: "vx", Feature: &S390X.HasVX},
 		{Name: "vxe", Feature: &S390X.HasVXE},
+		{Name: "vxe2", Feature: &S390X.HasVXE2},
+		{Name: "nnpa", Feature: &S390X.HasNNPA},
+		{Name: "msa9", Feature: &S390X.HasMSA9},
 		{Name: "kdsa", Feature: &S390X.HasKDSA},
 	}
 
@@ -185,7 +191,8 @@ func doinit() {
 			shake128, shake256,
 		}
 		S390X.HasSHA3 = kimd.Has(sha3...) && klmd.Has(sha3...)
-		S390X.HasKDSA = facilities.Has(msa9) // elliptic curves
+		S390X.HasMSA9 = facilities.Has(msa9)
+		S390X.HasKDSA = S390X.HasMSA9 // elliptic curves
 		if S390X.HasKDSA {
 			kdsa := kdsaQuery()
 			S390X.HasECDSA = kdsa.Has(ecdsaVerifyP256, ecdsaSignP256, ecdsaVerifyP384, ecdsaSignP384, ecdsaVerifyP521, ecdsaSignP521)
@@ -197,6 +204,11 @@ func doinit() {
 
 	if S390X.HasVX {
 		S390X.HasVXE = facilities.Has(vxe)
+		S390X.HasVXE2 = facilities.Has(vxe2)
+		// NNPA has state of its own that the kernel must
+		// enable, so unlike the vector enhancements it is
+		// only reported if HWCAP agrees.
+		S390X.HasNNPA = facilities.Has(nnpa) && isSet(HWCap, hwcap_NNPA)
 	}
 }
 
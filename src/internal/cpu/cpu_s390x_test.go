@@ -61,3 +61,25 @@ func TestS390XAgainstCPUInfo(t *testing.T) {
 		}
 	}
 }
+
+func TestS390XAgainstHWCap(t *testing.T) {
+	if len(os.Getenv("GODEBUG")) > 0 {
+		t.Skip("skipping test: GODEBUG may change feature bits")
+	}
+	for _, tt := range []struct {
+		name  string
+		has   bool
+		hwcap uint
+	}{
+		{"vx", S390X.HasVX, 1 << 11},
+		{"vxe", S390X.HasVXE, 1 << 13},
+		{"vxe2", S390X.HasVXE2, 1 << 15},
+		{"nnpa", S390X.HasNNPA, 1 << 20},
+	} {
+		// Older kernels do not report newer facilities,
+		// but whatever the kernel reports must be installed.
+		if kernel := HWCap&tt.hwcap != 0; kernel && !tt.has {
+			t.Errorf("%s: HWCAP reports %v, STFLE reports %v", tt.name, kernel, tt.has)
+		}
+	}
+}
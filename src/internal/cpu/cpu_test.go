@@ -10,6 +10,10 @@ import (
 	"internal/testenv"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
 	"testing"
 )
 
@@ -59,3 +63,172 @@ func TestAllCapabilitiesDisabled(t *testing.T) {
 		}
 	}
 }
+
+func TestProcessOptionsForce(t *testing.T) {
+	var detected, forceable, fixed, viaAll bool
+	detected = true
+	opts := []Option{
+		{Name: "detected", Feature: &detected},
+		{Name: "forceable", Feature: &forceable, Forceable: true},
+		{Name: "fixed", Feature: &fixed},
+		{Name: "viaall", Feature: &viaAll, Forceable: true},
+	}
+	ProcessOptions(opts, "cpu.forceable=on,cpu.fixed=on,cpu.all=on,cpu.detected=off")
+	if detected {
+		t.Errorf("detected feature not disabled")
+	}
+	if !forceable {
+		t.Errorf("forceable feature named on was not forced on")
+	}
+	if fixed {
+		t.Errorf("feature without Forceable was forced on")
+	}
+	if viaAll {
+		t.Errorf("cpu.all=on forced a feature on")
+	}
+}
+
+func TestProcessOptionsForceWarning(t *testing.T) {
+	if os.Getenv("GO_WANT_FORCE_CHILD") == "1" {
+		var forceable, fixed bool
+		ProcessOptions([]Option{
+			{Name: "forceable", Feature: &forceable, Forceable: true},
+			{Name: "fixed", Feature: &fixed},
+		}, "cpu.forceable=on,cpu.fixed=on")
+		return
+	}
+	testenv.MustHaveExec(t)
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestProcessOptionsForceWarning$")
+	cmd.Env = append(cmd.Environ(), "GO_WANT_FORCE_CHILD=1")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("child failed: %v\n%s", err, out)
+	}
+	for _, want := range []string{
+		`GODEBUG: WARNING: forcing on "forceable" without detected CPU support`,
+		`GODEBUG: can not enable "fixed", missing CPU support`,
+	} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("child output does not contain %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestSummary(t *testing.T) {
+	summary := " " + Summary() + " "
+	for _, f := range Features() {
+		switch {
+		case f.ForcedOff:
+			if !strings.Contains(summary, " -"+f.Name+" ") {
+				t.Errorf("Summary() = %q, want -%s", summary, f.Name)
+			}
+		case f.Enabled:
+			if !strings.Contains(summary, " "+f.Name+" ") {
+				t.Errorf("Summary() = %q, want %s", summary, f.Name)
+			}
+		default:
+			if strings.Contains(summary, " "+f.Name+" ") {
+				t.Errorf("Summary() = %q, want no %s", summary, f.Name)
+			}
+		}
+	}
+	for _, o := range Options {
+		for _, f := range Features() {
+			if f.Name == o.Name && f.Enabled != *o.Feature {
+				t.Errorf("Features() reports %s enabled = %v, want %v", f.Name, f.Enabled, *o.Feature)
+			}
+		}
+	}
+}
+
+func TestSummaryDisabled(t *testing.T) {
+	if name := os.Getenv("GO_WANT_SUMMARY_OFF"); name != "" {
+		if !strings.Contains(" "+Summary()+" ", " -"+name+" ") {
+			t.Fatalf("Summary() = %q, want -%s", Summary(), name)
+		}
+		return
+	}
+	MustHaveDebugOptionsSupport(t)
+	testenv.MustHaveExec(t)
+
+	var name string
+	for _, f := range Features() {
+		for _, o := range Options {
+			if f.Enabled && o.Name == f.Name {
+				name = f.Name
+				break
+			}
+		}
+		if name != "" {
+			break
+		}
+	}
+	if name == "" {
+		t.Skip("skipping test: no optional features detected")
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestSummaryDisabled$")
+	cmd.Env = append(cmd.Environ(), "GODEBUG=cpu."+name+"=off", "GO_WANT_SUMMARY_OFF="+name)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("child with GODEBUG=cpu.%s=off failed: %v\n%s", name, err, out)
+	}
+}
+
+// linuxCaches returns the data and unified caches of the first CPU as
+// reported by Linux in sysfs, or nil if they are not available.
+func linuxCaches() []Cache {
+	if runtime.GOOS != "linux" {
+		return nil
+	}
+	dirs, _ := filepath.Glob("/sys/devices/system/cpu/cpu0/cache/index*")
+	var caches []Cache
+	for _, dir := range dirs {
+		read := func(name string) string {
+			b, _ := os.ReadFile(filepath.Join(dir, name))
+			return strings.TrimSpace(string(b))
+		}
+		if typ := read("type"); typ != "Data" && typ != "Unified" {
+			continue
+		}
+		level, err1 := strconv.Atoi(read("level"))
+		line, err2 := strconv.Atoi(read("coherency_line_size"))
+		size, err3 := strconv.Atoi(strings.TrimSuffix(read("size"), "K"))
+		if err1 != nil || err2 != nil || err3 != nil {
+			return nil
+		}
+		caches = append(caches, Cache{Level: level, LineSize: line, Size: size * 1024})
+	}
+	return caches
+}
+
+func TestCacheLineSize(t *testing.T) {
+	size := CacheLineSize
+	if size < 16 || size > 1024 || size&(size-1) != 0 {
+		t.Fatalf("CacheLineSize = %d, want a power of two in [16, 1024]", size)
+	}
+
+	level := 0
+	for _, c := range CacheTopology {
+		if c.Level < level || c.Level < 1 {
+			t.Errorf("CacheTopology = %+v, want increasing levels from 1", CacheTopology)
+		}
+		level = c.Level
+		if c.LineSize <= 0 || c.LineSize&(c.LineSize-1) != 0 || c.Size < c.LineSize || c.SharedBy < 1 {
+			t.Errorf("implausible cache %+v", c)
+		}
+	}
+
+	caches := linuxCaches()
+	if len(caches) == 0 {
+		t.Skip("skipping comparison: cache geometry not available from the OS")
+	}
+	// CacheLineSize is the smallest line size of all data caches.
+	want := caches[0].LineSize
+	for _, c := range caches {
+		want = min(want, c.LineSize)
+	}
+	if int(size) != want {
+		t.Errorf("CacheLineSize = %d, want %d as reported by Linux", size, want)
+	}
+}
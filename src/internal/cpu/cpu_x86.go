@@ -30,6 +30,9 @@ const (
 	cpuid_OSXSAVE   = 1 << 27
 	cpuid_AVX       = 1 << 28
 
+	// edx bits for CPUID 1
+	cpuid_CLFSH = 1 << 19
+
 	// ebx bits
 	cpuid_BMI1     = 1 << 3
 	cpuid_AVX2     = 1 << 5
@@ -40,53 +43,89 @@ const (
 	cpuid_SHA      = 1 << 29
 	cpuid_AVX512BW = 1 << 30
 	cpuid_AVX512VL = 1 << 31
+	// ecx bits for CPUID 7
+	cpuid_AVX512VNNI      = 1 << 11
+	cpuid_AVX512VPOPCNTDQ = 1 << 14
 	// edx bits
-	cpuid_FSRM = 1 << 4
+	cpuid_FSRM       = 1 << 4
+	cpuid_AMXBF16    = 1 << 22
+	cpuid_AVX512FP16 = 1 << 23
+	cpuid_AMXTile    = 1 << 24
+	cpuid_AMXInt8    = 1 << 25
+	// eax bits for CPUID 7, subleaf 1
+	cpuid_AVX512BF16 = 1 << 5
 	// edx bits for CPUID 0x80000001
 	cpuid_RDTSCP = 1 << 27
 )
 
+// is64bit reports whether this is amd64 rather than 386.
+// AMX can only be used in 64-bit mode.
+const is64bit = ^uint(0)>>63 == 1
+
 var maxExtendedFunctionInformation uint32
 
 func doinit() {
+	// Extensions that use the AVX or AMX register state need operating
+	// system support and so cannot be forced on.
 	options = []option{
-		{Name: "adx", Feature: &X86.HasADX},
-		{Name: "aes", Feature: &X86.HasAES},
-		{Name: "erms", Feature: &X86.HasERMS},
-		{Name: "fsrm", Feature: &X86.HasFSRM},
-		{Name: "pclmulqdq", Feature: &X86.HasPCLMULQDQ},
-		{Name: "rdtscp", Feature: &X86.HasRDTSCP},
-		{Name: "sha", Feature: &X86.HasSHA},
+		{Name: "adx", Feature: &X86.HasADX, Forceable: true},
+		{Name: "aes", Feature: &X86.HasAES, Forceable: true},
+		{Name: "amxbf16", Feature: &X86.HasAMXBF16},
+		{Name: "amxint8", Feature: &X86.HasAMXInt8},
+		{Name: "amxtile", Feature: &X86.HasAMXTile},
+		{Name: "avx512bf16", Feature: &X86.HasAVX512BF16},
+		{Name: "avx512fp16", Feature: &X86.HasAVX512FP16},
+		{Name: "avx512vnni", Feature: &X86.HasAVX512VNNI},
+		{Name: "avx512vpopcntdq", Feature: &X86.HasAVX512VPOPCNTDQ},
+		{Name: "erms", Feature: &X86.HasERMS, Forceable: true},
+		{Name: "fsrm", Feature: &X86.HasFSRM, Forceable: true},
+		{Name: "pclmulqdq", Feature: &X86.HasPCLMULQDQ, Forceable: true},
+		{Name: "rdtscp", Feature: &X86.HasRDTSCP, Forceable: true},
+		{Name: "sha", Feature: &X86.HasSHA, Forceable: true},
 	}
 	level := getGOAMD64level()
+
+	// These options are required at level 2. At lower levels
+	// they can be turned off.
+	v2 := []option{
+		{Name: "popcnt", Feature: &X86.HasPOPCNT, Forceable: true},
+		{Name: "sse3", Feature: &X86.HasSSE3, Forceable: true},
+		{Name: "sse41", Feature: &X86.HasSSE41, Forceable: true},
+		{Name: "sse42", Feature: &X86.HasSSE42, Forceable: true},
+		{Name: "ssse3", Feature: &X86.HasSSSE3, Forceable: true},
+	}
 	if level < 2 {
-		// These options are required at level 2. At lower levels
-		// they can be turned off.
-		options = append(options,
-			option{Name: "popcnt", Feature: &X86.HasPOPCNT},
-			option{Name: "sse3", Feature: &X86.HasSSE3},
-			option{Name: "sse41", Feature: &X86.HasSSE41},
-			option{Name: "sse42", Feature: &X86.HasSSE42},
-			option{Name: "ssse3", Feature: &X86.HasSSSE3})
+		options = append(options, v2...)
+	} else {
+		baseline = append(baseline, v2...)
+	}
+
+	// These options are required at level 3. At lower levels
+	// they can be turned off.
+	v3 := []option{
+		{Name: "avx", Feature: &X86.HasAVX},
+		{Name: "avx2", Feature: &X86.HasAVX2},
+		{Name: "bmi1", Feature: &X86.HasBMI1, Forceable: true},
+		{Name: "bmi2", Feature: &X86.HasBMI2, Forceable: true},
+		{Name: "fma", Feature: &X86.HasFMA},
 	}
 	if level < 3 {
-		// These options are required at level 3. At lower levels
-		// they can be turned off.
-		options = append(options,
-			option{Name: "avx", Feature: &X86.HasAVX},
-			option{Name: "avx2", Feature: &X86.HasAVX2},
-			option{Name: "bmi1", Feature: &X86.HasBMI1},
-			option{Name: "bmi2", Feature: &X86.HasBMI2},
-			option{Name: "fma", Feature: &X86.HasFMA})
+		options = append(options, v3...)
+	} else {
+		baseline = append(baseline, v3...)
+	}
+
+	// These options are required at level 4. At lower levels
+	// they can be turned off.
+	v4 := []option{
+		{Name: "avx512f", Feature: &X86.HasAVX512F},
+		{Name: "avx512bw", Feature: &X86.HasAVX512BW},
+		{Name: "avx512vl", Feature: &X86.HasAVX512VL},
 	}
 	if level < 4 {
-		// These options are required at level 4. At lower levels
-		// they can be turned off.
-		options = append(options,
-			option{Name: "avx512f", Feature: &X86.HasAVX512F},
-			option{Name: "avx512bw", Feature: &X86.HasAVX512BW},
-			option{Name: "avx512vl", Feature: &X86.HasAVX512VL},
-		)
+		options = append(options, v4...)
+	} else {
+		baseline = append(baseline, v4...)
 	}
 
 	maxID, _, _, _ := cpuid(0, 0)
@@ -97,7 +136,13 @@ func doinit() {
 
 	maxExtendedFunctionInformation, _, _, _ = cpuid(0x80000000, 0)
 
-	_, _, ecx1, _ := cpuid(1, 0)
+	_, ebx1, ecx1, edx1 := cpuid(1, 0)
+
+	// CLFLUSH line size in units of 8 bytes.
+	if isSet(edx1, cpuid_CLFSH) {
+		setCacheLineSize(uintptr((ebx1>>8)&0xff) * 8)
+	}
+	cacheInit(maxID)
 
 	X86.HasSSE3 = isSet(ecx1, cpuid_SSE3)
 	X86.HasPCLMULQDQ = isSet(ecx1, cpuid_PCLMULQDQ)
@@ -120,6 +165,7 @@ func doinit() {
 
 	osSupportsAVX := false
 	osSupportsAVX512 := false
+	osSupportsAMX := false
 	// For XGETBV, OSXSAVE bit is required and sufficient.
 	if X86.HasOSXSAVE {
 		eax, _ := xgetbv()
@@ -131,6 +177,11 @@ func doinit() {
 		//
 		// Check if opmask, ZMMhi256 and Hi16_ZMM have OS support.
 		osSupportsAVX512 = osSupportsAVX && isSet(eax, 1<<5) && isSet(eax, 1<<6) && isSet(eax, 1<<7)
+
+		// Check if XTILECFG and XTILEDATA have OS support. The OS may
+		// still require the process to ask for XTILEDATA first; see
+		// EnableAMX.
+		osSupportsAMX = is64bit && isSet(eax, 1<<17) && isSet(eax, 1<<18)
 	}
 
 	X86.HasAVX = isSet(ecx1, cpuid_AVX) && osSupportsAVX
@@ -139,7 +190,7 @@ func doinit() {
 		return
 	}
 
-	_, ebx7, _, edx7 := cpuid(7, 0)
+	maxSubleaf7, ebx7, ecx7, edx7 := cpuid(7, 0)
 	X86.HasBMI1 = isSet(ebx7, cpuid_BMI1)
 	X86.HasAVX2 = isSet(ebx7, cpuid_AVX2) && osSupportsAVX
 	X86.HasBMI2 = isSet(ebx7, cpuid_BMI2)
@@ -151,10 +202,23 @@ func doinit() {
 	if X86.HasAVX512F {
 		X86.HasAVX512BW = isSet(ebx7, cpuid_AVX512BW)
 		X86.HasAVX512VL = isSet(ebx7, cpuid_AVX512VL)
+		X86.HasAVX512VNNI = isSet(ecx7, cpuid_AVX512VNNI)
+		X86.HasAVX512VPOPCNTDQ = isSet(ecx7, cpuid_AVX512VPOPCNTDQ)
+		X86.HasAVX512FP16 = isSet(edx7, cpuid_AVX512FP16)
+		if maxSubleaf7 >= 1 {
+			eax71, _, _, _ := cpuid(7, 1)
+			X86.HasAVX512BF16 = isSet(eax71, cpuid_AVX512BF16)
+		}
 	}
 
 	X86.HasFSRM = isSet(edx7, cpuid_FSRM)
 
+	X86.HasAMXTile = isSet(edx7, cpuid_AMXTile) && osSupportsAMX
+	if X86.HasAMXTile {
+		X86.HasAMXInt8 = isSet(edx7, cpuid_AMXInt8)
+		X86.HasAMXBF16 = isSet(edx7, cpuid_AMXBF16)
+	}
+
 	var maxExtendedInformation uint32
 	maxExtendedInformation, _, _, _ = cpuid(0x80000000, 0)
 
@@ -166,6 +230,48 @@ func doinit() {
 	X86.HasRDTSCP = isSet(edxExt1, cpuid_RDTSCP)
 }
 
+// cacheInit fills CacheTopology from the deterministic cache parameters
+// leaf, which is leaf 4 on Intel and leaf 0x8000001d on AMD and Hygon.
+func cacheInit(maxID uint32) {
+	leaf := uint32(4)
+	if maxID < 4 {
+		leaf = 0
+	}
+	if leaf != 0 {
+		if eax, _, _, _ := cpuid(leaf, 0); eax&0x1f == 0 {
+			leaf = 0
+		}
+	}
+	if leaf == 0 && maxExtendedFunctionInformation >= 0x8000001d {
+		leaf = 0x8000001d
+	}
+	if leaf == 0 {
+		return
+	}
+
+	for i := uint32(0); i < 16; i++ {
+		eax, ebx, ecx, _ := cpuid(leaf, i)
+		typ := eax & 0x1f
+		if typ == 0 {
+			break
+		}
+		// Skip instruction caches.
+		if typ != 1 && typ != 3 {
+			continue
+		}
+		lineSize := int(ebx&0xfff) + 1
+		partitions := int((ebx>>12)&0x3ff) + 1
+		ways := int(ebx>>22) + 1
+		sets := int(ecx) + 1
+		CacheTopology = append(CacheTopology, Cache{
+			Level:    int((eax >> 5) & 0x7),
+			LineSize: lineSize,
+			Size:     ways * partitions * lineSize * sets,
+			SharedBy: int((eax>>14)&0xfff) + 1,
+		})
+	}
+}
+
 func isSet(hwc uint32, value uint32) bool {
 	return hwc&value != 0
 }
@@ -9,6 +9,7 @@ package cpu_test
 import (
 	. "internal/cpu"
 	"internal/godebug"
+	"os"
 	"testing"
 )
 
@@ -55,3 +56,53 @@ func TestSSE3DebugOption(t *testing.T) {
 		t.Errorf("X86.HasSSE3 expected %v, got %v", want, got)
 	}
 }
+
+func TestX86AVX512SubsetsAgainstCPUID(t *testing.T) {
+	maxID, _, _, _ := CPUID(0, 0)
+	if maxID < 7 {
+		t.Skip("skipping test: CPUID leaf 7 not supported")
+	}
+	maxSubleaf7, _, ecx7, edx7 := CPUID(7, 0)
+	var eax71 uint32
+	if maxSubleaf7 >= 1 {
+		eax71, _, _, _ = CPUID(7, 1)
+	}
+	for _, tt := range []struct {
+		name string
+		has  bool
+		bit  bool
+	}{
+		{"AVX512VNNI", X86.HasAVX512VNNI, ecx7&(1<<11) != 0},
+		{"AVX512VPOPCNTDQ", X86.HasAVX512VPOPCNTDQ, ecx7&(1<<14) != 0},
+		{"AVX512FP16", X86.HasAVX512FP16, edx7&(1<<23) != 0},
+		{"AVX512BF16", X86.HasAVX512BF16, eax71&(1<<5) != 0},
+	} {
+		if tt.has && !X86.HasAVX512F {
+			t.Errorf("Has%s is true but HasAVX512F is false", tt.name)
+		}
+		if tt.has && !tt.bit {
+			t.Errorf("Has%s is true but the CPUID bit is clear", tt.name)
+		}
+		// Without GODEBUG overrides, a set CPUID bit must be
+		// reported whenever the OS has enabled AVX-512 state.
+		if X86.HasAVX512F && tt.bit && !tt.has && os.Getenv("GODEBUG") == "" {
+			t.Errorf("Has%s is false but the CPUID bit is set", tt.name)
+		}
+	}
+}
+
+func TestX86CacheTopologyAgainstLinux(t *testing.T) {
+	caches := linuxCaches()
+	if len(caches) == 0 {
+		t.Skip("skipping test: cache geometry not available from the OS")
+	}
+	if len(CacheTopology) != len(caches) {
+		t.Fatalf("CacheTopology = %+v, Linux reports %+v", CacheTopology, caches)
+	}
+	for i, c := range CacheTopology {
+		want := caches[i]
+		if c.Level != want.Level || c.LineSize != want.LineSize || c.Size != want.Size {
+			t.Errorf("CacheTopology[%d] = %+v, Linux reports %+v", i, c, want)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build arm64
+
+package cpu
+
+var (
+	SVECNTB            = sveCNTB
+	MIDRMicroarch      = midrMicroarch
+	CPUFamilyMicroarch = cpufamilyMicroarch
+)
+
+// WithARM64FromRegisters calls f with ARM64 temporarily repopulated from
+// the ID registers alone. The ID registers must be readable, which on
+// Linux requires HasCPUID.
+func WithARM64FromRegisters(f func()) {
+	saved := ARM64
+	defer func() { ARM64 = saved }()
+
+	ARM64.HasAES, ARM64.HasPMULL = false, false
+	ARM64.HasSHA1, ARM64.HasSHA2, ARM64.HasSHA512 = false, false, false
+	ARM64.HasSHA3, ARM64.HasSM3, ARM64.HasSM4 = false, false, false
+	ARM64.HasCRC32, ARM64.HasATOMICS = false, false
+	ARM64.HasDIT, ARM64.HasSVE, ARM64.HasSVE2 = false, false, false
+	pfr0 := getpfr0()
+	var zfr0 uint64
+	if pfr0HasSVE(pfr0) {
+		zfr0 = getzfr0()
+	}
+	parseARM64SystemRegisters(getisar0(), pfr0, zfr0)
+	f()
+}
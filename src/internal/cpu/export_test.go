@@ -7,3 +7,14 @@ package cpu
 var (
 	Options = options
 )
+
+type Option = option
+
+// ProcessOptions runs processOptions for env against opts
+// instead of the options of the running architecture.
+func ProcessOptions(opts []Option, env string) {
+	saved := options
+	defer func() { options = saved }()
+	options = opts
+	processOptions(env)
+}
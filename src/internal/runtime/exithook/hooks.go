@@ -11,73 +11,334 @@
 // similar circumstances. That is the say, we are expecting that F
 // uses normal / high-level Go code as opposed to one of the more
 // restricted dialects used for the trickier parts of the runtime.
+//
+// The runtime runs the hooks, through Run, only from an ordinary
+// goroutine with a normal, growable stack, while the scheduler and
+// the garbage collector are still running: when main.main returns,
+// when os.Exit is called, and, if enabled, on a goroutine started for
+// a terminating signal. Hooks are never run from a signal handler, on
+// the system stack, or once the program has started to die from a
+// panic or fatal error. So a hook may allocate, grow its stack, start
+// goroutines, and block on channels, locks and other goroutines, as
+// long as whatever it waits for does not itself wait for the exit.
+// A hook must not:
+//
+//   - call os.Exit on the goroutine running the hooks, which throws;
+//   - call runtime.Goexit, which throws;
+//   - panic, which throws unless recovered as described at
+//     SetRecoverPanics;
+//   - wait for a goroutine that is itself blocked in os.Exit,
+//     which deadlocks, as that goroutine waits for the hooks.
 package exithook
 
 import (
 	"internal/runtime/atomic"
+	"internal/runtime/sys"
 	_ "unsafe" // for linkname
 )
 
 // A Hook is a function to be run at program termination
 // (when someone invokes os.Exit, or when main.main returns).
-// Hooks are run in reverse order of registration:
+// Hooks are run by phase, first the PhaseFirst hooks, then the
+// PhaseNormal hooks, then the PhaseLast hooks. Within a phase,
+// hooks are run in reverse order of registration:
 // the first hook added is the last one run.
 type Hook struct {
-	F            func() // func to run
-	RunOnFailure bool   // whether to run on non-zero exit code
+	F            func()  // func to run
+	RunOnFailure bool    // whether to run on non-zero exit code
+	Phase        int     // PhaseNormal, PhaseFirst or PhaseLast
+	User         bool    // added by user code; see SetRecoverPanics and DefaultTimeout
+	PC           uintptr // where the hook was added, for reports; set by Add if zero
+
+	// Timeout, in nanoseconds, limits how long F may run; see Run.
+	// If it is zero, DefaultTimeout applies to User hooks. If it is
+	// negative, or zero with no default, F may run indefinitely.
+	Timeout int64
+
+	// ExitOnTimeout is whether to skip the remaining hooks when F
+	// overruns its timeout, rather than running them.
+	ExitOnTimeout bool
+
+	id uint64 // set by Add
 }
 
+// Hook phases. The zero phase is PhaseNormal.
+const (
+	PhaseNormal = iota
+	PhaseFirst
+	PhaseLast
+	numPhases
+)
+
+// phaseOrder lists the phases in the order they run.
+var phaseOrder = [numPhases]int{PhaseFirst, PhaseNormal, PhaseLast}
+
+// Run states. Only the first call to Run runs the hooks.
+const (
+	notStarted = iota
+	running
+	done
+)
+
 var (
-	locked  atomic.Int32
-	runGoid atomic.Uint64
-	hooks   []Hook
-	running bool
+	// The following are protected by Lock and Unlock.
+	hooks    [numPhases][]Hook // indexed by phase
+	lastID   uint64            // last ID returned by Add
+	state    int               // notStarted, running or done
+	runGoid  uint64            // goroutine running the hooks
+	runPC    uintptr           // PC of the hook running on runGoid, or 0
+	exitCode int               // exit code, possibly changed by SetExitCode
+	waiters  uint32            // goroutines in Run waiting for the hooks to finish
+
+	// doneSema is released once for each waiter when the hooks are done.
+	doneSema uint32
+
+	// recoverPanics is 1 if panics in recoverable hooks are recovered.
+	recoverPanics atomic.Uint32
 
 	// runtime sets these for us
-	Gosched func()
-	Goid    func() uint64
-	Throw   func(string)
+	Goid       func() uint64
+	Throw      func(msg string, pc uintptr) // pc is as in Hook.PC, or 0
+	Lock       func()
+	Unlock     func()
+	PrintPanic func(e any, pc uintptr)
+	Semacquire func(addr *uint32)
+	Semrelease func(addr *uint32)
+
+	// DefaultTimeout returns the timeout for User hooks that do not set one.
+	DefaultTimeout func() int64
+	// RunTimeout runs f(arg) on a new goroutine and waits for at most
+	// timeout nanoseconds for it to return. It reports whether f returned.
+	RunTimeout func(f func(any), arg any, timeout int64) bool
+	// ReportTimeout reports that the hook added at pc, running on
+	// goroutine goid, overran timeout.
+	ReportTimeout func(pc uintptr, goid uint64, timeout int64, exiting bool)
+	// HookStart and HookEnd are called on the goroutine running the
+	// hook added at pc, just before and just after the hook runs.
+	HookStart func(pc uintptr)
+	HookEnd   func(pc uintptr)
 )
 
-// Add adds a new exit hook.
-func Add(h Hook) {
-	for !locked.CompareAndSwap(0, 1) {
-		Gosched()
+// SetRecoverPanics sets whether a panic in a hook with User set
+// is recovered, in which case it is reported with PrintPanic and the
+// remaining hooks are run. It returns the previous setting.
+// A panic in any other hook is fatal.
+func SetRecoverPanics(enabled bool) bool {
+	v := uint32(0)
+	if enabled {
+		v = 1
+	}
+	return recoverPanics.Swap(v) != 0
+}
+
+// Add adds a new exit hook and returns an ID that can be passed
+// to Remove. IDs are never zero.
+// It may be called concurrently from multiple goroutines,
+// and from an exit hook, in which case the new hook is run
+// next, unless hooks of an earlier phase are pending.
+func Add(h Hook) uint64 {
+	if h.PC == 0 {
+		h.PC = sys.GetCallerPC()
+	}
+	if h.Phase < 0 || h.Phase >= numPhases {
+		Throw("invalid exit hook phase", h.PC)
+	}
+	Lock()
+	lastID++
+	h.id = lastID
+	hooks[h.Phase] = append(hooks[h.Phase], h)
+	Unlock()
+	return h.id
+}
+
+// Remove removes the exit hook with the given ID, and reports
+// whether it was removed before it started running. It may be
+// called concurrently with Run, and from an exit hook.
+func Remove(id uint64) bool {
+	Lock()
+	defer Unlock()
+	for p, hs := range hooks {
+		for i := len(hs) - 1; i >= 0; i-- {
+			if hs[i].id == id {
+				copy(hs[i:], hs[i+1:])
+				hs[len(hs)-1] = Hook{}
+				hooks[p] = hs[:len(hs)-1]
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// next removes and returns the next hook to run.
+// It must be called with the lock held.
+func next() (Hook, bool) {
+	for _, p := range phaseOrder {
+		if hs := hooks[p]; len(hs) > 0 {
+			h := hs[len(hs)-1]
+			hs[len(hs)-1] = Hook{}
+			hooks[p] = hs[:len(hs)-1]
+			return h, true
+		}
 	}
-	hooks = append(hooks, h)
-	locked.Store(0)
+	return Hook{}, false
 }
 
-// Run runs the exit hooks.
+// SetExitCode sets the exit code that Run returns. It reports whether
+// it was called by an exit hook, on the goroutine running the exit
+// hooks; if not, the exit code is not changed. Hooks that run after
+// the change see the new code when deciding whether to run on failure.
+func SetExitCode(code int) bool {
+	Lock()
+	defer Unlock()
+	if state != running || Goid() != runGoid {
+		return false
+	}
+	exitCode = code
+	return true
+}
+
+// Run runs the exit hooks and returns the exit code the program
+// should exit with: code, unless a hook changed it with SetExitCode.
 //
-// If an exit hook panics, Run will throw with the panic on the stack.
+// Each hook is run at most once. Only the first call to Run runs the
+// hooks; later calls, from other goroutines, wait for it to finish
+// and return the same exit code, so that all exits agree.
+//
+// If an exit hook panics, Run will throw with the panic on the stack,
+// unless the panic is recovered as described at SetRecoverPanics.
+// If an exit hook calls runtime.Goexit, Run will throw.
 // If an exit hook invokes exit in the same goroutine, the goroutine will throw.
 // If an exit hook invokes exit in another goroutine, that exit will block.
-func Run(code int) {
-	for !locked.CompareAndSwap(0, 1) {
-		if Goid() == runGoid.Load() {
-			Throw("exit hook invoked exit")
+//
+// The hooks are run without holding the lock, so that they may
+// block, allocate, and add further hooks. A hook with a timeout
+// (see Hook.Timeout) runs on a goroutine of its own, which is
+// abandoned if the hook overruns: it keeps running while the
+// remaining hooks run, or while the program exits.
+func Run(code int) int {
+	Lock()
+	if state == running {
+		if Goid() == runGoid {
+			pc := runPC
+			Unlock()
+			Throw("exit hook invoked exit", pc)
 		}
-		Gosched()
+		waiters++
+		Unlock()
+		Semacquire(&doneSema)
+		Lock()
+	}
+	if state == done {
+		code = exitCode
+		Unlock()
+		return code
 	}
-	defer locked.Store(0)
-	runGoid.Store(Goid())
-	defer runGoid.Store(0)
+	state = running
+	runGoid = Goid()
+	exitCode = code
+	Unlock()
 
-	defer func() {
-		if e := recover(); e != nil {
-			Throw("exit hook invoked panic")
+	goid := Goid()
+	for {
+		Lock()
+		runGoid = goid
+		runPC = 0
+		h, ok := next()
+		if !ok {
+			Unlock()
+			break
+		}
+		skip := exitCode != 0 && !h.RunOnFailure
+		Unlock()
+		if skip {
+			continue
 		}
-	}()
 
-	for len(hooks) > 0 {
-		h := hooks[len(hooks)-1]
-		hooks = hooks[:len(hooks)-1]
-		if code != 0 && !h.RunOnFailure {
+		timeout := h.Timeout
+		if timeout == 0 && h.User {
+			timeout = DefaultTimeout()
+		}
+		if timeout <= 0 {
+			Lock()
+			runPC = h.PC
+			Unlock()
+			runHook(h)
 			continue
 		}
-		h.F()
+		t := new(timedHook)
+		t.h = h
+		if !RunTimeout(runTimedHook, t, timeout) {
+			Lock()
+			t.abandoned = true
+			runGoid = goid
+			runPC = 0
+			id := t.goid
+			Unlock()
+			ReportTimeout(h.PC, id, timeout, h.ExitOnTimeout)
+			if h.ExitOnTimeout {
+				break
+			}
+		}
 	}
+	Lock()
+	state = done
+	runGoid = 0
+	runPC = 0
+	code = exitCode
+	n := waiters
+	waiters = 0
+	Unlock()
+	for range n {
+		Semrelease(&doneSema)
+	}
+	return code
+}
+
+// A timedHook is a hook run with a timeout.
+type timedHook struct {
+	h Hook
+
+	// The following are protected by Lock and Unlock.
+	goid      uint64 // goroutine running h, or 0 if not yet started
+	abandoned bool   // Run stopped waiting for h
+}
+
+// runTimedHook runs the timedHook t on the current goroutine.
+func runTimedHook(arg any) {
+	t := arg.(*timedHook)
+	Lock()
+	if !t.abandoned {
+		t.goid = Goid()
+		runGoid = t.goid
+		runPC = t.h.PC
+	}
+	Unlock()
+	runHook(t.h)
+}
+
+// runHook runs h, throwing if it panics or calls runtime.Goexit.
+// A panic is recovered and reported instead if h is a User hook
+// and SetRecoverPanics is enabled.
+func runHook(h Hook) {
+	done := false
+	defer func() {
+		HookEnd(h.PC)
+		if !done {
+			if e := recover(); e != nil {
+				if h.User && recoverPanics.Load() != 0 {
+					PrintPanic(e, h.PC)
+					return
+				}
+				Throw("exit hook invoked panic", h.PC)
+			}
+			// recover returns nil during Goexit.
+			Throw("exit hook invoked Goexit", h.PC)
+		}
+	}()
+	HookStart(h.PC)
+	h.F()
+	done = true
 }
 
 type exitError string
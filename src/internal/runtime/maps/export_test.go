@@ -10,6 +10,55 @@ import (
 )
 
 type CtrlGroup = ctrlGroup
+type Bitset = bitset
+
+const (
+	CtrlEmpty   = uint8(ctrlEmpty)
+	CtrlDeleted = uint8(ctrlDeleted)
+)
+
+// The portable implementations of the ctrlGroup match methods. Calls through
+// these variables are never replaced with intrinsics.
+var (
+	CtrlGroupMatchH2             = ctrlGroupMatchH2
+	CtrlGroupMatchEmpty          = ctrlGroupMatchEmpty
+	CtrlGroupMatchEmptyOrDeleted = ctrlGroupMatchEmptyOrDeleted
+	CtrlGroupMatchFull           = ctrlGroupMatchFull
+)
+
+func (g *CtrlGroup) Get(i uintptr) uint8         { return uint8(g.get(i)) }
+func (g *CtrlGroup) Set(i uintptr, c uint8)      { g.set(i, ctrl(c)) }
+func (g *CtrlGroup) SetEmpty()                   { g.setEmpty() }
+func (g *CtrlGroup) MatchH2(h uintptr) Bitset    { return g.matchH2(h) }
+func (g *CtrlGroup) MatchEmpty() Bitset          { return g.matchEmpty() }
+func (g *CtrlGroup) MatchEmptyOrDeleted() Bitset { return g.matchEmptyOrDeleted() }
+func (g *CtrlGroup) MatchFull() Bitset           { return g.matchFull() }
+
+// Slots returns the slots in b, in increasing order, using first and
+// removeFirst.
+func (b Bitset) Slots() []uintptr {
+	var slots []uintptr
+	for b != 0 {
+		slots = append(slots, b.first())
+		b = b.removeFirst()
+	}
+	return slots
+}
+
+func (b Bitset) RemoveBelow(i uintptr) Bitset { return b.removeBelow(i) }
+
+// LowestSlots returns the slots in b, in increasing order, using lowestSet
+// and shiftOutLowest.
+func (b Bitset) LowestSlots() []uintptr {
+	var slots []uintptr
+	for i := uintptr(0); b != 0; i++ {
+		if b.lowestSet() {
+			slots = append(slots, i)
+		}
+		b = b.shiftOutLowest()
+	}
+	return slots
+}
 
 const DebugLog = debugLog
 
@@ -17,6 +66,35 @@ var AlignUpPow2 = alignUpPow2
 
 const MaxTableCapacity = maxTableCapacity
 const MaxAvgGroupLoad = maxAvgGroupLoad
+const MaxGlobalDepth = maxGlobalDepth
+const MaxDeepTableCapacity = maxDeepTableCapacity
+
+// SetSplitCapacity makes tables split beyond capacity c, which must be a
+// power of two between 2*abi.SwissMapGroupSlots, the capacity of the
+// first table of a map, and MaxTableCapacity, and returns the previous
+// split capacity. Maps grown with a different split capacity must not
+// be used after it changes.
+func SetSplitCapacity(c uint16) (old uint16) {
+	if c < 2*abi.SwissMapGroupSlots || c > maxTableCapacity || c&(c-1) != 0 {
+		panic("invalid split capacity")
+	}
+	old = splitCapacity
+	splitCapacity = c
+	return old
+}
+
+// SetMaxSplitDepth makes tables at local depth d grow rather than split,
+// and returns the previous maximum split depth. d must be between 1 and
+// MaxGlobalDepth. Maps grown with a different maximum split depth must
+// not be used after it changes.
+func SetMaxSplitDepth(d uint8) (old uint8) {
+	if d < 1 || d > maxGlobalDepth {
+		panic("invalid max split depth")
+	}
+	old = maxSplitDepth
+	maxSplitDepth = d
+	return old
+}
 
 // This isn't equivalent to runtime.maxAlloc. It is fine for basic testing but
 // we can't properly test hint alloc overflows with this.
@@ -27,6 +105,12 @@ func NewTestMap[K comparable, V any](hint uintptr) (*Map, *abi.SwissMapType) {
 	return NewMap(mt, hint, nil, maxAllocTest), mt
 }
 
+// DebugCheck reports whether GODEBUG=swissmapcheck=1 enabled the
+// invariant checks.
+func DebugCheck() bool {
+	return debugCheck
+}
+
 func (m *Map) TableCount() int {
 	if m.dirLen <= 0 {
 		return 0
@@ -56,6 +140,30 @@ func (m *Map) GroupCount() uint64 {
 	return n
 }
 
+// Tables returns the distinct tables of the directory, in directory order.
+// Growing or splitting a table replaces it, so the result also tells
+// whether a table was rehashed.
+func (m *Map) Tables() []*table {
+	var tables []*table
+	for i := range m.dirLen {
+		t := m.directoryAt(uintptr(i))
+		if len(tables) > 0 && tables[len(tables)-1] == t {
+			continue
+		}
+		tables = append(tables, t)
+	}
+	return tables
+}
+
+// CachedGroups returns the start address and length of the groups that
+// the map caches for its single table, or nil and 0 if it doesn't.
+func (m *Map) CachedGroups() (unsafe.Pointer, uintptr) {
+	if m.groups.data == nil {
+		return nil, 0
+	}
+	return m.groups.data, uintptr(m.groups.lengthMask + 1)
+}
+
 // Return a key from a group containing no empty slots.
 //
 // Returns nil if there are no full groups.
@@ -98,6 +206,43 @@ func (m *Map) KeyFromFullGroup(typ *abi.SwissMapType) unsafe.Pointer {
 	return nil
 }
 
+// CheckInvariants checks the invariants of the map and of each of its
+// tables, as checkInvariants does when debugLog is set.
+func (m *Map) CheckInvariants(typ *abi.SwissMapType) {
+	for _, t := range m.Tables() {
+		t.verify(typ, m)
+	}
+	m.verify(typ)
+}
+
+// CorruptCtrl changes the control byte of the slot of key, in a map with
+// tables, to another H2, so that lookups no longer find the key. It
+// returns the index of the slot's group in its table, the slot, and the
+// new control byte.
+func (m *Map) CorruptCtrl(typ *abi.SwissMapType, key unsafe.Pointer) (group uint64, slot uintptr, c uint8) {
+	t := m.TableFor(typ, key)
+	for i := uint64(0); i <= t.groups.lengthMask; i++ {
+		g := t.groups.group(typ, i)
+		for j := uintptr(0); j < abi.SwissMapGroupSlots; j++ {
+			slotKey := g.key(typ, j)
+			if typ.IndirectKey() {
+				slotKey = *((*unsafe.Pointer)(slotKey))
+			}
+			if g.ctrls().get(j)&ctrlEmpty == 0 && typ.Key.Equal(key, slotKey) {
+				c := g.ctrls().get(j) ^ 1
+				g.ctrls().set(j, c)
+				return i, j, uint8(c)
+			}
+		}
+	}
+	panic("key not in map")
+}
+
+// SetUsed sets the map's count of entries, without changing its tables.
+func (m *Map) SetUsed(used uint64) {
+	m.used = used
+}
+
 // Returns nil if the map is small.
 func (m *Map) TableFor(typ *abi.SwissMapType, key unsafe.Pointer) *table {
 	if m.dirLen <= 0 {
@@ -122,3 +267,77 @@ func (t *table) GroupsStart() unsafe.Pointer {
 func (t *table) GroupsLength() uintptr {
 	return uintptr(t.groups.lengthMask + 1)
 }
+
+func (t *table) LocalDepth() uint8 {
+	return t.localDepth
+}
+
+func (m *Map) Seed() uintptr {
+	return m.seed
+}
+
+// ProbeLength returns the number of groups probed to find key, or 0 if
+// key is not in the map.
+func (m *Map) ProbeLength(typ *abi.SwissMapType, key unsafe.Pointer) int {
+	hash := typ.Hasher(key, m.seed)
+	if m.dirLen <= 0 {
+		if _, _, ok := m.getWithKeySmall(typ, hash, key); ok {
+			return 1
+		}
+		return 0
+	}
+
+	t := m.directoryAt(m.directoryIndex(hash))
+	seq := makeProbeSeq(h1(hash), t.groups.lengthMask)
+	for n := 1; ; n++ {
+		g := t.groups.group(typ, seq.offset)
+		match := g.ctrls().matchH2(h2(hash))
+		for match != 0 {
+			i := match.first()
+			slotKey := g.key(typ, i)
+			if typ.IndirectKey() {
+				slotKey = *((*unsafe.Pointer)(slotKey))
+			}
+			if typ.Key.Equal(key, slotKey) {
+				return n
+			}
+			match = match.removeFirst()
+		}
+		if g.ctrls().matchEmpty() != 0 {
+			return 0
+		}
+		seq = seq.next()
+	}
+}
+
+// H1 returns the H1 portion of the hash of key with the map's seed.
+func (m *Map) H1(typ *abi.SwissMapType, key unsafe.Pointer) uintptr {
+	return h1(typ.Hasher(key, m.seed))
+}
+
+// H2 returns the H2 portion of the hash of key with the map's seed.
+func (m *Map) H2(typ *abi.SwissMapType, key unsafe.Pointer) uintptr {
+	return h2(typ.Hasher(key, m.seed))
+}
+
+func (m *Map) GlobalDepth() uint8 {
+	return m.globalDepth
+}
+
+// Hash returns the hash of key with the map's seed.
+func (m *Map) Hash(typ *abi.SwissMapType, key unsafe.Pointer) uintptr {
+	return typ.Hasher(key, m.seed)
+}
+
+// CheckDirectory checks that the table for key in the directory covers
+// the directory index of key. It returns the directory index.
+// Returns 0 if the map is small.
+func (m *Map) CheckDirectory(typ *abi.SwissMapType, key unsafe.Pointer) (uintptr, bool) {
+	if m.dirLen <= 0 {
+		return 0, true
+	}
+	idx := m.directoryIndex(typ.Hasher(key, m.seed))
+	t := m.directoryAt(idx)
+	entries := 1 << (m.globalDepth - t.localDepth)
+	return idx, t.index <= int(idx) && int(idx) < t.index+entries
+}
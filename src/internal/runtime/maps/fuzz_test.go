@@ -9,6 +9,7 @@ import (
 	"bytes"
 	"encoding/binary"
 	"fmt"
+	"internal/abi"
 	"internal/runtime/maps"
 	"reflect"
 	"testing"
@@ -40,6 +41,28 @@ const (
 	fuzzOpGet fuzzOp = iota
 	fuzzOpPut
 	fuzzOpDelete
+
+	// The ops below are only interpreted by FuzzMap.
+
+	// Clear the map.
+	fuzzOpClear
+
+	// Start a new iteration, abandoning the current one, if any.
+	fuzzOpIterStart
+
+	// Advance the current iteration by Elem%16+1 entries.
+	fuzzOpIterNext
+
+	// Put keys from Key upward until the map has
+	// fuzzFillSizes[Elem%len(fuzzFillSizes)] entries.
+	fuzzOpFill
+
+	// Delete Elem%256 keys from Key upward.
+	fuzzOpDeleteRange
+
+	// Iterate over the whole map, without modifying it, and compare
+	// the entries to the reference.
+	fuzzOpSnapshot
 )
 
 func encode(fc []fuzzCommand) []byte {
@@ -210,3 +233,210 @@ func FuzzTable(f *testing.F) {
 		}
 	})
 }
+
+// fuzzFillSizes are the sizes fuzzOpFill fills a map to, on either side of
+// the size of a small map, of a table that must grow, and of a table that
+// must split, and a size that needs a directory of depth 3 or more.
+var fuzzFillSizes = []int{
+	abi.SwissMapGroupSlots - 1,
+	abi.SwissMapGroupSlots,
+	abi.SwissMapGroupSlots + 1,
+	2*maps.MaxAvgGroupLoad - 1,
+	2 * maps.MaxAvgGroupLoad,
+	2*maps.MaxAvgGroupLoad + 1,
+	maps.MaxTableCapacity*maps.MaxAvgGroupLoad/abi.SwissMapGroupSlots - 1,
+	maps.MaxTableCapacity * maps.MaxAvgGroupLoad / abi.SwissMapGroupSlots,
+	maps.MaxTableCapacity*maps.MaxAvgGroupLoad/abi.SwissMapGroupSlots + 1,
+	12000,
+}
+
+// fuzzRef is the reference for FuzzMap, a flat array indexed by key, and
+// the state of the current iteration.
+type fuzzRef struct {
+	present [1 << 16]bool
+	elem    [1 << 16]uint32
+	len     int
+
+	// iter reports whether an iteration is in progress.
+	iter bool
+	// mustSee reports whether a key was present when the iteration
+	// started and hasn't been deleted since. The iteration must
+	// return it.
+	mustSee [1 << 16]bool
+	// seen reports whether the iteration returned a key.
+	seen [1 << 16]bool
+	// renewed reports whether a key was deleted or added since the
+	// iteration started. The iteration may or may not return it, and
+	// may return it again.
+	renewed [1 << 16]bool
+}
+
+func (r *fuzzRef) put(k uint16, e uint32) {
+	if !r.present[k] {
+		r.present[k] = true
+		r.len++
+		r.renewed[k] = true
+	}
+	r.elem[k] = e
+}
+
+func (r *fuzzRef) delete(k uint16) {
+	if r.present[k] {
+		r.present[k] = false
+		r.len--
+		r.mustSee[k] = false
+		r.renewed[k] = true
+	}
+}
+
+func (r *fuzzRef) clear() {
+	for k := range r.present {
+		r.delete(uint16(k))
+	}
+}
+
+func (r *fuzzRef) startIter() {
+	r.iter = true
+	r.mustSee = r.present
+	r.seen = [1 << 16]bool{}
+	r.renewed = [1 << 16]bool{}
+}
+
+// next checks an entry returned by the iteration.
+func (r *fuzzRef) next(t *testing.T, k uint16, e uint32) {
+	t.Helper()
+	if !r.present[k] {
+		t.Fatalf("iteration returned key %d, which is not in the map", k)
+	}
+	if e != r.elem[k] {
+		t.Fatalf("iteration returned key %d elem %d, want %d", k, e, r.elem[k])
+	}
+	if r.seen[k] && !r.renewed[k] {
+		t.Fatalf("iteration returned key %d twice", k)
+	}
+	r.seen[k] = true
+}
+
+// end checks the end of the iteration.
+func (r *fuzzRef) end(t *testing.T) {
+	t.Helper()
+	for k := range r.mustSee {
+		if r.mustSee[k] && !r.seen[k] {
+			t.Fatalf("iteration ended without returning key %d", k)
+		}
+	}
+	r.iter = false
+}
+
+// snapshot checks that an iteration of m, without modification, returns
+// exactly the entries of the reference.
+func (r *fuzzRef) snapshot(t *testing.T, typ *abi.SwissMapType, m *maps.Map) {
+	t.Helper()
+	var seen [1 << 16]bool
+	n := 0
+	it := new(maps.Iter)
+	it.Init(typ, m)
+	for it.Next(); it.Key() != nil; it.Next() {
+		k, e := *(*uint16)(it.Key()), *(*uint32)(it.Elem())
+		if !r.present[k] || e != r.elem[k] {
+			t.Fatalf("snapshot returned key %d elem %d, want present %v elem %d", k, e, r.present[k], r.elem[k])
+		}
+		if seen[k] {
+			t.Fatalf("snapshot returned key %d twice", k)
+		}
+		seen[k] = true
+		n++
+	}
+	if n != r.len {
+		t.Fatalf("snapshot returned %d entries, want %d", n, r.len)
+	}
+}
+
+// FuzzMap interprets its input as commands on a Map[uint16, uint32], as
+// FuzzTable does, including commands to clear the map, to iterate over
+// it while it is modified, and to fill it to sizes at which it changes
+// shape. After every command, it compares the map with a reference.
+func FuzzMap(f *testing.F) {
+	f.Add(encode([]fuzzCommand{
+		{Op: fuzzOpFill, Key: 0, Elem: 1},
+		{Op: fuzzOpIterStart},
+		{Op: fuzzOpIterNext, Elem: 3},
+		{Op: fuzzOpPut, Key: 1000, Elem: 1},
+		{Op: fuzzOpIterNext, Elem: 15},
+		{Op: fuzzOpSnapshot},
+	}))
+
+	f.Fuzz(func(t *testing.T, in []byte) {
+		fc := decode(in)
+		if len(fc) == 0 {
+			return
+		}
+
+		m, typ := maps.NewTestMap[uint16, uint32](8)
+		ref := new(fuzzRef)
+		it := new(maps.Iter)
+		for _, c := range fc {
+			switch c.Op {
+			case fuzzOpGet:
+				elemPtr, ok := m.Get(typ, unsafe.Pointer(&c.Key))
+				if ok != ref.present[c.Key] {
+					t.Fatalf("Get(%d) got ok %v want ok %v", c.Key, ok, ref.present[c.Key])
+				}
+				if ok && *(*uint32)(elemPtr) != ref.elem[c.Key] {
+					t.Fatalf("Get(%d) got %d want %d", c.Key, *(*uint32)(elemPtr), ref.elem[c.Key])
+				}
+			case fuzzOpPut:
+				m.Put(typ, unsafe.Pointer(&c.Key), unsafe.Pointer(&c.Elem))
+				ref.put(c.Key, c.Elem)
+			case fuzzOpDelete:
+				m.Delete(typ, unsafe.Pointer(&c.Key))
+				ref.delete(c.Key)
+			case fuzzOpClear:
+				m.Clear(typ)
+				ref.clear()
+			case fuzzOpIterStart:
+				it = new(maps.Iter)
+				it.Init(typ, m)
+				ref.startIter()
+			case fuzzOpIterNext:
+				if !ref.iter {
+					continue
+				}
+				for range c.Elem%16 + 1 {
+					it.Next()
+					if it.Key() == nil {
+						ref.end(t)
+						break
+					}
+					ref.next(t, *(*uint16)(it.Key()), *(*uint32)(it.Elem()))
+				}
+			case fuzzOpFill:
+				size := fuzzFillSizes[c.Elem%uint32(len(fuzzFillSizes))]
+				for k, i := c.Key, 0; ref.len < size && i < 1<<16; k, i = k+1, i+1 {
+					if ref.present[k] {
+						continue
+					}
+					e := c.Elem ^ uint32(k)
+					m.Put(typ, unsafe.Pointer(&k), unsafe.Pointer(&e))
+					ref.put(k, e)
+				}
+			case fuzzOpDeleteRange:
+				for k, i := c.Key, uint32(0); i < c.Elem%256; k, i = k+1, i+1 {
+					m.Delete(typ, unsafe.Pointer(&k))
+					ref.delete(k)
+				}
+			case fuzzOpSnapshot:
+				ref.snapshot(t, typ, m)
+			default:
+				// Just skip this command to keep the fuzzer
+				// less constrained.
+				continue
+			}
+
+			if m.Used() != uint64(ref.len) {
+				t.Fatalf("Used() got %d want %d after %+v", m.Used(), ref.len, c)
+			}
+		}
+		ref.snapshot(t, typ, m)
+	})
+}
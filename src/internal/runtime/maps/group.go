@@ -12,12 +12,14 @@ import (
 )
 
 const (
-	// Maximum load factor prior to growing.
+	// Maximum number of full slots per group, on average, prior to
+	// growing.
 	//
-	// 7/8 is the same load factor used by Abseil, but Abseil defaults to
-	// 16 slots per group, so they get two empty slots vs our one empty
-	// slot. We may want to reevaluate if this is best for us.
-	maxAvgGroupLoad = 7
+	// This is a load factor of 7/8, the same load factor used by Abseil.
+	// With 16 slots per group (amd64), this leaves two empty slots per
+	// group, as in Abseil. With 8 slots per group, only one slot is left
+	// empty. We may want to reevaluate if this is best for us.
+	maxAvgGroupLoad = abi.SwissMapGroupSlots * 7 / 8
 
 	ctrlEmpty   ctrl = 0b10000000
 	ctrlDeleted ctrl = 0b11111110
@@ -26,20 +28,25 @@ const (
 	bitsetMSB     = 0x8080808080808080
 	bitsetEmpty   = bitsetLSB * uint64(ctrlEmpty)
 	bitsetDeleted = bitsetLSB * uint64(ctrlDeleted)
+
+	// packedBitset reports whether bitset uses one bit per slot rather
+	// than one byte per slot. See bitset.
+	packedBitset = abi.SwissMapGroupSlots > 8
 )
 
 // bitset represents a set of slots within a group.
 //
-// The underlying representation depends on GOARCH.
+// The underlying representation depends on the number of slots in a group.
 //
-// On AMD64, bitset uses one bit per slot, where the bit is set if the slot is
-// part of the set. All of the ctrlGroup.match* methods are replaced with
-// intrinsics that return this packed representation.
+// On AMD64, groups have 16 slots, and bitset uses one bit per slot, where the
+// bit is set if the slot is part of the set. All of the ctrlGroup.match*
+// methods are replaced with SIMD intrinsics that return this packed
+// representation, and their portable implementations produce it too.
 //
-// On other architectures, bitset uses one byte per slot, where each byte is
-// either 0x80 if the slot is part of the set or 0x00 otherwise. This makes it
-// convenient to calculate for an entire group at once using standard
-// arithemetic instructions.
+// On other architectures, groups have 8 slots, and bitset uses one byte per
+// slot, where each byte is either 0x80 if the slot is part of the set or 0x00
+// otherwise. This makes it convenient to calculate for an entire group at once
+// using standard arithemetic instructions.
 type bitset uint64
 
 // first returns the relative index of the first control byte in the group that
@@ -47,14 +54,9 @@ type bitset uint64
 //
 // Preconditions: b is not 0 (empty).
 func (b bitset) first() uintptr {
-	return bitsetFirst(b)
-}
-
-// Portable implementation of first.
-//
-// On AMD64, this is replaced with an intrisic that simply does
-// TrailingZeros64. There is no need to shift as the bitset is packed.
-func bitsetFirst(b bitset) uintptr {
+	if packedBitset {
+		return uintptr(sys.TrailingZeros64(uint64(b)))
+	}
 	return uintptr(sys.TrailingZeros64(uint64(b))) >> 3
 }
 
@@ -66,13 +68,11 @@ func (b bitset) removeFirst() bitset {
 
 // removeBelow clears all set bits below slot i (non-inclusive).
 func (b bitset) removeBelow(i uintptr) bitset {
-	return bitsetRemoveBelow(b, i)
-}
-
-// Portable implementation of removeBelow.
-//
-// On AMD64, this is replaced with an intrisic that clears the lower i bits.
-func bitsetRemoveBelow(b bitset, i uintptr) bitset {
+	if packedBitset {
+		// Clear the lower i bits.
+		mask := (uint64(1) << uint64(i)) - 1
+		return b &^ bitset(mask)
+	}
 	// Clear all bits below slot i's byte.
 	mask := (uint64(1) << (8 * uint64(i))) - 1
 	return b &^ bitset(mask)
@@ -83,27 +83,31 @@ func bitsetRemoveBelow(b bitset, i uintptr) bitset {
 // This is intended for use with shiftOutLowest to loop over all entries in the
 // bitset regardless of whether they are set.
 func (b bitset) lowestSet() bool {
-	return bitsetLowestSet(b)
-}
-
-// Portable implementation of lowestSet.
-//
-// On AMD64, this is replaced with an intrisic that checks the lowest bit.
-func bitsetLowestSet(b bitset) bool {
+	if packedBitset {
+		return b&1 != 0
+	}
 	return b&(1<<7) != 0
 }
 
 // shiftOutLowest shifts the lowest entry out of the bitset. Afterwards, the
 // lowest entry in the bitset corresponds to the next slot.
 func (b bitset) shiftOutLowest() bitset {
-	return bitsetShiftOutLowest(b)
+	if packedBitset {
+		return b >> 1
+	}
+	return b >> 8
 }
 
-// Portable implementation of shiftOutLowest.
-//
-// On AMD64, this is replaced with an intrisic that shifts a single bit.
-func bitsetShiftOutLowest(b bitset) bitset {
-	return b >> 8
+// wordBitset returns the bitset for the i-th control word of a group, given
+// the match result m for that word, which has bit 7 set in the byte of each
+// matching slot and all other bits clear.
+func wordBitset(m uint64, i uintptr) bitset {
+	if !packedBitset {
+		return bitset(m)
+	}
+	// Gather bit 7 of each byte into the low byte: the multiplication
+	// moves bit 8*j to bit 56+j.
+	return bitset((m>>7)*0x0102040810204080>>56) << (8 * i)
 }
 
 // Each slot in the hash table has a control byte which can have one of three
@@ -117,42 +121,50 @@ func bitsetShiftOutLowest(b bitset) bitset {
 type ctrl uint8
 
 // ctrlGroup is a fixed size array of abi.SwissMapGroupSlots control bytes
-// stored in a uint64.
-type ctrlGroup uint64
+// stored in abi.SwissMapCtrlWords uint64 words. Word i holds the control bytes
+// of slots 8*i through 8*i+7, with the lowest slot in the least significant
+// byte.
+//
+// A group has at most two control words, which the matching routines below
+// rely on.
+type ctrlGroup [abi.SwissMapCtrlWords]uint64
 
-// get returns the i-th control byte.
-func (g *ctrlGroup) get(i uintptr) ctrl {
+// ctrlOffset returns the offset of the i-th control byte in a ctrlGroup.
+func ctrlOffset(i uintptr) uintptr {
 	if goarch.BigEndian {
-		return *(*ctrl)(unsafe.Add(unsafe.Pointer(g), 7-i))
+		// The least significant byte of each word comes last.
+		return i ^ 7
 	}
-	return *(*ctrl)(unsafe.Add(unsafe.Pointer(g), i))
+	return i
+}
+
+// get returns the i-th control byte.
+func (g *ctrlGroup) get(i uintptr) ctrl {
+	return *(*ctrl)(unsafe.Add(unsafe.Pointer(g), ctrlOffset(i)))
 }
 
 // set sets the i-th control byte.
 func (g *ctrlGroup) set(i uintptr, c ctrl) {
-	if goarch.BigEndian {
-		*(*ctrl)(unsafe.Add(unsafe.Pointer(g), 7-i)) = c
-		return
-	}
-	*(*ctrl)(unsafe.Add(unsafe.Pointer(g), i)) = c
+	*(*ctrl)(unsafe.Add(unsafe.Pointer(g), ctrlOffset(i))) = c
 }
 
 // setEmpty sets all the control bytes to empty.
 func (g *ctrlGroup) setEmpty() {
-	*g = ctrlGroup(bitsetEmpty)
+	for i := range g {
+		g[i] = bitsetEmpty
+	}
 }
 
 // matchH2 returns the set of slots which are full and for which the 7-bit hash
 // matches the given value. May return false positives.
-func (g ctrlGroup) matchH2(h uintptr) bitset {
+func (g *ctrlGroup) matchH2(h uintptr) bitset {
 	return ctrlGroupMatchH2(g, h)
 }
 
 // Portable implementation of matchH2.
 //
-// Note: On AMD64, this is an intrinsic implemented with SIMD instructions. See
-// note on bitset about the packed instrinsified return value.
-func ctrlGroupMatchH2(g ctrlGroup, h uintptr) bitset {
+// Note: On AMD64, this is an intrinsic implemented with SIMD instructions.
+func ctrlGroupMatchH2(g *ctrlGroup, h uintptr) bitset {
 	// NB: This generic matching routine produces false positive matches when
 	// h is 2^N and the control bytes have a seq of 2^N followed by 2^N+1. For
 	// example: if ctrls==0x0302 and h=02, we'll compute v as 0x0100. When we
@@ -161,74 +173,87 @@ func ctrlGroupMatchH2(g ctrlGroup, h uintptr) bitset {
 	// just a rare inefficiency. Note that they only occur if there is a real
 	// match and never occur on ctrlEmpty, or ctrlDeleted. The subsequent key
 	// comparisons ensure that there is no correctness issue.
-	v := uint64(g) ^ (bitsetLSB * uint64(h))
-	return bitset(((v - bitsetLSB) &^ v) & bitsetMSB)
+	v := g[0] ^ (bitsetLSB * uint64(h))
+	b := wordBitset(((v-bitsetLSB)&^v)&bitsetMSB, 0)
+	if len(g) > 1 {
+		v := g[len(g)-1] ^ (bitsetLSB * uint64(h))
+		b |= wordBitset(((v-bitsetLSB)&^v)&bitsetMSB, 1)
+	}
+	return b
 }
 
 // matchEmpty returns the set of slots in the group that are empty.
-func (g ctrlGroup) matchEmpty() bitset {
+func (g *ctrlGroup) matchEmpty() bitset {
 	return ctrlGroupMatchEmpty(g)
 }
 
 // Portable implementation of matchEmpty.
 //
-// Note: On AMD64, this is an intrinsic implemented with SIMD instructions. See
-// note on bitset about the packed instrinsified return value.
-func ctrlGroupMatchEmpty(g ctrlGroup) bitset {
+// Note: On AMD64, this is an intrinsic implemented with SIMD instructions.
+func ctrlGroupMatchEmpty(g *ctrlGroup) bitset {
 	// An empty slot is   1000 0000
 	// A deleted slot is  1111 1110
 	// A full slot is     0??? ????
 	//
 	// A slot is empty iff bit 7 is set and bit 1 is not. We could select any
 	// of the other bits here (e.g. v << 1 would also work).
-	v := uint64(g)
-	return bitset((v &^ (v << 6)) & bitsetMSB)
+	v := g[0]
+	b := wordBitset((v&^(v<<6))&bitsetMSB, 0)
+	if len(g) > 1 {
+		v := g[len(g)-1]
+		b |= wordBitset((v&^(v<<6))&bitsetMSB, 1)
+	}
+	return b
 }
 
 // matchEmptyOrDeleted returns the set of slots in the group that are empty or
 // deleted.
-func (g ctrlGroup) matchEmptyOrDeleted() bitset {
+func (g *ctrlGroup) matchEmptyOrDeleted() bitset {
 	return ctrlGroupMatchEmptyOrDeleted(g)
 }
 
 // Portable implementation of matchEmptyOrDeleted.
 //
-// Note: On AMD64, this is an intrinsic implemented with SIMD instructions. See
-// note on bitset about the packed instrinsified return value.
-func ctrlGroupMatchEmptyOrDeleted(g ctrlGroup) bitset {
+// Note: On AMD64, this is an intrinsic implemented with SIMD instructions.
+func ctrlGroupMatchEmptyOrDeleted(g *ctrlGroup) bitset {
 	// An empty slot is  1000 0000
 	// A deleted slot is 1111 1110
 	// A full slot is    0??? ????
 	//
 	// A slot is empty or deleted iff bit 7 is set.
-	v := uint64(g)
-	return bitset(v & bitsetMSB)
+	b := wordBitset(g[0]&bitsetMSB, 0)
+	if len(g) > 1 {
+		b |= wordBitset(g[len(g)-1]&bitsetMSB, 1)
+	}
+	return b
 }
 
 // matchFull returns the set of slots in the group that are full.
-func (g ctrlGroup) matchFull() bitset {
+func (g *ctrlGroup) matchFull() bitset {
 	return ctrlGroupMatchFull(g)
 }
 
 // Portable implementation of matchFull.
 //
-// Note: On AMD64, this is an intrinsic implemented with SIMD instructions. See
-// note on bitset about the packed instrinsified return value.
-func ctrlGroupMatchFull(g ctrlGroup) bitset {
+// Note: On AMD64, this is an intrinsic implemented with SIMD instructions.
+func ctrlGroupMatchFull(g *ctrlGroup) bitset {
 	// An empty slot is  1000 0000
 	// A deleted slot is 1111 1110
 	// A full slot is    0??? ????
 	//
 	// A slot is full iff bit 7 is unset.
-	v := uint64(g)
-	return bitset(^v & bitsetMSB)
+	b := wordBitset(^g[0]&bitsetMSB, 0)
+	if len(g) > 1 {
+		b |= wordBitset(^g[len(g)-1]&bitsetMSB, 1)
+	}
+	return b
 }
 
 // groupReference is a wrapper type representing a single slot group stored at
 // data.
 //
 // A group holds abi.SwissMapGroupSlots slots (key/elem pairs) plus their
-// control word.
+// control words.
 type groupReference struct {
 	// data points to the group, which is described by typ.Group and has
 	// layout:
@@ -242,11 +267,16 @@ type groupReference struct {
 	// 	key  typ.Key
 	// 	elem typ.Elem
 	// }
+	//
+	// If typ.Elem is zero-size, it comes first in the slot, at the
+	// key's address, and if the slots are zero-size, they come first
+	// in the group, at the control word's address. This way, neither
+	// needs padding to keep pointers to it within the group.
 	data unsafe.Pointer // data *typ.Group
 }
 
 const (
-	ctrlGroupsSize   = unsafe.Sizeof(ctrlGroup(0))
+	ctrlGroupsSize   = unsafe.Sizeof(ctrlGroup{})
 	groupSlotsOffset = ctrlGroupsSize
 )
 
@@ -269,13 +299,16 @@ func alignUpPow2(n uint64) (uint64, bool) {
 	return v, false
 }
 
-// ctrls returns the group control word.
+// ctrls returns the group control words.
 func (g *groupReference) ctrls() *ctrlGroup {
 	return (*ctrlGroup)(g.data)
 }
 
 // key returns a pointer to the key at index i.
 func (g *groupReference) key(typ *abi.SwissMapType, i uintptr) unsafe.Pointer {
+	if typ.SlotSize == 0 {
+		return g.data
+	}
 	offset := groupSlotsOffset + i*typ.SlotSize
 
 	return unsafe.Pointer(uintptr(g.data) + offset)
@@ -283,6 +316,9 @@ func (g *groupReference) key(typ *abi.SwissMapType, i uintptr) unsafe.Pointer {
 
 // elem returns a pointer to the element at index i.
 func (g *groupReference) elem(typ *abi.SwissMapType, i uintptr) unsafe.Pointer {
+	if typ.SlotSize == 0 {
+		return g.data
+	}
 	offset := groupSlotsOffset + i*typ.SlotSize + typ.ElemOff
 
 	return unsafe.Pointer(uintptr(g.data) + offset)
@@ -305,13 +341,38 @@ type groupsReference struct {
 //
 // Length must be a power of two.
 func newGroups(typ *abi.SwissMapType, length uint64) groupsReference {
+	return newGroupsShared(typ, length, 1)
+}
+
+// newGroupsShared allocates n arrays of length groups as a single object,
+// and returns the first. Each of the others follows the one before it,
+// see next.
+//
+// Length must be a power of two.
+func newGroupsShared(typ *abi.SwissMapType, length, n uint64) groupsReference {
+	// TODO: make the length type the same throughout.
+	data := newarray(typ.Group, int(length*n))
+	size := typ.GroupSize * uintptr(length*n)
+	addStats(Stats{
+		Groups:      length * n,
+		GroupBytes:  uint64(size),
+		GroupUnused: uint64(roundupsize(size, !typ.Group.Pointers()) - size),
+	})
 	return groupsReference{
-		// TODO: make the length type the same throughout.
-		data:       newarray(typ.Group, int(length)),
+		data:       data,
 		lengthMask: length - 1,
 	}
 }
 
+// next returns the array of groups that follows g in an object allocated
+// by newGroupsShared. g must not be the last array of the object.
+func (g *groupsReference) next(typ *abi.SwissMapType) groupsReference {
+	return groupsReference{
+		data:       unsafe.Pointer(uintptr(g.data) + uintptr(g.lengthMask+1)*typ.GroupSize),
+		lengthMask: g.lengthMask,
+	}
+}
+
 // group returns the group at index i.
 func (g *groupsReference) group(typ *abi.SwissMapType, i uint64) groupReference {
 	// TODO(prattmic): Do something here about truncation on cast to
@@ -21,11 +21,12 @@ import (
 //
 // Terminology:
 // - Slot: A storage location of a single key/element pair.
-// - Group: A group of abi.SwissMapGroupSlots (8) slots, plus a control word.
-// - Control word: An 8-byte word which denotes whether each slot is empty,
-//   deleted, or used. If a slot is used, its control byte also contains the
-//   lower 7 bits of the hash (H2).
-// - H1: Upper 57 bits of a hash.
+// - Group: A group of abi.SwissMapGroupSlots (16 on amd64, 8 elsewhere)
+//   slots, plus one control word per 8 slots.
+// - Control word: An 8-byte word which denotes whether each of 8 slots is
+//   empty, deleted, or used. If a slot is used, its control byte also contains
+//   the lower 7 bits of the hash (H2).
+// - H1: Upper 57 bits of a hash (25 bits on 32-bit systems).
 // - H2: Lower 7 bits of a hash.
 // - Table: A complete "Swiss Table" hash table. A table consists of one or
 //   more groups for storage plus metadata to handle operation and determining
@@ -43,8 +44,8 @@ import (
 //
 // The key difference occurs within a group. In a standard open-addressed
 // linear probed hash table, we would check each slot one at a time to find a
-// match. A swiss table utilizes the extra control word to check all 8 slots in
-// parallel.
+// match. A swiss table utilizes the extra control words to check all slots of a
+// group in parallel.
 //
 // Each byte in the control word corresponds to one of the slots in the group.
 // In each byte, 1 bit is used to indicate whether the slot is in use, or if it
@@ -52,10 +53,10 @@ import (
 // the key in that slot. See [ctrl] for the exact encoding.
 //
 // During lookup, we can use some clever bitwise manipulation to compare all 8
-// 7-bit hashes against the input hash in parallel (see [ctrlGroup.matchH2]).
-// That is, we effectively perform 8 steps of probing in a single operation.
-// With SIMD instructions, this could be extended to 16 slots with a 16-byte
-// control word.
+// 7-bit hashes of a control word against the input hash in parallel (see
+// [ctrlGroup.matchH2]). That is, we effectively perform 8 steps of probing in
+// a single operation. On amd64, SIMD instructions compare all 16 control bytes
+// of a group at once, so groups there have 16 slots.
 //
 // Since we only use 7 bits of the 64 bit hash, there is a 1 in 128 (~0.7%)
 // probability of false positive on each slot, but that's fine: we always need
@@ -64,12 +65,14 @@ import (
 // Probing
 //
 // Probing is done using the upper 57 bits (H1) of the hash as an index into
-// the groups array. Probing walks through the groups using quadratic probing
-// until it finds a group with a match or a group with an empty slot. See
-// [probeSeq] for specifics about the probe sequence. Note the probe
-// invariants: the number of groups must be a power of two, and the end of a
-// probe sequence must be a group with an empty slot (the table can never be
-// 100% full).
+// the groups array. As the number of groups is at most
+// maxTableCapacity/abi.SwissMapGroupSlots, only the low maxProbeBits bits of
+// H1 select the first group to check. Probing walks through the groups using
+// quadratic probing until it finds a group with a match or a group with an
+// empty slot. See [probeSeq] for specifics about the probe sequence. Note
+// the probe invariants: the number of groups must be a power of two, and the
+// end of a probe sequence must be a group with an empty slot (the table can
+// never be 100% full).
 //
 // Deletion
 //
@@ -129,6 +132,33 @@ import (
 // Tables track the depth they were created at (localDepth). It is necessary to
 // grow the directory when splitting a table where globalDepth == localDepth.
 //
+// Hash bits
+//
+// Altogether, a hash is split as follows, from the most to the least
+// significant bit:
+//
+//	| directory index | ... | first group | H2 |
+//	  globalDepth bits        maxProbeBits  7 bits
+//	\____________________ H1 ____________________/
+//
+// All keys in a table share the top localDepth bits of their hashes, so the
+// bits that select the first group of a probe sequence must lie below the
+// directory index for keys to spread evenly across the groups of a table. On
+// 64-bit systems there are plenty of bits between the two. On 32-bit systems
+// there are only maxGlobalDepth (18) bits available for the directory index.
+//
+// The directory therefore never grows beyond maxGlobalDepth. A table at
+// that depth grows past maxTableCapacity instead of splitting, up to
+// [maxDeepTableCapacity]. With a good hasher, reaching that depth takes
+// more memory than any address space provides, but a hasher that maps
+// many keys to hashes with a common prefix can get there. The bits of H1
+// that select the first group of a probe sequence in a table larger than
+// maxTableCapacity overlap the directory index, which its keys share, so
+// they start probing in fewer groups than it has, making probe sequences
+// longer, but the map remains correct. H2 likewise never overlaps the
+// directory index in practice; if it did, it would only cause more false
+// positive matches.
+//
 // Iteration
 //
 // Iteration is the most complex part of the map due to Go's generous iteration
@@ -178,17 +208,37 @@ import (
 // grows. This is more straightforward, as the directory orders remains the
 // same after grow, so we just double the index if the directory size doubles.
 
-// Extracts the H1 portion of a hash: the 57 upper bits.
-// TODO(prattmic): what about 32-bit systems?
+const (
+	// h2Bits is the number of bits in H2.
+	h2Bits = 7
+
+	// maxProbeBits is the number of bits of H1 that select the first
+	// group of a probe sequence in a table of maxTableCapacity slots.
+	// It is 6 on amd64 and 7 elsewhere.
+	maxProbeBits = 10 - abi.SwissMapGroupSlotsBits
+
+	// maxGlobalDepth is the largest globalDepth at which the directory
+	// index does not overlap the bits of H1 that select the first
+	// group of a probe sequence. It is 51 on amd64, 50 on other 64-bit
+	// systems and 18 on 32-bit systems.
+	maxGlobalDepth = goarch.PtrSize*8 - h2Bits - maxProbeBits
+)
+
+// Ensure maxProbeBits matches maxTableCapacity.
+const _ = uint(maxTableCapacity/abi.SwissMapGroupSlots - 1<<maxProbeBits)
+const _ = uint(1<<maxProbeBits - maxTableCapacity/abi.SwissMapGroupSlots)
+
+// Extracts the H1 portion of a hash: the 57 upper bits, or 25 on 32-bit
+// systems.
 func h1(h uintptr) uintptr {
-	return h >> 7
+	return h >> h2Bits
 }
 
 // Extracts the H2 portion of a hash: the 7 bits not used for h1.
 //
 // These are used as an occupied control byte.
 func h2(h uintptr) uintptr {
-	return h & 0x7f
+	return h & (1<<h2Bits - 1)
 }
 
 type Map struct {
@@ -222,6 +272,12 @@ type Map struct {
 	dirPtr unsafe.Pointer
 	dirLen int
 
+	// groups caches the groups of the directory's only table while
+	// dirLen is 1, as most maps never split their first table, so that
+	// lookups probe them without loading the directory entry and the
+	// table first. It is zero if dirLen is not 1. See tableGroups.
+	groups groupsReference
+
 	// The number of bits to use in table directory lookups.
 	globalDepth uint8
 
@@ -236,7 +292,8 @@ type Map struct {
 	writing uint8
 
 	// clearSeq is a sequence counter of calls to Clear. It is used to
-	// detect map clears during iteration.
+	// detect map clears during iteration, which end the iteration; see
+	// Iter.Next.
 	clearSeq uint64
 }
 
@@ -260,10 +317,10 @@ func NewMap(mt *abi.SwissMapType, hint uintptr, m *Map, maxAlloc uintptr) *Map {
 	m.seed = uintptr(rand())
 
 	if hint <= abi.SwissMapGroupSlots {
-		// A small map can fill all 8 slots, so no need to increase
-		// target capacity.
+		// A small map can fill all slots of its group, so no need to
+		// increase target capacity.
 		//
-		// In fact, since an 8 slot group is what the first assignment
+		// In fact, since a single group is what the first assignment
 		// to an empty map would allocate anyway, it doesn't matter if
 		// we allocate here or on the first assignment.
 		//
@@ -286,14 +343,27 @@ func NewMap(mt *abi.SwissMapType, hint uintptr, m *Map, maxAlloc uintptr) *Map {
 		return m // return an empty map.
 	}
 
-	dirSize := (uint64(targetCapacity) + maxTableCapacity - 1) / maxTableCapacity
+	// A single table can hold exactly the entries that fit under the
+	// load factor. If more tables are needed, the hash spreads the
+	// entries over them unevenly, so each table is given maximum
+	// capacity and is expected to hold only 3/4 of what fits, which
+	// leaves a margin of several standard deviations. A table that
+	// overflowed would have to split.
+	dirSize := uint64(1)
+	tableCapacity := uint64(targetCapacity)
+	if targetCapacity > uintptr(splitCapacity) {
+		tableHint := uint64(splitCapacity) * maxAvgGroupLoad / abi.SwissMapGroupSlots * 3 / 4
+		dirSize = (uint64(hint) + tableHint - 1) / tableHint
+		tableCapacity = uint64(splitCapacity)
+	}
 	dirSize, overflow := alignUpPow2(dirSize)
-	if overflow || dirSize > uint64(math.MaxUintptr) {
+	if overflow || dirSize > uint64(math.MaxUintptr) || dirSize > 1<<maxSplitDepth {
 		return m // return an empty map.
 	}
+	tableCapacity, _ = alignUpPow2(tableCapacity)
 
 	// Reject hints that are obviously too large.
-	groups, overflow := math.MulUintptr(uintptr(dirSize), maxTableCapacity)
+	groups, overflow := math.MulUintptr(uintptr(dirSize), uintptr(tableCapacity/abi.SwissMapGroupSlots))
 	if overflow {
 		return m // return an empty map.
 	} else {
@@ -307,14 +377,17 @@ func NewMap(mt *abi.SwissMapType, hint uintptr, m *Map, maxAlloc uintptr) *Map {
 	m.globalShift = depthToShift(m.globalDepth)
 
 	directory := make([]*table, dirSize)
+	addStats(Stats{Directory: dirSize})
 
-	for i := range directory {
-		// TODO: Think more about initial table capacity.
-		directory[i] = newTable(mt, uint64(targetCapacity)/dirSize, i, m.globalDepth)
-	}
+	// The tables of a presized map are expected to last, so allocate
+	// their groups together.
+	newTables(mt, directory, tableCapacity, 0, m.globalDepth)
 
 	m.dirPtr = unsafe.Pointer(&directory[0])
 	m.dirLen = len(directory)
+	if m.dirLen == 1 {
+		m.groups = directory[0].groups
+	}
 
 	return m
 }
@@ -326,17 +399,30 @@ func NewEmptyMap() *Map {
 	return m
 }
 
+// directoryIndex returns the index in the directory of the table for hash:
+// the top globalDepth bits of the hash.
 func (m *Map) directoryIndex(hash uintptr) uintptr {
 	if m.dirLen == 1 {
+		// globalShift is the full width of the hash, which would
+		// be masked to a shift of zero below.
 		return 0
 	}
-	return hash >> (m.globalShift & 63)
+	return hash >> (m.globalShift & (goarch.PtrSize*8 - 1))
 }
 
 func (m *Map) directoryAt(i uintptr) *table {
 	return *(**table)(unsafe.Pointer(uintptr(m.dirPtr) + goarch.PtrSize*i))
 }
 
+// tableGroups returns the groups of the table for hash, in a map with a
+// directory. They are cached in m.groups if the map has a single table.
+func (m *Map) tableGroups(hash uintptr) groupsReference {
+	if m.dirLen == 1 {
+		return m.groups
+	}
+	return m.directoryAt(m.directoryIndex(hash)).groups
+}
+
 func (m *Map) directorySet(i uintptr, nt *table) {
 	*(**table)(unsafe.Pointer(uintptr(m.dirPtr) + goarch.PtrSize*i)) = nt
 }
@@ -349,6 +435,69 @@ func (m *Map) replaceTable(nt *table) {
 		//m.directory[nt.index+i] = nt
 		m.directorySet(uintptr(nt.index+i), nt)
 	}
+	if m.dirLen == 1 {
+		m.groups = nt.groups
+	}
+}
+
+// abortWrite is deferred by the functions that hash the entries of a
+// table into new tables as it grows, shrinks, splits or merges. Unless
+// *done is set, the hasher panicked, and abortWrite ends the write that
+// the caller started, as if the panic had come from hashing the key
+// before the write started. The new tables are installed only once every
+// entry is in them, so the map is left as it was, and is usable once the
+// panic is recovered.
+//
+// The hasher doesn't panic on keys that are in a map, which it hashed
+// when they were inserted, but a map must not become unusable if it does.
+func (m *Map) abortWrite(done *bool) {
+	if !*done {
+		m.writing ^= 1
+	}
+}
+
+// sibling returns the table that, with t, covers the entries of the
+// directory of t's parent, the table of depth t.localDepth-1 that t was
+// split from. If the sibling has split in turn, sibling returns the half
+// next to t, of a larger local depth. t.localDepth must be positive.
+func (m *Map) sibling(t *table) *table {
+	entries := 1 << (m.globalDepth - t.localDepth)
+	return m.directoryAt(uintptr(t.index ^ entries))
+}
+
+// maybeShrinkDirectory halves the directory as long as every table covers
+// at least two of its entries, after tables have been merged. Iterations
+// adjust to the smaller directory; see Iter.Next.
+//
+// The directory keeps at least two entries, so that resize doesn't change
+// the seed, which would move keys to other entries of the directory that
+// iterations started before the shrink still use.
+func (m *Map) maybeShrinkDirectory() {
+	for m.globalDepth > 1 {
+		for i := 0; i < m.dirLen; {
+			t := m.directoryAt(uintptr(i))
+			if t.localDepth == m.globalDepth {
+				return
+			}
+			i += 1 << (m.globalDepth - t.localDepth)
+		}
+
+		newDir := make([]*table, m.dirLen/2)
+		addStats(Stats{Directory: uint64(len(newDir))})
+		for i := range newDir {
+			t := m.directoryAt(uintptr(2 * i))
+			newDir[i] = t
+			// As in installTableSplit, update t.index only the
+			// first time we see t.
+			if t.index == 2*i {
+				t.index = i
+			}
+		}
+		m.globalDepth--
+		m.globalShift++
+		m.dirPtr = unsafe.Pointer(&newDir[0])
+		m.dirLen = len(newDir)
+	}
 }
 
 func (m *Map) installTableSplit(old, left, right *table) {
@@ -356,6 +505,7 @@ func (m *Map) installTableSplit(old, left, right *table) {
 		// No room for another level in the directory. Grow the
 		// directory.
 		newDir := make([]*table, m.dirLen*2)
+		addStats(Stats{Directory: uint64(len(newDir))})
 		for i := range m.dirLen {
 			t := m.directoryAt(uintptr(i))
 			newDir[2*i] = t
@@ -373,6 +523,8 @@ func (m *Map) installTableSplit(old, left, right *table) {
 		//m.directory = newDir
 		m.dirPtr = unsafe.Pointer(&newDir[0])
 		m.dirLen = len(newDir)
+		// The map no longer has a single table.
+		m.groups = groupsReference{}
 	}
 
 	// N.B. left and right may still consume multiple indicies if the
@@ -414,6 +566,27 @@ func (m *Map) getWithKey(typ *abi.SwissMapType, key unsafe.Pointer) (unsafe.Poin
 	return m.directoryAt(idx).getWithKey(typ, hash, key)
 }
 
+// getKey is like getWithKey, but doesn't look at the element, for
+// iterations that only use the keys.
+func (m *Map) getKey(typ *abi.SwissMapType, key unsafe.Pointer) (unsafe.Pointer, bool) {
+	if m.Used() == 0 {
+		return nil, false
+	}
+
+	if m.writing != 0 {
+		fatal("concurrent map read and map write")
+	}
+
+	hash := typ.Hasher(key, m.seed)
+
+	if m.dirLen == 0 {
+		return m.getKeySmall(typ, hash, key)
+	}
+
+	idx := m.directoryIndex(hash)
+	return m.directoryAt(idx).getKey(typ, hash, key)
+}
+
 func (m *Map) getWithoutKey(typ *abi.SwissMapType, key unsafe.Pointer) (unsafe.Pointer, bool) {
 	if m.Used() == 0 {
 		return nil, false
@@ -439,15 +612,14 @@ func (m *Map) getWithKeySmall(typ *abi.SwissMapType, hash uintptr, key unsafe.Po
 		data: m.dirPtr,
 	}
 
-	h2 := uint8(h2(hash))
-	ctrls := *g.ctrls()
+	// A small map is a single group, so there is no probe sequence to
+	// end at an empty slot, and no need for matchEmpty. matchH2 only
+	// returns full slots, whatever the low bits of the empty and
+	// deleted control bytes.
+	match := g.ctrls().matchH2(h2(hash))
 
-	for i := uintptr(0); i < abi.SwissMapGroupSlots; i++ {
-		c := uint8(ctrls)
-		ctrls >>= 8
-		if c != h2 {
-			continue
-		}
+	for match != 0 {
+		i := match.first()
 
 		slotKey := g.key(typ, i)
 		if typ.IndirectKey() {
@@ -461,11 +633,36 @@ func (m *Map) getWithKeySmall(typ *abi.SwissMapType, hash uintptr, key unsafe.Po
 			}
 			return slotKey, slotElem, true
 		}
+		match = match.removeFirst()
 	}
 
 	return nil, nil, false
 }
 
+func (m *Map) getKeySmall(typ *abi.SwissMapType, hash uintptr, key unsafe.Pointer) (unsafe.Pointer, bool) {
+	g := groupReference{
+		data: m.dirPtr,
+	}
+
+	match := g.ctrls().matchH2(h2(hash))
+
+	for match != 0 {
+		i := match.first()
+
+		slotKey := g.key(typ, i)
+		if typ.IndirectKey() {
+			slotKey = *((*unsafe.Pointer)(slotKey))
+		}
+
+		if typ.Key.Equal(key, slotKey) {
+			return slotKey, true
+		}
+		match = match.removeFirst()
+	}
+
+	return nil, false
+}
+
 func (m *Map) Put(typ *abi.SwissMapType, key, elem unsafe.Pointer) {
 	slotElem := m.PutSlot(typ, key)
 	typedmemmove(typ.Elem, slotElem, elem)
@@ -491,9 +688,9 @@ func (m *Map) PutSlot(typ *abi.SwissMapType, key unsafe.Pointer) unsafe.Pointer
 	}
 
 	if m.dirLen == 0 {
-		if m.used < abi.SwissMapGroupSlots {
-			elem := m.putSlotSmall(typ, hash, key)
-
+		// Assigning to a key that is already present needs no empty
+		// slot, so look for the key even if the group is full.
+		if elem := m.putSlotSmall(typ, hash, key); elem != nil {
 			if m.writing == 0 {
 				fatal("concurrent map writes")
 			}
@@ -503,9 +700,6 @@ func (m *Map) PutSlot(typ *abi.SwissMapType, key unsafe.Pointer) unsafe.Pointer
 		}
 
 		// Can't fit another entry, grow to full size map.
-		//
-		// TODO(prattmic): If this is an update to an existing key then
-		// we actually don't need to grow.
 		m.growToTable(typ)
 	}
 
@@ -513,6 +707,9 @@ func (m *Map) PutSlot(typ *abi.SwissMapType, key unsafe.Pointer) unsafe.Pointer
 		idx := m.directoryIndex(hash)
 		elem, ok := m.directoryAt(idx).PutSlot(typ, m, hash, key)
 		if !ok {
+			// The table was rehashed, which may have changed the
+			// seed.
+			hash = typ.Hasher(key, m.seed)
 			continue
 		}
 
@@ -525,6 +722,9 @@ func (m *Map) PutSlot(typ *abi.SwissMapType, key unsafe.Pointer) unsafe.Pointer
 	}
 }
 
+// putSlotSmall returns a pointer to the element slot for key in the small
+// map's group, inserting key if it is new. It returns nil if key is new and
+// the group is full, in which case the caller must grow the map.
 func (m *Map) putSlotSmall(typ *abi.SwissMapType, hash uintptr, key unsafe.Pointer) unsafe.Pointer {
 	g := groupReference{
 		data: m.dirPtr,
@@ -543,6 +743,7 @@ func (m *Map) putSlotSmall(typ *abi.SwissMapType, hash uintptr, key unsafe.Point
 		if typ.Key.Equal(key, slotKey) {
 			if typ.NeedKeyUpdate() {
 				typedmemmove(typ.Key, slotKey, key)
+				sanitizerWrite(slotKey, typ.Key.Size_)
 			}
 
 			slotElem := g.elem(typ, i)
@@ -560,6 +761,11 @@ func (m *Map) putSlotSmall(typ *abi.SwissMapType, hash uintptr, key unsafe.Point
 	// more efficient than matchEmpty.
 	match = g.ctrls().matchEmptyOrDeleted()
 	if match == 0 {
+		if m.used == abi.SwissMapGroupSlots {
+			// The key is new and the group is full. The caller
+			// must grow the map.
+			return nil
+		}
 		fatal("small map with no empty slot (concurrent map writes?)")
 		return nil
 	}
@@ -573,6 +779,7 @@ func (m *Map) putSlotSmall(typ *abi.SwissMapType, hash uintptr, key unsafe.Point
 		slotKey = kmem
 	}
 	typedmemmove(typ.Key, slotKey, key)
+	sanitizerWrite(slotKey, typ.Key.Size_)
 
 	slotElem := g.elem(typ, i)
 	if typ.IndirectElem() {
@@ -584,6 +791,8 @@ func (m *Map) putSlotSmall(typ *abi.SwissMapType, hash uintptr, key unsafe.Point
 	g.ctrls().set(i, ctrl(h2(hash)))
 	m.used++
 
+	m.checkInvariants(typ)
+
 	return slotElem
 }
 
@@ -598,6 +807,9 @@ func (m *Map) growToSmall(typ *abi.SwissMapType) {
 }
 
 func (m *Map) growToTable(typ *abi.SwissMapType) {
+	done := false
+	defer m.abortWrite(&done)
+
 	tab := newTable(typ, 2*abi.SwissMapGroupSlots, 0, 0)
 
 	g := groupReference{
@@ -624,24 +836,30 @@ func (m *Map) growToTable(typ *abi.SwissMapType) {
 
 		tab.uncheckedPutSlot(typ, hash, key, elem)
 	}
+	done = true
 
 	directory := make([]*table, 1)
+	addStats(Stats{Entries: uint64(m.used), Directory: 1})
 
 	directory[0] = tab
 
 	m.dirPtr = unsafe.Pointer(&directory[0])
 	m.dirLen = len(directory)
+	m.groups = tab.groups
 
 	m.globalDepth = 0
 	m.globalShift = depthToShift(m.globalDepth)
+
+	tab.checkInvariants(typ, m)
 }
 
-func (m *Map) Delete(typ *abi.SwissMapType, key unsafe.Pointer) {
+// Delete deletes key from the map, if present, and reports whether it was.
+func (m *Map) Delete(typ *abi.SwissMapType, key unsafe.Pointer) bool {
 	if m == nil || m.Used() == 0 {
 		if err := mapKeyError(typ, key); err != nil {
 			panic(err) // see issue 23734
 		}
-		return
+		return false
 	}
 
 	if m.writing != 0 {
@@ -654,11 +872,12 @@ func (m *Map) Delete(typ *abi.SwissMapType, key unsafe.Pointer) {
 	// case we have not actually done a write.
 	m.writing ^= 1 // toggle, see comment on writing
 
+	var found bool
 	if m.dirLen == 0 {
-		m.deleteSmall(typ, hash, key)
+		found = m.deleteSmall(typ, hash, key)
 	} else {
 		idx := m.directoryIndex(hash)
-		m.directoryAt(idx).Delete(typ, m, hash, key)
+		found = m.directoryAt(idx).Delete(typ, m, hash, key)
 	}
 
 	if m.used == 0 {
@@ -672,9 +891,11 @@ func (m *Map) Delete(typ *abi.SwissMapType, key unsafe.Pointer) {
 		fatal("concurrent map writes")
 	}
 	m.writing ^= 1
+
+	return found
 }
 
-func (m *Map) deleteSmall(typ *abi.SwissMapType, hash uintptr, key unsafe.Pointer) {
+func (m *Map) deleteSmall(typ *abi.SwissMapType, hash uintptr, key unsafe.Pointer) bool {
 	g := groupReference{
 		data: m.dirPtr,
 	}
@@ -684,41 +905,51 @@ func (m *Map) deleteSmall(typ *abi.SwissMapType, hash uintptr, key unsafe.Pointe
 	for match != 0 {
 		i := match.first()
 		slotKey := g.key(typ, i)
-		origSlotKey := slotKey
 		if typ.IndirectKey() {
 			slotKey = *((*unsafe.Pointer)(slotKey))
 		}
 		if typ.Key.Equal(key, slotKey) {
-			m.used--
-
-			if typ.IndirectKey() {
-				// Clearing the pointer is sufficient.
-				*(*unsafe.Pointer)(origSlotKey) = nil
-			} else if typ.Key.Pointers() {
-				// Only bother clearing if there are pointers.
-				typedmemclr(typ.Key, slotKey)
-			}
-
-			slotElem := g.elem(typ, i)
-			if typ.IndirectElem() {
-				// Clearing the pointer is sufficient.
-				*(*unsafe.Pointer)(slotElem) = nil
-			} else {
-				// Unlike keys, always clear the elem (even if
-				// it contains no pointers), as compound
-				// assignment operations depend on cleared
-				// deleted values. See
-				// https://go.dev/issue/25936.
-				typedmemclr(typ.Elem, slotElem)
-			}
-
-			// We only have 1 group, so it is OK to immediately
-			// reuse deleted slots.
-			g.ctrls().set(i, ctrlEmpty)
-			return
+			m.removeSlotSmall(typ, g, i)
+			return true
 		}
 		match = match.removeFirst()
 	}
+	return false
+}
+
+// removeSlotSmall removes the entry in full slot i of the small map's group g.
+func (m *Map) removeSlotSmall(typ *abi.SwissMapType, g groupReference, i uintptr) {
+	m.used--
+
+	slotKey := g.key(typ, i)
+	if typ.IndirectKey() {
+		// Clearing the pointer is sufficient.
+		*(*unsafe.Pointer)(slotKey) = nil
+	} else if typ.Key.Pointers() {
+		// Only bother clearing if there are pointers.
+		typedmemclr(typ.Key, slotKey)
+		sanitizerWrite(slotKey, typ.Key.Size_)
+	}
+
+	slotElem := g.elem(typ, i)
+	if typ.IndirectElem() {
+		// Clearing the pointer is sufficient.
+		*(*unsafe.Pointer)(slotElem) = nil
+	} else {
+		// Unlike keys, always clear the elem (even if
+		// it contains no pointers), as compound
+		// assignment operations depend on cleared
+		// deleted values. See
+		// https://go.dev/issue/25936.
+		typedmemclr(typ.Elem, slotElem)
+		sanitizerWrite(slotElem, typ.Elem.Size_)
+	}
+
+	// We only have 1 group, so it is OK to immediately
+	// reuse deleted slots.
+	g.ctrls().set(i, ctrlEmpty)
+
+	m.checkInvariants(typ)
 }
 
 // Clear deletes all entries from the map resulting in an empty map.
@@ -735,19 +966,9 @@ func (m *Map) Clear(typ *abi.SwissMapType) {
 	if m.dirLen == 0 {
 		m.clearSmall(typ)
 	} else {
-		var lastTab *table
-		for i := range m.dirLen {
-			t := m.directoryAt(uintptr(i))
-			if t == lastTab {
-				continue
-			}
-			t.Clear(typ)
-			lastTab = t
-		}
-		m.used = 0
-		m.clearSeq++
-		// TODO: shrink directory?
+		m.clearTables()
 	}
+	m.checkInvariants(typ)
 
 	// Reset the hash seed to make it more difficult for attackers to
 	// repeatedly trigger hash collisions. See https://go.dev/issue/25237.
@@ -765,8 +986,84 @@ func (m *Map) clearSmall(typ *abi.SwissMapType) {
 	}
 
 	typedmemclr(typ.Group, g.data)
+	sanitizerWrite(g.data, typ.Group.Size_)
 	g.ctrls().setEmpty()
 
 	m.used = 0
 	m.clearSeq++
 }
+
+// clearTables empties a map with a directory by dropping its tables
+// and returning to the small map representation, so that the memory of
+// the directory and the tables can be freed. The next insertion
+// allocates a single group, as for a new map.
+//
+// Like rehash, clearTables marks the dropped tables stale, but an
+// iterator holding one of them ends the iteration, as it sees that the
+// map was cleared.
+func (m *Map) clearTables() {
+	var lastTab *table
+	for i := range m.dirLen {
+		t := m.directoryAt(uintptr(i))
+		if t == lastTab {
+			continue
+		}
+		t.index = -1
+		lastTab = t
+	}
+
+	m.dirPtr = nil
+	m.dirLen = 0
+	m.groups = groupsReference{}
+	m.globalDepth = 0
+	m.globalShift = depthToShift(m.globalDepth)
+
+	m.used = 0
+	m.clearSeq++
+}
+
+// Clone returns a copy of m. The copy has the same seed and layout as
+// m, so its groups are copied as they are, without rehashing any keys.
+func (m *Map) Clone(typ *abi.SwissMapType) *Map {
+	if m.writing != 0 {
+		fatal("concurrent map clone and map write")
+	}
+
+	if m.used == 0 {
+		return NewEmptyMap()
+	}
+
+	c := &Map{
+		used:        m.used,
+		seed:        m.seed,
+		globalDepth: m.globalDepth,
+		globalShift: m.globalShift,
+	}
+
+	if m.dirLen == 0 {
+		grp := newGroups(typ, 1)
+		typedmemmove(typ.Group, grp.data, m.dirPtr)
+		cloneIndirect(typ, grp)
+		c.dirPtr = grp.data
+		return c
+	}
+
+	directory := make([]*table, m.dirLen)
+	addStats(Stats{Directory: uint64(m.dirLen)})
+	for i := range m.dirLen {
+		t := m.directoryAt(uintptr(i))
+		if i > 0 && t == m.directoryAt(uintptr(i-1)) {
+			// A table occupies consecutive entries of the directory.
+			directory[i] = directory[i-1]
+			continue
+		}
+		directory[i] = t.clone(typ)
+	}
+	c.dirPtr = unsafe.Pointer(&directory[0])
+	c.dirLen = len(directory)
+	if c.dirLen == 1 {
+		c.groups = directory[0].groups
+	}
+
+	return c
+}
@@ -0,0 +1,49 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build 386 || arm || mips || mipsle
+
+package maps_test
+
+import (
+	"internal/runtime/maps"
+	"testing"
+	"unsafe"
+)
+
+// On 32-bit systems, the directory index is the top globalDepth bits of
+// the 32-bit hash, leaving maxGlobalDepth bits for the directory before
+// it reaches the bits that select the first group of a probe.
+func TestMapHashSplit32(t *testing.T) {
+	if maps.MaxGlobalDepth != 32-7-7 {
+		t.Errorf("MaxGlobalDepth got %d want %d", maps.MaxGlobalDepth, 32-7-7)
+	}
+
+	m, typ := maps.NewTestMap[uint64, uint64](0)
+
+	// Grow the directory several times.
+	const n = 32 * maps.MaxTableCapacity
+	for i := uint64(0); i < n; i++ {
+		elem := i + 256
+		m.Put(typ, unsafe.Pointer(&i), unsafe.Pointer(&elem))
+	}
+	depth := m.GlobalDepth()
+	if depth < 4 {
+		t.Fatalf("GlobalDepth() got %d want at least 4", depth)
+	}
+
+	for i := uint64(0); i < n; i++ {
+		idx, ok := m.CheckDirectory(typ, unsafe.Pointer(&i))
+		if !ok {
+			t.Fatalf("key %d: table at directory index %d does not cover it", i, idx)
+		}
+		if want := m.Hash(typ, unsafe.Pointer(&i)) >> (32 - depth); idx != want {
+			t.Fatalf("key %d: directory index got %d want %d", i, idx, want)
+		}
+		got, ok := m.Get(typ, unsafe.Pointer(&i))
+		if !ok || *(*uint64)(got) != i+256 {
+			t.Fatalf("Get(%d) got %v, %v want %d", i, got, ok, i+256)
+		}
+	}
+}
@@ -210,3 +210,38 @@ func TestTableGroupCount(t *testing.T) {
 		}
 	})
 }
+
+// Maps made by each of the paths through the compiler and runtime get
+// seeds of their own.
+func TestMapSeed(t *testing.T) {
+	seed := func(m map[string]int) uintptr {
+		return (*(**maps.Map)(unsafe.Pointer(&m))).Seed()
+	}
+
+	makers := []struct {
+		name string
+		make func() (map[string]int, map[string]int)
+	}{
+		{"stack", func() (map[string]int, map[string]int) {
+			return make(map[string]int), make(map[string]int)
+		}},
+		{"literal", func() (map[string]int, map[string]int) {
+			return escape(map[string]int{}), escape(map[string]int{})
+		}},
+		{"nohint", func() (map[string]int, map[string]int) {
+			return escape(make(map[string]int)), escape(make(map[string]int))
+		}},
+		{"smallhint", func() (map[string]int, map[string]int) {
+			return escape(make(map[string]int, abi.SwissMapGroupSlots)), escape(make(map[string]int, abi.SwissMapGroupSlots))
+		}},
+		{"hint", func() (map[string]int, map[string]int) {
+			return escape(make(map[string]int, 100)), escape(make(map[string]int, 100))
+		}},
+	}
+	for _, mk := range makers {
+		m1, m2 := mk.make()
+		if s1, s2 := seed(m1), seed(m2); s1 == s2 {
+			t.Errorf("%s: two maps got the same seed %#x", mk.name, s1)
+		}
+	}
+}
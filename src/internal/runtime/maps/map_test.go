@@ -8,18 +8,155 @@ import (
 	"fmt"
 	"internal/abi"
 	"internal/runtime/maps"
+	"internal/testenv"
 	"math"
+	"math/bits"
+	"math/rand/v2"
+	"os"
+	"regexp"
+	"runtime"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 	"unsafe"
 )
 
 func TestCtrlSize(t *testing.T) {
-	cs := unsafe.Sizeof(maps.CtrlGroup(0))
+	cs := unsafe.Sizeof(maps.CtrlGroup{})
 	if cs != abi.SwissMapGroupSlots {
 		t.Errorf("ctrlGroup size got %d want abi.SwissMapGroupSlots %d", cs, abi.SwissMapGroupSlots)
 	}
 }
 
+func TestCtrlGroupGetSet(t *testing.T) {
+	var g maps.CtrlGroup
+	g.SetEmpty()
+	for i := range uintptr(abi.SwissMapGroupSlots) {
+		if c := g.Get(i); c != maps.CtrlEmpty {
+			t.Fatalf("Get(%d) after SetEmpty got %#x want %#x", i, c, maps.CtrlEmpty)
+		}
+	}
+	for i := range uintptr(abi.SwissMapGroupSlots) {
+		g.Set(i, uint8(i))
+	}
+	for i := range uintptr(abi.SwissMapGroupSlots) {
+		if c := g.Get(i); c != uint8(i) {
+			t.Errorf("Get(%d) got %#x want %#x", i, c, i)
+		}
+	}
+	// Slot i is in byte i%8 of word i/8, counting from the least
+	// significant byte.
+	for i := range uintptr(abi.SwissMapGroupSlots) {
+		if c := uint8(g[i/8] >> (8 * (i % 8))); c != uint8(i) {
+			t.Errorf("word %d byte %d got %#x want %#x", i/8, i%8, c, i)
+		}
+	}
+}
+
+// checkMatch checks the result of a ctrlGroup match method, got, against the
+// slots that match, want. If superset is set, got may also contain the slots
+// in extra, as matchH2 may return false positives.
+func checkMatch(t *testing.T, name string, g *maps.CtrlGroup, got maps.Bitset, want []uintptr, superset bool, extra []uintptr) {
+	t.Helper()
+	gotSlots := got.Slots()
+	if superset {
+		gotSlots = slices.DeleteFunc(gotSlots, func(i uintptr) bool {
+			return !slices.Contains(want, i) && slices.Contains(extra, i)
+		})
+	}
+	if !slices.Equal(gotSlots, want) {
+		t.Errorf("%s of %x got slots %v want %v", name, *g, got.Slots(), want)
+	}
+	if lowest := got.LowestSlots(); !slices.Equal(lowest, got.Slots()) {
+		t.Errorf("%s of %x: lowestSet/shiftOutLowest got slots %v, first/removeFirst got %v", name, *g, lowest, got.Slots())
+	}
+}
+
+func TestCtrlGroupMatch(t *testing.T) {
+	// Every combination of full and empty or deleted slots.
+	for pattern := range 1 << abi.SwissMapGroupSlots {
+		var g maps.CtrlGroup
+		var full, empty, emptyOrDeleted []uintptr
+		for i := range uintptr(abi.SwissMapGroupSlots) {
+			switch {
+			case pattern&(1<<i) != 0:
+				g.Set(i, uint8(i*5)&0x7f)
+				full = append(full, i)
+			case i%3 == 0:
+				g.Set(i, maps.CtrlDeleted)
+				emptyOrDeleted = append(emptyOrDeleted, i)
+			default:
+				g.Set(i, maps.CtrlEmpty)
+				empty = append(empty, i)
+				emptyOrDeleted = append(emptyOrDeleted, i)
+			}
+		}
+		checkMatch(t, "matchFull", &g, g.MatchFull(), full, false, nil)
+		checkMatch(t, "matchEmpty", &g, g.MatchEmpty(), empty, false, nil)
+		checkMatch(t, "matchEmptyOrDeleted", &g, g.MatchEmptyOrDeleted(), emptyOrDeleted, false, nil)
+		checkMatch(t, "portable matchFull", &g, maps.CtrlGroupMatchFull(&g), full, false, nil)
+		checkMatch(t, "portable matchEmpty", &g, maps.CtrlGroupMatchEmpty(&g), empty, false, nil)
+		checkMatch(t, "portable matchEmptyOrDeleted", &g, maps.CtrlGroupMatchEmptyOrDeleted(&g), emptyOrDeleted, false, nil)
+		if t.Failed() {
+			return
+		}
+	}
+
+	// Random groups, with few distinct H2 values so that several slots
+	// match.
+	r := rand.New(rand.NewPCG(1, 2))
+	for range 10000 {
+		var g maps.CtrlGroup
+		var full []uintptr
+		for i := range uintptr(abi.SwissMapGroupSlots) {
+			switch c := uint8(r.IntN(10)); c {
+			case 8:
+				g.Set(i, maps.CtrlEmpty)
+			case 9:
+				g.Set(i, maps.CtrlDeleted)
+			default:
+				g.Set(i, c)
+				full = append(full, i)
+			}
+		}
+		for h := range uintptr(8) {
+			var want []uintptr
+			for _, i := range full {
+				if uintptr(g.Get(i)) == h {
+					want = append(want, i)
+				}
+			}
+			// matchH2 may return false positives among the full
+			// slots.
+			name := fmt.Sprintf("matchH2(%d)", h)
+			checkMatch(t, name, &g, g.MatchH2(h), want, true, full)
+			checkMatch(t, "portable "+name, &g, maps.CtrlGroupMatchH2(&g, h), want, true, full)
+		}
+		if t.Failed() {
+			return
+		}
+	}
+}
+
+func TestBitsetRemoveBelow(t *testing.T) {
+	var g maps.CtrlGroup
+	for i := range uintptr(abi.SwissMapGroupSlots) {
+		g.Set(i, 0)
+	}
+	b := g.MatchFull()
+	for i := range uintptr(abi.SwissMapGroupSlots) + 1 {
+		got := b.RemoveBelow(i).Slots()
+		var want []uintptr
+		for j := i; j < abi.SwissMapGroupSlots; j++ {
+			want = append(want, j)
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("removeBelow(%d) got slots %v want %v", i, got, want)
+		}
+	}
+}
+
 func TestMapPut(t *testing.T) {
 	m, typ := maps.NewTestMap[uint32, uint64](8)
 
@@ -95,6 +232,59 @@ func TestMapSplit(t *testing.T) {
 	}
 }
 
+// A map caches the groups of its table while it has a single table, as
+// the table grows, and drops the cache when the table first splits. A
+// clone caches its own groups, and a cleared map none.
+func TestMapCachedGroups(t *testing.T) {
+	m, typ := maps.NewTestMap[uint32, uint64](0)
+	check := func(m *maps.Map, when string) {
+		t.Helper()
+		var want unsafe.Pointer
+		var wantLen uintptr
+		if tables := m.Tables(); len(tables) == 1 {
+			want, wantLen = tables[0].GroupsStart(), tables[0].GroupsLength()
+		}
+		if got, gotLen := m.CachedGroups(); got != want || gotLen != wantLen {
+			t.Fatalf("%s: CachedGroups() got %p, %d want %p, %d", when, got, gotLen, want, wantLen)
+		}
+	}
+	check(m, "new map")
+
+	grows := 0
+	lastGroups, _ := m.CachedGroups()
+	for key := uint32(0); m.TableCount() < 2; key++ {
+		elem := uint64(key) + 256
+		m.Put(typ, unsafe.Pointer(&key), unsafe.Pointer(&elem))
+		check(m, fmt.Sprintf("after put %d", key))
+		if groups, _ := m.CachedGroups(); groups != lastGroups && groups != nil {
+			grows++
+			lastGroups = groups
+		}
+
+		if m.TableCount() == 1 && key == 2*abi.SwissMapGroupSlots {
+			c := m.Clone(typ)
+			check(c, "clone")
+			if got, _ := c.CachedGroups(); got == lastGroups {
+				t.Errorf("clone caches the groups of the original map")
+			}
+		}
+	}
+	// The small map grows into a table of 16 slots, then doubles up to
+	// maximum capacity before splitting.
+	if want := bits.Len(maps.MaxTableCapacity / (2 * abi.SwissMapGroupSlots)); grows != want {
+		t.Errorf("cached groups changed %d times want %d", grows, want)
+	}
+
+	m.Clear(typ)
+	check(m, "after Clear")
+
+	m, typ = maps.NewTestMap[uint32, uint64](maps.MaxTableCapacity / 2)
+	check(m, "presized map")
+	if got, _ := m.CachedGroups(); got == nil {
+		t.Errorf("presized map with a single table caches no groups")
+	}
+}
+
 func TestMapDelete(t *testing.T) {
 	m, typ := maps.NewTestMap[uint32, uint64](32)
 
@@ -136,6 +326,100 @@ func TestMapDelete(t *testing.T) {
 	}
 }
 
+// Delete reports whether the key was present, in small maps and maps with
+// tables, and in a nil map.
+func TestMapDeleteFound(t *testing.T) {
+	for _, n := range []int{abi.SwissMapGroupSlots - 1, 4 * maps.MaxTableCapacity} {
+		t.Run(fmt.Sprint(n), func(t *testing.T) {
+			m, typ := maps.NewTestMap[uint32, uint64](0)
+			for i := 0; i < n; i++ {
+				key := uint32(i)
+				elem := uint64(i) + 256
+				m.Put(typ, unsafe.Pointer(&key), unsafe.Pointer(&elem))
+			}
+
+			for i := 0; i < n; i++ {
+				key := uint32(i)
+				if !m.Delete(typ, unsafe.Pointer(&key)) {
+					t.Errorf("Delete(%d) got false want true", key)
+				}
+				if m.Delete(typ, unsafe.Pointer(&key)) {
+					t.Errorf("Delete(%d) again got true want false", key)
+				}
+				missing := uint32(n + i)
+				if m.Delete(typ, unsafe.Pointer(&missing)) {
+					t.Errorf("Delete(%d) of missing key got true want false", missing)
+				}
+			}
+			if m.Used() != 0 {
+				t.Errorf("Used() got %d want 0", m.Used())
+			}
+		})
+	}
+
+	_, typ := maps.NewTestMap[uint32, uint64](0)
+	var m *maps.Map
+	key := uint32(1)
+	if m.Delete(typ, unsafe.Pointer(&key)) {
+		t.Errorf("Delete(%d) from nil map got true want false", key)
+	}
+}
+
+// Delete finds the keys that an iteration still takes from the table it
+// was on when the table split.
+func TestTableIterationSplitDeleteFound(t *testing.T) {
+	m, typ := maps.NewTestMap[uint32, uint64](0)
+
+	const n = maps.MaxTableCapacity / 2
+	for i := 0; i < n; i++ {
+		key := uint32(i)
+		elem := uint64(i) + 256
+		m.Put(typ, unsafe.Pointer(&key), unsafe.Pointer(&elem))
+	}
+
+	got := make(map[uint32]bool)
+	it := new(maps.Iter)
+	it.Init(typ, m)
+	it.Next()
+	got[*(*uint32)(it.Key())] = true
+
+	// Split the table under the iteration.
+	depth := m.GlobalDepth()
+	for i := n; i < 4*maps.MaxTableCapacity; i++ {
+		key := uint32(i)
+		elem := uint64(i) + 256
+		m.Put(typ, unsafe.Pointer(&key), unsafe.Pointer(&elem))
+	}
+	if m.GlobalDepth() == depth {
+		t.Fatalf("GlobalDepth() got %d after growth, want more", depth)
+	}
+
+	// Delete every other key of the old table, including keys that the
+	// iteration has yet to return from it.
+	for i := 0; i < n; i += 2 {
+		key := uint32(i)
+		if !m.Delete(typ, unsafe.Pointer(&key)) {
+			t.Errorf("Delete(%d) got false want true", key)
+		}
+		if m.Delete(typ, unsafe.Pointer(&key)) {
+			t.Errorf("Delete(%d) again got true want false", key)
+		}
+	}
+
+	for it.Next(); it.Key() != nil; it.Next() {
+		key := *(*uint32)(it.Key())
+		if key < n && key%2 == 0 && !got[key] {
+			t.Errorf("iteration got deleted key %d", key)
+		}
+		got[key] = true
+	}
+	for i := 1; i < n; i += 2 {
+		if !got[uint32(i)] {
+			t.Errorf("iteration missed key %d", i)
+		}
+	}
+}
+
 func TestTableClear(t *testing.T) {
 	m, typ := maps.NewTestMap[uint32, uint64](32)
 
@@ -173,6 +457,72 @@ func TestTableClear(t *testing.T) {
 
 // +0.0 and -0.0 compare equal, but we must still must update the key slot when
 // overwriting.
+// Clear returns a map with tables to the small map representation.
+func TestMapClearShrink(t *testing.T) {
+	m, typ := maps.NewTestMap[uint32, uint64](0)
+
+	for i := 0; i < 3*maps.MaxTableCapacity; i++ {
+		key := uint32(i)
+		elem := uint64(i) + 256
+		m.Put(typ, unsafe.Pointer(&key), unsafe.Pointer(&elem))
+	}
+	if m.TableCount() == 0 {
+		t.Fatalf("TableCount() got 0 before Clear")
+	}
+
+	m.Clear(typ)
+	if m.Used() != 0 || m.TableCount() != 0 || m.GroupCount() != 0 {
+		t.Errorf("after Clear got %d used, %d tables, %d groups, want 0, 0, 0",
+			m.Used(), m.TableCount(), m.GroupCount())
+	}
+
+	key := uint32(1)
+	elem := uint64(257)
+	m.Put(typ, unsafe.Pointer(&key), unsafe.Pointer(&elem))
+	if m.TableCount() != 0 || m.GroupCount() != 1 {
+		t.Errorf("after Put got %d tables, %d groups, want 0, 1", m.TableCount(), m.GroupCount())
+	}
+	if got, ok := m.Get(typ, unsafe.Pointer(&key)); !ok || *(*uint64)(got) != elem {
+		t.Errorf("Get(%d) got %v, %v want %d", key, got, ok, elem)
+	}
+}
+
+// Deleting most entries of a table shrinks the table.
+func TestTableDeleteShrink(t *testing.T) {
+	m, typ := maps.NewTestMap[uint32, uint64](0)
+
+	const n = maps.MaxTableCapacity / 2
+	for i := 0; i < n; i++ {
+		key := uint32(i)
+		elem := uint64(i) + 256
+		m.Put(typ, unsafe.Pointer(&key), unsafe.Pointer(&elem))
+	}
+	before := m.GroupCount()
+
+	for i := 0; i < n; i++ {
+		if i%64 == 0 {
+			continue
+		}
+		key := uint32(i)
+		m.Delete(typ, unsafe.Pointer(&key))
+	}
+	if after := m.GroupCount(); after >= before/8 {
+		t.Errorf("GroupCount() after Delete got %d want less than %d", after, before/8)
+	}
+
+	for i := 0; i < n; i++ {
+		key := uint32(i)
+		got, ok := m.Get(typ, unsafe.Pointer(&key))
+		if wantOK := i%64 == 0; ok != wantOK {
+			t.Errorf("Get(%d) got ok %v want %v", key, ok, wantOK)
+			continue
+		}
+		if ok && *(*uint64)(got) != uint64(i)+256 {
+			t.Errorf("Get(%d) got elem %d want %d", key, *(*uint64)(got), uint64(i)+256)
+		}
+	}
+}
+
 func TestTableKeyUpdate(t *testing.T) {
 	m, typ := maps.NewTestMap[float64, uint64](8)
 
@@ -276,6 +626,209 @@ func TestTablePutDelete(t *testing.T) {
 	}
 }
 
+// Deleting an entry from a full group leaves a tombstone, but clears its
+// key and element right away, so that the memory they point to can be
+// collected before the table is rehashed.
+func TestTableDeleteTombstoneRelease(t *testing.T) {
+	type bigKey struct {
+		p   *[64]byte
+		pad [abi.SwissMapMaxKeyBytes]byte
+	}
+	type bigElem struct {
+		p   *[64]byte
+		pad [abi.SwissMapMaxElemBytes]byte
+	}
+	t.Run("direct", func(t *testing.T) {
+		testTableDeleteTombstoneRelease(t,
+			func(p *[64]byte) *[64]byte { return p },
+			func(p *[64]byte) *[64]byte { return p })
+	})
+	t.Run("indirect", func(t *testing.T) {
+		testTableDeleteTombstoneRelease(t,
+			func(p *[64]byte) bigKey { return bigKey{p: p} },
+			func(p *[64]byte) bigElem { return bigElem{p: p} })
+	})
+}
+
+func testTableDeleteTombstoneRelease[K comparable, V any](t *testing.T, key func(*[64]byte) K, elem func(*[64]byte) V) {
+	// Avoid small maps, they have no tables.
+	m, typ := maps.NewTestMap[K, V](16)
+
+	released := make(chan string, 2)
+	deleteFromFullGroup(t, m, typ, key, elem, released)
+
+	for want := 2; want > 0; {
+		runtime.GC()
+		select {
+		case <-released:
+			want--
+		case <-time.After(5 * time.Second):
+			t.Fatalf("deleted key or element not collected")
+		}
+	}
+	runtime.KeepAlive(m)
+}
+
+// deleteFromFullGroup inserts entries into m until one is in a full
+// group, then deletes that entry, leaving a tombstone, after setting
+// finalizers that send to released on the memory its key and element
+// point to. It keeps no reference to that memory.
+//
+//go:noinline
+func deleteFromFullGroup[K comparable, V any](t *testing.T, m *maps.Map, typ *abi.SwissMapType, key func(*[64]byte) K, elem func(*[64]byte) V, released chan string) {
+	ptrs := make(map[K][2]*[64]byte)
+	var k K
+	for {
+		kp, ep := new([64]byte), new([64]byte)
+		k = key(kp)
+		e := elem(ep)
+		m.Put(typ, unsafe.Pointer(&k), unsafe.Pointer(&e))
+		ptrs[k] = [2]*[64]byte{kp, ep}
+
+		if p := m.KeyFromFullGroup(typ); p != nil {
+			k = *(*K)(p)
+			break
+		}
+		if len(ptrs) == 1<<16 {
+			t.Fatalf("no full group after %d insertions", len(ptrs))
+		}
+	}
+	runtime.SetFinalizer(ptrs[k][0], func(*[64]byte) { released <- "key" })
+	runtime.SetFinalizer(ptrs[k][1], func(*[64]byte) { released <- "elem" })
+	delete(ptrs, k)
+
+	tab := m.TableFor(typ, unsafe.Pointer(&k))
+	growthLeft := tab.GrowthLeft()
+	m.Delete(typ, unsafe.Pointer(&k))
+	if m.TableFor(typ, unsafe.Pointer(&k)) != tab || tab.GrowthLeft() != growthLeft {
+		t.Fatalf("Delete from a full group did not leave a tombstone")
+	}
+}
+
+// Deleting keys from full groups at the start of a probe sequence leaves
+// tombstones, so that the keys further along stay reachable.
+func TestTableDeleteProbeChain(t *testing.T) {
+	// A table of 8 groups. It must not grow or shrink, which would pick a
+	// new seed and break up the probe sequence.
+	m, typ := maps.NewTestMap[uint64, uint64](8 * maps.MaxAvgGroupLoad)
+	key := uint64(0)
+	tab := m.TableFor(typ, unsafe.Pointer(&key))
+	if tab == nil || tab.GroupsLength() != 8 {
+		t.Fatalf("want a single table of 8 groups: %v", m)
+	}
+
+	// Find keys that start probing at the same group, enough to fill two
+	// groups of the probe sequence and all but one slot of a third.
+	const n = 3*abi.SwissMapGroupSlots - 1
+	var keys []uint64
+	want := ^uintptr(0)
+	for ; len(keys) < n; key++ {
+		h := m.H1(typ, unsafe.Pointer(&key)) & 7
+		if want == ^uintptr(0) {
+			want = h
+		}
+		if h == want {
+			keys = append(keys, key)
+		}
+	}
+	for i := range keys {
+		m.Put(typ, unsafe.Pointer(&keys[i]), unsafe.Pointer(&keys[i]))
+	}
+	for i := range keys {
+		if got, want := m.ProbeLength(typ, unsafe.Pointer(&keys[i])), i/abi.SwissMapGroupSlots+1; got != want {
+			t.Fatalf("ProbeLength(%d) got %d want %d", keys[i], got, want)
+		}
+	}
+
+	checkKeys := func(present []uint64) {
+		t.Helper()
+		for i := range present {
+			elem, ok := m.Get(typ, unsafe.Pointer(&present[i]))
+			if !ok || *(*uint64)(elem) != present[i] {
+				t.Errorf("Get(%d) got ok false want true", present[i])
+			}
+		}
+	}
+
+	// Delete the keys in the first, full group, in between lookups of
+	// the rest. Each delete leaves a tombstone.
+	growthLeft := tab.GrowthLeft()
+	for i := range abi.SwissMapGroupSlots {
+		m.Delete(typ, unsafe.Pointer(&keys[i]))
+		if _, ok := m.Get(typ, unsafe.Pointer(&keys[i])); ok {
+			t.Errorf("Get(%d) got ok true want false after Delete", keys[i])
+		}
+		if got, want := m.Used(), uint64(n-i-1); got != want {
+			t.Errorf("Used() got %d want %d", got, want)
+		}
+		if got := tab.GrowthLeft(); got != growthLeft {
+			t.Errorf("GrowthLeft got %d want %d", got, growthLeft)
+		}
+		checkKeys(keys[i+1:])
+	}
+	if m.TableFor(typ, unsafe.Pointer(&keys[0])) != tab {
+		t.Fatalf("Delete replaced the table: %v", m)
+	}
+
+	// Re-inserting the keys reuses the tombstones.
+	for i := range abi.SwissMapGroupSlots {
+		m.Put(typ, unsafe.Pointer(&keys[i]), unsafe.Pointer(&keys[i]))
+	}
+	if got := tab.GrowthLeft(); got != growthLeft {
+		t.Errorf("GrowthLeft got %d want %d after reinsertion", got, growthLeft)
+	}
+	checkKeys(keys)
+
+	// The last group has an empty slot, so deleting from it marks the
+	// slot empty and frees it for growth.
+	last := keys[n-1]
+	m.Delete(typ, unsafe.Pointer(&last))
+	if got, want := tab.GrowthLeft(), growthLeft+1; got != want {
+		t.Errorf("GrowthLeft got %d want %d after deleting from the last group", got, want)
+	}
+	checkKeys(keys[:n-1])
+}
+
+// TestTableSteadyChurn inserts and deletes at a fixed size. Tombstones left
+// by the deletes must be purged by rehashing at the same size, rather than by
+// growing the table.
+func TestTableSteadyChurn(t *testing.T) {
+	cycles := 10_000_000
+	if testing.Short() {
+		cycles = 100_000
+	}
+
+	const live = 1000
+	m, typ := maps.NewTestMap[uint64, uint64](live)
+	for i := uint64(0); i < live; i++ {
+		m.Put(typ, unsafe.Pointer(&i), unsafe.Pointer(&i))
+	}
+	groups := m.GroupCount()
+
+	for i := uint64(live); i < uint64(live+cycles); i++ {
+		old := i - live
+		m.Delete(typ, unsafe.Pointer(&old))
+		m.Put(typ, unsafe.Pointer(&i), unsafe.Pointer(&i))
+
+		if i%(live*100) != 0 {
+			continue
+		}
+		if got := m.Used(); got != live {
+			t.Fatalf("cycle %d: Used() got %d want %d", i-live, got, live)
+		}
+		if got := m.GroupCount(); got != groups {
+			t.Fatalf("cycle %d: GroupCount() got %d want %d", i-live, got, groups)
+		}
+		var probes int
+		for k := i - live + 1; k <= i; k++ {
+			probes += m.ProbeLength(typ, unsafe.Pointer(&k))
+		}
+		if avg := float64(probes) / live; avg > 2 {
+			t.Fatalf("cycle %d: average probe length %.2f groups, want at most 2", i-live, avg)
+		}
+	}
+}
+
 func TestTableIteration(t *testing.T) {
 	m, typ := maps.NewTestMap[uint32, uint64](8)
 
@@ -329,6 +882,42 @@ func TestTableIteration(t *testing.T) {
 	}
 }
 
+// Iterating over a map with an Iter on the stack allocates nothing, and
+// leaves the key and elem pointers in the first two words of the Iter,
+// where compiled range loops read them.
+func TestTableIterationAllocs(t *testing.T) {
+	for _, n := range []uint64{0, 1, abi.SwissMapGroupSlots, 100, 8 * maps.MaxTableCapacity} {
+		m, typ := maps.NewTestMap[uint64, uint64](0)
+		for i := uint64(0); i < n; i++ {
+			elem := i + 256
+			m.Put(typ, unsafe.Pointer(&i), unsafe.Pointer(&elem))
+		}
+
+		var count uint64
+		allocs := testing.AllocsPerRun(10, func() {
+			var it maps.Iter
+			it.Init(typ, m)
+			for it.Next(); it.Key() != nil; it.Next() {
+				words := (*[2]unsafe.Pointer)(unsafe.Pointer(&it))
+				if words[0] != it.Key() || words[1] != it.Elem() {
+					t.Fatalf("Iter does not start with the key and elem pointers")
+				}
+				if *(*uint64)(it.Elem()) != *(*uint64)(it.Key())+256 {
+					t.Fatalf("key %d has elem %d", *(*uint64)(it.Key()), *(*uint64)(it.Elem()))
+				}
+				count++
+			}
+		})
+		if allocs != 0 {
+			t.Errorf("iteration over %d entries: got %v allocs want 0", n, allocs)
+		}
+		// AllocsPerRun runs the function once more to warm up.
+		if count != 11*n {
+			t.Errorf("iteration over %d entries returned %d entries in 11 runs want %d", n, count, 11*n)
+		}
+	}
+}
+
 // Deleted keys shouldn't be visible in iteration.
 func TestTableIterationDelete(t *testing.T) {
 	m, typ := maps.NewTestMap[uint32, uint64](8)
@@ -489,20 +1078,60 @@ func TestTableIterationGrowDelete(t *testing.T) {
 	}
 }
 
-func testTableIterationGrowDuplicate(t *testing.T, grow int) {
-	m, typ := maps.NewTestMap[uint32, uint64](8)
+// Keys that don't equal themselves can't be looked up in the grown
+// tables, so an iteration returns them from the tables it started with,
+// whether or not it uses the elements.
+func TestTableIterationGrowNaN(t *testing.T) {
+	for _, keysOnly := range []bool{false, true} {
+		for _, n := range []int{abi.SwissMapGroupSlots / 2, 100} {
+			m, typ := maps.NewTestMap[float64, uint64](0)
+			for i := 0; i < n; i++ {
+				key := math.NaN()
+				elem := uint64(i)
+				m.Put(typ, unsafe.Pointer(&key), unsafe.Pointer(&elem))
+			}
 
-	key := uint32(0)
-	elem := uint64(256 + 0)
+			count := 0
+			it := new(maps.Iter)
+			if keysOnly {
+				it.InitKeys(typ, m)
+			} else {
+				it.Init(typ, m)
+			}
+			for it.Next(); it.Key() != nil; it.Next() {
+				if count == 0 {
+					// Grow the map from a small map or split
+					// its table.
+					for i := 0; i < 2*maps.MaxTableCapacity; i++ {
+						key := float64(i)
+						m.Put(typ, unsafe.Pointer(&key), unsafe.Pointer(&key))
+					}
+				}
+				if key := *(*float64)(it.Key()); key == key {
+					continue
+				}
+				count++
+				if keysOnly != (it.Elem() == nil) {
+					t.Errorf("keys only %v: NaN key got elem pointer %p", keysOnly, it.Elem())
+				}
+			}
+			if count != n {
+				t.Errorf("keys only %v: iteration got %d NaN keys want %d", keysOnly, count, n)
+			}
+		}
+	}
+}
 
-	for i := 0; i < 31; i++ {
-		key += 1
-		elem += 1
-		m.Put(typ, unsafe.Pointer(&key), unsafe.Pointer(&elem))
+// An iterator that is mid-flight when the map is cleared and returns to
+// the small map representation returns none of the old entries.
+func TestTableIterationClearShrink(t *testing.T) {
+	m, typ := maps.NewTestMap[uint32, uint64](0)
 
-		if maps.DebugLog {
-			fmt.Printf("After put %d: %v\n", key, m)
-		}
+	const n = 3 * maps.MaxTableCapacity
+	for i := 0; i < n; i++ {
+		key := uint32(i)
+		elem := uint64(i) + 256
+		m.Put(typ, unsafe.Pointer(&key), unsafe.Pointer(&elem))
 	}
 
 	got := make(map[uint32]uint64)
@@ -516,56 +1145,778 @@ func testTableIterationGrowDuplicate(t *testing.T, grow int) {
 		}
 
 		key := *(*uint32)(keyPtr)
-		elem := *(*uint64)(elemPtr)
-		if elem != 256+uint64(key) {
-			t.Errorf("iteration got key %d elem %d want elem %d", key, elem, 256+uint64(key))
-		}
 		if _, ok := got[key]; ok {
 			t.Errorf("iteration got key %d more than once", key)
 		}
-		got[key] = elem
+		got[key] = *(*uint64)(elemPtr)
 
-		// Grow halfway through iteration.
 		if i == 16 {
-			key := uint32(32)
-			elem := uint64(256 + 32)
+			m.Clear(typ)
 
-			for i := 0; i < grow; i++ {
-				key += 1
-				elem += 1
+			// Refill with new keys, past the small map size, so
+			// that the map has a new, smaller directory.
+			for i := n; i < n+100; i++ {
+				key := uint32(i)
+				elem := uint64(i) + 256
 				m.Put(typ, unsafe.Pointer(&key), unsafe.Pointer(&elem))
-
-				if maps.DebugLog {
-					fmt.Printf("After put %d: %v\n", key, m)
-				}
 			}
 		}
 	}
 
-	// Don't check length: the number of new elements we'll see is
-	// unspecified.
+	if len(got) != 17 {
+		t.Errorf("iteration got %d entries, want the %d from before the clear", len(got), 17)
+	}
+	for key, elem := range got {
+		if elem != uint64(key)+256 {
+			t.Errorf("iteration key %d got elem %d want %d", key, elem, uint64(key)+256)
+		}
+	}
 }
 
-// Grow should not allow duplicate keys to appear.
-func TestTableIterationGrowDuplicate(t *testing.T) {
-	// Small grow, only enough to cause table grow.
-	t.Run("grow", func(t *testing.T) { testTableIterationGrowDuplicate(t, 32) })
+// An iteration ends at a Clear of its map. It returns no entry from before
+// the Clear, which deleted them all, whether or not the map reuses the
+// group or tables the iterator holds, and no entry added after it.
+func TestTableIterationClear(t *testing.T) {
+	for _, n := range []int{abi.SwissMapGroupSlots / 2, abi.SwissMapGroupSlots, 100, 3 * maps.MaxTableCapacity} {
+		for _, refill := range []struct {
+			name string
+			n    int
+			same bool // refill with the keys from before the clear
+		}{
+			{"none", 0, false},
+			{"small", abi.SwissMapGroupSlots / 2, false},
+			{"same", n, true},
+			{"tables", 4 * maps.MaxTableCapacity, false},
+		} {
+			t.Run(fmt.Sprintf("n=%d/refill=%s", n, refill.name), func(t *testing.T) {
+				m, typ := maps.NewTestMap[uint32, uint64](0)
+				for i := range uint32(n) {
+					elem := uint64(i) + 256
+					m.Put(typ, unsafe.Pointer(&i), unsafe.Pointer(&elem))
+				}
 
-	// Large grow, to cause table split.
-	t.Run("split", func(t *testing.T) { testTableIterationGrowDuplicate(t, 2*maps.MaxTableCapacity) })
+				it := new(maps.Iter)
+				it.Init(typ, m)
+				const before = 3
+				for range before {
+					it.Next()
+					if it.Key() == nil {
+						t.Fatalf("iteration ended before the clear")
+					}
+				}
+
+				m.Clear(typ)
+				for i := range uint32(refill.n) {
+					key := i
+					if !refill.same {
+						key += uint32(n)
+					}
+					elem := uint64(key) + 512
+					m.Put(typ, unsafe.Pointer(&key), unsafe.Pointer(&elem))
+				}
+
+				for it.Next(); it.Key() != nil; it.Next() {
+					t.Errorf("iteration after clear got key %d elem %d", *(*uint32)(it.Key()), *(*uint64)(it.Elem()))
+				}
+				if m.Used() != uint64(refill.n) {
+					t.Errorf("Used() got %d want %d", m.Used(), refill.n)
+				}
+			})
+		}
+	}
 }
 
-func TestAlignUpPow2(t *testing.T) {
-	tests := []struct {
-		in       uint64
-		want     uint64
-		overflow bool
-	}{
-		{
-			in:   0,
-			want: 0,
-		},
-		{
+// An iterator that is mid-flight when deletions shrink its table still
+// returns each remaining entry exactly once.
+func TestTableIterationDeleteShrink(t *testing.T) {
+	m, typ := maps.NewTestMap[uint32, uint64](0)
+
+	const n = maps.MaxTableCapacity / 2
+	for i := 0; i < n; i++ {
+		key := uint32(i)
+		elem := uint64(i) + 256
+		m.Put(typ, unsafe.Pointer(&key), unsafe.Pointer(&elem))
+	}
+	before := m.GroupCount()
+
+	got := make(map[uint32]uint64)
+	var first uint32
+	it := new(maps.Iter)
+	it.Init(typ, m)
+	for i := 0; ; i++ {
+		it.Next()
+		keyPtr, elemPtr := it.Key(), it.Elem()
+		if keyPtr == nil {
+			break
+		}
+
+		key := *(*uint32)(keyPtr)
+		if _, ok := got[key]; ok {
+			t.Errorf("iteration got key %d more than once", key)
+		}
+		got[key] = *(*uint64)(elemPtr)
+
+		if i == 0 {
+			first = key
+
+			// Delete all but every 64th key, shrinking the table,
+			// and update the elements of the remaining keys.
+			for i := 0; i < n; i++ {
+				key := uint32(i)
+				if i%64 != 0 {
+					m.Delete(typ, unsafe.Pointer(&key))
+					continue
+				}
+				elem := uint64(i) + 512
+				m.Put(typ, unsafe.Pointer(&key), unsafe.Pointer(&elem))
+			}
+			if after := m.GroupCount(); after >= before {
+				t.Fatalf("GroupCount() after Delete got %d want less than %d", after, before)
+			}
+		}
+	}
+
+	for i := 0; i < n; i++ {
+		key := uint32(i)
+		if key == first {
+			continue
+		}
+		elem, ok := got[key]
+		if wantOK := i%64 == 0; ok != wantOK {
+			t.Errorf("iteration key %d got ok %v want %v", key, ok, wantOK)
+			continue
+		}
+		if ok && elem != uint64(i)+512 {
+			t.Errorf("iteration key %d got elem %d want %d", key, elem, uint64(i)+512)
+		}
+	}
+}
+
+// An iterator that is mid-flight when deletions merge the tables of a
+// map and shrink its directory still returns each remaining entry
+// exactly once.
+func TestTableIterationMerge(t *testing.T) {
+	m, typ := maps.NewTestMap[uint32, uint64](0)
+
+	const n = 64 * maps.MaxTableCapacity
+	kept := func(i int) bool { return i%256 == 0 }
+	for i := 0; i < n; i++ {
+		key := uint32(i)
+		elem := uint64(i) + 256
+		m.Put(typ, unsafe.Pointer(&key), unsafe.Pointer(&elem))
+	}
+	depth := m.GlobalDepth()
+
+	// deleteRange deletes the keys in [lo, hi) that are not kept.
+	deleteRange := func(lo, hi int) {
+		for i := lo; i < hi; i++ {
+			if !kept(i) {
+				key := uint32(i)
+				m.Delete(typ, unsafe.Pointer(&key))
+			}
+		}
+	}
+
+	got := make(map[uint32]uint64)
+	it := new(maps.Iter)
+	it.Init(typ, m)
+	for i := 0; ; i++ {
+		it.Next()
+		keyPtr, elemPtr := it.Key(), it.Elem()
+		if keyPtr == nil {
+			break
+		}
+
+		key := *(*uint32)(keyPtr)
+		if _, ok := got[key]; ok {
+			t.Errorf("iteration got key %d more than once", key)
+		}
+		got[key] = *(*uint64)(elemPtr)
+
+		// Merge tables in two steps, once the iteration is well
+		// into the directory, and again after it has moved on.
+		switch i {
+		case n / 8:
+			deleteRange(0, n/2)
+		case n/8 + 32:
+			deleteRange(n/2, n)
+			if d := m.GlobalDepth(); d >= depth {
+				t.Fatalf("GlobalDepth() after Delete got %d want less than %d", d, depth)
+			}
+		}
+	}
+
+	for i := 0; i < n; i += 256 {
+		key := uint32(i)
+		elem, ok := got[key]
+		if !ok {
+			t.Errorf("iteration missed key %d", key)
+			continue
+		}
+		if elem != uint64(i)+256 {
+			t.Errorf("iteration key %d got elem %d want %d", key, elem, uint64(i)+256)
+		}
+	}
+}
+
+func testTableIterationGrowDuplicate(t *testing.T, grow int) {
+	m, typ := maps.NewTestMap[uint32, uint64](8)
+
+	key := uint32(0)
+	elem := uint64(256 + 0)
+
+	for i := 0; i < 31; i++ {
+		key += 1
+		elem += 1
+		m.Put(typ, unsafe.Pointer(&key), unsafe.Pointer(&elem))
+
+		if maps.DebugLog {
+			fmt.Printf("After put %d: %v\n", key, m)
+		}
+	}
+
+	got := make(map[uint32]uint64)
+	it := new(maps.Iter)
+	it.Init(typ, m)
+	for i := 0; ; i++ {
+		it.Next()
+		keyPtr, elemPtr := it.Key(), it.Elem()
+		if keyPtr == nil {
+			break
+		}
+
+		key := *(*uint32)(keyPtr)
+		elem := *(*uint64)(elemPtr)
+		if elem != 256+uint64(key) {
+			t.Errorf("iteration got key %d elem %d want elem %d", key, elem, 256+uint64(key))
+		}
+		if _, ok := got[key]; ok {
+			t.Errorf("iteration got key %d more than once", key)
+		}
+		got[key] = elem
+
+		// Grow halfway through iteration.
+		if i == 16 {
+			key := uint32(32)
+			elem := uint64(256 + 32)
+
+			for i := 0; i < grow; i++ {
+				key += 1
+				elem += 1
+				m.Put(typ, unsafe.Pointer(&key), unsafe.Pointer(&elem))
+
+				if maps.DebugLog {
+					fmt.Printf("After put %d: %v\n", key, m)
+				}
+			}
+		}
+	}
+
+	// Don't check length: the number of new elements we'll see is
+	// unspecified.
+}
+
+// Grow should not allow duplicate keys to appear.
+func TestTableIterationGrowDuplicate(t *testing.T) {
+	// Small grow, only enough to cause table grow.
+	t.Run("grow", func(t *testing.T) { testTableIterationGrowDuplicate(t, 32) })
+
+	// Large grow, to cause table split.
+	t.Run("split", func(t *testing.T) { testTableIterationGrowDuplicate(t, 2*maps.MaxTableCapacity) })
+}
+
+// An iterGrowStep mutates a map in the middle of an iteration over it.
+type iterGrowStep struct {
+	at  int  // entries the iteration has returned before the step
+	put int  // new keys to insert
+	del bool // delete every third key present at Init
+}
+
+// An iterGrowCase grows a map of n entries, with tables that split beyond
+// splitCapacity, in steps during an iteration.
+type iterGrowCase struct {
+	name          string
+	splitCapacity uint16
+	n             int
+	steps         []iterGrowStep
+}
+
+// iterGrowCases returns every combination of split capacity, map size,
+// position of the iteration and pattern of growth.
+func iterGrowCases() []iterGrowCase {
+	var cases []iterGrowCase
+	for _, c := range []int{2 * abi.SwissMapGroupSlots, 8 * abi.SwissMapGroupSlots} {
+		load := c * maps.MaxAvgGroupLoad / abi.SwissMapGroupSlots
+		sizes := []struct {
+			name string
+			n    int
+		}{
+			{"half", load / 2},    // one table, growing before it splits
+			{"full", load},        // one table, splitting on the next put
+			{"tables4", 4 * load}, // tables of several depths
+			{"tables16", 16 * load},
+		}
+		for _, size := range sizes {
+			n := size.n
+			for _, at := range []int{0, 1, n / 3, n / 2} {
+				patterns := []struct {
+					name  string
+					steps []iterGrowStep
+				}{
+					// Split the table, or one of them, once.
+					{"split", []iterGrowStep{{at: at, put: c}}},
+					// Split the same region several times
+					// over, each time the iteration moves on.
+					{"resplit", []iterGrowStep{{at: at, put: c}, {at: at + 1, put: 2 * c}, {at: at + 2, put: 4 * c}}},
+					// Grow the directory by several levels
+					// at once.
+					{"deep", []iterGrowStep{{at: at, put: 64 * c}}},
+					// Grow the directory by several levels,
+					// then again after the iteration has
+					// moved on to other tables.
+					{"deep2", []iterGrowStep{{at: at, put: 16 * c}, {at: at + n/8 + 1, put: 64 * c}}},
+				}
+				for _, pat := range patterns {
+					for _, del := range []bool{false, true} {
+						name := fmt.Sprintf("cap%d/%s/at%d/%s", c, size.name, at, pat.name)
+						steps := slices.Clone(pat.steps)
+						if del {
+							name += "/del"
+							for i := range steps {
+								steps[i].del = true
+							}
+						}
+						cases = append(cases, iterGrowCase{name, uint16(c), n, steps})
+					}
+				}
+			}
+		}
+	}
+	return cases
+}
+
+// Growing a map while an iteration is in the middle of a table, in every
+// pattern of splits and directory growth, returns each key at most once,
+// and every key present at Init that isn't deleted at least once, whether
+// or not the iteration uses the elements.
+func TestTableIterationGrowMatrix(t *testing.T) {
+	for _, tc := range iterGrowCases() {
+		t.Run(tc.name, func(t *testing.T) {
+			defer maps.SetSplitCapacity(maps.SetSplitCapacity(tc.splitCapacity))
+
+			// Cover several starting positions of the
+			// iteration, and a random one.
+			for _, seed := range []string{"1", "5", "77", "123456789", ""} {
+				if seed != "" {
+					t.Setenv("GODEBUG", "mapiterseed="+seed)
+				} else {
+					t.Setenv("GODEBUG", "")
+				}
+				for _, keysOnly := range []bool{false, true} {
+					testTableIterationGrow(t, tc, keysOnly)
+					if t.Failed() {
+						t.Fatalf("failed with GODEBUG=mapiterseed=%s, keys only %v", seed, keysOnly)
+					}
+				}
+			}
+		})
+	}
+}
+
+func testTableIterationGrow(t *testing.T, tc iterGrowCase, keysOnly bool) {
+	m, typ := maps.NewTestMap[uint32, uint64](0)
+	put := func(key uint32) {
+		elem := uint64(key) + 256
+		m.Put(typ, unsafe.Pointer(&key), unsafe.Pointer(&elem))
+	}
+	for i := 0; i < tc.n; i++ {
+		put(uint32(i))
+	}
+
+	deleted := make(map[uint32]bool)
+	next := uint32(tc.n)
+	steps := tc.steps
+	got := make(map[uint32]bool)
+	it := new(maps.Iter)
+	if keysOnly {
+		it.InitKeys(typ, m)
+	} else {
+		it.Init(typ, m)
+	}
+	for i := 0; ; i++ {
+		for len(steps) > 0 && steps[0].at == i {
+			for range steps[0].put {
+				put(next)
+				next++
+			}
+			if steps[0].del {
+				for key := uint32(0); key < uint32(tc.n); key += 3 {
+					m.Delete(typ, unsafe.Pointer(&key))
+					deleted[key] = true
+				}
+			}
+			steps = steps[1:]
+		}
+
+		it.Next()
+		keyPtr, elemPtr := it.Key(), it.Elem()
+		if keyPtr == nil {
+			break
+		}
+		key := *(*uint32)(keyPtr)
+		if got[key] {
+			t.Errorf("iteration got key %d more than once", key)
+		}
+		got[key] = true
+		if deleted[key] {
+			t.Errorf("iteration got key %d after it was deleted", key)
+		}
+		if keysOnly {
+			if elemPtr != nil {
+				t.Errorf("keys only iteration key %d got elem pointer %p want nil", key, elemPtr)
+			}
+		} else if elem := *(*uint64)(elemPtr); elem != uint64(key)+256 {
+			t.Errorf("iteration key %d got elem %d want %d", key, elem, uint64(key)+256)
+		}
+	}
+	if len(steps) > 0 {
+		t.Fatalf("iteration ended before step at %d", steps[0].at)
+	}
+
+	for key := uint32(0); key < uint32(tc.n); key++ {
+		if !got[key] && !deleted[key] {
+			t.Errorf("iteration missed key %d", key)
+		}
+	}
+}
+
+// Growing a map with several distinct NaN keys while an iteration is in
+// the middle of a table, in every pattern of iterGrowCases, returns each
+// NaN exactly once with its element, even if deletions of the other keys
+// merge the tables. NaN keys can't be looked up, so every NaN present at
+// Init is still present, with the same element.
+func TestTableIterationGrowMatrixNaN(t *testing.T) {
+	for _, tc := range iterGrowCases() {
+		t.Run(tc.name, func(t *testing.T) {
+			defer maps.SetSplitCapacity(maps.SetSplitCapacity(tc.splitCapacity))
+
+			for _, nans := range []int{1, 5, 40} {
+				for _, merge := range []bool{false, true} {
+					testTableIterationGrowNaN(t, tc, nans, merge)
+					if t.Failed() {
+						t.Fatalf("failed with %d NaN keys, merge %v", nans, merge)
+					}
+				}
+			}
+		})
+	}
+}
+
+// testTableIterationGrowNaN runs tc over a map that also holds nans NaN
+// keys, each with a distinct payload and element. If merge is set, each
+// step deletes all of the other keys, so that the tables merge.
+func testTableIterationGrowNaN(t *testing.T, tc iterGrowCase, nans int, merge bool) {
+	m, typ := maps.NewTestMap[float64, uint64](0)
+	put := func(key float64, elem uint64) {
+		m.Put(typ, unsafe.Pointer(&key), unsafe.Pointer(&elem))
+	}
+	const nanElem = 1 << 32
+	for i := 0; i < tc.n; i++ {
+		put(float64(i), uint64(i)+256)
+	}
+	for i := 0; i < nans; i++ {
+		put(math.Float64frombits(0x7ff8000000000001+uint64(i)), nanElem+uint64(i))
+	}
+
+	deleted := make(map[float64]bool)
+	next := tc.n
+	steps := tc.steps
+	got := make(map[float64]bool)
+	gotNaN := make(map[uint64]int)
+	it := new(maps.Iter)
+	it.Init(typ, m)
+	for i := 0; ; i++ {
+		for len(steps) > 0 && steps[0].at == i {
+			for range steps[0].put {
+				put(float64(next), uint64(next)+256)
+				next++
+			}
+			if steps[0].del || merge {
+				for i := 0; i < next; i++ {
+					if merge || (i < tc.n && i%3 == 0) {
+						key := float64(i)
+						m.Delete(typ, unsafe.Pointer(&key))
+						deleted[key] = true
+					}
+				}
+			}
+			steps = steps[1:]
+		}
+
+		it.Next()
+		keyPtr, elemPtr := it.Key(), it.Elem()
+		if keyPtr == nil {
+			break
+		}
+		key := *(*float64)(keyPtr)
+		elem := *(*uint64)(elemPtr)
+		if key != key {
+			if elem < nanElem || elem >= nanElem+uint64(nans) {
+				t.Errorf("iteration got NaN key with elem %d", elem)
+			}
+			gotNaN[elem]++
+			continue
+		}
+		if got[key] {
+			t.Errorf("iteration got key %v more than once", key)
+		}
+		got[key] = true
+		if deleted[key] {
+			t.Errorf("iteration got key %v after it was deleted", key)
+		}
+		if elem != uint64(key)+256 {
+			t.Errorf("iteration key %v got elem %d want %d", key, elem, uint64(key)+256)
+		}
+	}
+	if len(steps) > 0 && !merge {
+		// With merge, only the NaN keys may be left to return.
+		t.Fatalf("iteration ended before step at %d", steps[0].at)
+	}
+
+	for i := 0; i < nans; i++ {
+		if n := gotNaN[nanElem+uint64(i)]; n != 1 {
+			t.Errorf("iteration got NaN key %d %d times want 1", i, n)
+		}
+	}
+	for i := 0; i < tc.n; i++ {
+		if key := float64(i); !got[key] && !deleted[key] {
+			t.Errorf("iteration missed key %v", key)
+		}
+	}
+}
+
+// rehashFuncs are the functions that hash the entries of a map into new
+// tables.
+var rehashFuncs = []string{
+	"internal/runtime/maps.(*Map).growToTable",
+	"internal/runtime/maps.(*table).resize",
+	"internal/runtime/maps.(*table).split",
+	"internal/runtime/maps.(*table).merge",
+}
+
+// If the hasher panics while a map grows, shrinks, splits or merges
+// tables, the map is left as it was, and is usable once the panic is
+// recovered.
+func TestMapHasherPanic(t *testing.T) {
+	const sentinel = 7
+	slots := abi.SwissMapGroupSlots
+	for _, tc := range []struct {
+		name string
+		n    int  // keys in the map before the hasher is armed
+		put  bool // insert new keys rather than delete present ones
+		want string
+	}{
+		{"growToTable", slots, true, "(*Map).growToTable"},
+		{"grow", 2 * slots, true, "(*table).resize"},
+		{"split", maps.MaxTableCapacity / 2, true, "(*table).split"},
+		{"shrink", maps.MaxTableCapacity / 2, false, "(*table).resize"},
+		{"merge", 4 * maps.MaxTableCapacity, false, "(*table).merge"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			m, typ := maps.NewTestMap[uint64, uint64](0)
+
+			// Once armed, the hasher panics on the sentinel key,
+			// with the function rehashing it, if any.
+			armed := false
+			hasher := typ.Hasher
+			ptyp := *typ
+			ptyp.Hasher = func(p unsafe.Pointer, seed uintptr) uintptr {
+				if armed && *(*uint64)(p) == sentinel {
+					pcs := make([]uintptr, 32)
+					frames := runtime.CallersFrames(pcs[:runtime.Callers(1, pcs)])
+					for {
+						f, more := frames.Next()
+						if strings.HasSuffix(f.Function, ").verify") {
+							// GODEBUG=swissmapcheck=1
+							// hashes every key.
+							break
+						}
+						if slices.Contains(rehashFuncs, f.Function) {
+							panic(f.Function)
+						}
+						if !more {
+							panic("hashed sentinel outside of rehash")
+						}
+					}
+				}
+				return hasher(p, seed)
+			}
+			typ = &ptyp
+
+			present := make(map[uint64]bool)
+			for i := uint64(0); i < uint64(tc.n); i++ {
+				elem := i + 256
+				m.Put(typ, unsafe.Pointer(&i), unsafe.Pointer(&elem))
+				present[i] = true
+			}
+			check := func() {
+				t.Helper()
+				if m.Used() != uint64(len(present)) {
+					t.Fatalf("Used() got %d want %d", m.Used(), len(present))
+				}
+				for key := range present {
+					got, ok := m.Get(typ, unsafe.Pointer(&key))
+					if !ok || *(*uint64)(got) != key+256 {
+						t.Fatalf("Get(%d) got ok %v want elem %d", key, ok, key+256)
+					}
+				}
+				m.CheckInvariants(typ)
+			}
+
+			// op inserts or deletes key, with the hasher armed, and
+			// returns the function the hasher panicked in, if any.
+			op := func(key uint64) (panicked string) {
+				defer func() {
+					armed = false
+					if e := recover(); e != nil {
+						panicked = e.(string)
+					}
+				}()
+				armed = true
+				if tc.put {
+					elem := key + 256
+					m.Put(typ, unsafe.Pointer(&key), unsafe.Pointer(&elem))
+				} else {
+					m.Delete(typ, unsafe.Pointer(&key))
+				}
+				return ""
+			}
+
+			var seen []string
+			for i := uint64(0); i < uint64(tc.n)+maps.MaxTableCapacity && !slices.Contains(seen, "internal/runtime/maps."+tc.want); i++ {
+				key := uint64(tc.n) + i
+				if !tc.put {
+					key = i
+					if key == sentinel {
+						continue
+					}
+				}
+				f := op(key)
+				if f == "" {
+					present[key] = tc.put
+					if !tc.put {
+						delete(present, key)
+					}
+					continue
+				}
+				seen = append(seen, f)
+
+				// A Put that panics doesn't insert the key. A Delete
+				// panics after it has deleted the key.
+				delete(present, key)
+				if _, ok := m.Get(typ, unsafe.Pointer(&key)); ok {
+					t.Fatalf("Get(%d) after panic in %s got ok true want false", key, f)
+				}
+				check()
+			}
+			if !slices.Contains(seen, "internal/runtime/maps."+tc.want) {
+				t.Fatalf("hasher panicked in %v, want %s", seen, tc.want)
+			}
+
+			// The map still grows with the hasher disarmed.
+			for i := uint64(0); i < 4*maps.MaxTableCapacity; i++ {
+				key := 1<<32 + i
+				elem := key + 256
+				m.Put(typ, unsafe.Pointer(&key), unsafe.Pointer(&elem))
+				present[key] = true
+			}
+			check()
+		})
+	}
+}
+
+// A failed invariant check prints a dump of the map that shows the
+// corrupted group and counters.
+func TestMapInvariantDump(t *testing.T) {
+	if mode := os.Getenv("GO_TEST_MAP_INVARIANT_DUMP"); mode != "" {
+		m, typ := maps.NewTestMap[uint32, uint64](0)
+		for i := 0; i < 100; i++ {
+			key := uint32(i)
+			elem := uint64(i) + 256
+			m.Put(typ, unsafe.Pointer(&key), unsafe.Pointer(&elem))
+		}
+		switch mode {
+		case "ctrl":
+			key := uint32(42)
+			group, slot, c := m.CorruptCtrl(typ, unsafe.Pointer(&key))
+			fmt.Fprintf(os.Stderr, "corrupted group %d slot %d ctrl %02x\n", group, slot, c)
+		case "used":
+			m.SetUsed(m.Used() + 1)
+		}
+		m.CheckInvariants(typ)
+		fmt.Fprintf(os.Stderr, "no invariant failed\n")
+		return
+	}
+	testenv.MustHaveExec(t)
+
+	for _, tc := range []struct {
+		mode string
+		want []string
+	}{
+		{"ctrl", []string{
+			`invariant failed: table 0x[0-9a-f]+ slot\(\d+/\d+\): key .* not found`,
+			`probe sequence in table`,
+			`directory:\n\t\t0-0: table 0x[0-9a-f]+ index 0 localDepth 0\n`,
+			`used: 100\n`,
+		}},
+		{"used", []string{
+			`invariant failed: tables have 100 used slots, but map used count is 101`,
+			`used: 101\n`,
+			`directory:`,
+			`group 0:( (\.\.|--|[0-9a-f]{2})){` + fmt.Sprint(abi.SwissMapGroupSlots) + `}\n`,
+		}},
+	} {
+		t.Run(tc.mode, func(t *testing.T) {
+			cmd := testenv.Command(t, testenv.Executable(t), "-test.run=^TestMapInvariantDump$")
+			cmd.Env = append(cmd.Environ(), "GO_TEST_MAP_INVARIANT_DUMP="+tc.mode)
+			out, err := cmd.CombinedOutput()
+			if err == nil {
+				t.Fatalf("child did not fail:\n%s", out)
+			}
+			want := tc.want
+			if tc.mode == "ctrl" {
+				// The corrupted group is printed in the table
+				// and in the probe sequence of its key.
+				m := regexp.MustCompile(`corrupted group (\d+) slot (\d+) ctrl ([0-9a-f]{2})`).FindSubmatch(out)
+				if m == nil {
+					t.Fatalf("child did not corrupt a slot:\n%s", out)
+				}
+				group := fmt.Sprintf(`group %s:( \S\S){%s} %s`, m[1], m[2], m[3])
+				want = append(want, `\t\t\t`+group, `\t\t`+group)
+			}
+			for _, w := range want {
+				if !regexp.MustCompile(w).Match(out) {
+					t.Errorf("output does not match %q", w)
+				}
+			}
+			if t.Failed() || strings.Contains(string(out), "no invariant failed") {
+				t.Fatalf("output:\n%s", out)
+			}
+		})
+	}
+}
+
+func TestAlignUpPow2(t *testing.T) {
+	tests := []struct {
+		in       uint64
+		want     uint64
+		overflow bool
+	}{
+		{
+			in:   0,
+			want: 0,
+		},
+		{
 			in:   3,
 			want: 4,
 		},
@@ -598,9 +1949,263 @@ func TestAlignUpPow2(t *testing.T) {
 	}
 }
 
+// A map created with a hint holds that many entries without growing.
+func TestMapHint(t *testing.T) {
+	hints := []uintptr{
+		0,
+		abi.SwissMapGroupSlots,
+		abi.SwissMapGroupSlots + 1,
+		maps.MaxAvgGroupLoad * 4,
+		maps.MaxAvgGroupLoad*4 + 1,
+		1000,
+		maps.MaxTableCapacity * maps.MaxAvgGroupLoad / abi.SwissMapGroupSlots,
+		maps.MaxTableCapacity,
+		maps.MaxTableCapacity*4 + 1,
+		1e5,
+	}
+	for _, hint := range hints {
+		m, typ := maps.NewTestMap[uint64, uint64](hint)
+		tables, groups := m.Tables(), m.GroupCount()
+		for _, tab := range tables {
+			if got, want := tab.GrowthLeft(), tab.GroupsLength()*maps.MaxAvgGroupLoad; got != uint64(want) {
+				t.Errorf("hint %d: new table GrowthLeft() got %d want %d", hint, got, want)
+			}
+		}
+		for i := range uint64(hint) {
+			m.Put(typ, unsafe.Pointer(&i), unsafe.Pointer(&i))
+		}
+
+		if m.Used() != uint64(hint) {
+			t.Errorf("hint %d: Used() got %d want %d", hint, m.Used(), hint)
+		}
+		// Any rehash or split replaces a table.
+		if got := m.Tables(); !slices.Equal(got, tables) {
+			t.Errorf("hint %d: tables were rehashed or split: %d tables before, %d after", hint, len(tables), len(got))
+		}
+		if hint <= abi.SwissMapGroupSlots {
+			// The group of a small map is allocated by the first Put.
+			continue
+		}
+		if got := m.GroupCount(); got != groups {
+			t.Errorf("hint %d: GroupCount() got %d want %d", hint, got, groups)
+		}
+	}
+}
+
+// A hint too large to allocate, or that needs a directory deeper than
+// tables split to, gives an empty map, which grows as entries are added.
+func TestMapHugeHint(t *testing.T) {
+	// The number of entries NewMap presizes each table of a large map for.
+	const tableHint = maps.MaxTableCapacity * maps.MaxAvgGroupLoad / abi.SwissMapGroupSlots * 3 / 4
+	const depth = 2
+	defer maps.SetMaxSplitDepth(maps.SetMaxSplitDepth(depth))
+
+	for _, tc := range []struct {
+		hint      uintptr
+		wantDepth uint8 // 0 for an empty map
+	}{
+		{^uintptr(0), 0},
+		{math.MaxInt, 0},
+		{math.MaxInt - 1, 0},
+		{math.MaxInt/abi.SwissMapGroupSlots + 1, 0},
+		{math.MaxInt / maps.MaxAvgGroupLoad, 0},
+		{1 << 28, 0},
+		{tableHint << depth, depth},
+		{tableHint<<depth + 1, 0},
+	} {
+		m, typ := maps.NewTestMap[uint64, uint64](tc.hint)
+		if tc.wantDepth == 0 {
+			if m.TableCount() != 0 || m.GroupCount() != 0 {
+				t.Errorf("hint %#x: got %d tables of %d groups want empty map", tc.hint, m.TableCount(), m.GroupCount())
+			}
+		} else if got := m.GlobalDepth(); got != tc.wantDepth {
+			t.Errorf("hint %#x: GlobalDepth() got %d want %d", tc.hint, got, tc.wantDepth)
+		}
+
+		const n = 4 * maps.MaxTableCapacity
+		for i := uint64(0); i < n; i++ {
+			elem := i + 256
+			m.Put(typ, unsafe.Pointer(&i), unsafe.Pointer(&elem))
+		}
+		if m.Used() != n {
+			t.Errorf("hint %#x: Used() got %d want %d", tc.hint, m.Used(), n)
+		}
+		for i := uint64(0); i < n; i++ {
+			got, ok := m.Get(typ, unsafe.Pointer(&i))
+			if !ok || *(*uint64)(got) != i+256 {
+				t.Fatalf("hint %#x: Get(%d) got ok %v want elem %d", tc.hint, i, ok, i+256)
+			}
+		}
+		m.CheckInvariants(typ)
+	}
+}
+
+// Tables at the maximum split depth grow rather than split, so however
+// the hashes of the keys collide, the directory grows no deeper.
+func TestMapMaxSplitDepth(t *testing.T) {
+	const depth = 3
+	defer maps.SetMaxSplitDepth(maps.SetMaxSplitDepth(depth))
+
+	const top = 1 << (8*unsafe.Sizeof(uintptr(0)) - 1)
+	for _, tc := range []struct {
+		name string
+		hash func(h uintptr) uintptr
+	}{
+		// Every key has the directory index 0 at any depth a
+		// table splits to.
+		{"prefix", func(h uintptr) uintptr { return h >> 16 }},
+		// Keys split once, then every key of a table has the
+		// same directory index.
+		{"halves", func(h uintptr) uintptr { return h&top | h>>16 }},
+		// Every key collides.
+		{"constant", func(h uintptr) uintptr { return 0 }},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			m, typ := maps.NewTestMap[uint64, uint64](0)
+			hasher := typ.Hasher
+			htyp := *typ
+			htyp.Hasher = func(p unsafe.Pointer, seed uintptr) uintptr {
+				return tc.hash(hasher(p, seed))
+			}
+			typ = &htyp
+
+			const n = 4 * maps.MaxTableCapacity
+			for i := uint64(0); i < n; i++ {
+				elem := i + 256
+				m.Put(typ, unsafe.Pointer(&i), unsafe.Pointer(&elem))
+			}
+			if got := m.GlobalDepth(); got != depth {
+				t.Errorf("GlobalDepth() got %d want %d", got, depth)
+			}
+			deep := false
+			for _, tab := range m.Tables() {
+				if tab.LocalDepth() > depth {
+					t.Errorf("table local depth %d beyond maximum split depth %d", tab.LocalDepth(), depth)
+				}
+				if tab.GroupsLength()*abi.SwissMapGroupSlots > maps.MaxTableCapacity {
+					deep = true
+				}
+			}
+			if !deep {
+				t.Errorf("no table grew beyond MaxTableCapacity")
+			}
+			m.CheckInvariants(typ)
+
+			for i := uint64(0); i < n; i++ {
+				got, ok := m.Get(typ, unsafe.Pointer(&i))
+				if !ok || *(*uint64)(got) != i+256 {
+					t.Fatalf("Get(%d) got ok %v want elem %d", i, ok, i+256)
+				}
+			}
+			seen := make(map[uint64]bool)
+			it := new(maps.Iter)
+			it.Init(typ, m)
+			for it.Next(); it.Key() != nil; it.Next() {
+				key := *(*uint64)(it.Key())
+				if seen[key] {
+					t.Fatalf("iteration returned key %d twice", key)
+				}
+				seen[key] = true
+			}
+			if len(seen) != n {
+				t.Errorf("iteration returned %d keys want %d", len(seen), n)
+			}
+
+			// The large tables shrink and merge back as keys are
+			// deleted.
+			for i := uint64(0); i < n; i++ {
+				if !m.Delete(typ, unsafe.Pointer(&i)) {
+					t.Fatalf("Delete(%d) got false want true", i)
+				}
+			}
+			if m.Used() != 0 {
+				t.Errorf("Used() got %d want 0", m.Used())
+			}
+			if got := m.GlobalDepth(); got != 1 {
+				t.Errorf("GlobalDepth() after deleting every key got %d want 1", got)
+			}
+			m.CheckInvariants(typ)
+		})
+	}
+}
+
+// Too many keys with the same hash fill a table at the maximum split
+// depth, which can grow no further.
+func TestMapMaxSplitDepthFull(t *testing.T) {
+	if os.Getenv("GO_TEST_MAP_MAX_SPLIT_DEPTH_FULL") != "" {
+		maps.SetMaxSplitDepth(1)
+		m, typ := maps.NewTestMap[uint64, uint64](0)
+		ctyp := *typ
+		ctyp.Hasher = func(unsafe.Pointer, uintptr) uintptr { return 0 }
+		typ = &ctyp
+		for i := uint64(0); i <= maps.MaxDeepTableCapacity; i++ {
+			m.Put(typ, unsafe.Pointer(&i), unsafe.Pointer(&i))
+		}
+		fmt.Fprintf(os.Stderr, "put %d keys\n", m.Used())
+		return
+	}
+	testenv.MustHaveExec(t)
+	if testing.Short() {
+		t.Skip("skipping in short mode: filling a table of colliding keys is slow")
+	}
+
+	cmd := testenv.Command(t, testenv.Executable(t), "-test.run=^TestMapMaxSplitDepthFull$")
+	cmd.Env = append(cmd.Environ(), "GO_TEST_MAP_MAX_SPLIT_DEPTH_FULL=1")
+	out, err := cmd.CombinedOutput()
+	if err == nil {
+		t.Fatalf("child did not fail:\n%s", out)
+	}
+	if want := "fatal error: too many map keys with colliding hashes"; !strings.Contains(string(out), want) {
+		t.Errorf("output does not contain %q:\n%s", want, out)
+	}
+}
+
+// TestSwissMapCheck runs the tests of the package again, with
+// GODEBUG=swissmapcheck=1 checking the invariants of every map they change.
+func TestSwissMapCheck(t *testing.T) {
+	if os.Getenv("GO_TEST_SWISSMAPCHECK") != "" {
+		if !maps.DebugCheck() {
+			t.Fatal("GODEBUG=swissmapcheck=1 did not enable the invariant checks")
+		}
+		return
+	}
+	testenv.MustHaveExec(t)
+	if testing.Short() {
+		t.Skip("skipping in short mode: runs the tests of the package again")
+	}
+
+	// Skip the tests that build tables of colliding keys or many maps,
+	// which take minutes with the checks.
+	cmd := testenv.Command(t, testenv.Executable(t), "-test.short", "-test.skip=^(TestMapMaxSplitDepth|TestTableIterationGrowMatrix|TestTableIterationGrowMatrixNaN)$")
+	cmd.Env = append(cmd.Environ(), "GODEBUG=swissmapcheck=1", "GO_TEST_SWISSMAPCHECK=1")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("tests failed with GODEBUG=swissmapcheck=1: %v\n%s", err, out)
+	}
+}
+
+// BenchmarkMapPutHint fills a map created with a hint of its final size,
+// and reports the number of groups allocated by growth, which should be
+// zero.
+func BenchmarkMapPutHint(b *testing.B) {
+	for _, n := range []uintptr{abi.SwissMapGroupSlots + 1, 1e3, 1e4, 1e5} {
+		b.Run(fmt.Sprint("len=", n), func(b *testing.B) {
+			var grown uint64
+			for range b.N {
+				m, typ := maps.NewTestMap[uint64, uint64](n)
+				groups := m.GroupCount()
+				for i := range uint64(n) {
+					m.Put(typ, unsafe.Pointer(&i), unsafe.Pointer(&i))
+				}
+				grown += m.GroupCount() - groups
+			}
+			b.ReportMetric(float64(grown)/float64(b.N), "grown-groups/op")
+		})
+	}
+}
+
 // Verify that a map with zero-size slot is safe to use.
 func TestMapZeroSizeSlot(t *testing.T) {
-	m, typ := maps.NewTestMap[struct{}, struct{}](16)
+	m, typ := maps.NewTestMap[struct{}, struct{}](2 * abi.SwissMapGroupSlots)
 
 	key := struct{}{}
 	elem := struct{}{}
@@ -670,6 +2275,49 @@ func TestMapIndirect(t *testing.T) {
 	}
 }
 
+// Updating an entry of a full small map doesn't grow it.
+func TestMapSmallFullUpdate(t *testing.T) {
+	m, typ := maps.NewTestMap[uint32, uint64](0)
+
+	for i := range abi.SwissMapGroupSlots {
+		key := uint32(i)
+		elem := uint64(i)
+		m.Put(typ, unsafe.Pointer(&key), unsafe.Pointer(&elem))
+	}
+	if m.TableCount() != 0 || m.GroupCount() != 1 {
+		t.Fatalf("full small map has TableCount() %d GroupCount() %d, want 0 and 1", m.TableCount(), m.GroupCount())
+	}
+
+	for n := range 10 {
+		for i := range abi.SwissMapGroupSlots {
+			key := uint32(i)
+			elem := uint64(i + n)
+			m.Put(typ, unsafe.Pointer(&key), unsafe.Pointer(&elem))
+		}
+		if m.TableCount() != 0 || m.GroupCount() != 1 {
+			t.Fatalf("update grew the map to TableCount() %d GroupCount() %d", m.TableCount(), m.GroupCount())
+		}
+	}
+	for i := range abi.SwissMapGroupSlots {
+		key := uint32(i)
+		elem, ok := m.Get(typ, unsafe.Pointer(&key))
+		if !ok || *(*uint64)(elem) != uint64(i+9) {
+			t.Errorf("Get(%d) got %v, %v want %d, true", key, elem, ok, i+9)
+		}
+	}
+
+	// A new key does grow it.
+	key := uint32(abi.SwissMapGroupSlots)
+	elem := uint64(0)
+	m.Put(typ, unsafe.Pointer(&key), unsafe.Pointer(&elem))
+	if m.TableCount() != 1 {
+		t.Errorf("insert into a full small map got TableCount() %d, want 1", m.TableCount())
+	}
+	if got, want := m.Used(), uint64(abi.SwissMapGroupSlots+1); got != want {
+		t.Errorf("Used() got %d want %d", got, want)
+	}
+}
+
 // Delete should clear element. See https://go.dev/issue/25936.
 func TestMapDeleteClear(t *testing.T) {
 	m, typ := maps.NewTestMap[int64, int64](8)
@@ -699,3 +2347,271 @@ func TestMapDeleteClear(t *testing.T) {
 		t.Errorf("Delete(%d) failed to clear element. got %d want 0", key, gotElem)
 	}
 }
+
+func TestMapClone(t *testing.T) {
+	for _, n := range []int{0, 1, abi.SwissMapGroupSlots, 100, 3 * maps.MaxTableCapacity} {
+		t.Run(fmt.Sprint(n), func(t *testing.T) {
+			m, typ := maps.NewTestMap[uint32, uint64](0)
+			for i := 0; i < n; i++ {
+				key := uint32(i)
+				elem := uint64(i) + 256
+				m.Put(typ, unsafe.Pointer(&key), unsafe.Pointer(&elem))
+			}
+			// Delete some keys, leaving tombstones in the tables.
+			for i := 0; i < n; i += 3 {
+				key := uint32(i)
+				m.Delete(typ, unsafe.Pointer(&key))
+			}
+
+			c := m.Clone(typ)
+			if c.Used() != m.Used() {
+				t.Errorf("Clone Used() got %d want %d", c.Used(), m.Used())
+			}
+			// A non-empty clone has the layout of the original.
+			if m.Used() > 0 && (c.TableCount() != m.TableCount() || c.GroupCount() != m.GroupCount()) {
+				t.Errorf("Clone got %d tables, %d groups want %d tables, %d groups",
+					c.TableCount(), c.GroupCount(), m.TableCount(), m.GroupCount())
+			}
+			for i := 0; i < n; i++ {
+				key := uint32(i)
+				got, ok := c.Get(typ, unsafe.Pointer(&key))
+				if i%3 == 0 {
+					if ok {
+						t.Errorf("Get(%d) on clone got ok true want false", key)
+					}
+					continue
+				}
+				if !ok {
+					t.Errorf("Get(%d) on clone got ok false want true", key)
+					continue
+				}
+				if gotElem, want := *(*uint64)(got), uint64(i)+256; gotElem != want {
+					t.Errorf("Get(%d) on clone got elem %d want %d", key, gotElem, want)
+				}
+			}
+
+			// The clone and the original must not share storage.
+			for i := 0; i < n; i++ {
+				key := uint32(i)
+				elem := uint64(0)
+				c.Put(typ, unsafe.Pointer(&key), unsafe.Pointer(&elem))
+			}
+			for i := 1; i < n; i += 3 {
+				key := uint32(i)
+				got, ok := m.Get(typ, unsafe.Pointer(&key))
+				if !ok || *(*uint64)(got) != uint64(i)+256 {
+					t.Errorf("Get(%d) on original after writing clone got %v, %v", key, got, ok)
+				}
+			}
+		})
+	}
+}
+
+func TestMapCloneIndirect(t *testing.T) {
+	type big [abi.SwissMapMaxKeyBytes + abi.SwissMapMaxElemBytes]byte
+
+	for _, n := range []int{abi.SwissMapGroupSlots, 100} {
+		m, typ := maps.NewTestMap[big, big](0)
+		var key, elem big
+		for i := 0; i < n; i++ {
+			key[0], key[1] = byte(i), byte(i>>8)
+			elem[0] = byte(i)
+			m.Put(typ, unsafe.Pointer(&key), unsafe.Pointer(&elem))
+		}
+
+		c := m.Clone(typ)
+		for i := 0; i < n; i++ {
+			key[0], key[1] = byte(i), byte(i>>8)
+			got, ok := c.Get(typ, unsafe.Pointer(&key))
+			if !ok || (*big)(got)[0] != byte(i) {
+				t.Fatalf("n=%d: Get(%d) on clone got %v, %v", n, i, got, ok)
+			}
+			orig, _ := m.Get(typ, unsafe.Pointer(&key))
+			if got == orig {
+				t.Fatalf("n=%d: clone shares the element of key %d", n, i)
+			}
+		}
+	}
+}
+
+func TestMapSeedsDiffer(t *testing.T) {
+	m1, typ := maps.NewTestMap[string, int](0)
+	m2 := maps.NewMap(typ, 100, nil, 1<<30)
+	m3 := maps.NewEmptyMap()
+	if m1.Seed() == m2.Seed() || m1.Seed() == m3.Seed() || m2.Seed() == m3.Seed() {
+		t.Errorf("maps got seeds %#x, %#x, %#x, want all different", m1.Seed(), m2.Seed(), m3.Seed())
+	}
+}
+
+// Keys that all collide in the probe sequence under the map's initial
+// seed stop colliding once the table grows, as the map picks a new seed.
+func TestMapCollidingKeys(t *testing.T) {
+	m, typ := maps.NewTestMap[string, int](0)
+
+	// Find keys that start probing at the same group in every table of
+	// up to maxGroups groups.
+	const (
+		n         = maps.MaxTableCapacity / 2
+		maxGroups = maps.MaxTableCapacity / abi.SwissMapGroupSlots
+	)
+	var keys []string
+	want := ^uintptr(0)
+	for i := 0; len(keys) < n; i++ {
+		key := fmt.Sprint("key", i)
+		h := m.H1(typ, unsafe.Pointer(&key)) & (maxGroups - 1)
+		if want == ^uintptr(0) {
+			want = h
+		}
+		if h == want {
+			keys = append(keys, key)
+		}
+	}
+
+	for i := range keys {
+		m.Put(typ, unsafe.Pointer(&keys[i]), unsafe.Pointer(&i))
+	}
+	if m.TableCount() != 1 {
+		t.Fatalf("TableCount() got %d want 1", m.TableCount())
+	}
+
+	// With the initial seed, the keys would fill consecutive groups of
+	// the probe sequence, and the last ones would be found after n/8
+	// groups. With a random seed, a few groups are plenty.
+	const maxProbe = 16
+	for i := range keys {
+		if p := m.ProbeLength(typ, unsafe.Pointer(&keys[i])); p == 0 || p > maxProbe {
+			t.Errorf("ProbeLength(%q) got %d want between 1 and %d", keys[i], p, maxProbe)
+		}
+	}
+}
+
+// Look up keys in a small map whose H2 equals the low 7 bits of the empty
+// and deleted control bytes, so that a lookup comparing control bytes
+// without their high bit would match empty or deleted slots.
+func TestMapSmallSentinelH2(t *testing.T) {
+	for _, sentinel := range []uint8{maps.CtrlEmpty, maps.CtrlDeleted} {
+		m, typ := maps.NewTestMap[uint32, uint32](0)
+		want := uintptr(sentinel &^ 0x80)
+
+		// Put half as many keys as the group has slots, so that
+		// there are empty slots for a lookup to mistake for a match.
+		var keys []uint32
+		for k := uint32(0); len(keys) < abi.SwissMapGroupSlots; k++ {
+			if m.H2(typ, unsafe.Pointer(&k)) == want {
+				keys = append(keys, k)
+			}
+		}
+		present, missing := keys[:len(keys)/2], keys[len(keys)/2:]
+		for _, k := range present {
+			e := k + 1
+			m.Put(typ, unsafe.Pointer(&k), unsafe.Pointer(&e))
+		}
+		if m.TableCount() != 0 {
+			t.Fatalf("TableCount() got %d want 0", m.TableCount())
+		}
+
+		for _, k := range present {
+			got, ok := m.Get(typ, unsafe.Pointer(&k))
+			if !ok {
+				t.Errorf("Get(%d) got ok false want true", k)
+			} else if gotElem := *(*uint32)(got); gotElem != k+1 {
+				t.Errorf("Get(%d) got elem %d want %d", k, gotElem, k+1)
+			}
+		}
+		for _, k := range missing {
+			if _, ok := m.Get(typ, unsafe.Pointer(&k)); ok {
+				t.Errorf("Get(%d) got ok true want false", k)
+			}
+		}
+	}
+}
+
+// Grow a map to millions of entries, delete all but a few hundred, and
+// check that its tables merge and its directory shrinks.
+func TestMapMergeTables(t *testing.T) {
+	n := uint64(4 << 20)
+	if testing.Short() {
+		n = 256 << 10
+	}
+	const keep = 300
+	m, typ := maps.NewTestMap[uint64, uint64](0)
+	for i := uint64(0); i < n; i++ {
+		m.Put(typ, unsafe.Pointer(&i), unsafe.Pointer(&i))
+	}
+	depth, tables, groups := m.GlobalDepth(), len(m.Tables()), m.GroupCount()
+
+	// Keep keys spread over all the tables.
+	kept := func(i uint64) bool { return i%(n/keep) == 0 }
+	for i := uint64(0); i < n; i++ {
+		if !kept(i) {
+			m.Delete(typ, unsafe.Pointer(&i))
+		}
+	}
+	t.Logf("GlobalDepth %d -> %d, tables %d -> %d, groups %d -> %d", depth, m.GlobalDepth(), tables, len(m.Tables()), groups, m.GroupCount())
+
+	// The tables hold at most twice their entries, and merge as long
+	// as their siblings are small.
+	if d := m.GlobalDepth(); d > 3 {
+		t.Errorf("GlobalDepth() got %d want at most 3, from %d", d, depth)
+	}
+	if g, max := m.GroupCount(), uint64(4*keep/abi.SwissMapGroupSlots); g > max {
+		t.Errorf("GroupCount() got %d want at most %d, from %d", g, max, groups)
+	}
+	for i := uint64(0); i < n; i++ {
+		if idx, ok := m.CheckDirectory(typ, unsafe.Pointer(&i)); !ok {
+			t.Fatalf("key %d: table at directory index %d does not cover it", i, idx)
+		}
+		got, ok := m.Get(typ, unsafe.Pointer(&i))
+		if ok != kept(i) {
+			t.Fatalf("Get(%d) got ok %v want %v", i, ok, kept(i))
+		}
+		if ok && *(*uint64)(got) != i {
+			t.Fatalf("Get(%d) got elem %d want %d", i, *(*uint64)(got), i)
+		}
+	}
+
+	// The map grows again as usual.
+	for i := uint64(0); i < n; i++ {
+		m.Put(typ, unsafe.Pointer(&i), unsafe.Pointer(&i))
+	}
+	if m.Used() != n || m.GlobalDepth() < depth-1 {
+		t.Errorf("after refilling, Used() got %d want %d, GlobalDepth() got %d want about %d", m.Used(), n, m.GlobalDepth(), depth)
+	}
+}
+
+// Grow a map past several directory doublings, and check that every key
+// is still in the table covering its directory index.
+func TestMapDirectoryGrowth(t *testing.T) {
+	m, typ := maps.NewTestMap[uint64, uint64](0)
+
+	const n = 16 * maps.MaxTableCapacity
+	for i := uint64(0); i < n; i++ {
+		elem := i + 256
+		m.Put(typ, unsafe.Pointer(&i), unsafe.Pointer(&elem))
+	}
+	if d := m.GlobalDepth(); d < 3 {
+		t.Fatalf("GlobalDepth() got %d want at least 3", d)
+	}
+
+	check := func(deleted func(uint64) bool) {
+		t.Helper()
+		for i := uint64(0); i < n; i++ {
+			if idx, ok := m.CheckDirectory(typ, unsafe.Pointer(&i)); !ok {
+				t.Fatalf("key %d: table at directory index %d does not cover it", i, idx)
+			}
+			got, ok := m.Get(typ, unsafe.Pointer(&i))
+			if ok == deleted(i) {
+				t.Fatalf("Get(%d) got ok %v want %v", i, ok, !deleted(i))
+			}
+			if ok && *(*uint64)(got) != i+256 {
+				t.Fatalf("Get(%d) got elem %d want %d", i, *(*uint64)(got), i+256)
+			}
+		}
+	}
+	check(func(uint64) bool { return false })
+
+	for i := uint64(0); i < n; i += 2 {
+		m.Delete(typ, unsafe.Pointer(&i))
+	}
+	check(func(i uint64) bool { return i%2 == 0 })
+}
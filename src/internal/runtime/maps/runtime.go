@@ -6,6 +6,8 @@ package maps
 
 import (
 	"internal/abi"
+	"internal/asan"
+	"internal/msan"
 	"unsafe"
 )
 
@@ -17,9 +19,22 @@ func fatal(s string)
 //go:linkname rand
 func rand() uint64
 
+// iterSeed returns the value of GODEBUG=mapiterseed, or 0 if it is not set.
+//
+//go:linkname iterSeed
+func iterSeed() uint64
+
+// addStats adds s to the statistics of the current P.
+//
+//go:linkname addStats
+func addStats(s Stats)
+
 //go:linkname typedmemmove
 func typedmemmove(typ *abi.Type, dst, src unsafe.Pointer)
 
+//go:linkname memmoveFresh
+func memmoveFresh(dst, src unsafe.Pointer, size uintptr, pointers bool)
+
 //go:linkname typedmemclr
 func typedmemclr(typ *abi.Type, ptr unsafe.Pointer)
 
@@ -28,3 +43,85 @@ func newarray(typ *abi.Type, n int) unsafe.Pointer
 
 //go:linkname newobject
 func newobject(typ *abi.Type) unsafe.Pointer
+
+//go:linkname roundupsize
+func roundupsize(size uintptr, noscan bool) uintptr
+
+// Hash functions below are pulled from runtime. The fast variants call them
+// directly rather than through typ.Hasher, which is the same function for
+// the key types they handle.
+
+//go:linkname memhash32 runtime.memhash32
+func memhash32(p unsafe.Pointer, h uintptr) uintptr
+
+//go:linkname memhash64 runtime.memhash64
+func memhash64(p unsafe.Pointer, h uintptr) uintptr
+
+//go:linkname strhash runtime.strhash
+func strhash(p unsafe.Pointer, h uintptr) uintptr
+
+// sanitizerWrite tells msan and asan that size bytes at p have been written.
+// The stores of this package are not instrumented, so without it msan
+// would not know that a key, or an element handed back to the compiled
+// code, is initialized, and asan would not check the write.
+func sanitizerWrite(p unsafe.Pointer, size uintptr) {
+	if msan.Enabled {
+		msan.Write(p, size)
+	}
+	if asan.Enabled {
+		asan.Write(p, size)
+	}
+}
+
+// poisonSlot tells asan that slot i of group g holds no entry, so that an
+// access to it through a pointer kept from before the entry was deleted,
+// or moved by a rehash, is reported. unpoisonSlot makes the slot
+// accessible again, before an entry is put in it.
+//
+// Only the slots of tables are poisoned. The group of a small map may be
+// on the stack of the function that made the map, and poison would
+// outlive that function.
+func poisonSlot(typ *abi.SwissMapType, g groupReference, i uintptr) {
+	if asan.Enabled && typ.SlotSize != 0 {
+		asan.Poison(g.key(typ, i), typ.SlotSize)
+	}
+}
+
+// unpoisonSlot undoes poisonSlot.
+func unpoisonSlot(typ *abi.SwissMapType, g groupReference, i uintptr) {
+	if asan.Enabled && typ.SlotSize != 0 {
+		asan.Unpoison(g.key(typ, i), typ.SlotSize)
+	}
+}
+
+// poisonGroups poisons every slot of the groups of a new table, which are
+// all empty. The control words stay accessible.
+//
+// The groups of a table dropped by a rehash, merge or Clear are not
+// poisoned: an iterator started before may still read keys and elements
+// from them, and hash or compare the keys with functions of the user's,
+// asan-instrumented, package.
+func poisonGroups(typ *abi.SwissMapType, groups groupsReference) {
+	if !asan.Enabled || typ.SlotSize == 0 {
+		return
+	}
+	for i := uint64(0); i <= groups.lengthMask; i++ {
+		g := groups.group(typ, i)
+		asan.Poison(g.key(typ, 0), typ.SlotSize*abi.SwissMapGroupSlots)
+	}
+}
+
+// poisonEmptySlots poisons the empty and deleted slots of the groups.
+func poisonEmptySlots(typ *abi.SwissMapType, groups groupsReference) {
+	if !asan.Enabled || typ.SlotSize == 0 {
+		return
+	}
+	for i := uint64(0); i <= groups.lengthMask; i++ {
+		g := groups.group(typ, i)
+		match := g.ctrls().matchEmptyOrDeleted()
+		for match != 0 {
+			poisonSlot(typ, g, match.first())
+			match = match.removeFirst()
+		}
+	}
+}
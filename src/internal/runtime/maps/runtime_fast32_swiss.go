@@ -22,7 +22,7 @@ func runtime_mapaccess1_fast32(typ *abi.SwissMapType, m *Map, key uint32) unsafe
 	}
 
 	if m == nil || m.Used() == 0 {
-		return unsafe.Pointer(&zeroVal[0])
+		return unsafe.Pointer(&abi.ZeroVal[0])
 	}
 
 	if m.writing != 0 {
@@ -45,20 +45,19 @@ func runtime_mapaccess1_fast32(typ *abi.SwissMapType, m *Map, key uint32) unsafe
 			slotKey = unsafe.Pointer(uintptr(slotKey) + slotSize)
 			full = full.shiftOutLowest()
 		}
-		return unsafe.Pointer(&zeroVal[0])
+		return unsafe.Pointer(&abi.ZeroVal[0])
 	}
 
 	k := key
-	hash := typ.Hasher(abi.NoEscape(unsafe.Pointer(&k)), m.seed)
+	hash := memhash32(abi.NoEscape(unsafe.Pointer(&k)), m.seed)
 
 	// Select table.
-	idx := m.directoryIndex(hash)
-	t := m.directoryAt(idx)
+	groups := m.tableGroups(hash)
 
 	// Probe table.
-	seq := makeProbeSeq(h1(hash), t.groups.lengthMask)
+	seq := makeProbeSeq(h1(hash), groups.lengthMask)
 	for ; ; seq = seq.next() {
-		g := t.groups.group(typ, seq.offset)
+		g := groups.group(typ, seq.offset)
 
 		match := g.ctrls().matchH2(h2(hash))
 
@@ -77,7 +76,7 @@ func runtime_mapaccess1_fast32(typ *abi.SwissMapType, m *Map, key uint32) unsafe
 		if match != 0 {
 			// Finding an empty slot means we've reached the end of
 			// the probe sequence.
-			return unsafe.Pointer(&zeroVal[0])
+			return unsafe.Pointer(&abi.ZeroVal[0])
 		}
 	}
 }
@@ -91,7 +90,7 @@ func runtime_mapaccess2_fast32(typ *abi.SwissMapType, m *Map, key uint32) (unsaf
 	}
 
 	if m == nil || m.Used() == 0 {
-		return unsafe.Pointer(&zeroVal[0]), false
+		return unsafe.Pointer(&abi.ZeroVal[0]), false
 	}
 
 	if m.writing != 0 {
@@ -114,20 +113,19 @@ func runtime_mapaccess2_fast32(typ *abi.SwissMapType, m *Map, key uint32) (unsaf
 			slotKey = unsafe.Pointer(uintptr(slotKey) + slotSize)
 			full = full.shiftOutLowest()
 		}
-		return unsafe.Pointer(&zeroVal[0]), false
+		return unsafe.Pointer(&abi.ZeroVal[0]), false
 	}
 
 	k := key
-	hash := typ.Hasher(abi.NoEscape(unsafe.Pointer(&k)), m.seed)
+	hash := memhash32(abi.NoEscape(unsafe.Pointer(&k)), m.seed)
 
 	// Select table.
-	idx := m.directoryIndex(hash)
-	t := m.directoryAt(idx)
+	groups := m.tableGroups(hash)
 
 	// Probe table.
-	seq := makeProbeSeq(h1(hash), t.groups.lengthMask)
+	seq := makeProbeSeq(h1(hash), groups.lengthMask)
 	for ; ; seq = seq.next() {
-		g := t.groups.group(typ, seq.offset)
+		g := groups.group(typ, seq.offset)
 
 		match := g.ctrls().matchH2(h2(hash))
 
@@ -146,7 +144,7 @@ func runtime_mapaccess2_fast32(typ *abi.SwissMapType, m *Map, key uint32) (unsaf
 		if match != 0 {
 			// Finding an empty slot means we've reached the end of
 			// the probe sequence.
-			return unsafe.Pointer(&zeroVal[0]), false
+			return unsafe.Pointer(&abi.ZeroVal[0]), false
 		}
 	}
 }
@@ -175,6 +173,11 @@ func (m *Map) putSlotSmallFast32(typ *abi.SwissMapType, hash uintptr, key uint32
 	// more efficient than matchEmpty.
 	match = g.ctrls().matchEmptyOrDeleted()
 	if match == 0 {
+		if m.used == abi.SwissMapGroupSlots {
+			// The key is new and the group is full. The caller
+			// must grow the map.
+			return nil
+		}
 		fatal("small map with no empty slot (concurrent map writes?)")
 	}
 
@@ -188,6 +191,8 @@ func (m *Map) putSlotSmallFast32(typ *abi.SwissMapType, hash uintptr, key uint32
 	g.ctrls().set(i, ctrl(h2(hash)))
 	m.used++
 
+	m.checkInvariants(typ)
+
 	return slotElem
 }
 
@@ -206,7 +211,7 @@ func runtime_mapassign_fast32(typ *abi.SwissMapType, m *Map, key uint32) unsafe.
 	}
 
 	k := key
-	hash := typ.Hasher(abi.NoEscape(unsafe.Pointer(&k)), m.seed)
+	hash := memhash32(abi.NoEscape(unsafe.Pointer(&k)), m.seed)
 
 	// Set writing after calling Hasher, since Hasher may panic, in which
 	// case we have not actually done a write.
@@ -217,9 +222,9 @@ func runtime_mapassign_fast32(typ *abi.SwissMapType, m *Map, key uint32) unsafe.
 	}
 
 	if m.dirLen == 0 {
-		if m.used < abi.SwissMapGroupSlots {
-			elem := m.putSlotSmallFast32(typ, hash, key)
-
+		// Assigning to a key that is already present needs no empty
+		// slot, so look for the key even if the group is full.
+		if elem := m.putSlotSmallFast32(typ, hash, key); elem != nil {
 			if m.writing == 0 {
 				fatal("concurrent map writes")
 			}
@@ -235,11 +240,11 @@ func runtime_mapassign_fast32(typ *abi.SwissMapType, m *Map, key uint32) unsafe.
 	var slotElem unsafe.Pointer
 outer:
 	for {
-		// Select table.
-		idx := m.directoryIndex(hash)
-		t := m.directoryAt(idx)
+		// Select table. Its groups are enough to find the key, the
+		// table itself is only needed to insert it.
+		groups := m.tableGroups(hash)
 
-		seq := makeProbeSeq(h1(hash), t.groups.lengthMask)
+		seq := makeProbeSeq(h1(hash), groups.lengthMask)
 
 		// As we look for a match, keep track of the first deleted slot
 		// we find, which we'll use to insert the new entry if
@@ -248,7 +253,7 @@ outer:
 		var firstDeletedSlot uintptr
 
 		for ; ; seq = seq.next() {
-			g := t.groups.group(typ, seq.offset)
+			g := groups.group(typ, seq.offset)
 			match := g.ctrls().matchH2(h2(hash))
 
 			// Look for an existing slot containing this key.
@@ -259,7 +264,7 @@ outer:
 				if key == *(*uint32)(slotKey) {
 					slotElem = g.elem(typ, i)
 
-					t.checkInvariants(typ, m)
+					m.checkInvariants(typ)
 					break outer
 				}
 				match = match.removeFirst()
@@ -283,6 +288,7 @@ outer:
 			}
 			// We've found an empty slot, which means we've reached the end of
 			// the probe sequence.
+			t := m.directoryAt(m.directoryIndex(hash))
 
 			// If we found a deleted slot along the way, we can
 			// replace it without consuming growthLeft.
@@ -294,6 +300,7 @@ outer:
 
 			// If there is room left to grow, just insert the new entry.
 			if t.growthLeft > 0 {
+				unpoisonSlot(typ, g, i)
 				slotKey := g.key(typ, i)
 				*(*uint32)(slotKey) = key
 
@@ -309,6 +316,8 @@ outer:
 			}
 
 			t.rehash(typ, m)
+			// Rehashing may have changed the seed.
+			hash = memhash32(abi.NoEscape(unsafe.Pointer(&k)), m.seed)
 			continue outer
 		}
 	}
@@ -340,7 +349,7 @@ func runtime_mapassign_fast32ptr(typ *abi.SwissMapType, m *Map, key unsafe.Point
 	}
 
 	k := key
-	hash := typ.Hasher(abi.NoEscape(unsafe.Pointer(&k)), m.seed)
+	hash := memhash32(abi.NoEscape(unsafe.Pointer(&k)), m.seed)
 
 	// Set writing after calling Hasher, since Hasher may panic, in which
 	// case we have not actually done a write.
@@ -351,9 +360,9 @@ func runtime_mapassign_fast32ptr(typ *abi.SwissMapType, m *Map, key unsafe.Point
 	}
 
 	if m.dirLen == 0 {
-		if m.used < abi.SwissMapGroupSlots {
-			elem := m.putSlotSmallFastPtr(typ, hash, key)
-
+		// Assigning to a key that is already present needs no empty
+		// slot, so look for the key even if the group is full.
+		if elem := m.putSlotSmallFastPtr(typ, hash, key); elem != nil {
 			if m.writing == 0 {
 				fatal("concurrent map writes")
 			}
@@ -369,11 +378,11 @@ func runtime_mapassign_fast32ptr(typ *abi.SwissMapType, m *Map, key unsafe.Point
 	var slotElem unsafe.Pointer
 outer:
 	for {
-		// Select table.
-		idx := m.directoryIndex(hash)
-		t := m.directoryAt(idx)
+		// Select table. Its groups are enough to find the key, the
+		// table itself is only needed to insert it.
+		groups := m.tableGroups(hash)
 
-		seq := makeProbeSeq(h1(hash), t.groups.lengthMask)
+		seq := makeProbeSeq(h1(hash), groups.lengthMask)
 
 		// As we look for a match, keep track of the first deleted slot we
 		// find, which we'll use to insert the new entry if necessary.
@@ -381,7 +390,7 @@ outer:
 		var firstDeletedSlot uintptr
 
 		for ; ; seq = seq.next() {
-			g := t.groups.group(typ, seq.offset)
+			g := groups.group(typ, seq.offset)
 			match := g.ctrls().matchH2(h2(hash))
 
 			// Look for an existing slot containing this key.
@@ -392,7 +401,7 @@ outer:
 				if key == *(*unsafe.Pointer)(slotKey) {
 					slotElem = g.elem(typ, i)
 
-					t.checkInvariants(typ, m)
+					m.checkInvariants(typ)
 					break outer
 				}
 				match = match.removeFirst()
@@ -416,6 +425,7 @@ outer:
 			}
 			// We've found an empty slot, which means we've reached the end of
 			// the probe sequence.
+			t := m.directoryAt(m.directoryIndex(hash))
 
 			// If we found a deleted slot along the way, we can
 			// replace it without consuming growthLeft.
@@ -427,6 +437,7 @@ outer:
 
 			// If there is room left to grow, just insert the new entry.
 			if t.growthLeft > 0 {
+				unpoisonSlot(typ, g, i)
 				slotKey := g.key(typ, i)
 				*(*unsafe.Pointer)(slotKey) = key
 
@@ -442,6 +453,8 @@ outer:
 			}
 
 			t.rehash(typ, m)
+			// Rehashing may have changed the seed.
+			hash = memhash32(abi.NoEscape(unsafe.Pointer(&k)), m.seed)
 			continue outer
 		}
 	}
@@ -466,5 +479,75 @@ func runtime_mapdelete_fast32(typ *abi.SwissMapType, m *Map, key uint32) {
 		return
 	}
 
-	m.Delete(typ, abi.NoEscape(unsafe.Pointer(&key)))
+	if m.writing != 0 {
+		fatal("concurrent map writes")
+	}
+
+	m.writing ^= 1 // toggle, see comment on writing
+
+	if m.dirLen == 0 {
+		// Small maps are searched without hashing, as in
+		// runtime_mapaccess1_fast32.
+		m.deleteSmallFast32(typ, key)
+	} else {
+		k := key
+		hash := memhash32(abi.NoEscape(unsafe.Pointer(&k)), m.seed)
+		idx := m.directoryIndex(hash)
+		m.directoryAt(idx).deleteFast32(typ, m, hash, key)
+	}
+
+	if m.used == 0 {
+		// Reset the hash seed to make it more difficult for attackers
+		// to repeatedly trigger hash collisions. See
+		// https://go.dev/issue/25237.
+		m.seed = uintptr(rand())
+	}
+
+	if m.writing == 0 {
+		fatal("concurrent map writes")
+	}
+	m.writing ^= 1
+}
+
+func (m *Map) deleteSmallFast32(typ *abi.SwissMapType, key uint32) {
+	g := groupReference{
+		data: m.dirPtr,
+	}
+
+	full := g.ctrls().matchFull()
+	slotKey := g.key(typ, 0)
+	slotSize := typ.SlotSize
+	for i := uintptr(0); full != 0; i++ {
+		if key == *(*uint32)(slotKey) && full.lowestSet() {
+			m.removeSlotSmall(typ, g, i)
+			return
+		}
+		slotKey = unsafe.Pointer(uintptr(slotKey) + slotSize)
+		full = full.shiftOutLowest()
+	}
+}
+
+func (t *table) deleteFast32(typ *abi.SwissMapType, m *Map, hash uintptr, key uint32) {
+	seq := makeProbeSeq(h1(hash), t.groups.lengthMask)
+	for ; ; seq = seq.next() {
+		g := t.groups.group(typ, seq.offset)
+
+		match := g.ctrls().matchH2(h2(hash))
+
+		for match != 0 {
+			i := match.first()
+			if key == *(*uint32)(g.key(typ, i)) {
+				t.removeSlot(typ, m, g, i)
+				return
+			}
+			match = match.removeFirst()
+		}
+
+		match = g.ctrls().matchEmpty()
+		if match != 0 {
+			// Finding an empty slot means we've reached the end of
+			// the probe sequence.
+			return
+		}
+	}
 }
@@ -22,7 +22,7 @@ func runtime_mapaccess1_fast64(typ *abi.SwissMapType, m *Map, key uint64) unsafe
 	}
 
 	if m == nil || m.Used() == 0 {
-		return unsafe.Pointer(&zeroVal[0])
+		return unsafe.Pointer(&abi.ZeroVal[0])
 	}
 
 	if m.writing != 0 {
@@ -39,26 +39,25 @@ func runtime_mapaccess1_fast64(typ *abi.SwissMapType, m *Map, key uint64) unsafe
 		slotSize := typ.SlotSize
 		for full != 0 {
 			if key == *(*uint64)(slotKey) && full.lowestSet() {
-				slotElem := unsafe.Pointer(uintptr(slotKey) + 8)
+				slotElem := unsafe.Pointer(uintptr(slotKey) + typ.ElemOff)
 				return slotElem
 			}
 			slotKey = unsafe.Pointer(uintptr(slotKey) + slotSize)
 			full = full.shiftOutLowest()
 		}
-		return unsafe.Pointer(&zeroVal[0])
+		return unsafe.Pointer(&abi.ZeroVal[0])
 	}
 
 	k := key
-	hash := typ.Hasher(abi.NoEscape(unsafe.Pointer(&k)), m.seed)
+	hash := memhash64(abi.NoEscape(unsafe.Pointer(&k)), m.seed)
 
 	// Select table.
-	idx := m.directoryIndex(hash)
-	t := m.directoryAt(idx)
+	groups := m.tableGroups(hash)
 
 	// Probe table.
-	seq := makeProbeSeq(h1(hash), t.groups.lengthMask)
+	seq := makeProbeSeq(h1(hash), groups.lengthMask)
 	for ; ; seq = seq.next() {
-		g := t.groups.group(typ, seq.offset)
+		g := groups.group(typ, seq.offset)
 
 		match := g.ctrls().matchH2(h2(hash))
 
@@ -67,7 +66,7 @@ func runtime_mapaccess1_fast64(typ *abi.SwissMapType, m *Map, key uint64) unsafe
 
 			slotKey := g.key(typ, i)
 			if key == *(*uint64)(slotKey) {
-				slotElem := unsafe.Pointer(uintptr(slotKey) + 8)
+				slotElem := unsafe.Pointer(uintptr(slotKey) + typ.ElemOff)
 				return slotElem
 			}
 			match = match.removeFirst()
@@ -77,7 +76,7 @@ func runtime_mapaccess1_fast64(typ *abi.SwissMapType, m *Map, key uint64) unsafe
 		if match != 0 {
 			// Finding an empty slot means we've reached the end of
 			// the probe sequence.
-			return unsafe.Pointer(&zeroVal[0])
+			return unsafe.Pointer(&abi.ZeroVal[0])
 		}
 	}
 }
@@ -91,7 +90,7 @@ func runtime_mapaccess2_fast64(typ *abi.SwissMapType, m *Map, key uint64) (unsaf
 	}
 
 	if m == nil || m.Used() == 0 {
-		return unsafe.Pointer(&zeroVal[0]), false
+		return unsafe.Pointer(&abi.ZeroVal[0]), false
 	}
 
 	if m.writing != 0 {
@@ -108,26 +107,25 @@ func runtime_mapaccess2_fast64(typ *abi.SwissMapType, m *Map, key uint64) (unsaf
 		slotSize := typ.SlotSize
 		for full != 0 {
 			if key == *(*uint64)(slotKey) && full.lowestSet() {
-				slotElem := unsafe.Pointer(uintptr(slotKey) + 8)
+				slotElem := unsafe.Pointer(uintptr(slotKey) + typ.ElemOff)
 				return slotElem, true
 			}
 			slotKey = unsafe.Pointer(uintptr(slotKey) + slotSize)
 			full = full.shiftOutLowest()
 		}
-		return unsafe.Pointer(&zeroVal[0]), false
+		return unsafe.Pointer(&abi.ZeroVal[0]), false
 	}
 
 	k := key
-	hash := typ.Hasher(abi.NoEscape(unsafe.Pointer(&k)), m.seed)
+	hash := memhash64(abi.NoEscape(unsafe.Pointer(&k)), m.seed)
 
 	// Select table.
-	idx := m.directoryIndex(hash)
-	t := m.directoryAt(idx)
+	groups := m.tableGroups(hash)
 
 	// Probe table.
-	seq := makeProbeSeq(h1(hash), t.groups.lengthMask)
+	seq := makeProbeSeq(h1(hash), groups.lengthMask)
 	for ; ; seq = seq.next() {
-		g := t.groups.group(typ, seq.offset)
+		g := groups.group(typ, seq.offset)
 
 		match := g.ctrls().matchH2(h2(hash))
 
@@ -136,7 +134,7 @@ func runtime_mapaccess2_fast64(typ *abi.SwissMapType, m *Map, key uint64) (unsaf
 
 			slotKey := g.key(typ, i)
 			if key == *(*uint64)(slotKey) {
-				slotElem := unsafe.Pointer(uintptr(slotKey) + 8)
+				slotElem := unsafe.Pointer(uintptr(slotKey) + typ.ElemOff)
 				return slotElem, true
 			}
 			match = match.removeFirst()
@@ -146,7 +144,7 @@ func runtime_mapaccess2_fast64(typ *abi.SwissMapType, m *Map, key uint64) (unsaf
 		if match != 0 {
 			// Finding an empty slot means we've reached the end of
 			// the probe sequence.
-			return unsafe.Pointer(&zeroVal[0]), false
+			return unsafe.Pointer(&abi.ZeroVal[0]), false
 		}
 	}
 }
@@ -175,6 +173,11 @@ func (m *Map) putSlotSmallFast64(typ *abi.SwissMapType, hash uintptr, key uint64
 	// more efficient than matchEmpty.
 	match = g.ctrls().matchEmptyOrDeleted()
 	if match == 0 {
+		if m.used == abi.SwissMapGroupSlots {
+			// The key is new and the group is full. The caller
+			// must grow the map.
+			return nil
+		}
 		fatal("small map with no empty slot (concurrent map writes?)")
 	}
 
@@ -188,6 +191,8 @@ func (m *Map) putSlotSmallFast64(typ *abi.SwissMapType, hash uintptr, key uint64
 	g.ctrls().set(i, ctrl(h2(hash)))
 	m.used++
 
+	m.checkInvariants(typ)
+
 	return slotElem
 }
 
@@ -206,7 +211,7 @@ func runtime_mapassign_fast64(typ *abi.SwissMapType, m *Map, key uint64) unsafe.
 	}
 
 	k := key
-	hash := typ.Hasher(abi.NoEscape(unsafe.Pointer(&k)), m.seed)
+	hash := memhash64(abi.NoEscape(unsafe.Pointer(&k)), m.seed)
 
 	// Set writing after calling Hasher, since Hasher may panic, in which
 	// case we have not actually done a write.
@@ -217,9 +222,9 @@ func runtime_mapassign_fast64(typ *abi.SwissMapType, m *Map, key uint64) unsafe.
 	}
 
 	if m.dirLen == 0 {
-		if m.used < abi.SwissMapGroupSlots {
-			elem := m.putSlotSmallFast64(typ, hash, key)
-
+		// Assigning to a key that is already present needs no empty
+		// slot, so look for the key even if the group is full.
+		if elem := m.putSlotSmallFast64(typ, hash, key); elem != nil {
 			if m.writing == 0 {
 				fatal("concurrent map writes")
 			}
@@ -235,11 +240,11 @@ func runtime_mapassign_fast64(typ *abi.SwissMapType, m *Map, key uint64) unsafe.
 	var slotElem unsafe.Pointer
 outer:
 	for {
-		// Select table.
-		idx := m.directoryIndex(hash)
-		t := m.directoryAt(idx)
+		// Select table. Its groups are enough to find the key, the
+		// table itself is only needed to insert it.
+		groups := m.tableGroups(hash)
 
-		seq := makeProbeSeq(h1(hash), t.groups.lengthMask)
+		seq := makeProbeSeq(h1(hash), groups.lengthMask)
 
 		// As we look for a match, keep track of the first deleted slot
 		// we find, which we'll use to insert the new entry if
@@ -248,7 +253,7 @@ outer:
 		var firstDeletedSlot uintptr
 
 		for ; ; seq = seq.next() {
-			g := t.groups.group(typ, seq.offset)
+			g := groups.group(typ, seq.offset)
 			match := g.ctrls().matchH2(h2(hash))
 
 			// Look for an existing slot containing this key.
@@ -259,7 +264,7 @@ outer:
 				if key == *(*uint64)(slotKey) {
 					slotElem = g.elem(typ, i)
 
-					t.checkInvariants(typ, m)
+					m.checkInvariants(typ)
 					break outer
 				}
 				match = match.removeFirst()
@@ -283,6 +288,7 @@ outer:
 			}
 			// We've found an empty slot, which means we've reached the end of
 			// the probe sequence.
+			t := m.directoryAt(m.directoryIndex(hash))
 
 			// If we found a deleted slot along the way, we can
 			// replace it without consuming growthLeft.
@@ -294,6 +300,7 @@ outer:
 
 			// If there is room left to grow, just insert the new entry.
 			if t.growthLeft > 0 {
+				unpoisonSlot(typ, g, i)
 				slotKey := g.key(typ, i)
 				*(*uint64)(slotKey) = key
 
@@ -309,6 +316,8 @@ outer:
 			}
 
 			t.rehash(typ, m)
+			// Rehashing may have changed the seed.
+			hash = memhash64(abi.NoEscape(unsafe.Pointer(&k)), m.seed)
 			continue outer
 		}
 	}
@@ -345,6 +354,11 @@ func (m *Map) putSlotSmallFastPtr(typ *abi.SwissMapType, hash uintptr, key unsaf
 	// more efficient than matchEmpty.
 	match = g.ctrls().matchEmptyOrDeleted()
 	if match == 0 {
+		if m.used == abi.SwissMapGroupSlots {
+			// The key is new and the group is full. The caller
+			// must grow the map.
+			return nil
+		}
 		fatal("small map with no empty slot (concurrent map writes?)")
 	}
 
@@ -358,6 +372,8 @@ func (m *Map) putSlotSmallFastPtr(typ *abi.SwissMapType, hash uintptr, key unsaf
 	g.ctrls().set(i, ctrl(h2(hash)))
 	m.used++
 
+	m.checkInvariants(typ)
+
 	return slotElem
 }
 
@@ -378,7 +394,7 @@ func runtime_mapassign_fast64ptr(typ *abi.SwissMapType, m *Map, key unsafe.Point
 	}
 
 	k := key
-	hash := typ.Hasher(abi.NoEscape(unsafe.Pointer(&k)), m.seed)
+	hash := memhash64(abi.NoEscape(unsafe.Pointer(&k)), m.seed)
 
 	// Set writing after calling Hasher, since Hasher may panic, in which
 	// case we have not actually done a write.
@@ -389,9 +405,9 @@ func runtime_mapassign_fast64ptr(typ *abi.SwissMapType, m *Map, key unsafe.Point
 	}
 
 	if m.dirLen == 0 {
-		if m.used < abi.SwissMapGroupSlots {
-			elem := m.putSlotSmallFastPtr(typ, hash, key)
-
+		// Assigning to a key that is already present needs no empty
+		// slot, so look for the key even if the group is full.
+		if elem := m.putSlotSmallFastPtr(typ, hash, key); elem != nil {
 			if m.writing == 0 {
 				fatal("concurrent map writes")
 			}
@@ -407,11 +423,11 @@ func runtime_mapassign_fast64ptr(typ *abi.SwissMapType, m *Map, key unsafe.Point
 	var slotElem unsafe.Pointer
 outer:
 	for {
-		// Select table.
-		idx := m.directoryIndex(hash)
-		t := m.directoryAt(idx)
+		// Select table. Its groups are enough to find the key, the
+		// table itself is only needed to insert it.
+		groups := m.tableGroups(hash)
 
-		seq := makeProbeSeq(h1(hash), t.groups.lengthMask)
+		seq := makeProbeSeq(h1(hash), groups.lengthMask)
 
 		// As we look for a match, keep track of the first deleted slot
 		// we find, which we'll use to insert the new entry if
@@ -420,7 +436,7 @@ outer:
 		var firstDeletedSlot uintptr
 
 		for ; ; seq = seq.next() {
-			g := t.groups.group(typ, seq.offset)
+			g := groups.group(typ, seq.offset)
 			match := g.ctrls().matchH2(h2(hash))
 
 			// Look for an existing slot containing this key.
@@ -431,7 +447,7 @@ outer:
 				if key == *(*unsafe.Pointer)(slotKey) {
 					slotElem = g.elem(typ, i)
 
-					t.checkInvariants(typ, m)
+					m.checkInvariants(typ)
 					break outer
 				}
 				match = match.removeFirst()
@@ -455,6 +471,7 @@ outer:
 			}
 			// We've found an empty slot, which means we've reached the end of
 			// the probe sequence.
+			t := m.directoryAt(m.directoryIndex(hash))
 
 			// If we found a deleted slot along the way, we can
 			// replace it without consuming growthLeft.
@@ -466,6 +483,7 @@ outer:
 
 			// If there is room left to grow, just insert the new entry.
 			if t.growthLeft > 0 {
+				unpoisonSlot(typ, g, i)
 				slotKey := g.key(typ, i)
 				*(*unsafe.Pointer)(slotKey) = key
 
@@ -481,6 +499,8 @@ outer:
 			}
 
 			t.rehash(typ, m)
+			// Rehashing may have changed the seed.
+			hash = memhash64(abi.NoEscape(unsafe.Pointer(&k)), m.seed)
 			continue outer
 		}
 	}
@@ -505,5 +525,75 @@ func runtime_mapdelete_fast64(typ *abi.SwissMapType, m *Map, key uint64) {
 		return
 	}
 
-	m.Delete(typ, abi.NoEscape(unsafe.Pointer(&key)))
+	if m.writing != 0 {
+		fatal("concurrent map writes")
+	}
+
+	m.writing ^= 1 // toggle, see comment on writing
+
+	if m.dirLen == 0 {
+		// Small maps are searched without hashing, as in
+		// runtime_mapaccess1_fast64.
+		m.deleteSmallFast64(typ, key)
+	} else {
+		k := key
+		hash := memhash64(abi.NoEscape(unsafe.Pointer(&k)), m.seed)
+		idx := m.directoryIndex(hash)
+		m.directoryAt(idx).deleteFast64(typ, m, hash, key)
+	}
+
+	if m.used == 0 {
+		// Reset the hash seed to make it more difficult for attackers
+		// to repeatedly trigger hash collisions. See
+		// https://go.dev/issue/25237.
+		m.seed = uintptr(rand())
+	}
+
+	if m.writing == 0 {
+		fatal("concurrent map writes")
+	}
+	m.writing ^= 1
+}
+
+func (m *Map) deleteSmallFast64(typ *abi.SwissMapType, key uint64) {
+	g := groupReference{
+		data: m.dirPtr,
+	}
+
+	full := g.ctrls().matchFull()
+	slotKey := g.key(typ, 0)
+	slotSize := typ.SlotSize
+	for i := uintptr(0); full != 0; i++ {
+		if key == *(*uint64)(slotKey) && full.lowestSet() {
+			m.removeSlotSmall(typ, g, i)
+			return
+		}
+		slotKey = unsafe.Pointer(uintptr(slotKey) + slotSize)
+		full = full.shiftOutLowest()
+	}
+}
+
+func (t *table) deleteFast64(typ *abi.SwissMapType, m *Map, hash uintptr, key uint64) {
+	seq := makeProbeSeq(h1(hash), t.groups.lengthMask)
+	for ; ; seq = seq.next() {
+		g := t.groups.group(typ, seq.offset)
+
+		match := g.ctrls().matchH2(h2(hash))
+
+		for match != 0 {
+			i := match.first()
+			if key == *(*uint64)(g.key(typ, i)) {
+				t.removeSlot(typ, m, g, i)
+				return
+			}
+			match = match.removeFirst()
+		}
+
+		match = g.ctrls().matchEmpty()
+		if match != 0 {
+			// Finding an empty slot means we've reached the end of
+			// the probe sequence.
+			return
+		}
+	}
 }
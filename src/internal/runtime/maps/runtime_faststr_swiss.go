@@ -8,7 +8,6 @@ package maps
 
 import (
 	"internal/abi"
-	"internal/goarch"
 	"internal/race"
 	"internal/runtime/sys"
 	"unsafe"
@@ -19,7 +18,7 @@ func (m *Map) getWithoutKeySmallFastStr(typ *abi.SwissMapType, key string) unsaf
 		data: m.dirPtr,
 	}
 
-	ctrls := *g.ctrls()
+	full := g.ctrls().matchFull()
 	slotKey := g.key(typ, 0)
 	slotSize := typ.SlotSize
 
@@ -31,7 +30,7 @@ func (m *Map) getWithoutKeySmallFastStr(typ *abi.SwissMapType, key string) unsaf
 		// String hashing and equality might be expensive. Do a quick check first.
 		j := abi.SwissMapGroupSlots
 		for i := range abi.SwissMapGroupSlots {
-			if ctrls&(1<<7) == 0 && longStringQuickEqualityTest(key, *(*string)(slotKey)) {
+			if full.lowestSet() && longStringQuickEqualityTest(key, *(*string)(slotKey)) {
 				if j < abi.SwissMapGroupSlots {
 					// 2 strings both passed the quick equality test.
 					// Break out of this loop and do it the slow way.
@@ -40,7 +39,7 @@ func (m *Map) getWithoutKeySmallFastStr(typ *abi.SwissMapType, key string) unsaf
 				j = i
 			}
 			slotKey = unsafe.Pointer(uintptr(slotKey) + slotSize)
-			ctrls >>= 8
+			full = full.shiftOutLowest()
 		}
 		if j == abi.SwissMapGroupSlots {
 			// No slot passed the quick test.
@@ -49,24 +48,23 @@ func (m *Map) getWithoutKeySmallFastStr(typ *abi.SwissMapType, key string) unsaf
 		// There's exactly one slot that passed the quick test. Do the single expensive comparison.
 		slotKey = g.key(typ, uintptr(j))
 		if key == *(*string)(slotKey) {
-			return unsafe.Pointer(uintptr(slotKey) + 2*goarch.PtrSize)
+			return unsafe.Pointer(uintptr(slotKey) + typ.ElemOff)
 		}
 		return nil
 	}
 
 dohash:
 	// This path will cost 1 hash and 1+ε comparisons.
-	hash := typ.Hasher(abi.NoEscape(unsafe.Pointer(&key)), m.seed)
-	h2 := uint8(h2(hash))
-	ctrls = *g.ctrls()
+	hash := strhash(abi.NoEscape(unsafe.Pointer(&key)), m.seed)
+	match := g.ctrls().matchH2(h2(hash))
 	slotKey = g.key(typ, 0)
 
-	for range abi.SwissMapGroupSlots {
-		if uint8(ctrls) == h2 && key == *(*string)(slotKey) {
-			return unsafe.Pointer(uintptr(slotKey) + 2*goarch.PtrSize)
+	for match != 0 {
+		if match.lowestSet() && key == *(*string)(slotKey) {
+			return unsafe.Pointer(uintptr(slotKey) + typ.ElemOff)
 		}
 		slotKey = unsafe.Pointer(uintptr(slotKey) + slotSize)
-		ctrls >>= 8
+		match = match.shiftOutLowest()
 	}
 	return nil
 }
@@ -108,7 +106,7 @@ func runtime_mapaccess1_faststr(typ *abi.SwissMapType, m *Map, key string) unsaf
 	}
 
 	if m == nil || m.Used() == 0 {
-		return unsafe.Pointer(&zeroVal[0])
+		return unsafe.Pointer(&abi.ZeroVal[0])
 	}
 
 	if m.writing != 0 {
@@ -119,22 +117,21 @@ func runtime_mapaccess1_faststr(typ *abi.SwissMapType, m *Map, key string) unsaf
 	if m.dirLen <= 0 {
 		elem := m.getWithoutKeySmallFastStr(typ, key)
 		if elem == nil {
-			return unsafe.Pointer(&zeroVal[0])
+			return unsafe.Pointer(&abi.ZeroVal[0])
 		}
 		return elem
 	}
 
 	k := key
-	hash := typ.Hasher(abi.NoEscape(unsafe.Pointer(&k)), m.seed)
+	hash := strhash(abi.NoEscape(unsafe.Pointer(&k)), m.seed)
 
 	// Select table.
-	idx := m.directoryIndex(hash)
-	t := m.directoryAt(idx)
+	groups := m.tableGroups(hash)
 
 	// Probe table.
-	seq := makeProbeSeq(h1(hash), t.groups.lengthMask)
+	seq := makeProbeSeq(h1(hash), groups.lengthMask)
 	for ; ; seq = seq.next() {
-		g := t.groups.group(typ, seq.offset)
+		g := groups.group(typ, seq.offset)
 
 		match := g.ctrls().matchH2(h2(hash))
 
@@ -143,7 +140,7 @@ func runtime_mapaccess1_faststr(typ *abi.SwissMapType, m *Map, key string) unsaf
 
 			slotKey := g.key(typ, i)
 			if key == *(*string)(slotKey) {
-				slotElem := unsafe.Pointer(uintptr(slotKey) + 2*goarch.PtrSize)
+				slotElem := unsafe.Pointer(uintptr(slotKey) + typ.ElemOff)
 				return slotElem
 			}
 			match = match.removeFirst()
@@ -153,7 +150,7 @@ func runtime_mapaccess1_faststr(typ *abi.SwissMapType, m *Map, key string) unsaf
 		if match != 0 {
 			// Finding an empty slot means we've reached the end of
 			// the probe sequence.
-			return unsafe.Pointer(&zeroVal[0])
+			return unsafe.Pointer(&abi.ZeroVal[0])
 		}
 	}
 }
@@ -167,7 +164,7 @@ func runtime_mapaccess2_faststr(typ *abi.SwissMapType, m *Map, key string) (unsa
 	}
 
 	if m == nil || m.Used() == 0 {
-		return unsafe.Pointer(&zeroVal[0]), false
+		return unsafe.Pointer(&abi.ZeroVal[0]), false
 	}
 
 	if m.writing != 0 {
@@ -178,22 +175,21 @@ func runtime_mapaccess2_faststr(typ *abi.SwissMapType, m *Map, key string) (unsa
 	if m.dirLen <= 0 {
 		elem := m.getWithoutKeySmallFastStr(typ, key)
 		if elem == nil {
-			return unsafe.Pointer(&zeroVal[0]), false
+			return unsafe.Pointer(&abi.ZeroVal[0]), false
 		}
 		return elem, true
 	}
 
 	k := key
-	hash := typ.Hasher(abi.NoEscape(unsafe.Pointer(&k)), m.seed)
+	hash := strhash(abi.NoEscape(unsafe.Pointer(&k)), m.seed)
 
 	// Select table.
-	idx := m.directoryIndex(hash)
-	t := m.directoryAt(idx)
+	groups := m.tableGroups(hash)
 
 	// Probe table.
-	seq := makeProbeSeq(h1(hash), t.groups.lengthMask)
+	seq := makeProbeSeq(h1(hash), groups.lengthMask)
 	for ; ; seq = seq.next() {
-		g := t.groups.group(typ, seq.offset)
+		g := groups.group(typ, seq.offset)
 
 		match := g.ctrls().matchH2(h2(hash))
 
@@ -202,7 +198,7 @@ func runtime_mapaccess2_faststr(typ *abi.SwissMapType, m *Map, key string) (unsa
 
 			slotKey := g.key(typ, i)
 			if key == *(*string)(slotKey) {
-				slotElem := unsafe.Pointer(uintptr(slotKey) + 2*goarch.PtrSize)
+				slotElem := unsafe.Pointer(uintptr(slotKey) + typ.ElemOff)
 				return slotElem, true
 			}
 			match = match.removeFirst()
@@ -212,7 +208,7 @@ func runtime_mapaccess2_faststr(typ *abi.SwissMapType, m *Map, key string) (unsa
 		if match != 0 {
 			// Finding an empty slot means we've reached the end of
 			// the probe sequence.
-			return unsafe.Pointer(&zeroVal[0]), false
+			return unsafe.Pointer(&abi.ZeroVal[0]), false
 		}
 	}
 }
@@ -243,6 +239,11 @@ func (m *Map) putSlotSmallFastStr(typ *abi.SwissMapType, hash uintptr, key strin
 	// more efficient than matchEmpty.
 	match = g.ctrls().matchEmptyOrDeleted()
 	if match == 0 {
+		if m.used == abi.SwissMapGroupSlots {
+			// The key is new and the group is full. The caller
+			// must grow the map.
+			return nil
+		}
 		fatal("small map with no empty slot (concurrent map writes?)")
 	}
 
@@ -256,6 +257,8 @@ func (m *Map) putSlotSmallFastStr(typ *abi.SwissMapType, hash uintptr, key strin
 	g.ctrls().set(i, ctrl(h2(hash)))
 	m.used++
 
+	m.checkInvariants(typ)
+
 	return slotElem
 }
 
@@ -274,7 +277,7 @@ func runtime_mapassign_faststr(typ *abi.SwissMapType, m *Map, key string) unsafe
 	}
 
 	k := key
-	hash := typ.Hasher(abi.NoEscape(unsafe.Pointer(&k)), m.seed)
+	hash := strhash(abi.NoEscape(unsafe.Pointer(&k)), m.seed)
 
 	// Set writing after calling Hasher, since Hasher may panic, in which
 	// case we have not actually done a write.
@@ -285,9 +288,9 @@ func runtime_mapassign_faststr(typ *abi.SwissMapType, m *Map, key string) unsafe
 	}
 
 	if m.dirLen == 0 {
-		if m.used < abi.SwissMapGroupSlots {
-			elem := m.putSlotSmallFastStr(typ, hash, key)
-
+		// Assigning to a key that is already present needs no empty
+		// slot, so look for the key even if the group is full.
+		if elem := m.putSlotSmallFastStr(typ, hash, key); elem != nil {
 			if m.writing == 0 {
 				fatal("concurrent map writes")
 			}
@@ -303,11 +306,11 @@ func runtime_mapassign_faststr(typ *abi.SwissMapType, m *Map, key string) unsafe
 	var slotElem unsafe.Pointer
 outer:
 	for {
-		// Select table.
-		idx := m.directoryIndex(hash)
-		t := m.directoryAt(idx)
+		// Select table. Its groups are enough to find the key, the
+		// table itself is only needed to insert it.
+		groups := m.tableGroups(hash)
 
-		seq := makeProbeSeq(h1(hash), t.groups.lengthMask)
+		seq := makeProbeSeq(h1(hash), groups.lengthMask)
 
 		// As we look for a match, keep track of the first deleted slot
 		// we find, which we'll use to insert the new entry if
@@ -316,7 +319,7 @@ outer:
 		var firstDeletedSlot uintptr
 
 		for ; ; seq = seq.next() {
-			g := t.groups.group(typ, seq.offset)
+			g := groups.group(typ, seq.offset)
 			match := g.ctrls().matchH2(h2(hash))
 
 			// Look for an existing slot containing this key.
@@ -330,7 +333,7 @@ outer:
 					*(*string)(slotKey) = key
 					slotElem = g.elem(typ, i)
 
-					t.checkInvariants(typ, m)
+					m.checkInvariants(typ)
 					break outer
 				}
 				match = match.removeFirst()
@@ -354,6 +357,7 @@ outer:
 			}
 			// We've found an empty slot, which means we've reached the end of
 			// the probe sequence.
+			t := m.directoryAt(m.directoryIndex(hash))
 
 			// If we found a deleted slot along the way, we can
 			// replace it without consuming growthLeft.
@@ -365,6 +369,7 @@ outer:
 
 			// If there is room left to grow, just insert the new entry.
 			if t.growthLeft > 0 {
+				unpoisonSlot(typ, g, i)
 				slotKey := g.key(typ, i)
 				*(*string)(slotKey) = key
 
@@ -380,6 +385,8 @@ outer:
 			}
 
 			t.rehash(typ, m)
+			// Rehashing may have changed the seed.
+			hash = strhash(abi.NoEscape(unsafe.Pointer(&k)), m.seed)
 			continue outer
 		}
 	}
@@ -404,5 +411,73 @@ func runtime_mapdelete_faststr(typ *abi.SwissMapType, m *Map, key string) {
 		return
 	}
 
-	m.Delete(typ, abi.NoEscape(unsafe.Pointer(&key)))
+	if m.writing != 0 {
+		fatal("concurrent map writes")
+	}
+
+	k := key
+	hash := strhash(abi.NoEscape(unsafe.Pointer(&k)), m.seed)
+
+	m.writing ^= 1 // toggle, see comment on writing
+
+	if m.dirLen == 0 {
+		m.deleteSmallFastStr(typ, hash, key)
+	} else {
+		idx := m.directoryIndex(hash)
+		m.directoryAt(idx).deleteFastStr(typ, m, hash, key)
+	}
+
+	if m.used == 0 {
+		// Reset the hash seed to make it more difficult for attackers
+		// to repeatedly trigger hash collisions. See
+		// https://go.dev/issue/25237.
+		m.seed = uintptr(rand())
+	}
+
+	if m.writing == 0 {
+		fatal("concurrent map writes")
+	}
+	m.writing ^= 1
+}
+
+func (m *Map) deleteSmallFastStr(typ *abi.SwissMapType, hash uintptr, key string) {
+	g := groupReference{
+		data: m.dirPtr,
+	}
+
+	match := g.ctrls().matchH2(h2(hash))
+
+	for match != 0 {
+		i := match.first()
+		if key == *(*string)(g.key(typ, i)) {
+			m.removeSlotSmall(typ, g, i)
+			return
+		}
+		match = match.removeFirst()
+	}
+}
+
+func (t *table) deleteFastStr(typ *abi.SwissMapType, m *Map, hash uintptr, key string) {
+	seq := makeProbeSeq(h1(hash), t.groups.lengthMask)
+	for ; ; seq = seq.next() {
+		g := t.groups.group(typ, seq.offset)
+
+		match := g.ctrls().matchH2(h2(hash))
+
+		for match != 0 {
+			i := match.first()
+			if key == *(*string)(g.key(typ, i)) {
+				t.removeSlot(typ, m, g, i)
+				return
+			}
+			match = match.removeFirst()
+		}
+
+		match = g.ctrls().matchEmpty()
+		if match != 0 {
+			// Finding an empty slot means we've reached the end of
+			// the probe sequence.
+			return
+		}
+	}
 }
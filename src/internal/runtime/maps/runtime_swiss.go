@@ -25,13 +25,13 @@ func mapKeyError(typ *abi.SwissMapType, p unsafe.Pointer) error
 //go:linkname errNilAssign
 var errNilAssign error
 
-// Pull from runtime. It is important that is this the exact same copy as the
-// runtime because runtime.mapaccess1_fat compares the returned pointer with
-// &runtime.zeroVal[0].
-// TODO: move zeroVal to internal/abi?
-//
-//go:linkname zeroVal runtime.zeroVal
-var zeroVal [abi.ZeroValSize]byte
+// The lookup functions below return &abi.ZeroVal[0] for missing keys,
+// which runtime.mapaccess1_fat and mapaccess2_fat compare against. The
+// compiler only calls them for elements of at most abi.ZeroValSize
+// bytes; larger elements use the _fat variants. The fast variants rely
+// on the compiler choosing them only for elements of at most
+// abi.SwissMapMaxElemBytes, which must therefore fit in abi.ZeroVal.
+const _ uint = abi.ZeroValSize - abi.SwissMapMaxElemBytes
 
 // mapaccess1 returns a pointer to h[key].  Never returns nil, instead
 // it will return a reference to the zero object for the elem type if
@@ -58,7 +58,7 @@ func runtime_mapaccess1(typ *abi.SwissMapType, m *Map, key unsafe.Pointer) unsaf
 		if err := mapKeyError(typ, key); err != nil {
 			panic(err) // see issue 23734
 		}
-		return unsafe.Pointer(&zeroVal[0])
+		return unsafe.Pointer(&abi.ZeroVal[0])
 	}
 
 	if m.writing != 0 {
@@ -70,19 +70,18 @@ func runtime_mapaccess1(typ *abi.SwissMapType, m *Map, key unsafe.Pointer) unsaf
 	if m.dirLen <= 0 {
 		_, elem, ok := m.getWithKeySmall(typ, hash, key)
 		if !ok {
-			return unsafe.Pointer(&zeroVal[0])
+			return unsafe.Pointer(&abi.ZeroVal[0])
 		}
 		return elem
 	}
 
 	// Select table.
-	idx := m.directoryIndex(hash)
-	t := m.directoryAt(idx)
+	groups := m.tableGroups(hash)
 
 	// Probe table.
-	seq := makeProbeSeq(h1(hash), t.groups.lengthMask)
+	seq := makeProbeSeq(h1(hash), groups.lengthMask)
 	for ; ; seq = seq.next() {
-		g := t.groups.group(typ, seq.offset)
+		g := groups.group(typ, seq.offset)
 
 		match := g.ctrls().matchH2(h2(hash))
 
@@ -108,7 +107,7 @@ func runtime_mapaccess1(typ *abi.SwissMapType, m *Map, key unsafe.Pointer) unsaf
 		if match != 0 {
 			// Finding an empty slot means we've reached the end of
 			// the probe sequence.
-			return unsafe.Pointer(&zeroVal[0])
+			return unsafe.Pointer(&abi.ZeroVal[0])
 		}
 	}
 }
@@ -132,7 +131,7 @@ func runtime_mapaccess2(typ *abi.SwissMapType, m *Map, key unsafe.Pointer) (unsa
 		if err := mapKeyError(typ, key); err != nil {
 			panic(err) // see issue 23734
 		}
-		return unsafe.Pointer(&zeroVal[0]), false
+		return unsafe.Pointer(&abi.ZeroVal[0]), false
 	}
 
 	if m.writing != 0 {
@@ -144,19 +143,18 @@ func runtime_mapaccess2(typ *abi.SwissMapType, m *Map, key unsafe.Pointer) (unsa
 	if m.dirLen == 0 {
 		_, elem, ok := m.getWithKeySmall(typ, hash, key)
 		if !ok {
-			return unsafe.Pointer(&zeroVal[0]), false
+			return unsafe.Pointer(&abi.ZeroVal[0]), false
 		}
 		return elem, true
 	}
 
 	// Select table.
-	idx := m.directoryIndex(hash)
-	t := m.directoryAt(idx)
+	groups := m.tableGroups(hash)
 
 	// Probe table.
-	seq := makeProbeSeq(h1(hash), t.groups.lengthMask)
+	seq := makeProbeSeq(h1(hash), groups.lengthMask)
 	for ; ; seq = seq.next() {
-		g := t.groups.group(typ, seq.offset)
+		g := groups.group(typ, seq.offset)
 
 		match := g.ctrls().matchH2(h2(hash))
 
@@ -182,7 +180,7 @@ func runtime_mapaccess2(typ *abi.SwissMapType, m *Map, key unsafe.Pointer) (unsa
 		if match != 0 {
 			// Finding an empty slot means we've reached the end of
 			// the probe sequence.
-			return unsafe.Pointer(&zeroVal[0]), false
+			return unsafe.Pointer(&abi.ZeroVal[0]), false
 		}
 	}
 }
@@ -219,8 +217,10 @@ func runtime_mapassign(typ *abi.SwissMapType, m *Map, key unsafe.Pointer) unsafe
 	}
 
 	if m.dirLen == 0 {
-		if m.used < abi.SwissMapGroupSlots {
-			elem := m.putSlotSmall(typ, hash, key)
+		// Assigning to a key that is already present needs no empty
+		// slot, so look for the key even if the group is full.
+		if elem := m.putSlotSmall(typ, hash, key); elem != nil {
+			sanitizerWrite(elem, typ.Elem.Size_)
 
 			if m.writing == 0 {
 				fatal("concurrent map writes")
@@ -237,11 +237,11 @@ func runtime_mapassign(typ *abi.SwissMapType, m *Map, key unsafe.Pointer) unsafe
 	var slotElem unsafe.Pointer
 outer:
 	for {
-		// Select table.
-		idx := m.directoryIndex(hash)
-		t := m.directoryAt(idx)
+		// Select table. Its groups are enough to find the key, the
+		// table itself is only needed to insert it.
+		groups := m.tableGroups(hash)
 
-		seq := makeProbeSeq(h1(hash), t.groups.lengthMask)
+		seq := makeProbeSeq(h1(hash), groups.lengthMask)
 
 		// As we look for a match, keep track of the first deleted slot
 		// we find, which we'll use to insert the new entry if
@@ -250,7 +250,7 @@ outer:
 		var firstDeletedSlot uintptr
 
 		for ; ; seq = seq.next() {
-			g := t.groups.group(typ, seq.offset)
+			g := groups.group(typ, seq.offset)
 			match := g.ctrls().matchH2(h2(hash))
 
 			// Look for an existing slot containing this key.
@@ -265,6 +265,7 @@ outer:
 				if typ.Key.Equal(key, slotKey) {
 					if typ.NeedKeyUpdate() {
 						typedmemmove(typ.Key, slotKey, key)
+						sanitizerWrite(slotKey, typ.Key.Size_)
 					}
 
 					slotElem = unsafe.Pointer(uintptr(slotKeyOrig) + typ.ElemOff)
@@ -272,7 +273,7 @@ outer:
 						slotElem = *((*unsafe.Pointer)(slotElem))
 					}
 
-					t.checkInvariants(typ, m)
+					m.checkInvariants(typ)
 					break outer
 				}
 				match = match.removeFirst()
@@ -284,6 +285,7 @@ outer:
 			if match != 0 {
 				// Finding an empty slot means we've reached the end of
 				// the probe sequence.
+				t := m.directoryAt(m.directoryIndex(hash))
 
 				var i uintptr
 
@@ -300,6 +302,7 @@ outer:
 
 				// If there is room left to grow, just insert the new entry.
 				if t.growthLeft > 0 {
+					unpoisonSlot(typ, g, i)
 					slotKey := g.key(typ, i)
 					slotKeyOrig := slotKey
 					if typ.IndirectKey() {
@@ -308,6 +311,7 @@ outer:
 						slotKey = kmem
 					}
 					typedmemmove(typ.Key, slotKey, key)
+					sanitizerWrite(slotKey, typ.Key.Size_)
 
 					slotElem = unsafe.Pointer(uintptr(slotKeyOrig) + typ.ElemOff)
 					if typ.IndirectElem() {
@@ -326,6 +330,8 @@ outer:
 				}
 
 				t.rehash(typ, m)
+				// Rehashing may have changed the seed.
+				hash = typ.Hasher(key, m.seed)
 				continue outer
 			}
 
@@ -346,6 +352,8 @@ outer:
 		}
 	}
 
+	sanitizerWrite(slotElem, typ.Elem.Size_)
+
 	if m.writing == 0 {
 		fatal("concurrent map writes")
 	}
@@ -0,0 +1,43 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package maps
+
+// Stats are cumulative statistics about the storage of maps, reported by
+// runtime/metrics. They are only updated when a map allocates, so
+// lookups, and insertions and deletions that don't grow a map, don't
+// touch them.
+//
+// Entries and Tombstones count what growth moves and discards, which
+// shows how much work rehashing costs, not how full live maps are.
+//
+// TODO: add gauges of the slots that live maps use or hold tombstones
+// in. They need to be maintained without a counter update on every
+// insertion and deletion, and to drop when a map becomes unreachable,
+// which the runtime is not told about today.
+type Stats struct {
+	// Groups is the number of groups allocated, including the
+	// single group of small maps.
+	Groups uint64
+
+	// GroupBytes is the size in bytes of the groups allocated.
+	GroupBytes uint64
+
+	// GroupUnused is the number of bytes by which the allocations of
+	// groups were rounded up to a size class, or to whole pages for
+	// large allocations. Since the number of groups of a table is a
+	// power of two, this space can't hold more groups.
+	GroupUnused uint64
+
+	// Entries is the number of entries moved into new tables by
+	// growing, splitting, or rehashing tables.
+	Entries uint64
+
+	// Tombstones is the number of tombstones discarded by growing,
+	// splitting, or rehashing tables.
+	Tombstones uint64
+
+	// Directory is the number of directory entries allocated.
+	Directory uint64
+}
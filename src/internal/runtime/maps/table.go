@@ -19,9 +19,26 @@ import (
 // dominate grow latency for large objects.
 const maxTableCapacity = 1024
 
+// maxDeepTableCapacity is the maximum capacity of a table at
+// maxSplitDepth, which grows rather than splits. It is the largest power
+// of two that fits in uint16.
+const maxDeepTableCapacity = 1 << 15
+
 // Ensure the max capacity fits in uint16, used for capacity and growthLeft
 // below.
-var _ = uint16(maxTableCapacity)
+var _ = uint16(maxDeepTableCapacity)
+
+// splitCapacity is the capacity beyond which a table splits rather than
+// grows, and the capacity of the tables it splits into. It is
+// maxTableCapacity, except in tests, which lower it to split tables and
+// grow the directory with few entries.
+var splitCapacity uint16 = maxTableCapacity
+
+// maxSplitDepth is the local depth at which a table no longer splits, and
+// grows beyond splitCapacity instead, which bounds the depth of the
+// directory. It is maxGlobalDepth, except in tests, which lower it to
+// reach it with few entries.
+var maxSplitDepth uint8 = maxGlobalDepth
 
 // table is a Swiss table hash table structure.
 //
@@ -72,16 +89,44 @@ type table struct {
 }
 
 func newTable(typ *abi.SwissMapType, capacity uint64, index int, localDepth uint8) *table {
-	if capacity < abi.SwissMapGroupSlots {
-		capacity = abi.SwissMapGroupSlots
-	}
-
+	capacity = tableCapacity(capacity)
 	t := &table{
 		index:      index,
 		localDepth: localDepth,
 	}
+	t.reset(typ, newGroups(typ, capacity/abi.SwissMapGroupSlots))
+	return t
+}
+
+// newTables fills tables with new tables of the given capacity and local
+// depth, indexed from index. The tables, and their groups, are allocated
+// as one object each. This saves two allocations per table, but keeps all
+// the groups alive until all the tables are replaced, so it is only worth
+// it for tables that are expected to last, like those of a presized map.
+func newTables(typ *abi.SwissMapType, tables []*table, capacity uint64, index int, localDepth uint8) {
+	capacity = tableCapacity(capacity)
+	groups := newGroupsShared(typ, capacity/abi.SwissMapGroupSlots, uint64(len(tables)))
+	ts := make([]table, len(tables))
+	for i := range tables {
+		if i > 0 {
+			groups = groups.next(typ)
+		}
+		t := &ts[i]
+		t.index = index + i
+		t.localDepth = localDepth
+		t.reset(typ, groups)
+		tables[i] = t
+	}
+}
 
-	if capacity > maxTableCapacity {
+// tableCapacity returns the capacity of a new table that holds at least
+// capacity slots.
+func tableCapacity(capacity uint64) uint64 {
+	if capacity < abi.SwissMapGroupSlots {
+		capacity = abi.SwissMapGroupSlots
+	}
+
+	if capacity > maxDeepTableCapacity {
 		panic("initial table capacity too large")
 	}
 
@@ -91,29 +136,30 @@ func newTable(typ *abi.SwissMapType, capacity uint64, index int, localDepth uint
 	if overflow {
 		panic("rounded-up capacity overflows uint64")
 	}
-
-	t.reset(typ, uint16(capacity))
-
-	return t
+	return capacity
 }
 
-// reset resets the table with new, empty groups with the specified new total
-// capacity.
-func (t *table) reset(typ *abi.SwissMapType, capacity uint16) {
-	groupCount := uint64(capacity) / abi.SwissMapGroupSlots
-	t.groups = newGroups(typ, groupCount)
-	t.capacity = capacity
+// reset resets the table with groups, which must be newly allocated.
+func (t *table) reset(typ *abi.SwissMapType, groups groupsReference) {
+	t.groups = groups
+	t.capacity = uint16((groups.lengthMask + 1) * abi.SwissMapGroupSlots)
 	t.resetGrowthLeft()
 
 	for i := uint64(0); i <= t.groups.lengthMask; i++ {
 		g := t.groups.group(typ, i)
 		g.ctrls().setEmpty()
 	}
+	poisonGroups(typ, t.groups)
 }
 
 // Preconditions: table must be empty.
 func (t *table) resetGrowthLeft() {
-	var growthLeft uint16
+	t.growthLeft = t.maxGrowth()
+}
+
+// maxGrowth returns the number of slots that may be filled in an empty table
+// of t's capacity before it must be rehashed.
+func (t *table) maxGrowth() uint16 {
 	if t.capacity == 0 {
 		// No real reason to support zero capacity table, since an
 		// empty Map simply won't have a table.
@@ -125,15 +171,10 @@ func (t *table) resetGrowthLeft() {
 		//
 		// TODO(go.dev/issue/54766): With a special case in probing for
 		// single-group tables, we could fill all slots.
-		growthLeft = t.capacity - 1
-	} else {
-		if t.capacity*maxAvgGroupLoad < t.capacity {
-			// TODO(prattmic): Do something cleaner.
-			panic("overflow")
-		}
-		growthLeft = (t.capacity * maxAvgGroupLoad) / abi.SwissMapGroupSlots
+		return t.capacity - 1
 	}
-	t.growthLeft = growthLeft
+	// Tables at maxSplitDepth may be too large to multiply in uint16.
+	return uint16(uint32(t.capacity) * maxAvgGroupLoad / abi.SwissMapGroupSlots)
 }
 
 func (t *table) Used() uint64 {
@@ -223,6 +264,37 @@ func (t *table) getWithKey(typ *abi.SwissMapType, hash uintptr, key unsafe.Point
 	}
 }
 
+// getKey is like getWithKey, but doesn't look at the element, for
+// iterations that only use the keys.
+func (t *table) getKey(typ *abi.SwissMapType, hash uintptr, key unsafe.Pointer) (unsafe.Pointer, bool) {
+	seq := makeProbeSeq(h1(hash), t.groups.lengthMask)
+	for ; ; seq = seq.next() {
+		g := t.groups.group(typ, seq.offset)
+
+		match := g.ctrls().matchH2(h2(hash))
+
+		for match != 0 {
+			i := match.first()
+
+			slotKey := g.key(typ, i)
+			if typ.IndirectKey() {
+				slotKey = *((*unsafe.Pointer)(slotKey))
+			}
+			if typ.Key.Equal(key, slotKey) {
+				return slotKey, true
+			}
+			match = match.removeFirst()
+		}
+
+		match = g.ctrls().matchEmpty()
+		if match != 0 {
+			// Finding an empty slot means we've reached the end of
+			// the probe sequence.
+			return nil, false
+		}
+	}
+}
+
 func (t *table) getWithoutKey(typ *abi.SwissMapType, hash uintptr, key unsafe.Pointer) (unsafe.Pointer, bool) {
 	seq := makeProbeSeq(h1(hash), t.groups.lengthMask)
 	for ; ; seq = seq.next() {
@@ -328,6 +400,7 @@ func (t *table) PutSlot(typ *abi.SwissMapType, m *Map, hash uintptr, key unsafe.
 
 		// If there is room left to grow, just insert the new entry.
 		if t.growthLeft > 0 {
+			unpoisonSlot(typ, g, i)
 			slotKey := g.key(typ, i)
 			if typ.IndirectKey() {
 				kmem := newobject(typ.Key)
@@ -373,6 +446,10 @@ func (t *table) PutSlot(typ *abi.SwissMapType, m *Map, hash uintptr, key unsafe.
 // requires the caller to ensure that the referenced memory never
 // changes (by sourcing those pointers from another indirect key/elem
 // map).
+//
+// Requires that t is newly allocated and not yet reachable from a Map
+// directory, so that its slots have never been written. This lets
+// uncheckedPutSlot skip the write barriers on the old (nil) contents.
 func (t *table) uncheckedPutSlot(typ *abi.SwissMapType, hash uintptr, key, elem unsafe.Pointer) {
 	if t.growthLeft == 0 {
 		panic("invariant failed: growthLeft is unexpectedly 0")
@@ -390,18 +467,30 @@ func (t *table) uncheckedPutSlot(typ *abi.SwissMapType, hash uintptr, key, elem
 		if match != 0 {
 			i := match.first()
 
+			unpoisonSlot(typ, g, i)
 			slotKey := g.key(typ, i)
-			if typ.IndirectKey() {
-				*(*unsafe.Pointer)(slotKey) = key
+			if !typ.IndirectKey() && !typ.IndirectElem() && elem == unsafe.Pointer(uintptr(key)+typ.ElemOff) {
+				// key and elem are the two halves of a slot
+				// in another group. Since this table is not
+				// yet installed in the directory, its groups
+				// have not been written since allocation, so
+				// the destination slot is still zeroed. Move
+				// the whole slot at once, with barriers only
+				// for the incoming pointers.
+				memmoveFresh(slotKey, key, typ.SlotSize, typ.Group.Pointers())
 			} else {
-				typedmemmove(typ.Key, slotKey, key)
-			}
+				if typ.IndirectKey() {
+					*(*unsafe.Pointer)(slotKey) = key
+				} else {
+					typedmemmove(typ.Key, slotKey, key)
+				}
 
-			slotElem := g.elem(typ, i)
-			if typ.IndirectElem() {
-				*(*unsafe.Pointer)(slotElem) = elem
-			} else {
-				typedmemmove(typ.Elem, slotElem, elem)
+				slotElem := g.elem(typ, i)
+				if typ.IndirectElem() {
+					*(*unsafe.Pointer)(slotElem) = elem
+				} else {
+					typedmemmove(typ.Elem, slotElem, elem)
+				}
 			}
 
 			t.growthLeft--
@@ -412,7 +501,8 @@ func (t *table) uncheckedPutSlot(typ *abi.SwissMapType, hash uintptr, key, elem
 	}
 }
 
-func (t *table) Delete(typ *abi.SwissMapType, m *Map, hash uintptr, key unsafe.Pointer) {
+// Delete deletes key from the table, if present, and reports whether it was.
+func (t *table) Delete(typ *abi.SwissMapType, m *Map, hash uintptr, key unsafe.Pointer) bool {
 	seq := makeProbeSeq(h1(hash), t.groups.lengthMask)
 	for ; ; seq = seq.next() {
 		g := t.groups.group(typ, seq.offset)
@@ -422,54 +512,13 @@ func (t *table) Delete(typ *abi.SwissMapType, m *Map, hash uintptr, key unsafe.P
 			i := match.first()
 
 			slotKey := g.key(typ, i)
-			origSlotKey := slotKey
 			if typ.IndirectKey() {
 				slotKey = *((*unsafe.Pointer)(slotKey))
 			}
 
 			if typ.Key.Equal(key, slotKey) {
-				t.used--
-				m.used--
-
-				if typ.IndirectKey() {
-					// Clearing the pointer is sufficient.
-					*(*unsafe.Pointer)(origSlotKey) = nil
-				} else if typ.Key.Pointers() {
-					// Only bothing clear the key if there
-					// are pointers in it.
-					typedmemclr(typ.Key, slotKey)
-				}
-
-				slotElem := g.elem(typ, i)
-				if typ.IndirectElem() {
-					// Clearing the pointer is sufficient.
-					*(*unsafe.Pointer)(slotElem) = nil
-				} else {
-					// Unlike keys, always clear the elem (even if
-					// it contains no pointers), as compound
-					// assignment operations depend on cleared
-					// deleted values. See
-					// https://go.dev/issue/25936.
-					typedmemclr(typ.Elem, slotElem)
-				}
-
-				// Only a full group can appear in the middle
-				// of a probe sequence (a group with at least
-				// one empty slot terminates probing). Once a
-				// group becomes full, it stays full until
-				// rehashing/resizing. So if the group isn't
-				// full now, we can simply remove the element.
-				// Otherwise, we create a tombstone to mark the
-				// slot as deleted.
-				if g.ctrls().matchEmpty() != 0 {
-					g.ctrls().set(i, ctrlEmpty)
-					t.growthLeft++
-				} else {
-					g.ctrls().set(i, ctrlDeleted)
-				}
-
-				t.checkInvariants(typ, m)
-				return
+				t.removeSlot(typ, m, g, i)
+				return true
 			}
 			match = match.removeFirst()
 		}
@@ -478,7 +527,210 @@ func (t *table) Delete(typ *abi.SwissMapType, m *Map, hash uintptr, key unsafe.P
 		if match != 0 {
 			// Finding an empty slot means we've reached the end of
 			// the probe sequence.
-			return
+			return false
+		}
+	}
+}
+
+// removeSlot removes the entry in full slot i of group g, and shrinks the
+// table if it has become sparse. If the table is replaced, t is now stale and
+// should not be modified, as after rehash.
+func (t *table) removeSlot(typ *abi.SwissMapType, m *Map, g groupReference, i uintptr) {
+	t.used--
+	m.used--
+
+	slotKey := g.key(typ, i)
+	if typ.IndirectKey() {
+		// Clearing the pointer is sufficient.
+		*(*unsafe.Pointer)(slotKey) = nil
+	} else if typ.Key.Pointers() {
+		// Only bothing clear the key if there
+		// are pointers in it.
+		typedmemclr(typ.Key, slotKey)
+		sanitizerWrite(slotKey, typ.Key.Size_)
+	}
+
+	slotElem := g.elem(typ, i)
+	if typ.IndirectElem() {
+		// Clearing the pointer is sufficient.
+		*(*unsafe.Pointer)(slotElem) = nil
+	} else {
+		// Unlike keys, always clear the elem (even if
+		// it contains no pointers), as compound
+		// assignment operations depend on cleared
+		// deleted values. See
+		// https://go.dev/issue/25936.
+		typedmemclr(typ.Elem, slotElem)
+		sanitizerWrite(slotElem, typ.Elem.Size_)
+	}
+	poisonSlot(typ, g, i)
+
+	// Only a full group can appear in the middle
+	// of a probe sequence (a group with at least
+	// one empty slot terminates probing). Once a
+	// group becomes full, it stays full until
+	// rehashing/resizing. So if the group isn't
+	// full now, we can simply remove the element.
+	// Otherwise, we create a tombstone to mark the
+	// slot as deleted.
+	if g.ctrls().matchEmpty() != 0 {
+		g.ctrls().set(i, ctrlEmpty)
+		t.growthLeft++
+	} else {
+		g.ctrls().set(i, ctrlDeleted)
+	}
+
+	t.checkInvariants(typ, m)
+	t.maybeShrink(typ, m)
+}
+
+// maybeShrink replaces the table with a smaller one if deletions have
+// left at most 1/shrinkLoadDivisor of its slots in use, so that the
+// memory of its groups can be freed. The new table is half full, so
+// that it takes many insertions or deletions to grow or shrink it
+// again. If the table is replaced, t is now stale and should not be
+// modified, as after rehash.
+//
+// If the table and its sibling, the other half of the table they were
+// split from, hold few enough entries together, maybeShrink merges them
+// instead, see merge.
+func (t *table) maybeShrink(typ *abi.SwissMapType, m *Map) {
+	if t.maybeMerge(typ, m) {
+		return
+	}
+	if t.capacity <= abi.SwissMapGroupSlots || t.used > t.capacity/shrinkLoadDivisor {
+		return
+	}
+	newCapacity, _ := alignUpPow2(max(2*uint64(t.used), abi.SwissMapGroupSlots))
+	t.resize(typ, m, uint16(newCapacity))
+}
+
+// A table is shrunk by maybeShrink when at most 1/shrinkLoadDivisor of
+// its slots are in use.
+const shrinkLoadDivisor = 8
+
+// maxMergeUsed returns the number of entries that sibling tables hold at
+// most together when maybeShrink merges them, as few as a table of
+// maximum capacity is shrunk at. The merged table is at most half full,
+// far from splitting again.
+func maxMergeUsed() uint16 {
+	return splitCapacity / shrinkLoadDivisor
+}
+
+// maybeMerge merges the table with its sibling, and the merged table with
+// its own sibling, and so on, as long as they hold at most maxMergeUsed
+// entries together, then shrinks the directory if it can. It reports
+// whether it merged the table, in which case t is now stale and should
+// not be modified, as after rehash.
+//
+// Tables holding keys that aren't equal to themselves (NaN) are not
+// merged, see hasNaN.
+func (t *table) maybeMerge(typ *abi.SwissMapType, m *Map) bool {
+	merged := false
+	for t.used <= maxMergeUsed() && t.localDepth > 0 {
+		sib := m.sibling(t)
+		if sib.localDepth != t.localDepth || t.used+sib.used > maxMergeUsed() {
+			break
+		}
+		if t.hasNaN(typ) || sib.hasNaN(typ) {
+			break
+		}
+		t = t.merge(typ, m, sib)
+		merged = true
+	}
+	if merged {
+		m.maybeShrinkDirectory()
+	}
+	return merged
+}
+
+// hasNaN reports whether the table holds a key that isn't equal to itself
+// (NaN).
+//
+// An iteration that has returned the entries of one of two merged tables
+// skips them in the merged table by the directory entry their hash
+// selects (see Iter.elsewhere), but the hash of a NaN is random, so the
+// iteration can't tell which of the tables a NaN came from. It would
+// return the NaN twice or not at all. Tables holding NaN are rare, and
+// hold few entries when maybeMerge asks, so we check them all and keep
+// such tables apart instead.
+func (t *table) hasNaN(typ *abi.SwissMapType) bool {
+	if !typ.NeedKeyUpdate() {
+		// Keys of this type are always equal to themselves.
+		return false
+	}
+	for i := uint64(0); i <= t.groups.lengthMask; i++ {
+		g := t.groups.group(typ, i)
+		match := g.ctrls().matchFull()
+		for match != 0 {
+			j := match.first()
+			match = match.removeFirst()
+			key := g.key(typ, j)
+			if typ.IndirectKey() {
+				key = *((*unsafe.Pointer)(key))
+			}
+			if !typ.Key.Equal(key, key) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// merge replaces t and sib, sibling tables of the same local depth, by a
+// single table of their parent's depth, sized as maybeShrink sizes a
+// shrunk table, and returns it. Since the tables are replaced, t and sib
+// are now stale and should not be modified, as after rehash.
+//
+// Iterations that have returned the entries of one of the tables, but not
+// those of the other, skip the entries of the first in the merged table;
+// see Iter.elsewhere.
+func (t *table) merge(typ *abi.SwissMapType, m *Map, sib *table) *table {
+	done := false
+	defer m.abortWrite(&done)
+
+	used := uint64(t.used) + uint64(sib.used)
+	newCapacity, _ := alignUpPow2(max(2*used, abi.SwissMapGroupSlots))
+	nt := newTable(typ, newCapacity, min(t.index, sib.index), t.localDepth-1)
+	t.putAll(typ, m.seed, nt)
+	sib.putAll(typ, m.seed, nt)
+	done = true
+	nt.checkInvariants(typ, m)
+
+	addStats(Stats{
+		Entries:    used,
+		Tombstones: uint64(t.tombstones()) + uint64(sib.tombstones()),
+	})
+	m.replaceTable(nt)
+	t.index = -1
+	sib.index = -1
+	return nt
+}
+
+// putAll puts every entry of t into nt, a new table in which none of them
+// are present, hashing the keys with seed.
+func (t *table) putAll(typ *abi.SwissMapType, seed uintptr, nt *table) {
+	for i := uint64(0); i <= t.groups.lengthMask; i++ {
+		g := t.groups.group(typ, i)
+		for j := uintptr(0); j < abi.SwissMapGroupSlots; j++ {
+			if (g.ctrls().get(j) & ctrlEmpty) == ctrlEmpty {
+				// Empty or deleted
+				continue
+			}
+
+			key := g.key(typ, j)
+			if typ.IndirectKey() {
+				key = *((*unsafe.Pointer)(key))
+			}
+
+			elem := g.elem(typ, j)
+			if typ.IndirectElem() {
+				elem = *((*unsafe.Pointer)(elem))
+			}
+
+			hash := typ.Hasher(key, seed)
+
+			nt.uncheckedPutSlot(typ, hash, key, elem)
 		}
 	}
 }
@@ -487,19 +739,49 @@ func (t *table) Delete(typ *abi.SwissMapType, m *Map, hash uintptr, key unsafe.P
 // tombstone is a slot that has been deleted but is still considered occupied
 // so as not to violate the probing invariant.
 func (t *table) tombstones() uint16 {
-	return (t.capacity*maxAvgGroupLoad)/abi.SwissMapGroupSlots - t.used - t.growthLeft
+	return t.maxGrowth() - t.used - t.growthLeft
 }
 
-// Clear deletes all entries from the map resulting in an empty map.
-func (t *table) Clear(typ *abi.SwissMapType) {
-	for i := uint64(0); i <= t.groups.lengthMask; i++ {
-		g := t.groups.group(typ, i)
-		typedmemclr(typ.Group, g.data)
-		g.ctrls().setEmpty()
-	}
+// clone returns a copy of t, with the same layout, including any
+// tombstones.
+func (t *table) clone(typ *abi.SwissMapType) *table {
+	nt := new(table)
+	*nt = *t
+	nt.groups = newGroups(typ, t.groups.lengthMask+1)
+	size := uintptr(t.groups.lengthMask+1) * typ.GroupSize
+	memmoveFresh(nt.groups.data, t.groups.data, size, typ.Group.Pointers())
+	cloneIndirect(typ, nt.groups)
+	poisonEmptySlots(typ, nt.groups)
+	return nt
+}
 
-	t.used = 0
-	t.resetGrowthLeft()
+// cloneIndirect replaces the indirect keys and elements in the freshly
+// copied groups by copies, so that they are not shared with the source
+// of the copy.
+func cloneIndirect(typ *abi.SwissMapType, groups groupsReference) {
+	if !typ.IndirectKey() && !typ.IndirectElem() {
+		return
+	}
+	for i := uint64(0); i <= groups.lengthMask; i++ {
+		g := groups.group(typ, i)
+		match := g.ctrls().matchFull()
+		for match != 0 {
+			j := match.first()
+			match = match.removeFirst()
+			if typ.IndirectKey() {
+				slotKey := (*unsafe.Pointer)(g.key(typ, j))
+				kmem := newobject(typ.Key)
+				typedmemmove(typ.Key, kmem, *slotKey)
+				*slotKey = kmem
+			}
+			if typ.IndirectElem() {
+				slotElem := (*unsafe.Pointer)(g.elem(typ, j))
+				emem := newobject(typ.Elem)
+				typedmemmove(typ.Elem, emem, *slotElem)
+				*slotElem = emem
+			}
+		}
+	}
 }
 
 type Iter struct {
@@ -522,6 +804,11 @@ type Iter struct {
 	// detect directory grow during iteration.
 	globalDepth uint8
 
+	// keysOnly is set by InitKeys for iterations that don't use the
+	// elements. Next then leaves elem nil, and doesn't look up the
+	// elements of entries in tables that have grown.
+	keysOnly bool
+
 	// dirIdx is the current directory index, prior to adjustment by
 	// dirOffset.
 	dirIdx int
@@ -555,14 +842,29 @@ func (it *Iter) Init(typ *abi.SwissMapType, m *Map) {
 	}
 
 	it.m = m
-	it.entryOffset = rand()
-	it.dirOffset = rand()
+	if seed := iterSeed(); seed != 0 {
+		// GODEBUG=mapiterseed: start at a fixed position, so that
+		// iterations over an unmodified map return the same order.
+		// Only the offsets are fixed, not the hash seed.
+		it.entryOffset = seed
+		it.dirOffset = seed
+	} else {
+		it.entryOffset = rand()
+		it.dirOffset = rand()
+	}
 	it.globalDepth = m.globalDepth
 	it.dirIdx = dirIdx
 	it.group = groupSmall
 	it.clearSeq = m.clearSeq
 }
 
+// InitKeys initializes Iter for an iteration that only uses the keys, as
+// in a range loop without an element variable. Next leaves Elem nil.
+func (it *Iter) InitKeys(typ *abi.SwissMapType, m *Map) {
+	it.Init(typ, m)
+	it.keysOnly = true
+}
+
 func (it *Iter) Initialized() bool {
 	return it.typ != nil
 }
@@ -614,17 +916,58 @@ func (it *Iter) nextDirIdx() {
 	// We can achieve both of these by using to difference between
 	// the directory and table depth to compute how many entries
 	// the table covers.
-	entries := 1 << (it.m.globalDepth - it.tab.localDepth)
-	it.dirIdx += entries
+	//
+	// If it.tab was resolved in the middle of its run of entries,
+	// because tables merged since we passed the start of the run, we
+	// skip the rest of the run.
+	entries := 1 << (it.globalDepth - it.tab.localDepth)
+	dirIdx := int((uint64(it.dirIdx) + it.dirOffset) & (1<<it.globalDepth - 1))
+	it.dirIdx += entries - dirIdx&(entries-1)
 	it.tab = nil
 	it.group = groupReference{}
 	it.entryIdx = 0
 }
 
+// elsewhere reports whether key, from it.tab, belongs to a directory
+// entry of it.tab's run other than those the iteration visits from
+// it.dirIdx to the end of the run, so that it is returned at another
+// visit, or has been already. This only happens for tables merged since
+// Init, whose run may start before it.dirIdx, or wrap around the end of
+// the iteration.
+func (it *Iter) elsewhere(key unsafe.Pointer) bool {
+	entries := uint64(1) << (it.globalDepth - it.tab.localDepth)
+	dirIdx := (uint64(it.dirIdx) + it.dirOffset) & (1<<it.globalDepth - 1)
+	// The iteration ends at 1<<it.globalDepth, before the end of a run
+	// that wraps around.
+	visited := min(entries-dirIdx&(entries-1), 1<<it.globalDepth-uint64(it.dirIdx))
+	if visited == entries {
+		// We visit the whole run.
+		return false
+	}
+	if !it.typ.Key.Equal(key, key) {
+		// The entry of a key that isn't equal to itself (NaN)
+		// depends on a random hash. Tables holding NaN are not
+		// merged (see table.hasNaN), so a NaN here was added to
+		// the merged table since, and iteration may return it.
+		return false
+	}
+	hash := it.typ.Hasher(key, it.m.seed)
+	keyIdx := uint64(hash >> depthToShift(it.globalDepth))
+	return (keyIdx-dirIdx)&(1<<it.globalDepth-1) >= visited
+}
+
 // Return the appropriate key/elem for key at slotIdx index within it.group, if
 // any.
 func (it *Iter) grownKeyElem(key unsafe.Pointer, slotIdx uintptr) (unsafe.Pointer, unsafe.Pointer, bool) {
-	newKey, newElem, ok := it.m.getWithKey(it.typ, key)
+	var newKey, newElem unsafe.Pointer
+	var ok bool
+	if it.keysOnly {
+		// Deleted entries must still be skipped, but only the key
+		// need be found.
+		newKey, ok = it.m.getKey(it.typ, key)
+	} else {
+		newKey, newElem, ok = it.m.getWithKey(it.typ, key)
+	}
 	if !ok {
 		// Key has likely been deleted, and
 		// should be skipped.
@@ -638,23 +981,12 @@ func (it *Iter) grownKeyElem(key unsafe.Pointer, slotIdx uintptr) (unsafe.Pointe
 		// However, we are in luck because such
 		// keys cannot be updated and they
 		// cannot be deleted except with clear.
-		// Thus if no clear has occurred, the
-		// key/elem must still exist exactly as
-		// in the old groups, so we can return
-		// them from there.
-		//
-		// TODO(prattmic): Consider checking
-		// clearSeq early. If a clear occurred,
-		// Next could always return
-		// immediately, as iteration doesn't
-		// need to return anything added after
-		// clear.
-		if it.clearSeq == it.m.clearSeq && !it.typ.Key.Equal(key, key) {
-			elem := it.group.elem(it.typ, slotIdx)
-			if it.typ.IndirectElem() {
-				elem = *((*unsafe.Pointer)(elem))
-			}
-			return key, elem, true
+		// Next returns early if a clear has
+		// occurred, so the key/elem must still
+		// exist exactly as in the old groups,
+		// and we can return them from there.
+		if !it.typ.Key.Equal(key, key) {
+			return key, it.slotElem(slotIdx), true
 		}
 
 		// This entry doesn't exist anymore.
@@ -664,12 +996,31 @@ func (it *Iter) grownKeyElem(key unsafe.Pointer, slotIdx uintptr) (unsafe.Pointe
 	return newKey, newElem, true
 }
 
+// slotElem returns the element of the slot at slotIdx of it.group, or
+// nil if the iteration only uses the keys.
+func (it *Iter) slotElem(slotIdx uintptr) unsafe.Pointer {
+	if it.keysOnly {
+		return nil
+	}
+	elem := it.group.elem(it.typ, slotIdx)
+	if it.typ.IndirectElem() {
+		elem = *((*unsafe.Pointer)(elem))
+	}
+	return elem
+}
+
 // Next proceeds to the next element in iteration, which can be accessed via
 // the Key and Elem methods.
 //
 // The table can be mutated during iteration, though there is no guarantee that
 // the mutations will be visible to the iteration.
 //
+// The exception is Clear, which ends the iteration: the first call to Next
+// after a Clear returns no entry. All entries from before the Clear were
+// deleted, so must not be returned, even though the iterator may still
+// hold their group or table, which the map may reuse. Entries added after
+// the Clear are new, and the spec permits iteration to skip them.
+//
 // Init must be called prior to Next.
 func (it *Iter) Next() {
 	if it.m == nil {
@@ -684,6 +1035,15 @@ func (it *Iter) Next() {
 		return
 	}
 
+	if it.clearSeq != it.m.clearSeq {
+		// The map has been cleared since Init. Every entry that
+		// existed at Init has been deleted, and iteration need not
+		// return entries added since, so we are done.
+		it.key = nil
+		it.elem = nil
+		return
+	}
+
 	if it.dirIdx < 0 {
 		// Map was small at Init.
 		for ; it.entryIdx < abi.SwissMapGroupSlots; it.entryIdx++ {
@@ -707,26 +1067,12 @@ func (it *Iter) Next() {
 			var elem unsafe.Pointer
 			if grown {
 				var ok bool
-				newKey, newElem, ok := it.m.getWithKey(it.typ, key)
+				key, elem, ok = it.grownKeyElem(key, k)
 				if !ok {
-					// See comment below.
-					if it.clearSeq == it.m.clearSeq && !it.typ.Key.Equal(key, key) {
-						elem = it.group.elem(it.typ, k)
-						if it.typ.IndirectElem() {
-							elem = *((*unsafe.Pointer)(elem))
-						}
-					} else {
-						continue
-					}
-				} else {
-					key = newKey
-					elem = newElem
+					continue
 				}
 			} else {
-				elem = it.group.elem(it.typ, k)
-				if it.typ.IndirectElem() {
-					elem = *((*unsafe.Pointer)(elem))
-				}
+				elem = it.slotElem(k)
 			}
 
 			it.entryIdx++
@@ -739,7 +1085,7 @@ func (it *Iter) Next() {
 		return
 	}
 
-	if it.globalDepth != it.m.globalDepth {
+	if it.globalDepth < it.m.globalDepth {
 		// Directory has grown since the last call to Next. Adjust our
 		// directory index.
 		//
@@ -777,17 +1123,25 @@ func (it *Iter) Next() {
 		it.globalDepth = it.m.globalDepth
 	}
 
+	// If the directory has shrunk since Init (see
+	// Map.maybeShrinkDirectory), we keep iterating over the larger
+	// directory it.globalDepth describes, as if it had not shrunk.
+	// Each of its entries refers to the entry of the actual directory
+	// that it would have been merged into.
+	shrunk := it.globalDepth - it.m.globalDepth
+
 	// Continue iteration until we find a full slot.
-	for ; it.dirIdx < it.m.dirLen; it.nextDirIdx() {
+	for ; it.dirIdx < 1<<it.globalDepth; it.nextDirIdx() {
 		// Resolve the table.
 		if it.tab == nil {
-			dirIdx := int((uint64(it.dirIdx) + it.dirOffset) & uint64(it.m.dirLen-1))
-			newTab := it.m.directoryAt(uintptr(dirIdx))
-			if newTab.index != dirIdx {
+			dirIdx := int((uint64(it.dirIdx) + it.dirOffset) & (1<<it.globalDepth - 1))
+			newTab := it.m.directoryAt(uintptr(dirIdx >> shrunk))
+			if start := newTab.index << shrunk; it.dirIdx == 0 && start != dirIdx {
 				// Normally we skip past all duplicates of the
 				// same entry in the table (see updates to
 				// it.dirIdx at the end of the loop below), so
-				// this case wouldn't occur.
+				// this case wouldn't occur, unless tables
+				// have been merged (see it.elsewhere).
 				//
 				// But on the very first call, we have a
 				// completely randomized dirIdx that may refer
@@ -795,9 +1149,8 @@ func (it *Iter) Next() {
 				// directory. Do a one-time adjustment of the
 				// offset to ensure we start at first index for
 				// newTable.
-				diff := dirIdx - newTab.index
+				diff := dirIdx - start
 				it.dirOffset -= uint64(diff)
-				dirIdx = newTab.index
 			}
 			it.tab = newTab
 		}
@@ -815,7 +1168,7 @@ func (it *Iter) Next() {
 		// Fast path: skip matching and directly check if entryIdx is a
 		// full slot.
 		//
-		// In the slow path below, we perform an 8-slot match check to
+		// In the slow path below, we perform a group match check to
 		// look for full slots within the group.
 		//
 		// However, with a max load factor of 7/8, each slot in a
@@ -842,6 +1195,10 @@ func (it *Iter) Next() {
 				key = *((*unsafe.Pointer)(key))
 			}
 
+			if it.elsewhere(key) {
+				goto next
+			}
+
 			grown := it.tab.index == -1
 			var elem unsafe.Pointer
 			if grown {
@@ -856,10 +1213,7 @@ func (it *Iter) Next() {
 					elem = newElem
 				}
 			} else {
-				elem = it.group.elem(it.typ, slotIdx)
-				if it.typ.IndirectElem() {
-					elem = *((*unsafe.Pointer)(elem))
-				}
+				elem = it.slotElem(slotIdx)
 			}
 
 			it.entryIdx++
@@ -947,34 +1301,29 @@ func (it *Iter) Next() {
 			// keys to lookup in order to avoid returning
 			// the same key twice.
 			grown := it.tab.index == -1
+			ok := !it.elsewhere(key)
 			var elem unsafe.Pointer
-			if grown {
-				newKey, newElem, ok := it.grownKeyElem(key, slotIdx)
-				if !ok {
-					// This entry doesn't exist anymore.
-					// Continue to the next one.
-					groupMatch = groupMatch.removeFirst()
-					if groupMatch == 0 {
-						// No more entries in this
-						// group. Continue to next
-						// group.
-						it.entryIdx += abi.SwissMapGroupSlots - uint64(slotIdx)
-						continue
-					}
-
-					// Next full slot.
-					i := groupMatch.first()
-					it.entryIdx += uint64(i - slotIdx)
+			if ok && grown {
+				key, elem, ok = it.grownKeyElem(key, slotIdx)
+			} else if ok {
+				elem = it.slotElem(slotIdx)
+			}
+			if !ok {
+				// This entry doesn't exist anymore, or was
+				// returned already. Continue to the next one.
+				groupMatch = groupMatch.removeFirst()
+				if groupMatch == 0 {
+					// No more entries in this
+					// group. Continue to next
+					// group.
+					it.entryIdx += abi.SwissMapGroupSlots - uint64(slotIdx)
 					continue
-				} else {
-					key = newKey
-					elem = newElem
-				}
-			} else {
-				elem = it.group.elem(it.typ, slotIdx)
-				if it.typ.IndirectElem() {
-					elem = *((*unsafe.Pointer)(elem))
 				}
+
+				// Next full slot.
+				i := groupMatch.first()
+				it.entryIdx += uint64(i - slotIdx)
+				continue
 			}
 
 			// Jump ahead to the next full slot or next group.
@@ -1005,30 +1354,51 @@ func (it *Iter) Next() {
 
 // Replaces the table with one larger table or two split tables to fit more
 // entries. Since the table is replaced, t is now stale and should not be
-// modified.
+// modified. If t is the only table, rehash also changes the map's seed;
+// see resize.
 func (t *table) rehash(typ *abi.SwissMapType, m *Map) {
-	// TODO(prattmic): SwissTables typically perform a "rehash in place"
-	// operation which recovers capacity consumed by tombstones without growing
-	// the table by reordering slots as necessary to maintain the probe
-	// invariant while eliminating all tombstones.
+	// SwissTables typically perform a "rehash in place" operation which
+	// recovers capacity consumed by tombstones without growing the table
+	// by reordering slots as necessary to maintain the probe invariant
+	// while eliminating all tombstones.
 	//
 	// However, it is unclear how to make rehash in place work with
 	// iteration. Since iteration simply walks through all slots in order
 	// (with random start offset), reordering the slots would break
 	// iteration.
 	//
-	// As an alternative, we could do a "resize" to new groups allocation
-	// of the same size. This would eliminate the tombstones, but using a
-	// new allocation, so the existing grow support in iteration would
-	// continue to work.
-
-	newCapacity := 2 * t.capacity
-	if newCapacity <= maxTableCapacity {
-		t.grow(typ, m, newCapacity)
+	// Instead, if tombstones take up at least a quarter of the growth budget,
+	// we "resize" to a new groups allocation of the same size. This
+	// eliminates the tombstones, but uses a new allocation, so the
+	// existing grow support in iteration continues to work. Without this,
+	// a map that repeatedly inserts and deletes at a steady size would
+	// keep doubling its table. Requiring a quarter of the budget to be
+	// recovered keeps the cost of these rehashes amortized constant per
+	// insert.
+	if t.used <= t.maxGrowth()/4*3 {
+		t.resize(typ, m, t.capacity)
 		return
 	}
 
-	t.split(typ, m)
+	if t.capacity < splitCapacity {
+		t.resize(typ, m, 2*t.capacity)
+		return
+	}
+
+	if t.localDepth < maxSplitDepth {
+		t.split(typ, m)
+		return
+	}
+
+	// Splitting further would select tables by the bits of the hash
+	// that select the first group of a probe sequence, and grow a
+	// directory that a good hasher never needs. Grow the table instead.
+	// Its keys share the top maxSplitDepth bits of their hashes, so
+	// filling a table of maximum capacity takes a broken hasher.
+	if t.capacity >= maxDeepTableCapacity {
+		fatal("too many map keys with colliding hashes")
+	}
+	t.resize(typ, m, 2*t.capacity)
 }
 
 // Bitmask for the last selection bit at this depth.
@@ -1041,12 +1411,15 @@ func localDepthMask(localDepth uint8) uintptr {
 
 // split the table into two, installing the new tables in the map directory.
 func (t *table) split(typ *abi.SwissMapType, m *Map) {
+	done := false
+	defer m.abortWrite(&done)
+
 	localDepth := t.localDepth
 	localDepth++
 
 	// TODO: is this the best capacity?
-	left := newTable(typ, maxTableCapacity, -1, localDepth)
-	right := newTable(typ, maxTableCapacity, -1, localDepth)
+	left := newTable(typ, uint64(splitCapacity), -1, localDepth)
+	right := newTable(typ, uint64(splitCapacity), -1, localDepth)
 
 	// Split in half at the localDepth bit from the top.
 	mask := localDepthMask(localDepth)
@@ -1079,45 +1452,51 @@ func (t *table) split(typ *abi.SwissMapType, m *Map) {
 			newTable.uncheckedPutSlot(typ, hash, key, elem)
 		}
 	}
+	done = true
 
+	addStats(Stats{Entries: uint64(t.used), Tombstones: uint64(t.tombstones())})
 	m.installTableSplit(t, left, right)
 	t.index = -1
+
+	left.checkInvariants(typ, m)
+	right.checkInvariants(typ, m)
 }
 
-// grow the capacity of the table by allocating a new table with a bigger array
-// and uncheckedPutting each element of the table into the new table (we know
-// that no insertion here will Put an already-present value), and discard the
-// old table.
-func (t *table) grow(typ *abi.SwissMapType, m *Map, newCapacity uint16) {
+// resize the table by allocating a new table with a bigger (or, when
+// shrinking, smaller) array and uncheckedPutting each element of the table
+// into the new table (we know that no insertion here will Put an
+// already-present value), and discard the old table.
+func (t *table) resize(typ *abi.SwissMapType, m *Map, newCapacity uint16) {
+	done := false
+	defer m.abortWrite(&done)
+
+	seed := m.seed
+	if m.dirLen == 1 {
+		// t is the only table, so every entry of the map is about to
+		// be rehashed. Pick a new seed, so that keys an attacker has
+		// found to collide with the old seed don't keep extending a
+		// probe sequence as the table grows. With more tables, the
+		// seed also selects the table, and changing it would mean
+		// rehashing all of them.
+		//
+		// The seed is only installed with the new table, as t can't
+		// be used with it.
+		//
+		// Callers that computed a hash before rehashing must compute
+		// it again.
+		seed = uintptr(rand())
+	}
+
 	newTable := newTable(typ, uint64(newCapacity), t.index, t.localDepth)
 
 	if t.capacity > 0 {
-		for i := uint64(0); i <= t.groups.lengthMask; i++ {
-			g := t.groups.group(typ, i)
-			for j := uintptr(0); j < abi.SwissMapGroupSlots; j++ {
-				if (g.ctrls().get(j) & ctrlEmpty) == ctrlEmpty {
-					// Empty or deleted
-					continue
-				}
-
-				key := g.key(typ, j)
-				if typ.IndirectKey() {
-					key = *((*unsafe.Pointer)(key))
-				}
-
-				elem := g.elem(typ, j)
-				if typ.IndirectElem() {
-					elem = *((*unsafe.Pointer)(elem))
-				}
-
-				hash := typ.Hasher(key, m.seed)
-
-				newTable.uncheckedPutSlot(typ, hash, key, elem)
-			}
-		}
+		t.putAll(typ, seed, newTable)
 	}
+	done = true
+	m.seed = seed
 
 	newTable.checkInvariants(typ, m)
+	addStats(Stats{Entries: uint64(t.used), Tombstones: uint64(t.tombstones())})
 	m.replaceTable(newTable)
 	t.index = -1
 }
@@ -1133,12 +1512,22 @@ func (t *table) grow(typ *abi.SwissMapType, m *Map, newCapacity uint16) {
 // It turns out that this probe sequence visits every group exactly once if
 // the number of groups is a power of two, since (i^2+i)/2 is a bijection in
 // Z/(2^m). See https://en.wikipedia.org/wiki/Quadratic_probing
+//
+// Lookups don't prefetch the next group of the sequence while they check
+// the current one. A lookup that finds its key almost always does so in
+// the first group, as a false H2 match is rare, so the prefetch is wasted
+// work and memory bandwidth. A miss only moves on after checking the
+// control word, which leaves nothing to overlap with the load of the next
+// group.
 type probeSeq struct {
 	mask   uint64
 	offset uint64
 	index  uint64
 }
 
+// makeProbeSeq returns the probe sequence for H1 hash in a table whose
+// group count is mask+1. The first group is given by the low bits of
+// hash; see "Hash bits" in map.go.
 func makeProbeSeq(hash uintptr, mask uint64) probeSeq {
 	return probeSeq{
 		mask:   mask,
@@ -10,13 +10,49 @@ import (
 	"unsafe"
 )
 
+// debugLog enables checking the invariants of a table, and of the map's
+// directory, after each change to the table. A failed check prints the
+// directory, the counters and control bytes of every table, and the
+// probe sequence of any key that can't be found, then panics.
 const debugLog = false
 
+// debugCheck enables the checks of debugLog at run time, without
+// rebuilding. The runtime sets it from GODEBUG=swissmapcheck=1 at start-up;
+// see SetDebugCheck.
+var debugCheck bool
+
+// SetDebugCheck enables or disables checking the invariants of the
+// changed table, or of the small map, after each change to a map.
+func SetDebugCheck(enabled bool) {
+	debugCheck = enabled
+}
+
 func (t *table) checkInvariants(typ *abi.SwissMapType, m *Map) {
-	if !debugLog {
+	if !debugLog && !debugCheck {
 		return
 	}
+	// Out of line, so that checkInvariants is inlined into the callers.
+	t.verifyInMap(typ, m)
+}
 
+// verifyInMap checks the invariants of the table and of the directory of m.
+func (t *table) verifyInMap(typ *abi.SwissMapType, m *Map) {
+	t.verify(typ, m)
+	m.verify(typ)
+}
+
+// checkInvariants checks the invariants of the map's directory, or of its
+// group if it is small, as table.checkInvariants does.
+func (m *Map) checkInvariants(typ *abi.SwissMapType) {
+	if !debugLog && !debugCheck {
+		return
+	}
+	m.verify(typ)
+}
+
+// verify checks the invariants of the table, which need not be in the
+// directory of m yet.
+func (t *table) verify(typ *abi.SwissMapType, m *Map) {
 	// For every non-empty slot, verify we can retrieve the key using Get.
 	// Count the number of used and deleted slots.
 	var used uint16
@@ -47,79 +83,234 @@ func (t *table) checkInvariants(typ *abi.SwissMapType, m *Map) {
 
 				if _, ok := t.Get(typ, m, key); !ok {
 					hash := typ.Hasher(key, m.seed)
-					print("invariant failed: slot(", i, "/", j, "): key ")
+					print("invariant failed: table ", t, " slot(", i, "/", j, "): key ")
 					dump(key, typ.Key.Size_)
-					print(" not found [hash=", hash, ", h2=", h2(hash), " h1=", h1(hash), "]\n")
-					t.Print(typ, m)
-					panic("invariant failed: slot: key not found")
+					print(" not found [hash=", hash, ", h2=")
+					printCtrl(ctrl(h2(hash)))
+					print(" h1=", h1(hash), "]\n")
+					t.printProbeSeq(typ, hash)
+					m.invariantFailed(typ, t, "invariant failed: slot: key not found")
 				}
 			}
 		}
 	}
 
 	if used != t.used {
-		print("invariant failed: found ", used, " used slots, but used count is ", t.used, "\n")
-		t.Print(typ, m)
-		panic("invariant failed: found mismatched used slot count")
+		print("invariant failed: table ", t, ": found ", used, " used slots, but used count is ", t.used, "\n")
+		m.invariantFailed(typ, t, "invariant failed: found mismatched used slot count")
 	}
 
-	growthLeft := (t.capacity*maxAvgGroupLoad)/abi.SwissMapGroupSlots - t.used - deleted
+	growthLeft := t.maxGrowth() - t.used - deleted
 	if growthLeft != t.growthLeft {
-		print("invariant failed: found ", t.growthLeft, " growthLeft, but expected ", growthLeft, "\n")
-		t.Print(typ, m)
-		panic("invariant failed: found mismatched growthLeft")
+		print("invariant failed: table ", t, ": found ", t.growthLeft, " growthLeft, but expected ", growthLeft, "\n")
+		m.invariantFailed(typ, t, "invariant failed: found mismatched growthLeft")
 	}
 	if deleted != t.tombstones() {
-		print("invariant failed: found ", deleted, " tombstones, but expected ", t.tombstones(), "\n")
-		t.Print(typ, m)
-		panic("invariant failed: found mismatched tombstones")
+		print("invariant failed: table ", t, ": found ", deleted, " tombstones, but expected ", t.tombstones(), "\n")
+		m.invariantFailed(typ, t, "invariant failed: found mismatched tombstones")
 	}
 
 	if empty == 0 {
-		print("invariant failed: found no empty slots (violates probe invariant)\n")
+		print("invariant failed: table ", t, ": found no empty slots (violates probe invariant)\n")
+		m.invariantFailed(typ, t, "invariant failed: found no empty slots (violates probe invariant)")
+	}
+}
+
+// verify checks the invariants that span the tables of the map: each
+// table covers the aligned run of directory entries that its index and
+// local depth describe, and the used counts of the tables add up to the
+// map's. It does not check the tables themselves.
+func (m *Map) verify(typ *abi.SwissMapType) {
+	if m.dirLen <= 0 {
+		if m.dirPtr == nil {
+			return
+		}
+		g := groupReference{data: m.dirPtr}
+		var used uint64
+		for j := uintptr(0); j < abi.SwissMapGroupSlots; j++ {
+			c := g.ctrls().get(j)
+			if c == ctrlDeleted {
+				print("invariant failed: small map slot ", j, " is deleted\n")
+				m.invariantFailed(typ, nil, "invariant failed: found deleted slot in small map")
+			}
+			if c&ctrlEmpty != 0 {
+				continue
+			}
+			used++
+
+			key := g.key(typ, j)
+			if typ.IndirectKey() {
+				key = *((*unsafe.Pointer)(key))
+			}
+			if !typ.Key.Equal(key, key) {
+				continue // NaN hashes randomly
+			}
+			hash := typ.Hasher(key, m.seed)
+			if c != ctrl(h2(hash)) {
+				print("invariant failed: small map slot ", j, ": control byte ")
+				printCtrl(c)
+				print(" is not the H2 of key ")
+				dump(key, typ.Key.Size_)
+				print(" [hash=", hash, ", h2=")
+				printCtrl(ctrl(h2(hash)))
+				print("]\n")
+				m.invariantFailed(typ, nil, "invariant failed: small map slot: control byte does not match key")
+			}
+		}
+		if used != m.used {
+			print("invariant failed: small map has ", used, " full slots, but used count is ", m.used, "\n")
+			m.invariantFailed(typ, nil, "invariant failed: found mismatched small map used count")
+		}
+		return
+	}
+
+	if m.dirLen != 1<<m.globalDepth {
+		print("invariant failed: directory has ", m.dirLen, " entries at global depth ", m.globalDepth, "\n")
+		m.invariantFailed(typ, nil, "invariant failed: found mismatched directory length")
+	}
+	var groups groupsReference
+	if m.dirLen == 1 {
+		groups = m.directoryAt(0).groups
+	}
+	if m.groups != groups {
+		print("invariant failed: map caches groups ", m.groups.data, " of length mask ", m.groups.lengthMask, ", want ", groups.data, " of length mask ", groups.lengthMask, "\n")
+		m.invariantFailed(typ, nil, "invariant failed: found stale cached groups")
+	}
+
+	var used uint64
+	for i := 0; i < m.dirLen; {
+		t := m.directoryAt(uintptr(i))
+		if t.localDepth > m.globalDepth || t.index != i {
+			print("invariant failed: directory entry ", i, " starts table ", t, " of index ", t.index, " and local depth ", t.localDepth, "\n")
+			m.invariantFailed(typ, t, "invariant failed: found misplaced table")
+		}
+		entries := 1 << (m.globalDepth - t.localDepth)
+		for j := i; j < i+entries; j++ {
+			if m.directoryAt(uintptr(j)) != t {
+				print("invariant failed: directory entry ", j, " is not table ", t, ", which covers entries ", i, " to ", i+entries-1, "\n")
+				m.invariantFailed(typ, t, "invariant failed: found non-contiguous directory entries")
+			}
+		}
+		used += uint64(t.used)
+		i += entries
+	}
+	if used != m.used {
+		print("invariant failed: tables have ", used, " used slots, but map used count is ", m.used, "\n")
+		m.invariantFailed(typ, nil, "invariant failed: found mismatched map used count")
+	}
+}
+
+// invariantFailed prints the map and bad, the table that failed a check,
+// if any and not in the directory, then panics with msg.
+func (m *Map) invariantFailed(typ *abi.SwissMapType, bad *table, msg string) {
+	m.Print(typ)
+	if bad != nil && (bad.index < 0 || bad.index >= m.dirLen || m.directoryAt(uintptr(bad.index)) != bad) {
+		print("table not in directory:\n")
+		bad.Print(typ, m)
+	}
+	panic(msg)
+}
+
+// Print prints the map's counters, directory and tables. Each group is
+// printed as a line of control bytes, with the H2 of full slots in hex,
+// "--" for deleted slots and ".." for empty ones.
+func (m *Map) Print(typ *abi.SwissMapType) {
+	print(`map{
+	used: `, m.used, `
+	seed: `, m.seed, `
+	globalDepth: `, m.globalDepth, `
+	dirLen: `, m.dirLen, `
+`)
+	if m.dirLen <= 0 {
+		if m.dirPtr != nil {
+			print("\tsmall ")
+			printGroup(groupReference{data: m.dirPtr}, 0)
+		}
+		print("}\n")
+		return
+	}
+
+	print("\tdirectory:\n")
+	for i := 0; i < m.dirLen; {
+		t := m.directoryAt(uintptr(i))
+		j := i + 1
+		for j < m.dirLen && m.directoryAt(uintptr(j)) == t {
+			j++
+		}
+		print("\t\t", i, "-", j-1, ": table ", t, " index ", t.index, " localDepth ", t.localDepth, "\n")
+		i = j
+	}
+	var last *table
+	for i := 0; i < m.dirLen; i++ {
+		t := m.directoryAt(uintptr(i))
+		if t == last {
+			continue
+		}
+		last = t
 		t.Print(typ, m)
-		panic("invariant failed: found no empty slots (violates probe invariant)")
 	}
+	print("}\n")
 }
+
+// Print prints the table's counters, and each of its groups as a line of
+// control bytes; see Map.Print.
 func (t *table) Print(typ *abi.SwissMapType, m *Map) {
-	print(`table{
-	index: `, t.index, `
-	localDepth: `, t.localDepth, `
-	capacity: `, t.capacity, `
-	used: `, t.used, `
-	growthLeft: `, t.growthLeft, `
-	groups:
+	print("\ttable ", t, `{
+		index: `, t.index, `
+		localDepth: `, t.localDepth, `
+		capacity: `, t.capacity, `
+		used: `, t.used, `
+		growthLeft: `, t.growthLeft, `
+		tombstones: `, t.tombstones(), `
+		groups:
 `)
-
 	for i := uint64(0); i <= t.groups.lengthMask; i++ {
-		print("\t\tgroup ", i, "\n")
-
-		g := t.groups.group(typ, i)
-		ctrls := g.ctrls()
-		for j := uintptr(0); j < abi.SwissMapGroupSlots; j++ {
-			print("\t\t\tslot ", j, "\n")
-
-			c := ctrls.get(j)
-			print("\t\t\t\tctrl ", c)
-			switch c {
-			case ctrlEmpty:
-				print(" (empty)\n")
-			case ctrlDeleted:
-				print(" (deleted)\n")
-			default:
-				print("\n")
-			}
+		print("\t\t\t")
+		printGroup(t.groups.group(typ, i), i)
+	}
+	print("\t}\n")
+}
 
-			print("\t\t\t\tkey  ")
-			dump(g.key(typ, j), typ.Key.Size_)
-			println("")
-			print("\t\t\t\telem ")
-			dump(g.elem(typ, j), typ.Elem.Size_)
-			println("")
+// printProbeSeq prints the groups that a lookup of hash probes in t, up
+// to the first with an empty slot, which ends the lookup.
+func (t *table) printProbeSeq(typ *abi.SwissMapType, hash uintptr) {
+	print("\tprobe sequence in table ", t, ":\n")
+	seq := makeProbeSeq(h1(hash), t.groups.lengthMask)
+	for i := uint64(0); i <= t.groups.lengthMask; i, seq = i+1, seq.next() {
+		g := t.groups.group(typ, seq.offset)
+		print("\t\t")
+		printGroup(g, seq.offset)
+		if g.ctrls().matchEmpty() != 0 {
+			break
 		}
 	}
 }
 
+// printGroup prints the control bytes of group g, at index i of its table,
+// on a line.
+func printGroup(g groupReference, i uint64) {
+	print("group ", i, ":")
+	for j := uintptr(0); j < abi.SwissMapGroupSlots; j++ {
+		print(" ")
+		printCtrl(g.ctrls().get(j))
+	}
+	print("\n")
+}
+
+// printCtrl prints control byte c: the H2 of a full slot in hex, "--" for
+// a deleted slot and ".." for an empty one.
+func printCtrl(c ctrl) {
+	switch c {
+	case ctrlEmpty:
+		print("..")
+	case ctrlDeleted:
+		print("--")
+	default:
+		const hex = "0123456789abcdef"
+		print(hex[c>>4:c>>4+1], hex[c&0xf:c&0xf+1])
+	}
+}
+
 // TODO(prattmic): not in hex because print doesn't have a way to print in hex
 // outside the runtime.
 func dump(ptr unsafe.Pointer, size uintptr) {
@@ -12,6 +12,7 @@ const (
 	SYS_MPROTECT      = 226
 	SYS_EPOLL_PWAIT2  = 441
 	SYS_EVENTFD2      = 19
+	SYS_RISCV_HWPROBE = 258
 
 	EFD_NONBLOCK = 0x800
 )
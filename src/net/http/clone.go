@@ -21,12 +21,7 @@ import (
 //
 //go:linkname cloneURLValues
 func cloneURLValues(v url.Values) url.Values {
-	if v == nil {
-		return nil
-	}
-	// http.Header and url.Values have the same representation, so temporarily
-	// treat it like http.Header, which does have a clone:
-	return url.Values(Header(v).Clone())
+	return v.Clone()
 }
 
 // cloneURL should be an internal detail,
@@ -39,16 +34,7 @@ func cloneURLValues(v url.Values) url.Values {
 //
 //go:linkname cloneURL
 func cloneURL(u *url.URL) *url.URL {
-	if u == nil {
-		return nil
-	}
-	u2 := new(url.URL)
-	*u2 = *u
-	if u.User != nil {
-		u2.User = new(url.Userinfo)
-		*u2.User = *u.User
-	}
-	return u2
+	return u.Clone()
 }
 
 // cloneMultipartForm should be an internal detail,
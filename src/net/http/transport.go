@@ -2936,6 +2936,10 @@ func idnaASCIIFromURL(url *url.URL) string {
 	if v, err := idnaASCII(addr); err == nil {
 		addr = v
 	}
+	if zone := url.Zone(); zone != "" && !strings.Contains(addr, "%") {
+		// Dialing a link-local address needs its zone.
+		addr += "%" + zone
+	}
 	return addr
 }
 
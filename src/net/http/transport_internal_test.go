@@ -14,6 +14,7 @@ import (
 	"io"
 	"net"
 	"net/http/internal/testcert"
+	"net/url"
 	"strings"
 	"testing"
 )
@@ -267,3 +268,23 @@ func TestTransportBodyAltRewind(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestCanonicalAddrZone(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"http://[fe80::1%25en0]/", "[fe80::1%en0]:80"},
+		{"https://[fe80::1%25en0]:8443/", "[fe80::1%en0]:8443"},
+		{"http://[fe80::1]/", "[fe80::1]:80"},
+	}
+	for _, tt := range tests {
+		u, err := url.Parse(tt.in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := canonicalAddr(u); got != tt.want {
+			t.Errorf("canonicalAddr(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package url
+
+// This file converts between file URLs (RFC 8089) and file system paths.
+// Windows paths are mapped as described in
+// https://learn.microsoft.com/archive/blogs/ie/file-uris-in-windows:
+// a drive letter becomes the first segment of the path, and the host of
+// a UNC path becomes the authority.
+
+import (
+	"errors"
+	"runtime"
+	"strings"
+)
+
+var errNotAbsolute = errors.New("net/url: file path is not absolute")
+
+// FromFilePath returns a file URL for the absolute file system path,
+// as described by RFC 8089. On Unix systems, /path/to/file becomes
+// file:///path/to/file. On Windows, C:\path\to\file becomes
+// file:///C:/path/to/file, and the UNC path \\host\share\file becomes
+// file://host/share/file. Characters such as spaces, '%', '?' and '#'
+// in the path are escaped when the URL is formatted.
+//
+// FromFilePath returns an error if path is not absolute, including
+// Windows paths relative to the current directory of a drive, such as
+// C:file, and Windows device paths such as \\?\C:\file.
+func FromFilePath(path string) (*URL, error) {
+	return fromFilePath(path, runtime.GOOS)
+}
+
+// ToFilePath returns the absolute file system path named by the file
+// URL u. It is the inverse of [FromFilePath], and accepts an empty host
+// or "localhost" for the local machine. On Windows, another host names
+// the server of a UNC path; on other systems it is an error. The query
+// and fragment of u are ignored.
+//
+// ToFilePath returns an error if u is not a file URL, if its path is not
+// absolute, or if its path contains an encoded slash or a NUL byte.
+func (u *URL) ToFilePath() (string, error) {
+	return u.toFilePath(runtime.GOOS)
+}
+
+func fromFilePath(path, goos string) (*URL, error) {
+	if goos != "windows" {
+		if !strings.HasPrefix(path, "/") {
+			return nil, errNotAbsolute
+		}
+		return &URL{Scheme: "file", Path: path}, nil
+	}
+
+	if len(path) >= 4 && isWindowsSep(path[0]) && isWindowsSep(path[1]) &&
+		(path[2] == '?' || path[2] == '.') && isWindowsSep(path[3]) {
+		return nil, errors.New("net/url: cannot convert Windows device path to file URL")
+	}
+	vol := windowsVolumeName(path)
+	switch {
+	case vol == "":
+		return nil, errNotAbsolute
+	case len(vol) == 2:
+		// C:\path\to\file becomes file:///C:/path/to/file.
+		if len(path) == 2 || !isWindowsSep(path[2]) {
+			return nil, errNotAbsolute
+		}
+		return &URL{Scheme: "file", Path: "/" + windowsToSlash(path)}, nil
+	default:
+		// \\host\share\path\to\file becomes file://host/share/path/to/file.
+		path = windowsToSlash(path[2:])
+		i := strings.IndexByte(path, '/')
+		return &URL{Scheme: "file", Host: path[:i], Path: path[i:]}, nil
+	}
+}
+
+func (u *URL) toFilePath(goos string) (string, error) {
+	if !strings.EqualFold(u.Scheme, "file") {
+		return "", errors.New("net/url: non-file URL")
+	}
+	path := u.Path
+	if path == "" {
+		// RFC 8089, Section E.2 allows file:c:/path/to/file,
+		// which Parse stores in Opaque.
+		if u.Host != "" || u.Opaque == "" {
+			return "", errors.New("net/url: file URL missing path")
+		}
+		var err error
+		if path, err = PathUnescape(u.Opaque); err != nil {
+			return "", err
+		}
+	} else if strings.Contains(strings.ToLower(u.EscapedPath()), "%2f") {
+		return "", errors.New("net/url: file URL path contains encoded slash")
+	}
+	if strings.IndexByte(path, 0) >= 0 {
+		return "", errors.New("net/url: file URL path contains NUL byte")
+	}
+
+	if goos == "windows" {
+		return windowsFilePath(u.Host, path)
+	}
+	if u.Host != "" && !strings.EqualFold(u.Host, "localhost") {
+		return "", errors.New("net/url: file URL specifies non-local host")
+	}
+	if !strings.HasPrefix(path, "/") {
+		return "", errNotAbsolute
+	}
+	return path, nil
+}
+
+// windowsFilePath returns the Windows path for the host and path of a
+// file URL.
+func windowsFilePath(host, path string) (string, error) {
+	if host != "" && !strings.EqualFold(host, "localhost") {
+		// A common legacy format encodes the drive letter as the host:
+		// file://C:/path/to/file. It is not supported, but deserves a
+		// helpful error.
+		if len(windowsVolumeName(host)) == 2 {
+			return "", errors.New("net/url: file URL encodes volume in host field: too few slashes?")
+		}
+		if !strings.HasPrefix(path, "/") {
+			return "", errNotAbsolute
+		}
+		p := `\\` + host + windowsFromSlash(path)
+		if len(windowsVolumeName(p)) <= 2 {
+			return "", errNotAbsolute
+		}
+		return p, nil
+	}
+
+	// The path must be a drive letter and an absolute path on that drive,
+	// with a leading slash unless the URL is in the form file:c:/path.
+	p := windowsFromSlash(strings.TrimPrefix(path, "/"))
+	if len(windowsVolumeName(p)) != 2 {
+		return "", errors.New("net/url: file URL missing drive letter")
+	}
+	if len(p) == 2 || p[2] != '\\' {
+		return "", errNotAbsolute
+	}
+	return p, nil
+}
+
+// windowsVolumeName returns the volume name at the start of the Windows
+// path: a drive letter such as "C:" or a UNC prefix such as `\\host\share`.
+// It returns "" if path has no volume name.
+func windowsVolumeName(path string) string {
+	if len(path) >= 2 && path[1] == ':' && ('a' <= path[0] && path[0] <= 'z' || 'A' <= path[0] && path[0] <= 'Z') {
+		return path[:2]
+	}
+	if len(path) < 2 || !isWindowsSep(path[0]) || !isWindowsSep(path[1]) {
+		return ""
+	}
+	// UNC path: \\host\share.
+	i := strings.IndexAny(path[2:], `\/`) + 2
+	if i <= 2 {
+		return ""
+	}
+	j := strings.IndexAny(path[i+1:], `\/`) + i + 1
+	if j == i {
+		j = len(path)
+	}
+	if j == i+1 {
+		return ""
+	}
+	return path[:j]
+}
+
+func isWindowsSep(c byte) bool {
+	return c == '\\' || c == '/'
+}
+
+func windowsToSlash(path string) string {
+	return strings.ReplaceAll(path, `\`, "/")
+}
+
+func windowsFromSlash(path string) string {
+	return strings.ReplaceAll(path, "/", `\`)
+}
@@ -0,0 +1,154 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package url
+
+import (
+	"runtime"
+	"testing"
+)
+
+var filePathTests = []struct {
+	goos         string
+	url          string
+	filePath     string
+	canonicalURL string // if empty, assume equal to url
+	wantErr      string
+}{
+	// Examples from RFC 8089.
+	{goos: "linux", url: `file:///path/to/file`, filePath: `/path/to/file`},
+	{goos: "linux", url: `file:/path/to/file`, filePath: `/path/to/file`, canonicalURL: `file:///path/to/file`},
+	{goos: "linux", url: `file://localhost/path/to/file`, filePath: `/path/to/file`, canonicalURL: `file:///path/to/file`},
+	{goos: "linux", url: `file://LOCALHOST/path/to/file`, filePath: `/path/to/file`, canonicalURL: `file:///path/to/file`},
+	{goos: "linux", url: `file://host.example.com/path/to/file`, wantErr: "net/url: file URL specifies non-local host"},
+
+	// Escaping.
+	{goos: "linux", url: `file:///tmp/a%20b/c%25d%23e%3Ff`, filePath: `/tmp/a b/c%d#e?f`},
+	{goos: "linux", url: `file:///tmp/%E6%97%A5%E6%9C%AC`, filePath: `/tmp/日本`},
+	{goos: "linux", url: `file:///tmp/file?q=1#frag`, filePath: `/tmp/file`, canonicalURL: `file:///tmp/file`},
+	{goos: "linux", url: `file:///tmp/a%2Fb`, wantErr: "net/url: file URL path contains encoded slash"},
+	{goos: "linux", url: `file:///tmp/a%00b`, wantErr: "net/url: file URL path contains NUL byte"},
+
+	// Relative paths and other errors.
+	{goos: "linux", filePath: `relative/path`, wantErr: errNotAbsolute.Error()},
+	{goos: "linux", filePath: ``, wantErr: errNotAbsolute.Error()},
+	{goos: "linux", url: `file:relative/path`, wantErr: errNotAbsolute.Error()},
+	{goos: "linux", url: `file://localhost`, wantErr: "net/url: file URL missing path"},
+	{goos: "linux", url: `http://example.com/path`, wantErr: "net/url: non-file URL"},
+
+	// Examples from https://learn.microsoft.com/archive/blogs/ie/file-uris-in-windows.
+	{goos: "windows", url: `file://laptop/My%20Documents/FileSchemeURIs.doc`, filePath: `\\laptop\My Documents\FileSchemeURIs.doc`},
+	{goos: "windows", url: `file:///C:/Documents%20and%20Settings/davris/FileSchemeURIs.doc`, filePath: `C:\Documents and Settings\davris\FileSchemeURIs.doc`},
+	{goos: "windows", url: `file:///D:/Program%20Files/Viewer/startup.htm`, filePath: `D:\Program Files\Viewer\startup.htm`},
+	{goos: "windows", url: `file:///C:/Program%20Files/Music/Web%20Sys/main.html?REQUEST=RADIO`, filePath: `C:\Program Files\Music\Web Sys\main.html`, canonicalURL: `file:///C:/Program%20Files/Music/Web%20Sys/main.html`},
+	{goos: "windows", url: `file://applib/products/a-b/abc_9/4148.920a/media/start.swf`, filePath: `\\applib\products\a-b\abc_9\4148.920a\media\start.swf`},
+	{goos: "windows", url: `file:////applib/products/a%2Db/abc%5F9/4148.920a/media/start.swf`, wantErr: "net/url: file URL missing drive letter"},
+	{goos: "windows", url: `C:\Program Files\Music\Web Sys\main.html?REQUEST=RADIO`, wantErr: "net/url: non-file URL"},
+
+	// The example "file://D:\Program Files\Viewer\startup.htm" does not
+	// parse, so use a slash-based path instead.
+	{goos: "windows", url: `file://D:/Program Files/Viewer/startup.htm`, wantErr: "net/url: file URL encodes volume in host field: too few slashes?"},
+
+	// Non-ASCII characters are escaped in the canonical form.
+	{goos: "windows", url: `file:///C:/exampleㄓ.txt`, filePath: `C:\exampleㄓ.txt`, canonicalURL: `file:///C:/example%E3%84%93.txt`},
+	{goos: "windows", url: `file:///C:/example%E3%84%93.txt`, filePath: `C:\exampleㄓ.txt`},
+	{goos: "windows", url: `file:///C:/a%20b%23c.txt`, filePath: `C:\a b#c.txt`},
+
+	// RFC 8089, Section E.2: the drive letter form without slashes is
+	// accepted but not generated.
+	{goos: "windows", url: `file:c:/path/to/file`, filePath: `c:\path\to\file`, canonicalURL: `file:///c:/path/to/file`},
+	{goos: "windows", url: `file://localhost/c:/path/to/file`, filePath: `c:\path\to\file`, canonicalURL: `file:///c:/path/to/file`},
+
+	// RFC 8089, Section E.3.1: the UNC host is the authority.
+	{goos: "windows", url: `file://host.example.com/Share/path/to/file.txt`, filePath: `\\host.example.com\Share\path\to\file.txt`},
+	{goos: "windows", url: `file://host.example.com/Share`, filePath: `\\host.example.com\Share`},
+	{goos: "windows", url: `file://host.example.com/`, wantErr: errNotAbsolute.Error()},
+
+	// RFC 8089, Section E.3.2: the four- and five-slash forms are declined,
+	// as their paths would change meaning under path.Clean.
+	{goos: "windows", url: `file:////host.example.com/path/to/file`, wantErr: "net/url: file URL missing drive letter"},
+	{goos: "windows", url: `file://///host.example.com/path/to/file`, wantErr: "net/url: file URL missing drive letter"},
+
+	// Drive-relative, rooted and device paths are not absolute.
+	{goos: "windows", filePath: `C:file.txt`, wantErr: errNotAbsolute.Error()},
+	{goos: "windows", filePath: `C:`, wantErr: errNotAbsolute.Error()},
+	{goos: "windows", filePath: `\path\to\file`, wantErr: errNotAbsolute.Error()},
+	{goos: "windows", filePath: `\\host`, wantErr: errNotAbsolute.Error()},
+	{goos: "windows", filePath: `\\?\C:\file`, wantErr: "net/url: cannot convert Windows device path to file URL"},
+	{goos: "windows", filePath: `\\.\pipe\name`, wantErr: "net/url: cannot convert Windows device path to file URL"},
+	{goos: "windows", url: `file:///C:`, wantErr: errNotAbsolute.Error()},
+	{goos: "windows", url: `file:///C:file.txt`, wantErr: errNotAbsolute.Error()},
+	{goos: "windows", url: `file:///path/to/file`, wantErr: "net/url: file URL missing drive letter"},
+	{goos: "windows", url: `file:///C:/a%2Fb`, wantErr: "net/url: file URL path contains encoded slash"},
+}
+
+func TestToFilePath(t *testing.T) {
+	for _, tt := range filePathTests {
+		if tt.url == "" {
+			continue
+		}
+		u, err := Parse(tt.url)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.url, err)
+			continue
+		}
+		path, err := u.toFilePath(tt.goos)
+		if tt.wantErr != "" {
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("%s: ToFilePath(%q) = %q, %v, want error %q", tt.goos, tt.url, path, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || path != tt.filePath {
+			t.Errorf("%s: ToFilePath(%q) = %q, %v, want %q", tt.goos, tt.url, path, err, tt.filePath)
+		}
+	}
+}
+
+func TestFromFilePath(t *testing.T) {
+	for _, tt := range filePathTests {
+		if tt.filePath == "" && tt.url != "" {
+			continue
+		}
+		u, err := fromFilePath(tt.filePath, tt.goos)
+		if tt.url == "" {
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("%s: FromFilePath(%q) = %v, %v, want error %q", tt.goos, tt.filePath, u, err, tt.wantErr)
+			}
+			continue
+		}
+		want := tt.url
+		if tt.canonicalURL != "" {
+			want = tt.canonicalURL
+		}
+		if err != nil || u.String() != want {
+			t.Errorf("%s: FromFilePath(%q) = %v, %v, want %s", tt.goos, tt.filePath, u, err, want)
+			continue
+		}
+
+		// The canonical URL must convert back to the same path.
+		u, err = Parse(want)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if path, err := u.toFilePath(tt.goos); err != nil || path != tt.filePath {
+			t.Errorf("%s: ToFilePath(%q) = %q, %v, want %q", tt.goos, want, path, err, tt.filePath)
+		}
+	}
+}
+
+func TestFilePathRoundTrip(t *testing.T) {
+	path := "/tmp/a b/c#d"
+	if runtime.GOOS == "windows" {
+		path = `C:\a b\c#d`
+	}
+	u, err := FromFilePath(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := u.ToFilePath()
+	if err != nil || got != path {
+		t.Errorf("FromFilePath(%q).ToFilePath() = %q, %v", path, got, err)
+	}
+}
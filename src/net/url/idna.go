@@ -0,0 +1,471 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package url
+
+// This file implements the subset of IDNA (RFC 5890 and RFC 5891) and its
+// UTS #46 processing needed to convert host names between their Unicode
+// and ASCII forms. This package sits below golang.org/x/net/idna in the
+// dependency order, so the UTS #46 mapping and validity tables are not
+// available here. Instead, labels are mapped by folding fullwidth ASCII
+// to ASCII and lowercasing, and validated with the STD3 and hyphen rules
+// and with IDNA2008 validity and the Bidi rule of RFC 5893 approximated
+// from Unicode categories and scripts. Programs that need the exact
+// tables should use golang.org/x/net/idna.
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// HostASCII returns u.Host with its host name converted to the ASCII form
+// used by DNS and in HTTP requests, as described in RFC 5891, Section 5.
+// Labels are mapped and validated as for [URL.HostUnicode], and labels
+// with non-ASCII characters are then encoded with Punycode into labels
+// starting with "xn--". The port, if any, is kept, and IP literals are
+// returned unchanged.
+//
+// It returns an error if a label is empty, too long, not valid UTF-8,
+// starts with "xn--" but is not valid Punycode, or is not a valid IDNA
+// label.
+func (u *URL) HostASCII() (string, error) {
+	return u.convertHost(true)
+}
+
+// HostUnicode returns u.Host with its host name converted to the Unicode
+// form suitable for display. Fullwidth forms of ASCII characters are
+// mapped to ASCII, letters are lowercased, and labels starting with
+// "xn--" are decoded from Punycode. Each label must then consist of
+// lowercase letters, digits and hyphens, or of non-ASCII letters, marks
+// and digits, must not start or end with a hyphen, and must satisfy the
+// Bidi rule of RFC 5893. The port, if any, is kept, and IP literals are
+// returned unchanged.
+//
+// It returns an error if a label is empty, not valid UTF-8, starts with
+// "xn--" but is not valid Punycode, or is not a valid IDNA label.
+func (u *URL) HostUnicode() (string, error) {
+	return u.convertHost(false)
+}
+
+// convertHost maps and validates the labels of the host name in u.Host
+// and returns u.Host with them in their ASCII form if ascii is set, or in
+// their Unicode form otherwise.
+func (u *URL) convertHost(ascii bool) (string, error) {
+	if u.Host == "" || strings.HasPrefix(u.Host, "[") {
+		return u.Host, nil
+	}
+	host, _ := splitHostPort(u.Host)
+	colonPort := u.Host[len(host):]
+
+	host = labelSeparators.Replace(host)
+	root := strings.HasSuffix(host, ".")
+	if root {
+		host = host[:len(host)-1]
+	}
+
+	labels := strings.Split(host, ".")
+	for i, label := range labels {
+		if label == "" {
+			return "", idnaError(host, "empty label")
+		}
+		l, err := toUnicodeLabel(label)
+		if err != nil {
+			return "", idnaError(label, err.Error())
+		}
+		labels[i] = l
+	}
+	if label, ok := checkBidi(labels); !ok {
+		return "", idnaError(label, "violates the Bidi rule")
+	}
+
+	var b strings.Builder
+	for i, label := range labels {
+		if i > 0 {
+			b.WriteByte('.')
+		}
+		if ascii {
+			l, err := toASCIILabel(label)
+			if err != nil {
+				return "", idnaError(label, err.Error())
+			}
+			label = l
+		}
+		b.WriteString(label)
+	}
+	if root {
+		b.WriteByte('.')
+	}
+	b.WriteString(colonPort)
+	return b.String(), nil
+}
+
+// labelSeparators maps U+3002, U+FF0E and U+FF61, which RFC 3490
+// treats as label separators, to ".".
+var labelSeparators = strings.NewReplacer("。", ".", "．", ".", "｡", ".")
+
+func idnaError(label, msg string) error {
+	return errors.New("net/url: invalid host name label " + strconv.Quote(label) + ": " + msg)
+}
+
+const acePrefix = "xn--"
+
+// maxLabelLen is the maximum length of a DNS label in octets.
+const maxLabelLen = 63
+
+// toUnicodeLabel maps label, decodes it if it starts with "xn--", and
+// validates the result.
+func toUnicodeLabel(label string) (string, error) {
+	if !utf8.ValidString(label) {
+		return "", errors.New("invalid UTF-8")
+	}
+	label = mapLabel(label)
+	if strings.HasPrefix(label, acePrefix) {
+		dec, err := decodeACELabel(label)
+		if err != nil {
+			return "", err
+		}
+		label = dec
+	}
+	if err := validateLabel(label); err != nil {
+		return "", err
+	}
+	return label, nil
+}
+
+// toASCIILabel encodes the mapped and validated label with Punycode if
+// it is not ASCII.
+func toASCIILabel(label string) (string, error) {
+	if !isASCII(label) {
+		enc, err := punycodeEncode(label)
+		if err != nil {
+			return "", err
+		}
+		label = acePrefix + enc
+	}
+	if len(label) > maxLabelLen {
+		return "", errors.New("label too long")
+	}
+	return label, nil
+}
+
+// mapLabel applies the parts of the UTS #46 mapping that need no tables:
+// fullwidth forms of ASCII characters are mapped to ASCII, and letters
+// are lowercased.
+func mapLabel(label string) string {
+	label = strings.Map(func(r rune) rune {
+		if '！' <= r && r <= '～' {
+			return r - '！' + '!'
+		}
+		return r
+	}, label)
+	return strings.ToLower(label)
+}
+
+// validateLabel reports whether the mapped, Unicode form of a label is
+// valid. ASCII characters must be lowercase letters, digits or hyphens
+// (UseSTD3ASCIIRules in UTS #46). Other characters must be letters,
+// nonspacing or spacing marks, or decimal digits, the general categories
+// from which RFC 5892 derives the valid code points. A label must not
+// start or end with a hyphen, have hyphens in its third and fourth
+// positions (CheckHyphens in UTS #46), or start with a mark.
+func validateLabel(label string) error {
+	if strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-") {
+		return errors.New("leading or trailing hyphen")
+	}
+	if r := []rune(label); len(r) >= 4 && r[2] == '-' && r[3] == '-' {
+		return errors.New("hyphens in the third and fourth positions")
+	}
+	for i, r := range label {
+		switch {
+		case 'a' <= r && r <= 'z', '0' <= r && r <= '9', r == '-':
+		case r < utf8.RuneSelf:
+			return errors.New("disallowed character " + strconv.QuoteRune(r))
+		case unicode.In(r, unicode.Mn, unicode.Mc):
+			if i == 0 {
+				return errors.New("leading combining mark")
+			}
+		case !unicode.In(r, unicode.Ll, unicode.Lm, unicode.Lo, unicode.Nd):
+			return errors.New("disallowed character " + strconv.QuoteRune(r))
+		}
+	}
+	return nil
+}
+
+// A bidiClass is the bidirectional character type of a character in a
+// valid label, as far as RFC 5893 distinguishes them.
+type bidiClass uint8
+
+const (
+	bidiL   bidiClass = iota // left-to-right
+	bidiR                    // right-to-left (R and AL)
+	bidiEN                   // European number
+	bidiAN                   // Arabic number
+	bidiES                   // European separator, the hyphen
+	bidiNSM                  // nonspacing mark
+)
+
+// rtlScripts are the scripts whose letters are right-to-left.
+var rtlScripts = []*unicode.RangeTable{
+	unicode.Adlam,
+	unicode.Arabic,
+	unicode.Hanifi_Rohingya,
+	unicode.Hebrew,
+	unicode.Mandaic,
+	unicode.Mende_Kikakui,
+	unicode.Nko,
+	unicode.Samaritan,
+	unicode.Syriac,
+	unicode.Thaana,
+	unicode.Yezidi,
+}
+
+// bidiClassOf returns the bidirectional character type of r, which must
+// be valid in a label. It is derived from the category and script of r,
+// which give the Unicode bidirectional class for all such characters
+// except those of a few rare scripts.
+func bidiClassOf(r rune) bidiClass {
+	switch {
+	case r == '-':
+		return bidiES
+	case '0' <= r && r <= '9', '۰' <= r && r <= '۹':
+		return bidiEN
+	case unicode.Is(unicode.Mn, r):
+		return bidiNSM
+	case unicode.In(r, rtlScripts...):
+		if unicode.Is(unicode.Nd, r) && unicode.Is(unicode.Arabic, r) {
+			return bidiAN
+		}
+		return bidiR
+	}
+	return bidiL
+}
+
+// checkBidi checks the Bidi rule of RFC 5893, Section 2, for the labels
+// of a host name, and returns the first label that violates it. The rule
+// only applies if a label has right-to-left characters.
+func checkBidi(labels []string) (label string, ok bool) {
+	bidi := false
+	for _, label := range labels {
+		for _, r := range label {
+			if c := bidiClassOf(r); c == bidiR || c == bidiAN {
+				bidi = true
+			}
+		}
+	}
+	if !bidi {
+		return "", true
+	}
+	for _, label := range labels {
+		first, _ := utf8.DecodeRuneInString(label)
+		rtl := bidiClassOf(first) == bidiR
+		if !rtl && bidiClassOf(first) != bidiL {
+			return label, false // rule 1
+		}
+		var hasEN, hasAN bool
+		last := bidiNSM
+		for _, r := range label {
+			c := bidiClassOf(r)
+			switch {
+			case rtl && c == bidiL, !rtl && (c == bidiR || c == bidiAN):
+				return label, false // rules 2 and 5
+			}
+			hasEN = hasEN || c == bidiEN
+			hasAN = hasAN || c == bidiAN
+			if c != bidiNSM {
+				last = c
+			}
+		}
+		if rtl && (last == bidiES || hasEN && hasAN) {
+			return label, false // rules 3 and 4
+		}
+		if !rtl && last != bidiL && last != bidiEN {
+			return label, false // rule 6
+		}
+	}
+	return "", true
+}
+
+// decodeACELabel decodes a label starting with "xn--", ignoring case.
+// The label must be the canonical encoding of a non-ASCII label.
+func decodeACELabel(label string) (string, error) {
+	enc := strings.ToLower(label[len(acePrefix):])
+	dec, err := punycodeDecode(enc)
+	if err != nil {
+		return "", err
+	}
+	if isASCII(dec) {
+		return "", errPunycode
+	}
+	if reenc, err := punycodeEncode(dec); err != nil || reenc != enc {
+		return "", errPunycode
+	}
+	return dec, nil
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// Punycode parameters, from RFC 3492, Section 5.
+const (
+	punyBase        int32 = 36
+	punyTMin        int32 = 1
+	punyTMax        int32 = 26
+	punySkew        int32 = 38
+	punyDamp        int32 = 700
+	punyInitialBias int32 = 72
+	punyInitialN    int32 = 128
+)
+
+var errPunycode = errors.New("invalid Punycode")
+
+// punycodeDecode decodes s per RFC 3492, Section 6.2.
+func punycodeDecode(s string) (string, error) {
+	if s == "" {
+		return "", errPunycode
+	}
+	pos := 0
+	var output []rune
+	if i := strings.LastIndexByte(s, '-'); i >= 0 {
+		for _, c := range []byte(s[:i]) {
+			if c >= utf8.RuneSelf {
+				return "", errPunycode
+			}
+			output = append(output, rune(c))
+		}
+		pos = i + 1
+	}
+	i, n, bias := int32(0), punyInitialN, punyInitialBias
+	for pos < len(s) {
+		oldI, w := i, int32(1)
+		for k := punyBase; ; k += punyBase {
+			if pos == len(s) {
+				return "", errPunycode
+			}
+			digit, ok := decodePunyDigit(s[pos])
+			pos++
+			if !ok || digit > (utf8.MaxRune-i)/w {
+				return "", errPunycode
+			}
+			i += digit * w
+			t := min(max(k-bias, punyTMin), punyTMax)
+			if digit < t {
+				break
+			}
+			if w > utf8.MaxRune/(punyBase-t) {
+				return "", errPunycode
+			}
+			w *= punyBase - t
+		}
+		x := int32(len(output) + 1)
+		bias = punyAdapt(i-oldI, x, oldI == 0)
+		n += i / x
+		i %= x
+		if n < 0 || n > utf8.MaxRune || !utf8.ValidRune(n) {
+			return "", errPunycode
+		}
+		output = append(output, 0)
+		copy(output[i+1:], output[i:])
+		output[i] = n
+		i++
+	}
+	return string(output), nil
+}
+
+// punycodeEncode encodes s per RFC 3492, Section 6.3.
+func punycodeEncode(s string) (string, error) {
+	runes := []rune(s)
+	output := make([]byte, 0, len(s)+8)
+	for _, r := range runes {
+		if r < utf8.RuneSelf {
+			output = append(output, byte(r))
+		}
+	}
+	b := int32(len(output))
+	h := b
+	if b > 0 {
+		output = append(output, '-')
+	}
+	n, delta, bias := punyInitialN, int32(0), punyInitialBias
+	for h < int32(len(runes)) {
+		m := int32(utf8.MaxRune + 1)
+		for _, r := range runes {
+			if r >= n && r < m {
+				m = r
+			}
+		}
+		if m-n > (utf8.MaxRune-delta)/(h+1) {
+			return "", errPunycode
+		}
+		delta += (m - n) * (h + 1)
+		n = m
+		for _, r := range runes {
+			if r < n {
+				delta++
+			}
+			if r != n {
+				continue
+			}
+			q := delta
+			for k := punyBase; ; k += punyBase {
+				t := min(max(k-bias, punyTMin), punyTMax)
+				if q < t {
+					break
+				}
+				output = append(output, encodePunyDigit(t+(q-t)%(punyBase-t)))
+				q = (q - t) / (punyBase - t)
+			}
+			output = append(output, encodePunyDigit(q))
+			bias = punyAdapt(delta, h+1, h == b)
+			delta = 0
+			h++
+		}
+		delta++
+		n++
+	}
+	return string(output), nil
+}
+
+// punyAdapt is the bias adaptation function of RFC 3492, Section 6.1.
+func punyAdapt(delta, numPoints int32, firstTime bool) int32 {
+	if firstTime {
+		delta /= punyDamp
+	} else {
+		delta /= 2
+	}
+	delta += delta / numPoints
+	k := int32(0)
+	for delta > ((punyBase-punyTMin)*punyTMax)/2 {
+		delta /= punyBase - punyTMin
+		k += punyBase
+	}
+	return k + (punyBase-punyTMin+1)*delta/(delta+punySkew)
+}
+
+func decodePunyDigit(c byte) (int32, bool) {
+	switch {
+	case '0' <= c && c <= '9':
+		return int32(c - ('0' - 26)), true
+	case 'A' <= c && c <= 'Z':
+		return int32(c - 'A'), true
+	case 'a' <= c && c <= 'z':
+		return int32(c - 'a'), true
+	}
+	return 0, false
+}
+
+func encodePunyDigit(d int32) byte {
+	if d < 26 {
+		return byte(d) + 'a'
+	}
+	return byte(d-26) + '0'
+}
@@ -0,0 +1,119 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package url
+
+import (
+	"strings"
+	"testing"
+)
+
+// punycodeTests are from RFC 3492, Section 7.1, and common host names.
+var punycodeTests = []struct {
+	unicode string
+	ascii   string
+}{
+	{"ليهمابتكلموشعربي؟", "egbpdaj6bu4bxfgehfvwxn"},
+	{"3年B組金八先生", "3B-ww4c5e180e575a65lsy2b"},
+	{"Pročprostěnemluvíčesky", "Proprostnemluvesky-uyb24dma41a"},
+	{"bücher", "bcher-kva"},
+	{"例え", "r8jz45g"},
+	{"faß", "fa-hia"},
+	{"abc-", "abc--"},
+}
+
+func TestPunycode(t *testing.T) {
+	for _, tt := range punycodeTests {
+		got, err := punycodeEncode(tt.unicode)
+		if err != nil || got != tt.ascii {
+			t.Errorf("punycodeEncode(%q) = %q, %v, want %q", tt.unicode, got, err, tt.ascii)
+		}
+		got, err = punycodeDecode(tt.ascii)
+		if err != nil || got != tt.unicode {
+			t.Errorf("punycodeDecode(%q) = %q, %v, want %q", tt.ascii, got, err, tt.unicode)
+		}
+	}
+	for _, s := range []string{"", "99999999999", "bcher-kv@", "ü-kva", "zzzzzzzzzzzz"} {
+		if got, err := punycodeDecode(s); err == nil {
+			t.Errorf("punycodeDecode(%q) = %q, want error", s, got)
+		}
+	}
+}
+
+func TestHostASCIIUnicode(t *testing.T) {
+	tests := []struct {
+		host    string
+		ascii   string
+		unicode string
+	}{
+		{"bücher.example", "xn--bcher-kva.example", "bücher.example"},
+		{"BÜCHER.Example:8080", "xn--bcher-kva.example:8080", "bücher.example:8080"},
+		{"MÜnchen.DE", "xn--mnchen-3ya.de", "münchen.de"},
+		{"ＥＸＡＭＰＬＥ.com", "example.com", "example.com"},
+		{"ｇｏ．ｄｅｖ:80", "go.dev:80", "go.dev:80"},
+		{"faß.de", "xn--fa-hia.de", "faß.de"},
+		{"مثال.example", "xn--mgbh0fb.example", "مثال.example"},
+		{"שלום.example", "xn--9dbne9b.example", "שלום.example"},
+		{"例え.テスト", "xn--r8jz45g.xn--zckzah", "例え.テスト"},
+		{"例え。テスト", "xn--r8jz45g.xn--zckzah", "例え.テスト"},
+		{"xn--bcher-kva.example", "xn--bcher-kva.example", "bücher.example"},
+		{"XN--BCHER-KVA.example:443", "xn--bcher-kva.example:443", "bücher.example:443"},
+		{"example.com.", "example.com.", "example.com."},
+		{"golang.org:", "golang.org:", "golang.org:"},
+		{"192.0.2.1:80", "192.0.2.1:80", "192.0.2.1:80"},
+		{"[2001:db8::1]:8080", "[2001:db8::1]:8080", "[2001:db8::1]:8080"},
+		{"[fe80::1%en0]", "[fe80::1%en0]", "[fe80::1%en0]"},
+		{"", "", ""},
+	}
+	for _, tt := range tests {
+		u := &URL{Scheme: "https", Host: tt.host}
+		if got, err := u.HostASCII(); err != nil || got != tt.ascii {
+			t.Errorf("HostASCII() for Host %q = %q, %v, want %q", tt.host, got, err, tt.ascii)
+		}
+		if got, err := u.HostUnicode(); err != nil || got != tt.unicode {
+			t.Errorf("HostUnicode() for Host %q = %q, %v, want %q", tt.host, got, err, tt.unicode)
+		}
+	}
+
+	for _, host := range []string{
+		"a..b",
+		".example.com",
+		"xn--zzzzzzzzzzzz.example",
+		"xn--.example",
+		"xn--a-.example",
+		strings.Repeat("ü", 64) + ".example",
+		strings.Repeat("a", 64) + ".example",
+		"\xff.example",
+		"bad host!.com",
+		"a_b.example",
+		"-a.example",
+		"a-.example",
+		"ab--c.example",
+		"\u0301a.example",
+		"a\u200db.example",
+		"a\u00a0b.example",
+		"☃.example",
+		"שלוםabc.example",
+		"abcשלום.example",
+		"1שלום.example",
+		"مثال١2.example",
+	} {
+		u := &URL{Host: host}
+		if got, err := u.HostASCII(); err == nil {
+			t.Errorf("HostASCII() for Host %q = %q, want error", host, got)
+		}
+	}
+	for _, host := range []string{
+		"xn--zzzzzzzzzzzz.example",
+		"xn--.example:80",
+		"xn--a-.example",
+		"xn--abc-.example",
+		"xn--bcher-kva-.example",
+	} {
+		u := &URL{Host: host}
+		if got, err := u.HostUnicode(); err == nil {
+			t.Errorf("HostUnicode() for Host %q = %q, want error", host, got)
+		}
+	}
+}
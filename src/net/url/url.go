@@ -13,11 +13,14 @@ package url
 import (
 	"errors"
 	"fmt"
+	"internal/bytealg"
+	"internal/godebug"
 	"maps"
 	"path"
 	"slices"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 	_ "unsafe" // for linkname
 )
 
@@ -160,6 +163,16 @@ func shouldEscape(c byte, mode encoding) bool {
 		}
 	}
 
+	if mode == encodeUserPassword {
+		// §3.2.1 allows all sub-delims in userinfo. Leaving the rest of
+		// them unescaped too makes the encoded form accept exactly what
+		// Parse accepts, so that valid userinfo round-trips unchanged.
+		switch c {
+		case '!', '\'', '(', ')', '*':
+			return false
+		}
+	}
+
 	if mode == encodeFragment {
 		// RFC 3986 §2.2 allows not escaping sub-delims. A subset of sub-delims are
 		// included in reserved from RFC 2396 §2.2. The remaining sub-delims do not
@@ -278,6 +291,13 @@ func QueryEscape(s string) string {
 	return escape(s, encodeQueryComponent)
 }
 
+// queryEscapePercent is like QueryEscape but escapes spaces as %20.
+// A literal "+" is always escaped by QueryEscape, so any "+" in its
+// result stands for a space.
+func queryEscapePercent(s string) string {
+	return strings.ReplaceAll(QueryEscape(s), "+", "%20")
+}
+
 // PathEscape escapes the string so it can be safely placed inside a [URL] path segment,
 // replacing special characters (including /) with %XX sequences as needed.
 func PathEscape(s string) string {
@@ -426,9 +446,40 @@ func (u *Userinfo) Password() (string, bool) {
 	return u.password, u.passwordSet
 }
 
+// ParseUserinfo parses the encoded userinfo component of a URL, in the
+// form "username[:password]", as it appears before the "@" in the
+// authority. The username and password are unescaped as by [Parse].
+// It returns an error if encoded contains a character that must be
+// escaped in userinfo, or an invalid escape.
+func ParseUserinfo(encoded string) (*Userinfo, error) {
+	if !validUserinfo(encoded) {
+		return nil, errors.New("net/url: invalid userinfo")
+	}
+	username, password, hasPassword := strings.Cut(encoded, ":")
+	username, err := unescape(username, encodeUserPassword)
+	if err != nil {
+		return nil, err
+	}
+	if !hasPassword {
+		return User(username), nil
+	}
+	if password, err = unescape(password, encodeUserPassword); err != nil {
+		return nil, err
+	}
+	return UserPassword(username, password), nil
+}
+
 // String returns the encoded userinfo information in the standard form
-// of "username[:password]".
+// of "username[:password]". It is the same as [Userinfo.EncodedString].
 func (u *Userinfo) String() string {
+	return u.EncodedString()
+}
+
+// EncodedString returns the encoded userinfo information in the form
+// used in a URL, "username[:password]", escaping only the characters
+// that are not valid in the userinfo component. [ParseUserinfo] of the
+// result returns a Userinfo equal to u.
+func (u *Userinfo) EncodedString() string {
 	if u == nil {
 		return ""
 	}
@@ -439,6 +490,15 @@ func (u *Userinfo) String() string {
 	return s
 }
 
+// Clone returns a copy of u, or nil if u is nil.
+func (u *Userinfo) Clone() *Userinfo {
+	if u == nil {
+		return nil
+	}
+	u2 := *u
+	return &u2
+}
+
 // Maybe rawURL is of the form scheme:path.
 // (Scheme must be [a-zA-Z][a-zA-Z0-9+.-]*)
 // If so, return scheme, path; else return "", rawURL.
@@ -472,6 +532,11 @@ func getScheme(rawURL string) (scheme, path string, err error) {
 // (starting with a scheme). Trying to parse a hostname and path
 // without a scheme is invalid but may not necessarily return an
 // error, due to parsing ambiguities.
+//
+// Parse takes time and memory linear in len(rawURL), whatever its
+// contents, so the cost of parsing untrusted input can be bounded by
+// limiting its length beforehand. The same holds for [URL.Parse] and
+// [URL.ResolveReference] in the combined length of their inputs.
 func Parse(rawURL string) (*URL, error) {
 	// Cut off #frag
 	u, frag, _ := strings.Cut(rawURL, "#")
@@ -501,6 +566,126 @@ func ParseRequestURI(rawURL string) (*URL, error) {
 	return url, nil
 }
 
+// ParseStrict is like [Parse] but rejects URLs that Parse tolerates for
+// compatibility. In addition to the checks made by Parse, it requires
+// that rawURL is valid UTF-8, that no component contains an ASCII
+// control character or a space, that every % in any component starts
+// a valid percent-encoding, and that a host name consists only of the
+// characters allowed by RFC 3986, Section 3.2.2, or non-ASCII characters.
+// These errors are reported as a [*Error] wrapping a [*ComponentError].
+func ParseStrict(rawURL string) (*URL, error) {
+	if err := checkStrict(rawURL); err != nil {
+		return nil, &Error{"parse", rawURL, err}
+	}
+	return Parse(rawURL)
+}
+
+// A ComponentError reports an error in a component of a URL rejected
+// by [ParseStrict].
+type ComponentError struct {
+	Component string // "scheme", "userinfo", "host", "path", "opaque", "query" or "fragment"
+	Offset    int    // byte offset of the error in the URL
+	Err       error
+}
+
+func (e *ComponentError) Unwrap() error { return e.Err }
+func (e *ComponentError) Error() string {
+	return fmt.Sprintf("%s in %s at offset %d", e.Err, e.Component, e.Offset)
+}
+
+// checkStrict checks the components of rawURL for ParseStrict. It splits
+// rawURL the same way parse does, but leaves the errors that parse
+// reports itself to parse.
+func checkStrict(rawURL string) error {
+	type component struct {
+		name string
+		off  int
+		s    string
+	}
+	var comps []component
+
+	rest, frag, hasFrag := strings.Cut(rawURL, "#")
+	scheme, rest, err := getScheme(rest)
+	if err != nil {
+		return nil
+	}
+	off := len(scheme)
+	if scheme != "" {
+		comps = append(comps, component{"scheme", 0, scheme})
+		off++
+	}
+	rest, query, hasQuery := strings.Cut(rest, "?")
+	if hasQuery {
+		comps = append(comps, component{"query", off + len(rest) + 1, query})
+	}
+	if scheme != "" && !strings.HasPrefix(rest, "/") {
+		comps = append(comps, component{"opaque", off, rest})
+		rest = ""
+	} else if strings.HasPrefix(rest, "//") && (scheme != "" || !strings.HasPrefix(rest, "///")) {
+		authority := rest[2:]
+		off += 2
+		if i := strings.Index(authority, "/"); i >= 0 {
+			authority, rest = authority[:i], authority[i:]
+		} else {
+			rest = ""
+		}
+		if i := strings.LastIndex(authority, "@"); i >= 0 {
+			comps = append(comps, component{"userinfo", off, authority[:i]})
+			off += i + 1
+			authority = authority[i+1:]
+		}
+		comps = append(comps, component{"host", off, authority})
+		off += len(authority)
+	}
+	comps = append(comps, component{"path", off, rest})
+	if hasFrag {
+		comps = append(comps, component{"fragment", len(rawURL) - len(frag), frag})
+	}
+
+	for _, c := range comps {
+		if i, err := checkStrictComponent(c.s, c.name == "host"); err != nil {
+			return &ComponentError{Component: c.name, Offset: c.off + i, Err: err}
+		}
+	}
+	return nil
+}
+
+// checkStrictComponent checks one component of a URL for ParseStrict,
+// returning the offset of the error in s.
+func checkStrictComponent(s string, host bool) (int, error) {
+	literal := host && strings.HasPrefix(s, "[")
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c >= utf8.RuneSelf:
+			r, size := utf8.DecodeRuneInString(s[i:])
+			if r == utf8.RuneError && size == 1 {
+				return i, errors.New("invalid UTF-8")
+			}
+			i += size
+			continue
+		case c <= ' ' || c == 0x7f:
+			return i, errors.New("invalid character " + strconv.QuoteRune(rune(c)))
+		case c == '%':
+			if i+2 >= len(s) || !ishex(s[i+1]) || !ishex(s[i+2]) {
+				e := s[i:min(i+3, len(s))]
+				return i, EscapeError(e)
+			}
+			i += 3
+			continue
+		case host && !literal && !isUnreserved(c):
+			switch c {
+			case '!', '$', '&', '\'', '(', ')', '*', '+', ',', ';', '=', ':':
+				// sub-delims, and : before the port
+			default:
+				return i, InvalidHostError(s[i : i+1])
+			}
+		}
+		i++
+	}
+	return 0, nil
+}
+
 // parse parses a URL from a string in one of two contexts. If
 // viaRequest is true, the URL is assumed to have arrived via an HTTP request,
 // in which case only absolute URLs or path-absolute relative URLs are allowed.
@@ -598,24 +783,8 @@ func parseAuthority(authority string) (user *Userinfo, host string, err error) {
 	if i < 0 {
 		return nil, host, nil
 	}
-	userinfo := authority[:i]
-	if !validUserinfo(userinfo) {
-		return nil, "", errors.New("net/url: invalid userinfo")
-	}
-	if !strings.Contains(userinfo, ":") {
-		if userinfo, err = unescape(userinfo, encodeUserPassword); err != nil {
-			return nil, "", err
-		}
-		user = User(userinfo)
-	} else {
-		username, password, _ := strings.Cut(userinfo, ":")
-		if username, err = unescape(username, encodeUserPassword); err != nil {
-			return nil, "", err
-		}
-		if password, err = unescape(password, encodeUserPassword); err != nil {
-			return nil, "", err
-		}
-		user = UserPassword(username, password)
+	if user, err = ParseUserinfo(authority[:i]); err != nil {
+		return nil, "", err
 	}
 	return user, host, nil
 }
@@ -643,6 +812,9 @@ func parseHost(host string) (string, error) {
 		// like newlines.
 		zone := strings.Index(host[:i], "%25")
 		if zone >= 0 {
+			if zone+len("%25") == i {
+				return "", errors.New("empty IPv6 zone identifier in host")
+			}
 			host1, err := unescape(host[:zone], encodeHost)
 			if err != nil {
 				return "", err
@@ -686,7 +858,7 @@ func parseHost(host string) (string, error) {
 //   - github.com/sagernet/sing
 //
 // Do not remove or change the type signature.
-// See go.dev/issue/67401.
+// See go.dev/issue/67401. New code should use [URL.SetPathEscaped].
 //
 //go:linkname badSetPath net/url.(*URL).setPath
 func (u *URL) setPath(p string) error {
@@ -729,6 +901,76 @@ func (u *URL) EscapedPath() string {
 	return escape(u.Path, encodePath)
 }
 
+// SetPathEscaped sets u.Path to the unescaped form of the escaped path raw,
+// and u.RawPath to raw if it differs from the default encoding of u.Path,
+// in the same way as [Parse] sets them. Encoded slashes such as %2F are
+// thus preserved by [URL.EscapedPath] and [URL.String].
+// It returns an error if raw contains an ASCII control character or an
+// invalid escape, in which case u is unchanged.
+func (u *URL) SetPathEscaped(raw string) error {
+	if stringContainsCTLByte(raw) {
+		return errors.New("net/url: invalid control character in URL")
+	}
+	return u.setPath(raw)
+}
+
+// PathSegments returns the unescaped segments of u's path, split at
+// each slash that is not encoded, so that an encoded slash such as %2F
+// is returned as a "/" within its segment rather than splitting it.
+// A path starting with a slash has an empty first segment, and
+// consecutive slashes yield empty segments. PathSegments returns nil
+// if the path is empty.
+func (u *URL) PathSegments() ([]string, error) {
+	segs := u.EscapedPathSegments()
+	for i, seg := range segs {
+		s, err := PathUnescape(seg)
+		if err != nil {
+			return nil, err
+		}
+		segs[i] = s
+	}
+	return segs, nil
+}
+
+// SetPathSegments sets the path of u to the unescaped segments joined
+// by slashes, as returned by [URL.PathSegments]. Each segment is escaped
+// with [PathEscape], so a slash within a segment is encoded as %2F, and
+// u.Path and u.RawPath are set as [URL.SetPathEscaped] would set them.
+func (u *URL) SetPathSegments(segments []string) {
+	escaped := make([]string, len(segments))
+	for i, seg := range segments {
+		escaped[i] = PathEscape(seg)
+	}
+	// The escaped path is valid, so setPath cannot fail.
+	u.setPath(strings.Join(escaped, "/"))
+}
+
+// EscapedPathSegments returns the escaped form of u.Path split at each
+// slash that is not encoded, so that segments may contain %2F.
+// Joining the segments with "/" yields [URL.EscapedPath]; in particular,
+// a path starting with a slash has an empty first segment.
+// EscapedPathSegments returns nil if the path is empty.
+func (u *URL) EscapedPathSegments() []string {
+	p := u.EscapedPath()
+	if p == "" {
+		return nil
+	}
+	return strings.Split(p, "/")
+}
+
+// SetEscapedPathSegments sets the path of u from escaped segments, as
+// returned by [URL.EscapedPathSegments]. It returns an error if a segment
+// contains an unescaped slash, an ASCII control character or an invalid
+// escape, in which case u is unchanged.
+func (u *URL) SetEscapedPathSegments(segments []string) error {
+	for _, seg := range segments {
+		if strings.Contains(seg, "/") {
+			return errors.New("net/url: path segment " + strconv.Quote(seg) + " contains a slash")
+		}
+	}
+	return u.SetPathEscaped(strings.Join(segments, "/"))
+}
+
 // validEncoded reports whether s is a valid encoded path or fragment,
 // according to mode.
 // It must not contain any bytes that require escaping during encoding.
@@ -789,6 +1031,39 @@ func (u *URL) EscapedFragment() string {
 	return escape(u.Fragment, encodeFragment)
 }
 
+// FragmentQuery parses the query embedded in the fragment of u, as used
+// by the OAuth 2.0 implicit flow ("#access_token=...&state=...") and by
+// client-side routers ("#/callback?code=..."), and returns the
+// corresponding values. The query is the part of [URL.EscapedFragment]
+// after its first unescaped "?", or the whole fragment if it has none.
+// Errors are reported as by [ParseQuery], and the returned map contains
+// all the valid parameters found.
+func (u *URL) FragmentQuery() (Values, error) {
+	_, query := splitFragmentQuery(u.EscapedFragment())
+	return ParseQuery(query)
+}
+
+// SetFragmentQuery sets the query embedded in the fragment of u, as
+// returned by [URL.FragmentQuery], to v.Encode(), keeping any part of
+// the fragment before a "?". If v is empty, the query and the "?" are
+// removed. Both u.Fragment and u.RawFragment are updated.
+func (u *URL) SetFragmentQuery(v Values) {
+	prefix, _ := splitFragmentQuery(u.EscapedFragment())
+	if len(v) == 0 {
+		prefix = strings.TrimSuffix(prefix, "?")
+	}
+	// The encoded query is validly escaped, so setFragment cannot fail.
+	u.setFragment(prefix + v.Encode())
+}
+
+// splitFragmentQuery splits the escaped fragment frag after its first
+// "?" into the part up to and including it and the embedded query.
+// If frag has no "?", the whole fragment is the query.
+func splitFragmentQuery(frag string) (prefix, query string) {
+	i := strings.IndexByte(frag, '?') + 1
+	return frag[:i], frag[i:]
+}
+
 // validOptionalPort reports whether port is either an empty string
 // or matches /^:\d*$/
 func validOptionalPort(port string) bool {
@@ -910,6 +1185,88 @@ func (u *URL) Redacted() string {
 	return ru.String()
 }
 
+// RedactedParams is like [URL.Redacted] but also replaces with "xxxxx"
+// the values of the query parameters named by keys, such as "token" or
+// "signature". Keys are matched against the decoded parameter names
+// without regard to case. Parameters are redacted both in the query and
+// in a query embedded in the fragment, after its first "?" if it has
+// one, as used by OAuth and by client-side routers. The rest of the URL
+// is written exactly as by [URL.String].
+func (u *URL) RedactedParams(keys ...string) string {
+	if u == nil {
+		return ""
+	}
+
+	ru := *u
+	if _, has := ru.User.Password(); has {
+		ru.User = UserPassword(ru.User.Username(), "xxxxx")
+	}
+	ru.RawQuery = redactQuery(ru.RawQuery, keys)
+	if ru.Fragment != "" {
+		prefix, query := splitFragmentQuery(ru.EscapedFragment())
+		if q := redactQuery(query, keys); q != query {
+			// The redacted fragment is validly escaped because the
+			// escaped fragment is.
+			ru.setFragment(prefix + q)
+		}
+	}
+	return ru.String()
+}
+
+// redactQuery returns query with the values of the settings whose keys
+// match one of keys replaced by "xxxxx". Settings are separated by
+// ampersands or semicolons, and everything else in query is unchanged.
+func redactQuery(query string, keys []string) string {
+	var b strings.Builder
+	last := 0
+	for off := 0; off < len(query); {
+		pair := query[off:]
+		if i := strings.IndexAny(pair, "&;"); i >= 0 {
+			pair = pair[:i]
+		}
+		if key, value, ok := strings.Cut(pair, "="); ok && value != "" && matchKey(key, keys) {
+			valueOff := off + len(key) + 1
+			b.WriteString(query[last:valueOff])
+			b.WriteString("xxxxx")
+			last = valueOff + len(value)
+		}
+		off += len(pair) + 1
+	}
+	if last == 0 {
+		return query
+	}
+	b.WriteString(query[last:])
+	return b.String()
+}
+
+// Clone returns a deep copy of u that shares no memory with it,
+// including its [Userinfo]. Clone returns nil if u is nil.
+//
+// Copying a URL with an assignment such as u2 := *u copies only the
+// pointer to its Userinfo.
+func (u *URL) Clone() *URL {
+	if u == nil {
+		return nil
+	}
+	u2 := *u
+	u2.User = u.User.Clone()
+	return &u2
+}
+
+// matchKey reports whether the escaped query key matches one of keys,
+// ignoring case.
+func matchKey(key string, keys []string) bool {
+	if k, err := QueryUnescape(key); err == nil {
+		key = k
+	}
+	for _, k := range keys {
+		if strings.EqualFold(key, k) {
+			return true
+		}
+	}
+	return false
+}
+
 // Values maps a string key to a list of values.
 // It is typically used for query parameters and form values.
 // Unlike in the http.Header map, the keys in a Values map
@@ -951,11 +1308,38 @@ func (v Values) Has(key string) bool {
 	return ok
 }
 
+// Clone returns a copy of v, with the value slices copied too, so that
+// changes to either do not affect the other. Clone returns nil if v is nil.
+func (v Values) Clone() Values {
+	if v == nil {
+		return nil
+	}
+
+	// Find total number of values.
+	nv := 0
+	for _, vv := range v {
+		nv += len(vv)
+	}
+	sv := make([]string, nv) // shared backing array for values
+	v2 := make(Values, len(v))
+	for k, vv := range v {
+		if vv == nil {
+			// Preserve nil values.
+			v2[k] = nil
+			continue
+		}
+		n := copy(sv, vv)
+		v2[k] = sv[:n:n]
+		sv = sv[n:]
+	}
+	return v2
+}
+
 // ParseQuery parses the URL-encoded query string and returns
 // a map listing the values specified for each key.
 // ParseQuery always returns a non-nil map containing all the
-// valid query parameters found; err describes the first decoding error
-// encountered, if any.
+// valid query parameters found; err describes the invalid settings,
+// if any, as a [*QueryError] for each one joined with [errors.Join].
 //
 // Query is expected to be a list of key=value settings separated by ampersands.
 // A setting without an equals sign is interpreted as a key set to an empty
@@ -967,59 +1351,315 @@ func ParseQuery(query string) (Values, error) {
 	return m, err
 }
 
-func parseQuery(m Values, query string) (err error) {
-	for query != "" {
-		var key string
-		key, query, _ = strings.Cut(query, "&")
-		if strings.Contains(key, ";") {
-			err = fmt.Errorf("invalid semicolon separator in query")
-			continue
-		}
-		if key == "" {
-			continue
+func parseQuery(m Values, query string) error {
+	return rangeQuery(query, QueryOptions{}, func(_, key, value string) {
+		m[key] = append(m[key], value)
+	})
+}
+
+// QueryOptions controls how [ParseQueryWithOptions] parses and
+// [Values.EncodeWithOptions] encodes a query.
+type QueryOptions struct {
+	// Separators lists the characters that separate settings, such as
+	// "&;" to accept both ampersands and semicolons. If it is empty,
+	// settings are separated by "&" and settings containing a semicolon
+	// are invalid, as in ParseQuery. When encoding, the first separator
+	// is used. Separators must be ASCII characters: ParseQueryWithOptions
+	// returns an error and EncodeWithOptions panics otherwise.
+	Separators string
+
+	// RejectBareKeys makes a setting without an equals sign invalid
+	// rather than setting the key to an empty value.
+	RejectBareKeys bool
+
+	// StopOnError makes parsing stop at the first invalid setting rather
+	// than skipping it and continuing with the next one.
+	StopOnError bool
+
+	// PercentSpaces selects the generic URI syntax of RFC 3986, in which
+	// a space is escaped as %20 and "+" stands for itself, instead of the
+	// application/x-www-form-urlencoded syntax of the HTML specification
+	// used by ParseQuery and Encode, in which "+" stands for a space.
+	// OAuth 1.0 signature base strings (RFC 5849, Section 3.6) and AWS
+	// Signature Version 4 canonical queries require the RFC 3986 form.
+	// When encoding, plus signs are escaped as %2B in either form.
+	PercentSpaces bool
+}
+
+// ParseQueryWithOptions is like [ParseQuery] but parses the query as
+// described by opts. It always returns a non-nil map containing all
+// the valid query parameters found before parsing stopped; err
+// describes the invalid settings, if any, as for ParseQuery.
+func ParseQueryWithOptions(query string, opts QueryOptions) (Values, error) {
+	m := make(Values)
+	if !asciiSeparators(opts.Separators) {
+		return m, errNonASCIISeparator
+	}
+	err := rangeQuery(query, opts, func(_, key, value string) {
+		m[key] = append(m[key], value)
+	})
+	return m, err
+}
+
+var errNonASCIISeparator = errors.New("net/url: non-ASCII character in QueryOptions.Separators")
+
+// asciiSeparators reports whether seps, as in QueryOptions.Separators,
+// consists of ASCII characters only. Queries are split and joined a byte
+// at a time, so a multi-byte separator would be taken apart.
+func asciiSeparators(seps string) bool {
+	for i := 0; i < len(seps); i++ {
+		if seps[i] >= utf8.RuneSelf {
+			return false
 		}
-		key, value, _ := strings.Cut(key, "=")
-		key, err1 := QueryUnescape(key)
-		if err1 != nil {
-			if err == nil {
-				err = err1
-			}
-			continue
+	}
+	return true
+}
+
+// A QueryError records an invalid setting in a query and its position.
+type QueryError struct {
+	Pair   string // the setting as it appears in the query
+	Offset int    // byte offset of the setting in the query
+	Err    error
+}
+
+func (e *QueryError) Unwrap() error { return e.Err }
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("%s in query pair %q at offset %d", e.Err, e.Pair, e.Offset)
+}
+
+// rangeQuery calls f for each valid key=value setting of query, in order,
+// with the setting in its encoded form raw and the decoded key and value.
+// It returns a [*QueryError] for each invalid setting, joined with
+// [errors.Join].
+func rangeQuery(query string, opts QueryOptions, f func(raw, key, value string)) error {
+	seps := opts.Separators
+	if seps == "" {
+		seps = "&"
+	}
+	var errs []error
+	for off := 0; off < len(query); {
+		raw := query[off:]
+		if i := strings.IndexAny(raw, seps); i >= 0 {
+			raw = raw[:i]
 		}
-		value, err1 = QueryUnescape(value)
-		if err1 != nil {
-			if err == nil {
-				err = err1
+		if err := parseSetting(raw, seps, opts, f); err != nil {
+			errs = append(errs, &QueryError{Pair: raw, Offset: off, Err: err})
+			if opts.StopOnError {
+				break
 			}
-			continue
 		}
-		m[key] = append(m[key], value)
+		off += len(raw) + 1
 	}
-	return err
+	return errors.Join(errs...)
+}
+
+// parseSetting decodes a single setting of a query for rangeQuery.
+func parseSetting(raw, seps string, opts QueryOptions, f func(raw, key, value string)) error {
+	if !strings.Contains(seps, ";") && strings.Contains(raw, ";") {
+		return fmt.Errorf("invalid semicolon separator in query")
+	}
+	if raw == "" {
+		return nil
+	}
+	key, value, ok := strings.Cut(raw, "=")
+	if !ok && opts.RejectBareKeys {
+		return fmt.Errorf("missing value for key %q in query", key)
+	}
+	mode := encodeQueryComponent
+	if opts.PercentSpaces {
+		// Unescape as a path segment, which leaves "+" alone.
+		mode = encodePathSegment
+	}
+	key, err := unescape(key, mode)
+	if err != nil {
+		return err
+	}
+	value, err = unescape(value, mode)
+	if err != nil {
+		return err
+	}
+	f(raw, key, value)
+	return nil
 }
 
 // Encode encodes the values into “URL encoded” form
 // ("bar=baz&foo=quux") sorted by key.
 func (v Values) Encode() string {
+	return v.EncodeWithOptions(QueryOptions{})
+}
+
+// EncodeWithOptions is like [Values.Encode] but separates the settings
+// with the first of opts.Separators, such as ';' for "bar=baz;foo=quux",
+// and escapes spaces as %20 if opts.PercentSpaces is set.
+// The other options are ignored.
+func (v Values) EncodeWithOptions(opts QueryOptions) string {
+	if !asciiSeparators(opts.Separators) {
+		panic(errNonASCIISeparator)
+	}
 	if len(v) == 0 {
 		return ""
 	}
+	sep := byte('&')
+	if opts.Separators != "" {
+		sep = opts.Separators[0]
+	}
+	escape := QueryEscape
+	if opts.PercentSpaces {
+		escape = queryEscapePercent
+	}
 	var buf strings.Builder
 	for _, k := range slices.Sorted(maps.Keys(v)) {
 		vs := v[k]
-		keyEscaped := QueryEscape(k)
+		keyEscaped := escape(k)
 		for _, v := range vs {
 			if buf.Len() > 0 {
-				buf.WriteByte('&')
+				buf.WriteByte(sep)
 			}
 			buf.WriteString(keyEscaped)
 			buf.WriteByte('=')
-			buf.WriteString(QueryEscape(v))
+			buf.WriteString(escape(v))
+		}
+	}
+	return buf.String()
+}
+
+// A Param is a single key/value pair of an [OrderedValues] list.
+type Param struct {
+	Key   string
+	Value string
+
+	// raw is the encoded form of the pair as parsed, if any. It is
+	// only used by Encode if it still decodes to Key and Value.
+	raw string
+}
+
+// OrderedValues is a list of key/value pairs in the order they appear
+// in a query string or form. Unlike [Values], it preserves the order of
+// and between keys, so that a query string can be decoded and encoded
+// again without reordering its parameters.
+type OrderedValues []Param
+
+// ParseQueryOrdered is like [ParseQuery] but returns the parameters in
+// the order they appear in the query. It always returns all the valid
+// parameters found; err describes the invalid settings, if any, as for
+// ParseQuery.
+func ParseQueryOrdered(query string) (OrderedValues, error) {
+	var v OrderedValues
+	err := rangeQuery(query, QueryOptions{}, func(raw, key, value string) {
+		v = append(v, Param{Key: key, Value: value, raw: raw})
+	})
+	return v, err
+}
+
+// Get gets the value of the first pair with the given key.
+// If there is no such pair, Get returns the empty string.
+func (v OrderedValues) Get(key string) string {
+	if i := v.index(key); i >= 0 {
+		return v[i].Value
+	}
+	return ""
+}
+
+// Has checks whether a pair with the given key is present.
+func (v OrderedValues) Has(key string) bool {
+	return v.index(key) >= 0
+}
+
+// Set sets the value of the first pair with the given key, keeping its
+// position, or appends a new pair if there is none. Later pairs with the
+// same key are left unchanged.
+func (v *OrderedValues) Set(key, value string) {
+	if i := v.index(key); i >= 0 {
+		(*v)[i] = Param{Key: key, Value: value}
+		return
+	}
+	v.Add(key, value)
+}
+
+// Add appends a pair with the given key and value.
+func (v *OrderedValues) Add(key, value string) {
+	*v = append(*v, Param{Key: key, Value: value})
+}
+
+// Del deletes the first pair with the given key.
+func (v *OrderedValues) Del(key string) {
+	if i := v.index(key); i >= 0 {
+		*v = slices.Delete(*v, i, i+1)
+	}
+}
+
+func (v OrderedValues) index(key string) int {
+	for i := range v {
+		if v[i].Key == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// Encode encodes the pairs into “URL encoded” form ("foo=quux&bar=baz")
+// in order. Pairs that were parsed by [ParseQueryOrdered] and not changed
+// since keep their original encoding, so that encoding a parsed query
+// string reproduces it unless it had empty or invalid pairs.
+func (v OrderedValues) Encode() string {
+	var buf strings.Builder
+	for _, p := range v {
+		if buf.Len() > 0 {
+			buf.WriteByte('&')
+		}
+		if p.raw != "" && p.rawValid() {
+			buf.WriteString(p.raw)
+			continue
 		}
+		buf.WriteString(QueryEscape(p.Key))
+		buf.WriteByte('=')
+		buf.WriteString(QueryEscape(p.Value))
 	}
 	return buf.String()
 }
 
+// rawValid reports whether p.raw is a valid encoding of p.Key and p.Value.
+func (p Param) rawValid() bool {
+	for i := 0; i < len(p.raw); i++ {
+		switch c := p.raw[i]; c {
+		case '&', ';', '#':
+			return false
+		case '/', '?':
+			// ok - allowed in queries by RFC 3986, Section 3.4
+		default:
+			if !validEncoded(p.raw[i:i+1], encodeQueryComponent) {
+				return false
+			}
+		}
+	}
+	key, value, _ := strings.Cut(p.raw, "=")
+	key, err1 := QueryUnescape(key)
+	value, err2 := QueryUnescape(value)
+	return err1 == nil && err2 == nil && key == p.Key && value == p.Value
+}
+
+// Values returns the pairs as a [Values] map. The order of keys is lost;
+// the values of each key stay in order.
+func (v OrderedValues) Values() Values {
+	m := make(Values)
+	for _, p := range v {
+		m[p.Key] = append(m[p.Key], p.Value)
+	}
+	return m
+}
+
+// Ordered returns the values as an [OrderedValues] list, sorted by key
+// as [Values.Encode] would encode them.
+func (v Values) Ordered() OrderedValues {
+	var o OrderedValues
+	for _, k := range slices.Sorted(maps.Keys(v)) {
+		for _, value := range v[k] {
+			o = append(o, Param{Key: k, Value: value})
+		}
+	}
+	return o
+}
+
 // resolvePath applies special path segments from refs and applies
 // them to base, per RFC 3986.
 func resolvePath(base, ref string) string {
@@ -1036,14 +1676,12 @@ func resolvePath(base, ref string) string {
 		return ""
 	}
 
-	var (
-		elem string
-		dst  strings.Builder
-	)
+	var elem string
 	first := true
 	remaining := full
 	// We want to return a leading '/', so write it now.
-	dst.WriteByte('/')
+	dst := make([]byte, 1, len(full)+2)
+	dst[0] = '/'
 	found := true
 	for found {
 		elem, remaining, found = strings.Cut(remaining, "/")
@@ -1054,36 +1692,35 @@ func resolvePath(base, ref string) string {
 		}
 
 		if elem == ".." {
-			// Ignore the leading '/' we already wrote.
-			str := dst.String()[1:]
-			index := strings.LastIndexByte(str, '/')
-
-			dst.Reset()
-			dst.WriteByte('/')
+			// Drop the last segment, ignoring the leading '/' we already
+			// wrote. Truncating in place rather than copying what is left
+			// keeps this linear in len(full), as each byte is dropped at
+			// most once.
+			index := bytealg.LastIndexByte(dst[1:], '/')
 			if index == -1 {
+				dst = dst[:1]
 				first = true
 			} else {
-				dst.WriteString(str[:index])
+				dst = dst[:index+1]
 			}
 		} else {
 			if !first {
-				dst.WriteByte('/')
+				dst = append(dst, '/')
 			}
-			dst.WriteString(elem)
+			dst = append(dst, elem...)
 			first = false
 		}
 	}
 
 	if elem == "." || elem == ".." {
-		dst.WriteByte('/')
+		dst = append(dst, '/')
 	}
 
 	// We wrote an initial '/', but we don't want two.
-	r := dst.String()
-	if len(r) > 1 && r[1] == '/' {
-		r = r[1:]
+	if len(dst) > 1 && dst[1] == '/' {
+		dst = dst[1:]
 	}
-	return r
+	return string(dst)
 }
 
 // IsAbs reports whether the [URL] is absolute.
@@ -1110,6 +1747,35 @@ func (u *URL) Parse(ref string) (*URL, error) {
 // base or reference. If ref is an absolute URL, then ResolveReference
 // ignores base and returns a copy of ref.
 func (u *URL) ResolveReference(ref *URL) *URL {
+	return u.ResolveReferenceWithOptions(ref, ResolveOptions{})
+}
+
+// ResolveOptions controls how [URL.ResolveReferenceWithOptions] resolves
+// a reference.
+type ResolveOptions struct {
+	// NonStrict makes a reference with the same scheme as the base,
+	// ignoring case, resolve as if it had no scheme, for backward
+	// compatibility with parsers that predate RFC 3986, as described
+	// in its Section 5.2.2. For example, "http:g" resolves against
+	// "http://a/b/c/d;p?q" to "http://a/b/c/g" rather than "http:g".
+	NonStrict bool
+}
+
+// ResolveReferenceWithOptions is like [URL.ResolveReference] but
+// resolves ref as described by opts.
+func (u *URL) ResolveReferenceWithOptions(ref *URL, opts ResolveOptions) *URL {
+	if opts.NonStrict && ref.Scheme != "" && strings.EqualFold(ref.Scheme, u.Scheme) {
+		r := *ref
+		r.Scheme = ""
+		r.OmitHost = false
+		if r.Opaque != "" {
+			// Parse stores a rootless path that follows a scheme in Opaque.
+			r.setPath(r.Opaque)
+			r.Opaque = ""
+		}
+		ref = &r
+	}
+
 	url := *ref
 	if ref.Scheme == "" {
 		url.Scheme = u.Scheme
@@ -1134,20 +1800,74 @@ func (u *URL) ResolveReference(ref *URL) *URL {
 			url.RawFragment = u.RawFragment
 		}
 	}
-	if ref.Path == "" && u.Opaque != "" {
-		url.Opaque = u.Opaque
+	if u.Opaque != "" {
+		// The base has no authority and a rootless path, which Parse
+		// stores in Opaque. Merge the paths per RFC 3986, Section 5.2.3,
+		// keeping a rootless result opaque.
 		url.User = nil
 		url.Host = ""
 		url.Path = ""
+		url.RawPath = ""
+		var path string
+		switch {
+		case ref.Path == "":
+			url.Opaque = u.Opaque
+			return &url
+		case ref.Path[0] == '/':
+			path = removeDotSegments(ref.EscapedPath())
+		default:
+			i := strings.LastIndexByte(u.Opaque, '/')
+			path = removeDotSegments(u.Opaque[:i+1] + ref.EscapedPath())
+		}
+		if strings.HasPrefix(path, "/") {
+			url.OmitHost = true
+			url.setPath(path)
+		} else {
+			url.Opaque = path
+		}
 		return &url
 	}
 	// The "abs_path" or "rel_path" cases.
 	url.Host = u.Host
 	url.User = u.User
+	url.OmitHost = u.OmitHost
 	url.setPath(resolvePath(u.EscapedPath(), ref.EscapedPath()))
 	return &url
 }
 
+// removeDotSegments removes the "." and ".." segments from path
+// following the algorithm of RFC 3986, Section 5.2.4. Unlike
+// resolvePath, it keeps a relative path relative.
+func removeDotSegments(path string) string {
+	out := make([]byte, 0, len(path))
+	for path != "" {
+		switch {
+		case strings.HasPrefix(path, "../"):
+			path = path[3:]
+		case strings.HasPrefix(path, "./"), strings.HasPrefix(path, "/./"):
+			path = path[2:]
+		case path == "/.":
+			path = "/"
+		case strings.HasPrefix(path, "/../"):
+			path = path[3:]
+			out = out[:max(bytealg.LastIndexByte(out, '/'), 0)]
+		case path == "/..":
+			path = "/"
+			out = out[:max(bytealg.LastIndexByte(out, '/'), 0)]
+		case path == "." || path == "..":
+			path = ""
+		default:
+			i := strings.IndexByte(path[1:], '/') + 1
+			if i == 0 {
+				i = len(path)
+			}
+			out = append(out, path[:i]...)
+			path = path[i:]
+		}
+	}
+	return string(out)
+}
+
 // Query parses RawQuery and returns the corresponding values.
 // It silently discards malformed value pairs.
 // To check errors use [ParseQuery].
@@ -1176,15 +1896,46 @@ func (u *URL) RequestURI() string {
 	return result
 }
 
+// GODEBUG=urlhostnamezone=1 restores the pre-1.24 behavior of including
+// the IPv6 zone identifier in the result of URL.Hostname.
+var urlhostnamezone = godebug.New("urlhostnamezone")
+
 // Hostname returns u.Host, stripping any valid port number if present.
 //
 // If the result is enclosed in square brackets, as literal IPv6 addresses are,
-// the square brackets are removed from the result.
+// the square brackets are removed from the result, as is any RFC 6874 zone
+// identifier. Use [URL.Zone] to get the zone identifier.
 func (u *URL) Hostname() string {
 	host, _ := splitHostPort(u.Host)
+	if addr, zone := splitHostZone(u.Host, host); zone != "" {
+		if urlhostnamezone.Value() == "1" {
+			urlhostnamezone.IncNonDefault()
+			return host
+		}
+		return addr
+	}
 	return host
 }
 
+// Zone returns the zone identifier of a literal IPv6 address in u.Host,
+// as in "en0" for the host "[fe80::1%en0]:8080", in its unescaped form.
+// If there is no zone identifier, Zone returns an empty string.
+func (u *URL) Zone() string {
+	host, _ := splitHostPort(u.Host)
+	_, zone := splitHostZone(u.Host, host)
+	return zone
+}
+
+// splitHostZone separates the zone identifier from host, the result of
+// splitHostPort for hostPort. Only bracketed IPv6 literals have zones.
+func splitHostZone(hostPort, host string) (addr, zone string) {
+	if !strings.HasPrefix(hostPort, "[") {
+		return host, ""
+	}
+	addr, zone, _ = strings.Cut(host, "%")
+	return addr, zone
+}
+
 // Port returns the port part of u.Host, without the leading colon.
 //
 // If u.Host doesn't contain a valid numeric port, Port returns an empty string.
@@ -1193,6 +1944,70 @@ func (u *URL) Port() string {
 	return port
 }
 
+// PortErr is like [URL.Port] but reports whether u.Host is well formed.
+// It returns an empty port and a nil error if u.Host has no port or an
+// empty one, as in "example.com" or "example.com:". It returns a
+// [*HostPortError] if the port is not a decimal number between 0 and
+// 65535, if an IPv6 literal is not enclosed in balanced brackets, or if
+// the port cannot be told apart from the host, as in ":::8080".
+func (u *URL) PortErr() (string, error) {
+	host := u.Host
+	hostPortError := func(msg string) (string, error) {
+		return "", &HostPortError{Host: host, Msg: msg}
+	}
+	var colonPort string
+	if strings.HasPrefix(host, "[") {
+		i := strings.IndexByte(host, ']')
+		if i < 0 {
+			return hostPortError("missing ']' in IPv6 literal")
+		}
+		if strings.Contains(host[1:i], "[") || strings.ContainsAny(host[i+1:], "[]") {
+			return hostPortError("unbalanced brackets")
+		}
+		colonPort = host[i+1:]
+		if colonPort != "" && colonPort[0] != ':' {
+			return hostPortError("unexpected " + strconv.Quote(colonPort) + " after IPv6 literal")
+		}
+	} else {
+		if strings.ContainsAny(host, "[]") {
+			return hostPortError("unbalanced brackets")
+		}
+		switch strings.Count(host, ":") {
+		case 0:
+		case 1:
+			colonPort = host[strings.IndexByte(host, ':'):]
+		default:
+			return hostPortError("too many colons; IPv6 literals must be enclosed in brackets")
+		}
+	}
+	if len(colonPort) <= 1 {
+		return "", nil
+	}
+	port := colonPort[1:]
+	n := 0
+	for i := 0; i < len(port); i++ {
+		c := port[i]
+		if c < '0' || c > '9' {
+			return hostPortError("invalid port " + strconv.Quote(port))
+		}
+		if n = n*10 + int(c-'0'); n > 65535 {
+			return hostPortError("port " + port + " out of range")
+		}
+	}
+	return port, nil
+}
+
+// A HostPortError reports a malformed host:port in [URL.Host], as
+// returned by [URL.PortErr].
+type HostPortError struct {
+	Host string // the host:port, as in URL.Host
+	Msg  string // description of the problem
+}
+
+func (e *HostPortError) Error() string {
+	return "invalid host:port " + strconv.Quote(e.Host) + ": " + e.Msg
+}
+
 // splitHostPort separates host and port. If the port is not valid, it returns
 // the entire input as host, and it doesn't check the validity of the host.
 // Unlike net.SplitHostPort, but per RFC 3986, it requires ports to be numeric.
@@ -1234,6 +2049,7 @@ func (u *URL) UnmarshalBinary(text []byte) error {
 // JoinPath returns a new [URL] with the provided path elements joined to
 // any existing path and the resulting path cleaned of any ./ or ../ elements.
 // Any sequences of multiple / characters will be reduced to a single /.
+// Use [URL.JoinPathWithOptions] to join paths without cleaning them.
 func (u *URL) JoinPath(elem ...string) *URL {
 	elem = append([]string{u.EscapedPath()}, elem...)
 	var p string
@@ -1255,6 +2071,167 @@ func (u *URL) JoinPath(elem ...string) *URL {
 	return &url
 }
 
+// JoinPathOptions controls how [URL.JoinPathWithOptions] joins path elements.
+type JoinPathOptions struct {
+	// KeepDotSegments leaves "." and ".." segments in the joined path
+	// instead of resolving them.
+	KeepDotSegments bool
+}
+
+// JoinPathWithOptions is like [URL.JoinPath] but does not clean the joined
+// path: sequences of multiple / characters within the path and its elements
+// are kept, and a trailing slash is preserved. Consecutive elements are
+// separated by a single /, and empty elements are ignored. Like the path
+// of u, the elements are in escaped form, so %2F in an element is kept
+// in [URL.EscapedPath] rather than becoming a separator.
+//
+// Unless opts.KeepDotSegments is set, "." and ".." segments are resolved
+// as described in RFC 3986, Section 5.2.4. A relative path never gains
+// leading ".." segments.
+//
+// If u is opaque, JoinPathWithOptions returns a copy of u.
+func (u *URL) JoinPathWithOptions(opts JoinPathOptions, elem ...string) *URL {
+	url := *u
+	if u.Opaque != "" {
+		return &url
+	}
+	p := u.EscapedPath()
+	for _, e := range elem {
+		if e == "" {
+			continue
+		}
+		e = strings.TrimLeft(e, "/")
+		if p == "" {
+			p = e
+			continue
+		}
+		p = strings.TrimRight(p, "/") + "/" + e
+	}
+	if u.Host != "" && p != "" && p[0] != '/' {
+		// A path following an authority must be absolute.
+		p = "/" + p
+	}
+	if !opts.KeepDotSegments && p != "" {
+		if p[0] == '/' {
+			p = resolvePath(p, "")
+		} else {
+			p = resolvePath("/"+p, "")[1:]
+		}
+	}
+	url.setPath(p)
+	return &url
+}
+
+// NormalizeOptions controls the optional steps of [URL.NormalizeWithOptions].
+type NormalizeOptions struct {
+	// SchemeBased enables the scheme-based normalization of RFC 3986,
+	// Section 6.2.3, for the http, https, ws and wss schemes: the default
+	// port is removed from the host, and an empty path becomes "/".
+	SchemeBased bool
+}
+
+// Normalize returns a copy of u in normal form, for comparing or caching
+// URLs. It is equivalent to [URL.NormalizeWithOptions] with zero options.
+func (u *URL) Normalize() *URL {
+	return u.NormalizeWithOptions(NormalizeOptions{})
+}
+
+// NormalizeWithOptions returns a copy of u in normal form, applying the
+// syntax-based normalization of RFC 3986, Section 6.2.2:
+//   - The scheme and host are lowercased, except for an IPv6 zone identifier.
+//   - Percent-encoded unreserved characters in the path, query and fragment
+//     are decoded, and the hexadecimal digits of the remaining escapes
+//     are uppercased. Escapes of reserved characters such as %2F are kept,
+//     since decoding them could change the meaning of the URL.
+//   - "." and ".." segments are removed from the path if it is absolute.
+//   - An empty port after the host is removed.
+//
+// The userinfo and an opaque part are left unchanged, as are the order of
+// the query parameters and relative paths, which keep their meaning only
+// when resolved against a base URL.
+func (u *URL) NormalizeWithOptions(opts NormalizeOptions) *URL {
+	url := *u
+	if u.User != nil {
+		user := *u.User
+		url.User = &user
+	}
+	url.Scheme = strings.ToLower(u.Scheme)
+
+	host, port := splitHostPort(u.Host)
+	addr, zone := splitHostZone(u.Host, host)
+	addr = strings.ToLower(addr)
+	if zone != "" {
+		addr += "%" + zone
+	}
+	if strings.HasPrefix(u.Host, "[") {
+		addr = "[" + addr + "]"
+	}
+	if opts.SchemeBased && port == defaultPorts[url.Scheme] {
+		port = ""
+	}
+	if port != "" {
+		addr += ":" + port
+	}
+	url.Host = addr
+
+	if u.Opaque == "" {
+		p := normalizeEscapes(u.EscapedPath())
+		if strings.HasPrefix(p, "/") {
+			p = resolvePath(p, "")
+		}
+		if p == "" && url.Host != "" && opts.SchemeBased && defaultPorts[url.Scheme] != "" {
+			p = "/"
+		}
+		url.setPath(p)
+	}
+	url.RawQuery = normalizeEscapes(u.RawQuery)
+	url.setFragment(normalizeEscapes(u.EscapedFragment()))
+	return &url
+}
+
+// defaultPorts maps schemes to their default port for
+// scheme-based normalization.
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+	"ws":    "80",
+	"wss":   "443",
+}
+
+// normalizeEscapes decodes the escapes of unreserved characters in s and
+// uppercases the hexadecimal digits of the others. Invalid escapes are
+// left alone.
+func normalizeEscapes(s string) string {
+	if !strings.Contains(s, "%") {
+		return s
+	}
+	t := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' || i+2 >= len(s) || !ishex(s[i+1]) || !ishex(s[i+2]) {
+			t = append(t, s[i])
+			continue
+		}
+		c := unhex(s[i+1])<<4 | unhex(s[i+2])
+		if isUnreserved(c) {
+			t = append(t, c)
+		} else {
+			t = append(t, '%', upperhex[c>>4], upperhex[c&15])
+		}
+		i += 2
+	}
+	return string(t)
+}
+
+// isUnreserved reports whether c is an unreserved character per RFC 3986,
+// Section 2.3.
+func isUnreserved(c byte) bool {
+	switch {
+	case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		return true
+	}
+	return c == '-' || c == '.' || c == '_' || c == '~'
+}
+
 // validUserinfo reports whether s is a valid userinfo string per RFC 3986
 // Section 3.2.1:
 //
@@ -1265,22 +2242,16 @@ func (u *URL) JoinPath(elem ...string) *URL {
 //
 // It doesn't validate pct-encoded. The caller does that via func unescape.
 func validUserinfo(s string) bool {
-	for _, r := range s {
-		if 'A' <= r && r <= 'Z' {
-			continue
-		}
-		if 'a' <= r && r <= 'z' {
-			continue
-		}
-		if '0' <= r && r <= '9' {
-			continue
-		}
-		switch r {
-		case '-', '.', '_', ':', '~', '!', '$', '&', '\'',
-			'(', ')', '*', '+', ',', ';', '=', '%', '@':
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case ':', '%', '@':
+			// The separator, escapes, and for compatibility the '@'
+			// that Parse allows by splitting at the last one.
 			continue
 		default:
-			return false
+			if shouldEscape(c, encodeUserPassword) {
+				return false
+			}
 		}
 	}
 	return true
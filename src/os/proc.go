@@ -71,10 +71,11 @@ func Exit(code int) {
 	// enabled, this will give race detector a chance to fail the
 	// program (racy programs do not have the right to finish
 	// successfully). If coverage is enabled, then this call will
-	// enable us to write out a coverage data file.
-	runtime_beforeExit(code)
+	// enable us to write out a coverage data file. Exit hooks may
+	// change the exit code.
+	code = runtime_beforeExit(code)
 
 	syscall.Exit(code)
 }
 
-func runtime_beforeExit(exitCode int) // implemented in runtime
+func runtime_beforeExit(exitCode int) int // implemented in runtime
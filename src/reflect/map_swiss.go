@@ -73,7 +73,8 @@ func MapOf(key, elem Type) Type {
 	}
 	mt.GroupSize = mt.Group.Size()
 	mt.SlotSize = slot.Size()
-	mt.ElemOff = slot.Field(1).Offset
+	elemField, _ := slot.FieldByName("Elem")
+	mt.ElemOff = elemField.Offset
 	mt.Flags = 0
 	if needKeyUpdate(ktyp) {
 		mt.Flags |= abi.SwissMapNeedKeyUpdate
@@ -84,7 +85,7 @@ func MapOf(key, elem Type) Type {
 	if ktyp.Size_ > abi.SwissMapMaxKeyBytes {
 		mt.Flags |= abi.SwissMapIndirectKey
 	}
-	if etyp.Size_ > abi.SwissMapMaxKeyBytes {
+	if etyp.Size_ > abi.SwissMapMaxElemBytes {
 		mt.Flags |= abi.SwissMapIndirectElem
 	}
 	mt.PtrToThis = 0
@@ -95,7 +96,7 @@ func MapOf(key, elem Type) Type {
 
 func groupAndSlotOf(ktyp, etyp Type) (Type, Type) {
 	// type group struct {
-	//     ctrl uint64
+	//     ctrl  [abi.SwissMapCtrlWords]uint64
 	//     slots [abi.SwissMapGroupSlots]struct {
 	//         key  keyType
 	//         elem elemType
@@ -109,6 +110,9 @@ func groupAndSlotOf(ktyp, etyp Type) (Type, Type) {
 		etyp = PointerTo(etyp)
 	}
 
+	// As in cmd/compile/internal/reflectdata.SwissMapGroupType, a
+	// zero-size elem goes before the key, and zero-size slots before
+	// the control word, so that they need no padding.
 	fields := []StructField{
 		{
 			Name: "Key",
@@ -119,18 +123,24 @@ func groupAndSlotOf(ktyp, etyp Type) (Type, Type) {
 			Type: etyp,
 		},
 	}
+	if etyp.Size() == 0 {
+		fields[0], fields[1] = fields[1], fields[0]
+	}
 	slot := StructOf(fields)
 
 	fields = []StructField{
 		{
 			Name: "Ctrl",
-			Type: TypeFor[uint64](),
+			Type: ArrayOf(abi.SwissMapCtrlWords, TypeFor[uint64]()),
 		},
 		{
 			Name: "Slots",
 			Type: ArrayOf(abi.SwissMapGroupSlots, slot),
 		},
 	}
+	if slot.Size() == 0 {
+		fields[0], fields[1] = fields[1], fields[0]
+	}
 	group := StructOf(fields)
 	return group, slot
 }
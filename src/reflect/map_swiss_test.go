@@ -7,8 +7,10 @@
 package reflect_test
 
 import (
+	"internal/abi"
 	"reflect"
 	"testing"
+	"unsafe"
 )
 
 func testGCBitsMap(t *testing.T) {
@@ -19,12 +21,46 @@ func testGCBitsMap(t *testing.T) {
 // See also runtime_test.TestGroupSizeZero.
 func TestGroupSizeZero(t *testing.T) {
 	st := reflect.TypeFor[struct{}]()
-	grp := reflect.MapGroupOf(st, st)
+	it := reflect.TypeFor[int]()
+	ctrlSize := uintptr(8 * abi.SwissMapCtrlWords)
 
-	// internal/runtime/maps when create pointers to slots, even if slots
-	// are size 0. We should have reserved an extra word to ensure that
-	// pointers to the zero-size type at the end of group are valid.
-	if grp.Size() <= 8 {
-		t.Errorf("Group size got %d want >8", grp.Size())
+	// Zero-size elems and slots take no space, and need no padding to
+	// keep pointers to them within the group. The groups must match
+	// the ones the compiler makes.
+	for _, tc := range []struct {
+		k, e reflect.Type
+		m    any
+	}{
+		{st, st, map[struct{}]struct{}(nil)},
+		{it, st, map[int]struct{}(nil)},
+		{st, it, map[struct{}]int(nil)},
+	} {
+		grp := reflect.MapGroupOf(tc.k, tc.e)
+		want := ctrlSize + abi.SwissMapGroupSlots*tc.k.Size() + abi.SwissMapGroupSlots*tc.e.Size()
+		if grp.Size() != want {
+			t.Errorf("MapGroupOf(%v, %v) size got %d want %d", tc.k, tc.e, grp.Size(), want)
+		}
+		cmt := (*abi.SwissMapType)(unsafe.Pointer(abi.TypeOf(tc.m)))
+		if cmt.Group.Size() != grp.Size() {
+			t.Errorf("MapGroupOf(%v, %v) size got %d, compiler's %d", tc.k, tc.e, grp.Size(), cmt.Group.Size())
+		}
+
+		// A map type made by MapOf must work like the compiler's.
+		mt := reflect.MapOf(tc.k, tc.e)
+		m := reflect.MakeMap(mt)
+		k, e := reflect.New(tc.k).Elem(), reflect.New(tc.e).Elem()
+		m.SetMapIndex(k, e)
+		if m.Len() != 1 || !m.MapIndex(k).IsValid() {
+			t.Errorf("%v: lost entry after SetMapIndex", mt)
+		}
+		for iter := m.MapRange(); iter.Next(); {
+			if !iter.Key().Equal(k) || !iter.Value().Equal(e) {
+				t.Errorf("%v: iteration got %v: %v", mt, iter.Key(), iter.Value())
+			}
+		}
+		m.SetMapIndex(k, reflect.Value{})
+		if m.Len() != 0 {
+			t.Errorf("%v: Len() after delete got %d want 0", mt, m.Len())
+		}
 	}
 }
@@ -0,0 +1,50 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package runtime
+
+import (
+	"internal/runtime/atomic"
+	_ "unsafe" // for go:linkname
+)
+
+// amxState records the outcome of asking the OS for AMX tile data
+// permission. It is one of the amx* constants below.
+var amxState atomic.Uint32
+
+const (
+	amxUnknown = iota
+	amxRequesting
+	amxAllowed
+	amxDenied
+)
+
+// internal_cpu_requestAMX asks the OS, at most once per process, for
+// permission to use the AMX tile data state, and reports the result.
+// Concurrent first calls wait for the one that asks.
+//
+//go:linkname internal_cpu_requestAMX internal/cpu.requestAMX
+func internal_cpu_requestAMX() bool {
+	for {
+		switch amxState.Load() {
+		case amxAllowed:
+			return true
+		case amxDenied:
+			return false
+		case amxUnknown:
+			if !amxState.CompareAndSwap(amxUnknown, amxRequesting) {
+				continue
+			}
+			if osRequestAMX() {
+				amxState.Store(amxAllowed)
+				return true
+			}
+			amxState.Store(amxDenied)
+			return false
+		}
+		// Another call is asking the OS. It only makes a system call,
+		// so yield until it is done.
+		osyield()
+	}
+}
@@ -0,0 +1,21 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package runtime
+
+import "internal/runtime/syscall"
+
+const (
+	_SYS_arch_prctl      = 158
+	_ARCH_REQ_XCOMP_PERM = 0x1023
+	_XFEATURE_XTILEDATA  = 18
+)
+
+// osRequestAMX asks Linux to enable the tile data state for this
+// process. Linux keeps the state disabled through XFD until asked,
+// and a tile instruction would otherwise raise SIGILL.
+func osRequestAMX() bool {
+	_, _, errno := syscall.Syscall6(_SYS_arch_prctl, _ARCH_REQ_XCOMP_PERM, _XFEATURE_XTILEDATA, 0, 0, 0, 0)
+	return errno == 0
+}
@@ -0,0 +1,14 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !linux
+
+package runtime
+
+// osRequestAMX reports whether the tile data state may be used.
+// Other operating systems do not require a request: if they enabled
+// the state in XCR0, which internal/cpu checks, it is usable.
+func osRequestAMX() bool {
+	return true
+}
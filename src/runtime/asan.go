@@ -52,9 +52,11 @@ func doasanread(addr unsafe.Pointer, sz, sp, pc uintptr)
 //go:noescape
 func doasanwrite(addr unsafe.Pointer, sz, sp, pc uintptr)
 
+//go:linkname asanunpoison
 //go:noescape
 func asanunpoison(addr unsafe.Pointer, sz uintptr)
 
+//go:linkname asanpoison
 //go:noescape
 func asanpoison(addr unsafe.Pointer, sz uintptr)
 
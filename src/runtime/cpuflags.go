@@ -38,3 +38,20 @@ var (
 	loong64HasLAM_BH bool
 	loong64HasLSX    bool
 )
+
+// printCPUFeatures prints the line of CPU features that
+// internal/cpu.Summary would return, without allocating.
+func printCPUFeatures() {
+	print("cpu features:")
+	for _, f := range cpu.Features() {
+		if !f.Enabled && !f.ForcedOff {
+			continue
+		}
+		print(" ")
+		if f.ForcedOff {
+			print("-")
+		}
+		print(f.Name)
+	}
+	print("\n")
+}
@@ -10,6 +10,7 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"internal/goexperiment"
 	"internal/testenv"
 	traceparse "internal/trace"
 	"io"
@@ -20,6 +21,7 @@ import (
 	"regexp"
 	"runtime"
 	"runtime/trace"
+	"slices"
 	"strings"
 	"sync"
 	"testing"
@@ -69,9 +71,11 @@ var testprog struct {
 }
 
 type buildexe struct {
-	once sync.Once
-	exe  string
-	err  error
+	done    chan struct{} // closed when the build is finished
+	cmd     string        // the build command, for logging
+	elapsed time.Duration // how long the build took
+	exe     string
+	err     error
 }
 
 func runTestProg(t *testing.T, binary, name string, env ...string) string {
@@ -119,15 +123,48 @@ func runBuiltTestProg(t *testing.T, exe, name string, env ...string) string {
 	return string(out)
 }
 
-var serializeBuild = make(chan bool, 2)
+// serializeBuild limits the number of concurrent "go build"s,
+// to keep load from getting too high.
+var serializeBuild = make(chan bool, max(2, runtime.NumCPU()/4))
 
+// buildTestProg builds the program in testdata/binary with the given
+// build flags, and returns the path of the executable. Executables are
+// shared by all the tests in the process.
 func buildTestProg(t *testing.T, binary string, flags ...string) (string, error) {
+	t.Helper()
+	target, err := startBuildTestProg(t, binary, flags...)
+	if err != nil {
+		return "", err
+	}
+	<-target.done
+	if target.err == nil {
+		t.Logf("%v: built in %v", target.cmd, target.elapsed)
+	}
+	return target.exe, target.err
+}
+
+// startBuildTestProg starts building the program in testdata/binary with
+// the given build flags in the background, unless it is already built or
+// being built, and returns the target. A test that needs several
+// programs can start all their builds before waiting for the first with
+// buildTestProg, so that they are built concurrently.
+func startBuildTestProg(t *testing.T, binary string, flags ...string) (*buildexe, error) {
 	if *flagQuick {
 		t.Skip("-quick")
 	}
 	testenv.MustHaveGoBuild(t)
+	gotool := testenv.GoToolPath(t)
+
+	// Callers pass an empty flag for the default build mode.
+	flags = slices.DeleteFunc(slices.Clone(flags), func(f string) bool { return f == "" })
+	srcDir := filepath.Join("testdata", binary)
+	key := binary
+	if len(flags) > 0 {
+		key += "_" + strings.Join(flags, "_")
+	}
 
 	testprog.Lock()
+	defer testprog.Unlock()
 	if testprog.dir == "" {
 		dir, err := os.MkdirTemp("", "go-build")
 		if err != nil {
@@ -136,68 +173,49 @@ func buildTestProg(t *testing.T, binary string, flags ...string) (string, error)
 		testprog.dir = dir
 		toRemove = append(toRemove, dir)
 	}
-
 	if testprog.target == nil {
 		testprog.target = make(map[string]*buildexe)
 	}
-	name := binary
-	if len(flags) > 0 {
-		name += "_" + strings.Join(flags, "_")
-	}
-	target, ok := testprog.target[name]
-	if !ok {
-		target = &buildexe{}
-		testprog.target[name] = target
+	if target, ok := testprog.target[key]; ok {
+		return target, nil
 	}
 
-	dir := testprog.dir
+	exe := filepath.Join(testprog.dir, key+".exe")
+	cmd := exec.Command(gotool, append([]string{"build", "-o", exe}, flags...)...)
+	cmd.Dir = srcDir
+	cmd = testenv.CleanCmdEnv(cmd)
 
-	// Unlock testprog while actually building, so that other
-	// tests can look up executables that were already built.
-	testprog.Unlock()
+	// Add the rangefunc GOEXPERIMENT unconditionally since some tests depend on it.
+	// TODO(61405): Remove this once it's enabled by default.
+	edited := false
+	for i := range cmd.Env {
+		e := cmd.Env[i]
+		if _, vars, ok := strings.Cut(e, "GOEXPERIMENT="); ok {
+			cmd.Env[i] = "GOEXPERIMENT=" + vars + ",rangefunc"
+			edited = true
+		}
+	}
+	if !edited {
+		cmd.Env = append(cmd.Env, "GOEXPERIMENT=rangefunc")
+	}
 
-	target.once.Do(func() {
-		// Only do two "go build"'s at a time,
-		// to keep load from getting too high.
+	target := &buildexe{done: make(chan struct{}), cmd: cmd.String()}
+	testprog.target[key] = target
+	go func() {
+		defer close(target.done)
 		serializeBuild <- true
 		defer func() { <-serializeBuild }()
 
-		// Don't get confused if testenv.GoToolPath calls t.Skip.
-		target.err = errors.New("building test called t.Skip")
-
-		exe := filepath.Join(dir, name+".exe")
-
 		start := time.Now()
-		cmd := exec.Command(testenv.GoToolPath(t), append([]string{"build", "-o", exe}, flags...)...)
-		t.Logf("running %v", cmd)
-		cmd.Dir = "testdata/" + binary
-		cmd = testenv.CleanCmdEnv(cmd)
-
-		// Add the rangefunc GOEXPERIMENT unconditionally since some tests depend on it.
-		// TODO(61405): Remove this once it's enabled by default.
-		edited := false
-		for i := range cmd.Env {
-			e := cmd.Env[i]
-			if _, vars, ok := strings.Cut(e, "GOEXPERIMENT="); ok {
-				cmd.Env[i] = "GOEXPERIMENT=" + vars + ",rangefunc"
-				edited = true
-			}
-		}
-		if !edited {
-			cmd.Env = append(cmd.Env, "GOEXPERIMENT=rangefunc")
-		}
-
 		out, err := cmd.CombinedOutput()
+		target.elapsed = time.Since(start)
 		if err != nil {
 			target.err = fmt.Errorf("building %s %v: %v\n%s", binary, flags, err, out)
 		} else {
-			t.Logf("built %v in %v", name, time.Since(start))
 			target.exe = exe
-			target.err = nil
 		}
-	})
-
-	return target.exe, target.err
+	}()
+	return target, nil
 }
 
 func TestVDSO(t *testing.T) {
@@ -661,6 +679,27 @@ func TestConcurrentMapIterateWrite(t *testing.T) {
 	}
 }
 
+func TestConcurrentMapDeleteClearWrite(t *testing.T) {
+	if !*concurrentMapTest {
+		t.Skip("skipping without -run_concurrent_map_tests")
+	}
+	testenv.MustHaveGoRun(t)
+	// delete and clear are map writes, so racing with one is reported
+	// like concurrent writes.
+	for _, name := range []string{"concurrentMapDeleteWrite", "concurrentMapClearWrite"} {
+		t.Run(name, func(t *testing.T) {
+			output := runTestProg(t, "testprog", name)
+			want := "fatal error: concurrent map writes\n"
+			// Concurrent writes can corrupt the map in a way that we
+			// detect with a separate throw.
+			want2 := "fatal error: small map with no empty slot (concurrent map writes?)\n"
+			if !strings.HasPrefix(output, want) && !strings.HasPrefix(output, want2) {
+				t.Fatalf("output does not start with %q:\n%s", want, output)
+			}
+		})
+	}
+}
+
 func TestConcurrentMapWritesIssue69447(t *testing.T) {
 	testenv.MustHaveGoRun(t)
 	exe, err := buildTestProg(t, "testprog")
@@ -830,6 +869,27 @@ func TestAbort(t *testing.T) {
 	}
 }
 
+// Test that a fatal error under GOTRACEBACK=system summarizes the
+// state of the GC CPU limiter and the locks held by the crashing M.
+func TestFatalRuntimeState(t *testing.T) {
+	// External linking brings in cgo, causing deadlock detection not working.
+	testenv.MustInternalLink(t, false)
+
+	output := runTestProg(t, "testprog", "SimpleDeadlock", "GOTRACEBACK=system")
+	want := []string{
+		"fatal error: all goroutines are asleep - deadlock!\n",
+		"\ngc cpu limiter: enabled=false fill=",
+	}
+	if goexperiment.StaticLockRanking {
+		want = append(want, "\nlocks held by m")
+	}
+	for _, w := range want {
+		if !strings.Contains(output, w) {
+			t.Errorf("output does not contain %q:\n%s", w, output)
+		}
+	}
+}
+
 // For TestRuntimePanic: test a panic in the runtime package without
 // involving the testing harness.
 func init() {
@@ -0,0 +1,21 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debug
+
+import "internal/cpu"
+
+// CPUFeatures returns a single line naming the optional CPU features
+// that the Go runtime and standard library detected and use, separated
+// by spaces. Features that were detected but disabled with a
+// GODEBUG=cpu.name=off setting are listed with a leading '-'.
+// The names are those accepted by the GODEBUG cpu settings.
+// The same line is printed in fatal error output with GOTRACEBACK=system.
+//
+// The set and spelling of the names depend on the architecture and may
+// change between releases; the result is meant for logging and bug
+// reports, not for parsing.
+func CPUFeatures() string {
+	return cpu.Summary()
+}
@@ -0,0 +1,40 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package debug
+
+// SetExitHooksOnSignal arranges for the exit hooks registered with
+// [runtime.AddExitHook] to run when the program is about to be killed
+// by a SIGINT or SIGTERM signal that it does not handle. The hooks run
+// for at most timeout nanoseconds, like the Timeout of
+// [runtime.ExitHookOptions]; the signal then terminates the program
+// as usual, so its exit status still reports death by the signal,
+// even if the hooks have not finished. A second such signal terminates
+// the program immediately.
+//
+// Signals delivered to a channel by [os/signal.Notify], or ignored
+// by [os/signal.Ignore], are not affected.
+//
+// A timeout that is zero or negative disables running the exit hooks
+// on signals, which is the initial setting. SetExitHooksOnSignal
+// returns the previous setting.
+//
+// SetExitHooksOnSignal has no effect on Darwin, iOS, and non-Unix
+// systems.
+func SetExitHooksOnSignal(timeout int64) int64 {
+	return setExitHooksOnSignal(timeout)
+}
+
+// SetRecoverExitHookPanics sets whether a panic in an exit hook
+// registered with [runtime.AddExitHook] is recovered. If so, the panic
+// value is printed to standard error, together with the function and
+// line that registered the hook, and the remaining hooks are run.
+// Otherwise, which is the initial setting, a panic in an exit hook
+// is a fatal error. Exit hooks used internally by the Go toolchain,
+// such as those writing coverage data, are not affected.
+//
+// SetRecoverExitHookPanics returns the previous setting.
+func SetRecoverExitHookPanics(enabled bool) bool {
+	return setRecoverExitHookPanics(enabled)
+}
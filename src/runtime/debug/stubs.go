@@ -16,3 +16,5 @@ func setGCPercent(int32) int32
 func setPanicOnFault(bool) bool
 func setMaxThreads(int) int
 func setMemoryLimit(int64) int64
+func setExitHooksOnSignal(int64) int64
+func setRecoverExitHookPanics(bool) bool
@@ -49,6 +49,7 @@ const (
 	_SIGSYS    = 0xc
 	_SIGPIPE   = 0xd
 	_SIGALRM   = 0xe
+	_SIGTERM   = 0xf
 	_SIGUSR1   = 0x10
 	_SIGUSR2   = 0x11
 	_SIGCHLD   = 0x12
@@ -0,0 +1,63 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package runtime
+
+import (
+	"internal/runtime/sys"
+	_ "unsafe" // for go:linkname
+)
+
+// Data independent timing (DIT) is a per-thread processor mode, but
+// crypto/subtle.WithDataIndependentTiming requests it for a goroutine.
+// By default crypto/subtle bridges the two by locking the goroutine to
+// its thread. With GODEBUG=ditgoroutine=1 the runtime instead records
+// the request in g.ditWanted and execute sets or clears the mode on
+// whichever thread the goroutine is scheduled on, so the goroutine may
+// be preempted and migrate freely. Asynchronous preemption needs no
+// extra work: the kernel saves and restores PSTATE.DIT with the rest of
+// the signal context.
+//
+// GODEBUG=dataindependenttiming=1 enables DIT on every thread and
+// takes precedence over both mechanisms.
+
+// ditSync sets or clears DIT on the current thread, which must be mp.
+//
+//go:nosplit
+func ditSync(mp *m, enable bool) {
+	if debug.dataindependenttiming == 1 {
+		return
+	}
+	if enable {
+		sys.EnableDIT()
+	} else {
+		sys.DisableDIT()
+	}
+	mp.ditEnabled = enable
+}
+
+// crypto_subtle_setDITWanted records whether the calling goroutine wants
+// data independent timing and applies it to the current thread.
+// It returns the previous request, and ok == false if the runtime is not
+// tracking DIT per goroutine, in which case nothing was changed and the
+// caller must manage DIT itself.
+//
+//go:linkname crypto_subtle_setDITWanted crypto/subtle.setDITWanted
+func crypto_subtle_setDITWanted(want bool) (old, ok bool) {
+	if !sys.DITSupported || debug.ditgoroutine == 0 {
+		return false, false
+	}
+	// Stay on this M between updating the request and the thread
+	// state. If gp were rescheduled in between, execute would apply
+	// the new request on the new thread anyway.
+	mp := acquirem()
+	gp := mp.curg
+	old = gp.ditWanted
+	gp.ditWanted = want
+	if want != mp.ditEnabled {
+		ditSync(mp, want)
+	}
+	releasem(mp)
+	return old, true
+}
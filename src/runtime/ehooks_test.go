@@ -5,89 +5,394 @@
 package runtime_test
 
 import (
+	"bytes"
 	"internal/platform"
 	"internal/testenv"
+	traceparse "internal/trace"
+	"io"
 	"os/exec"
 	"runtime"
+	"slices"
 	"strings"
 	"testing"
 )
 
+// An exitHookBuild is a way of building testexithooks. Exit hooks run
+// on paths that have historically behaved differently depending on how
+// the program is built and linked.
+type exitHookBuild struct {
+	name  string   // the name of the build, used by exitHookScenario.builds
+	flags []string // build flags passed to buildTestProg
+
+	// unsupported reports why the build is not supported on this
+	// platform, or "" if it is supported.
+	unsupported func() string
+}
+
+var exitHookBuilds = []exitHookBuild{
+	{
+		name:        "default",
+		unsupported: func() string { return "" },
+	},
+	{
+		name:  "race",
+		flags: []string{"-race"},
+		unsupported: func() string {
+			// The race detector needs cgo, which may be disabled
+			// even where the race detector is supported.
+			if !platform.RaceDetectorSupported(runtime.GOOS, runtime.GOARCH) || !testenv.HasCGO() {
+				return "race detector not supported"
+			}
+			return ""
+		},
+	},
+	{
+		name:  "pie",
+		flags: []string{"-buildmode=pie"},
+		unsupported: func() string {
+			if !platform.BuildModeSupported(runtime.Compiler, "pie", runtime.GOOS, runtime.GOARCH) {
+				return "-buildmode=pie not supported"
+			}
+			return ""
+		},
+	},
+	{
+		name:  "external",
+		flags: []string{"-ldflags=-linkmode=external"},
+		unsupported: func() string {
+			if !testenv.HasCGO() {
+				return "external linking requires cgo"
+			}
+			return ""
+		},
+	},
+}
+
+// allExitHookBuilds runs a scenario in every supported build.
+var allExitHookBuilds = []string{"default", "race", "pie", "external"}
+
+// An exitHookScenario is a run of testexithooks in one mode, and the
+// output and exit status it must produce.
+type exitHookScenario struct {
+	mode string
+	env  string // extra environment variable, if any
+
+	// builds are the names of the builds the scenario runs in.
+	// If nil, it runs in the default and race builds.
+	builds []string
+
+	// expected is the exact output, with newlines replaced by spaces.
+	// If it is empty and musthave is nil, there must be no output.
+	expected    string
+	musthave    []string
+	mustnothave []string
+
+	exitCode int
+}
+
+var exitHookScenarios = []exitHookScenario{
+	{
+		mode:     "simple",
+		builds:   allExitHookBuilds,
+		expected: "bar foo baz",
+	},
+	{
+		mode:     "goodexit",
+		builds:   allExitHookBuilds,
+		expected: "pear orange apple",
+	},
+	{
+		mode:     "badexit",
+		builds:   allExitHookBuilds,
+		expected: "blub blix",
+		exitCode: 1,
+	},
+	{
+		mode:   "panics",
+		builds: allExitHookBuilds,
+		musthave: []string{
+			"fatal error: exit hook invoked panic; registered at main.testPanics (testexithooks.go:",
+		},
+		exitCode: 2,
+	},
+	{
+		mode:   "callsexit",
+		builds: allExitHookBuilds,
+		musthave: []string{
+			"fatal error: exit hook invoked exit; registered at main.testHookCallsExit (testexithooks.go:",
+		},
+		exitCode: 2,
+	},
+	{
+		mode:     "exit2",
+		expected: "",
+	},
+	{
+		mode:     "public",
+		expected: "two one",
+		exitCode: 3,
+	},
+	{
+		mode:     "concurrent",
+		expected: "ran 100",
+	},
+	{
+		mode:     "addfromhook",
+		expected: "first second last",
+	},
+	{
+		mode: "goexit",
+		musthave: []string{
+			"fatal error: exit hook invoked Goexit; registered at main.testHookCallsGoexit (testexithooks.go:",
+		},
+		exitCode: 2,
+	},
+	{
+		mode: "panicsrecover",
+		musthave: []string{
+			"good exit hook panicked: BADBADBAD \tregistered at main.testPanicsRecover (testexithooks.go:",
+			" ok",
+		},
+	},
+	{
+		mode: "panicspublic",
+		musthave: []string{
+			"fatal error: exit hook invoked panic; registered at main.testPanicsPublic (testexithooks.go:",
+		},
+		exitCode: 2,
+	},
+	{
+		mode: "panicsinternal",
+		musthave: []string{
+			"fatal error: exit hook invoked panic; registered at main.testPanicsInternal (testexithooks.go:",
+		},
+		exitCode: 2,
+	},
+	{
+		mode:     "setexitcode",
+		builds:   allExitHookBuilds,
+		expected: "setting after",
+		exitCode: 7,
+	},
+	{
+		mode:     "setexitcodemain",
+		expected: "",
+		exitCode: 9,
+	},
+	{
+		mode:     "setexitcodeoutside",
+		expected: "recovered runtime.SetExitCode called outside of an exit hook",
+	},
+	{
+		mode: "setexitcodeexit",
+		musthave: []string{
+			"fatal error: exit hook invoked exit; registered at main.testSetExitCodeThenExit (testexithooks.go:",
+		},
+		exitCode: 2,
+	},
+	{
+		mode:   "timeout",
+		builds: allExitHookBuilds,
+		musthave: []string{
+			"before exit hook timed out after 100ms; running remaining exit hooks",
+			"\tregistered at main.testTimeout (testexithooks.go:",
+			"goroutine ",
+			"main.sleepForever",
+			" first",
+		},
+	},
+	{
+		mode: "timeoutexit",
+		musthave: []string{
+			"before exit hook timed out after 100ms; exiting",
+			"\tregistered at main.testTimeoutExit (testexithooks.go:",
+			"main.sleepForever",
+		},
+		mustnothave: []string{"first"},
+	},
+	{
+		mode: "timeoutgodebug",
+		env:  "GODEBUG=exithooktimeout=100ms",
+		musthave: []string{
+			"second exit hook timed out after 100ms; running remaining exit hooks",
+			"\tregistered at main.testTimeoutGODEBUG (testexithooks.go:",
+			"main.sleepForever",
+			" first",
+		},
+		exitCode: 5,
+	},
+	{
+		mode:     "exitrace",
+		builds:   allExitHookBuilds,
+		expected: "hook",
+		exitCode: 3,
+	},
+	{
+		mode:     "exitcodefirst",
+		expected: "hook",
+		exitCode: 4,
+	},
+	{
+		mode: "twohooks",
+		musthave: []string{
+			"fatal error: exit hook invoked panic; registered at main.addBadHook (testexithooks.go:",
+		},
+		mustnothave: []string{"main.addGoodHook ("},
+		exitCode:    2,
+	},
+	{
+		mode:     "phases",
+		expected: "F2 F1 N2 F3 N3 N1 L2 L1",
+	},
+	{
+		mode:     "allocate",
+		expected: "allocated",
+	},
+	{
+		mode:     "channel",
+		expected: "received 42",
+		exitCode: 3,
+	},
+	{
+		mode:     "spawn",
+		expected: "spawned 10",
+		exitCode: 1,
+	},
+	{
+		mode:     "remove",
+		expected: "three one",
+	},
+	{
+		mode:     "removeduringexit",
+		expected: "three true false one",
+	},
+	{
+		mode:     "removerace",
+		expected: "ok",
+	},
+}
+
+// runsIn reports whether the scenario runs in the named build.
+func (s *exitHookScenario) runsIn(build string) bool {
+	if s.builds == nil {
+		return build == "default" || build == "race"
+	}
+	return slices.Contains(s.builds, build)
+}
+
+// run runs the scenario with the testexithooks binary exe, and checks
+// its output and exit status.
+func (s *exitHookScenario) run(t *testing.T, exe string) {
+	cmd := testenv.Command(t, exe, "-mode", s.mode)
+	if s.env != "" {
+		cmd.Env = append(cmd.Environ(), s.env)
+	}
+	out, err := cmd.CombinedOutput()
+	code := 0
+	if err != nil {
+		ee, ok := err.(*exec.ExitError)
+		if !ok {
+			t.Fatalf("running mode %s: %v", s.mode, err)
+		}
+		code = ee.ExitCode()
+	}
+	outs := strings.ReplaceAll(string(out), "\n", " ")
+	outs = strings.TrimSpace(outs)
+	if s.expected != "" && s.expected != outs {
+		t.Errorf("wanted %q\noutput:\n%s", s.expected, outs)
+	}
+	for _, need := range s.musthave {
+		if !strings.Contains(outs, need) {
+			t.Errorf("output does not contain %q\noutput:\n%s", need, outs)
+		}
+	}
+	for _, bad := range s.mustnothave {
+		if strings.Contains(outs, bad) {
+			t.Errorf("output contains %q\noutput:\n%s", bad, outs)
+		}
+	}
+	if s.expected == "" && s.musthave == nil && outs != "" {
+		t.Errorf("wanted no output\noutput:\n%s", outs)
+	}
+	if code != s.exitCode {
+		t.Errorf("got exit code %d, want %d", code, s.exitCode)
+	}
+}
+
 func TestExitHooks(t *testing.T) {
-	bmodes := []string{""}
 	if testing.Short() {
 		t.Skip("skipping due to -short")
 	}
-	// Note the HasCGO() test below; this is to prevent the test
-	// running if CGO_ENABLED=0 is in effect.
-	haverace := platform.RaceDetectorSupported(runtime.GOOS, runtime.GOARCH)
-	if haverace && testenv.HasCGO() {
-		bmodes = append(bmodes, "-race")
-	}
-	for _, bmode := range bmodes {
-		scenarios := []struct {
-			mode     string
-			expected string
-			musthave []string
-		}{
-			{
-				mode:     "simple",
-				expected: "bar foo",
-			},
-			{
-				mode:     "goodexit",
-				expected: "orange apple",
-			},
-			{
-				mode:     "badexit",
-				expected: "blub blix",
-			},
-			{
-				mode: "panics",
-				musthave: []string{
-					"fatal error: exit hook invoked panic",
-					"main.testPanics",
-				},
-			},
-			{
-				mode: "callsexit",
-				musthave: []string{
-					"fatal error: exit hook invoked exit",
-				},
-			},
-			{
-				mode:     "exit2",
-				expected: "",
-			},
-		}
 
-		exe, err := buildTestProg(t, "testexithooks", bmode)
-		if err != nil {
+	var builds []exitHookBuild
+	for _, b := range exitHookBuilds {
+		if reason := b.unsupported(); reason != "" {
+			t.Logf("skipping %s build: %s", b.name, reason)
+			continue
+		}
+		// Start all the builds, so that they run concurrently.
+		if _, err := startBuildTestProg(t, "testexithooks", b.flags...); err != nil {
 			t.Fatal(err)
 		}
+		builds = append(builds, b)
+	}
 
-		bt := ""
-		if bmode != "" {
-			bt = " bmode: " + bmode
-		}
-		for _, s := range scenarios {
-			cmd := exec.Command(exe, []string{"-mode", s.mode}...)
-			out, _ := cmd.CombinedOutput()
-			outs := strings.ReplaceAll(string(out), "\n", " ")
-			outs = strings.TrimSpace(outs)
-			if s.expected != "" && s.expected != outs {
-				t.Fatalf("failed%s mode %s: wanted %q\noutput:\n%s", bt,
-					s.mode, s.expected, outs)
+	for _, b := range builds {
+		t.Run(b.name, func(t *testing.T) {
+			exe, err := buildTestProg(t, "testexithooks", b.flags...)
+			if err != nil {
+				t.Fatal(err)
 			}
-			for _, need := range s.musthave {
-				if !strings.Contains(outs, need) {
-					t.Fatalf("failed mode %s: output does not contain %q\noutput:\n%s",
-						s.mode, need, outs)
+			for _, s := range exitHookScenarios {
+				if s.runsIn(b.name) {
+					t.Run(s.mode, func(t *testing.T) {
+						s.run(t, exe)
+					})
 				}
 			}
-			if s.expected == "" && s.musthave == nil && outs != "" {
-				t.Errorf("failed mode %s: wanted no output\noutput:\n%s", s.mode, outs)
-			}
+		})
+	}
+}
+
+func TestExitHooksTrace(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping due to -short")
+	}
+
+	exe, err := buildTestProg(t, "testexithooks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd := testenv.Command(t, exe, "-mode", "trace")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("%v\nstderr:\n%s", err, stderr.Bytes())
+	}
+
+	r, err := traceparse.NewReader(bytes.NewReader(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	const want = "exit hook main.testTrace (testexithooks.go:"
+	var begin, end bool
+	for {
+		ev, err := r.ReadEvent()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch ev.Kind() {
+		case traceparse.EventRegionBegin:
+			begin = begin || strings.HasPrefix(ev.Region().Type, want)
+		case traceparse.EventRegionEnd:
+			end = end || strings.HasPrefix(ev.Region().Type, want)
 		}
 	}
+	if !begin || !end {
+		t.Errorf("got region begin %v, end %v for %q; want both", begin, end, want)
+	}
 }
@@ -0,0 +1,118 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build unix
+
+package runtime_test
+
+import (
+	"bufio"
+	"internal/testenv"
+	"io"
+	"runtime"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestExitHooksSignal(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping due to -short")
+	}
+	if runtime.GOOS == "darwin" || runtime.GOOS == "ios" {
+		t.Skipf("exit hooks are not run on signals on %s", runtime.GOOS)
+	}
+
+	exe, err := buildTestProg(t, "testexithooks")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scenarios := []struct {
+		mode     string
+		sig      syscall.Signal
+		expected string
+		exitSig  syscall.Signal // 0 for a successful exit
+	}{
+		{"signal", syscall.SIGTERM, "flushed", syscall.SIGTERM},
+		{"signal", syscall.SIGINT, "flushed", syscall.SIGINT},
+		{"signalblock", syscall.SIGTERM, "blocking", syscall.SIGTERM},
+		{"signalnotify", syscall.SIGINT, "notified flushed", 0},
+	}
+	for _, s := range scenarios {
+		cmd := testenv.Command(t, exe, "-mode", s.mode)
+		stderr, err := cmd.StderrPipe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := cmd.Start(); err != nil {
+			t.Fatal(err)
+		}
+		r := bufio.NewReader(stderr)
+		if line, err := r.ReadString('\n'); err != nil || line != "ready\n" {
+			cmd.Process.Kill()
+			cmd.Wait()
+			t.Fatalf("mode %s: got %q, %v, want ready", s.mode, line, err)
+		}
+		if err := cmd.Process.Signal(s.sig); err != nil {
+			t.Fatal(err)
+		}
+		out, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cmd.Wait()
+
+		outs := strings.TrimSpace(strings.ReplaceAll(string(out), "\n", " "))
+		if outs != s.expected {
+			t.Errorf("mode %s with %v: wanted %q\noutput:\n%s", s.mode, s.sig, s.expected, outs)
+		}
+		status := cmd.ProcessState.Sys().(syscall.WaitStatus)
+		if s.exitSig == 0 {
+			if !status.Exited() || status.ExitStatus() != 0 {
+				t.Errorf("mode %s with %v: got %v, want exit status 0", s.mode, s.sig, cmd.ProcessState)
+			}
+		} else if !status.Signaled() || status.Signal() != s.exitSig {
+			t.Errorf("mode %s with %v: got %v, want death by %v", s.mode, s.sig, cmd.ProcessState, s.exitSig)
+		}
+	}
+}
+
+func TestExitHooksCrash(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping due to -short")
+	}
+
+	exe, err := buildTestProg(t, "testexithooks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd := testenv.Command(t, exe, "-mode", "crash")
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	r := bufio.NewReader(stderr)
+	if line, err := r.ReadString('\n'); err != nil || line != "ready\n" {
+		cmd.Process.Kill()
+		cmd.Wait()
+		t.Fatalf("got %q, %v, want ready", line, err)
+	}
+	if err := cmd.Process.Signal(syscall.SIGQUIT); err != nil {
+		t.Fatal(err)
+	}
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd.Wait()
+
+	const want = "running exit hook registered at main.testCrash (testexithooks.go:"
+	if !strings.Contains(string(out), want) {
+		t.Errorf("output does not contain %q:\n%s", want, out)
+	}
+}
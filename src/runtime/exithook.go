@@ -0,0 +1,350 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package runtime
+
+import (
+	"internal/runtime/atomic"
+	"internal/runtime/exithook"
+	"internal/runtime/sys"
+	_ "unsafe" // for go:linkname
+)
+
+// exitHooksLock protects the list of exit hooks.
+// It is only held while the list is updated, never while a hook runs.
+var exitHooksLock mutex
+
+func init() {
+	exithook.Goid = func() uint64 { return getg().goid }
+	exithook.Throw = throwExitHook
+	exithook.Lock = func() { lock(&exitHooksLock) }
+	exithook.Unlock = func() { unlock(&exitHooksLock) }
+	exithook.PrintPanic = printExitHookPanic
+	exithook.Semacquire = semacquire
+	exithook.Semrelease = semrelease
+	exithook.DefaultTimeout = exitHookTimeout.Load
+	exithook.RunTimeout = runExitHookTimeout
+	exithook.ReportTimeout = reportExitHookTimeout
+	exithook.HookStart = exitHookStart
+	exithook.HookEnd = exitHookEnd
+}
+
+// runExitHooks runs the exit hooks and returns the exit code,
+// which the hooks may have changed with SetExitCode.
+func runExitHooks(code int) int {
+	code = exithook.Run(code)
+
+	// If we're still tracing, flush the current generation, so that
+	// the events of the exit hooks have a chance to reach the trace.
+	if traceEnabled() {
+		traceAdvance(false)
+	}
+	return code
+}
+
+// printExitHookPanic reports a recovered panic e in an exit hook
+// added by the call at pc.
+func printExitHookPanic(e any, pc uintptr) {
+	print("exit hook panicked: ")
+	printpanicval(e)
+	print("\n\tregistered at ", exitHookSite(pc), "\n")
+}
+
+// throwExitHook throws msg, naming the site of the call at pc that
+// added the misbehaving exit hook, if known.
+func throwExitHook(msg string, pc uintptr) {
+	if pc != 0 {
+		msg += "; registered at " + exitHookSite(pc)
+	}
+	throw(msg)
+}
+
+// exitHookSite describes the call at pc that added an exit hook,
+// in the form "pkg.func (file.go:123)".
+func exitHookSite(pc uintptr) string {
+	fn, file, line, ok := exitHookSiteInfo(pc)
+	if !ok {
+		return "unknown function"
+	}
+	var buf [20]byte
+	return fn + " (" + file + ":" + string(itoa(buf[:], uint64(line))) + ")"
+}
+
+// printExitHookSite prints the site of the call at pc as exitHookSite
+// does, without allocating, for use in crash output.
+//
+//go:nowritebarrierrec
+func printExitHookSite(pc uintptr) {
+	fn, file, line, ok := exitHookSiteInfo(pc)
+	if !ok {
+		print("unknown function")
+		return
+	}
+	print(fn, " (", file, ":", line, ")")
+}
+
+// exitHookSiteInfo returns the function, the base name of the file,
+// and the line of the call at pc. It reports false if pc is unknown.
+//
+//go:nowritebarrierrec
+func exitHookSiteInfo(pc uintptr) (fn, file string, line int, ok bool) {
+	f := findfunc(pc)
+	if !f.valid() {
+		return "", "", 0, false
+	}
+	u, uf := newInlineUnwinder(f, pc-1)
+	file, line = u.fileLine(uf)
+	for i := len(file) - 1; i >= 0; i-- {
+		if file[i] == '/' {
+			file = file[i+1:]
+			break
+		}
+	}
+	return u.srcFunc(uf).name(), file, line, true
+}
+
+// exitHookRunning is the PC of the call that added the exit hook
+// that is running, or 0 if none is, for crash output.
+var exitHookRunning atomic.Uintptr
+
+// exitHookStart records that the exit hook added by the call at pc
+// is starting, and marks the start in the execution trace, if
+// tracing is still enabled.
+func exitHookStart(pc uintptr) {
+	exitHookRunning.Store(pc)
+	if traceEnabled() {
+		trace_userRegion(0, 0, "exit hook "+exitHookSite(pc))
+	}
+}
+
+// exitHookEnd records that the exit hook added by the call at pc
+// has returned.
+func exitHookEnd(pc uintptr) {
+	if traceEnabled() {
+		trace_userRegion(0, 1, "exit hook "+exitHookSite(pc))
+	}
+	// A hook that overran its timeout may return while a later
+	// hook is running. Leave the later hook's PC in place.
+	exitHookRunning.CompareAndSwap(pc, 0)
+}
+
+// printRunningExitHook prints the site that added the exit hook that
+// is running, if any, as part of the crash output for a fatal signal.
+//
+//go:nowritebarrierrec
+func printRunningExitHook() {
+	if pc := exitHookRunning.Load(); pc != 0 {
+		print("running exit hook registered at ")
+		printExitHookSite(pc)
+		print("\n")
+	}
+}
+
+// exitHookTimeout is the timeout, in nanoseconds, for exit hooks
+// added by AddExitHook, set by GODEBUG=exithooktimeout.
+// If it is zero, there is no timeout.
+var exitHookTimeout atomic.Int64
+
+// runExitHookTimeout runs f(arg) on a new goroutine, and waits for at
+// most timeout nanoseconds for it to return. It reports whether f
+// returned.
+func runExitHookTimeout(f func(any), arg any, timeout int64) bool {
+	done := new(note)
+	go runExitHookGoroutine(f, arg, done)
+	return notetsleepg(done, timeout)
+}
+
+func runExitHookGoroutine(f func(any), arg any, done *note) {
+	f(arg)
+	notewakeup(done)
+}
+
+// reportExitHookTimeout reports that the exit hook added by the call
+// at pc overran timeout, and prints the stack of goroutine goid, which
+// is running the hook.
+func reportExitHookTimeout(pc uintptr, goid uint64, timeout int64, exiting bool) {
+	print("exit hook timed out after ", timeout/1e6, "ms")
+	if exiting {
+		print("; exiting\n")
+	} else {
+		print("; running remaining exit hooks\n")
+	}
+	print("\tregistered at ", exitHookSite(pc), "\n")
+	if goid == 0 {
+		// The hook never started.
+		return
+	}
+	stw := stopTheWorld(stwExitHookTimeout)
+	systemstack(func() {
+		forEachG(func(gp *g) {
+			if gp.goid == goid && readgstatus(gp) != _Gdead {
+				print("\n")
+				goroutineheader(gp)
+				traceback(^uintptr(0), ^uintptr(0), 0, gp)
+			}
+		})
+	})
+	startTheWorld(stw)
+}
+
+//go:linkname setRecoverExitHookPanics runtime/debug.setRecoverExitHookPanics
+func setRecoverExitHookPanics(enabled bool) bool {
+	return exithook.SetRecoverPanics(enabled)
+}
+
+// exitHookSignalTimeout is how long, in nanoseconds, the exit hooks
+// may run when the program is about to die from SIGINT or SIGTERM.
+// If it is not positive, the exit hooks are not run on signal death.
+var exitHookSignalTimeout atomic.Int64
+
+//go:linkname setExitHooksOnSignal runtime/debug.setExitHooksOnSignal
+func setExitHooksOnSignal(timeout int64) int64 {
+	old := exitHookSignalTimeout.Swap(timeout)
+	if timeout > 0 {
+		startExitHookSignalLoop()
+	}
+	return old
+}
+
+// SetExitCode sets the status code that the program exits with,
+// overriding the code passed to [os.Exit], or zero if main.main
+// returned. It may only be called by an exit hook added by
+// [AddExitHook], on the goroutine running the hook; otherwise it
+// panics. A hook must still not call os.Exit.
+//
+// SetExitCode has no effect when the exit hooks run because of a
+// signal; see [runtime/debug.SetExitHooksOnSignal].
+func SetExitCode(code int) {
+	if !exithook.SetExitCode(code) {
+		panic(plainError("runtime.SetExitCode called outside of an exit hook"))
+	}
+}
+
+// An ExitHook is a handle for an exit hook added by [AddExitHook].
+// The zero ExitHook refers to no hook.
+type ExitHook struct {
+	id uint64
+}
+
+// An ExitHookPhase selects when an exit hook runs relative to other
+// exit hooks. The ExitHookFirst hooks run first, then the
+// ExitHookNormal hooks, then the ExitHookLast hooks. Within a phase,
+// hooks run in reverse order of registration.
+//
+// Phases are a coarse tool for hooks that must run before or after
+// most others, such as a hook that stops tracing; they are not a
+// dependency system. The runtime's own exit hooks, such as those
+// writing coverage data, run in the ExitHookFirst phase.
+type ExitHookPhase int
+
+const (
+	ExitHookNormal ExitHookPhase = iota // the default phase
+	ExitHookFirst                       // run before the ExitHookNormal hooks
+	ExitHookLast                        // run after the ExitHookNormal hooks
+)
+
+// ExitHookOptions are options for an exit hook added by
+// [AddExitHookWithOptions].
+type ExitHookOptions struct {
+	// Timeout, in nanoseconds, limits how long the hook may run.
+	// A hook with a timeout runs on a goroutine of its own. If it
+	// overruns the timeout, it is reported on standard error,
+	// together with the stack of its goroutine, and the program
+	// stops waiting for it; the goroutine is left running.
+	//
+	// If Timeout is zero, the timeout set by GODEBUG=exithooktimeout
+	// applies, if any. If it is negative, the hook may run for any
+	// length of time.
+	Timeout int64
+
+	// ExitOnTimeout makes the program exit immediately if the hook
+	// overruns its timeout, without running the remaining hooks.
+	ExitOnTimeout bool
+
+	// Phase is the phase in which the hook runs.
+	Phase ExitHookPhase
+}
+
+// AddExitHook registers f to be called when the program exits
+// by returning from main.main or by calling [os.Exit], whatever
+// the exit code. Hooks are run in reverse order of registration:
+// the first hook added is the last one run. Hooks added by
+// [AddExitHookWithOptions] may select an earlier or later phase
+// (see [ExitHookPhase]). Unless it has a timeout
+// (see [ExitHookOptions]), a hook runs on the exiting goroutine.
+// The returned handle may be used to remove the hook.
+//
+// Exit hooks are not run when the program terminates because of an
+// unrecovered panic, a fatal runtime error, or a signal. They are
+// also not run by [syscall.Exit].
+//
+// An exit hook must not call [os.Exit] or [Goexit], and must not
+// panic; doing so is a fatal error. Panics may instead be recovered
+// and reported by calling [runtime/debug.SetRecoverExitHookPanics].
+// Hooks run on an ordinary goroutine while the rest of the program
+// is still running, so a hook may allocate, start goroutines, and
+// communicate with other goroutines. A hook may block, but the
+// program does not exit until every hook has returned or overrun its
+// timeout. A call to os.Exit from another goroutine while the hooks
+// are running blocks until they are done, so a hook must not wait
+// for such a goroutine.
+//
+// AddExitHook may be called concurrently from multiple goroutines,
+// including from an exit hook, in which case f is run next,
+// unless hooks of an earlier phase are pending.
+//
+// If an execution trace is being collected when the program exits,
+// each hook runs inside a region named "exit hook" followed by the
+// site that added it. The trace is not stopped at exit, so a program
+// that wants these regions in its trace should stop tracing from a
+// hook in the ExitHookLast phase.
+func AddExitHook(f func()) ExitHook {
+	return addExitHook(f, ExitHookOptions{}, sys.GetCallerPC())
+}
+
+// AddExitHookWithOptions is like [AddExitHook], but applies opts
+// to the hook.
+func AddExitHookWithOptions(f func(), opts ExitHookOptions) ExitHook {
+	return addExitHook(f, opts, sys.GetCallerPC())
+}
+
+func addExitHook(f func(), opts ExitHookOptions, pc uintptr) ExitHook {
+	if f == nil {
+		panic(plainError("runtime.AddExitHook: f is nil"))
+	}
+	var phase int
+	switch opts.Phase {
+	case ExitHookNormal:
+		phase = exithook.PhaseNormal
+	case ExitHookFirst:
+		phase = exithook.PhaseFirst
+	case ExitHookLast:
+		phase = exithook.PhaseLast
+	default:
+		panic(plainError("runtime.AddExitHookWithOptions: invalid phase"))
+	}
+	return ExitHook{exithook.Add(exithook.Hook{
+		F:             f,
+		RunOnFailure:  true,
+		Phase:         phase,
+		User:          true,
+		PC:            pc,
+		Timeout:       opts.Timeout,
+		ExitOnTimeout: opts.ExitOnTimeout,
+	})}
+}
+
+// Remove removes the exit hook, so that it is not run when the
+// program exits. It reports whether the hook was removed; it
+// returns false if the hook has already been removed or has
+// already started running.
+//
+// Remove may be called concurrently with other goroutines calling
+// [os.Exit], and from an exit hook.
+func (h ExitHook) Remove() bool {
+	if h.id == 0 {
+		return false
+	}
+	return exithook.Remove(h.id)
+}
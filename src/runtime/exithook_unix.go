@@ -0,0 +1,72 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build unix
+
+package runtime
+
+import "internal/runtime/atomic"
+
+// exitHookSignal is the state used to run the exit hooks when the
+// program is about to die from SIGINT or SIGTERM.
+// See setExitHooksOnSignal.
+var exitHookSignal struct {
+	started atomic.Uint32 // 1 if exitHookSignalLoop is running
+	sig     atomic.Uint32 // signal being handled, or 0
+	wake    note          // wakes exitHookSignalLoop
+	done    note          // the exit hooks have run
+}
+
+// startExitHookSignalLoop starts the goroutine that runs the exit
+// hooks on signal death, if it is not already running.
+//
+// On Darwin, notes cannot be woken from a signal handler,
+// so exit hooks are never run on signal death.
+func startExitHookSignalLoop() {
+	if GOOS == "darwin" || GOOS == "ios" {
+		return
+	}
+	if exitHookSignal.started.CompareAndSwap(0, 1) {
+		go exitHookSignalLoop()
+	}
+}
+
+// exitHookSignalLoop waits for sigExitHooks to report a terminating
+// signal, runs the exit hooks for at most the configured timeout,
+// and then lets the signal kill the program.
+func exitHookSignalLoop() {
+	notetsleepg(&exitHookSignal.wake, -1)
+	sig := exitHookSignal.sig.Load()
+	if timeout := exitHookSignalTimeout.Load(); timeout > 0 {
+		// Run the hooks on another goroutine, so that
+		// a hook that blocks cannot delay death forever.
+		go func() {
+			runExitHooks(128 + int(sig))
+			notewakeup(&exitHookSignal.done)
+		}()
+		notetsleepg(&exitHookSignal.done, timeout)
+	}
+	dieFromSignal(sig)
+}
+
+// sigExitHooks is called by the signal handler when sig is about
+// to kill the program. It reports whether the exit hooks will be run
+// before the program dies, in which case the caller must not kill
+// the program itself. A second signal kills the program immediately.
+//
+//go:nosplit
+//go:nowritebarrierrec
+func sigExitHooks(sig uint32) bool {
+	if sig != _SIGINT && sig != _SIGTERM {
+		return false
+	}
+	if exitHookSignal.started.Load() == 0 || exitHookSignalTimeout.Load() <= 0 {
+		return false
+	}
+	if !exitHookSignal.sig.CompareAndSwap(0, sig) {
+		return false
+	}
+	notewakeup(&exitHookSignal.wake)
+	return true
+}
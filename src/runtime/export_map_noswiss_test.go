@@ -62,3 +62,11 @@ func MapTombstoneCheck(m map[int]int) {
 		}
 	}
 }
+
+// MapDelete2 deletes k from m as delete does, using mapdelete2, and
+// reports whether m contained k.
+func MapDelete2[K comparable, V any](m map[K]V, k K) bool {
+	i := any(m)
+	t := *(**maptype)(unsafe.Pointer(&i))
+	return mapdelete2(t, *(**hmap)(unsafe.Pointer(&m)), unsafe.Pointer(&k))
+}
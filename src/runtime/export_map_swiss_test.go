@@ -6,6 +6,20 @@
 
 package runtime
 
+import (
+	"internal/abi"
+	"internal/runtime/maps"
+	"unsafe"
+)
+
 func MapTombstoneCheck(m map[int]int) {
 	// TODO
 }
+
+// MapDelete2 deletes k from m as delete does, using mapdelete2, and
+// reports whether m contained k.
+func MapDelete2[K comparable, V any](m map[K]V, k K) bool {
+	i := any(m)
+	t := *(**abi.SwissMapType)(unsafe.Pointer(&i))
+	return mapdelete2(t, *(**maps.Map)(unsafe.Pointer(&m)), unsafe.Pointer(&k))
+}
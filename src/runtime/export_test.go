@@ -37,6 +37,7 @@ var Fastlog2 = fastlog2
 var Atoi = atoi
 var Atoi32 = atoi32
 var ParseByteCount = parseByteCount
+var ParseDuration = parseDuration
 
 var Nanotime = nanotime
 var NetpollBreak = netpollBreak
@@ -1486,7 +1487,19 @@ func (l *GCCPULimiter) Capacity() uint64 {
 }
 
 func (l *GCCPULimiter) Overflow() uint64 {
-	return l.limiter.overflow
+	return l.limiter.overflow.Load()
+}
+
+func (l *GCCPULimiter) OverflowSeconds() float64 {
+	return l.limiter.overflowSeconds()
+}
+
+func (l *GCCPULimiter) EnabledTime() int64 {
+	return l.limiter.enabledTime.Load()
+}
+
+func (l *GCCPULimiter) EnabledSeconds() float64 {
+	return l.limiter.enabledSeconds()
 }
 
 func (l *GCCPULimiter) Limiting() bool {
@@ -1513,6 +1526,18 @@ func (l *GCCPULimiter) AddAssistTime(t int64) {
 	l.limiter.addAssistTime(t)
 }
 
+func (l *GCCPULimiter) AddMarkWorkerTime(t int64) {
+	l.limiter.addMarkWorkerTime(t)
+}
+
+func (l *GCCPULimiter) AddIdleMarkTime(t int64) {
+	l.limiter.addIdleMarkTime(t)
+}
+
+func (l *GCCPULimiter) AddIdleTime(t int64) {
+	l.limiter.addIdleTime(t)
+}
+
 func (l *GCCPULimiter) ResetCapacity(now int64, nprocs int32) {
 	l.limiter.resetCapacity(now, nprocs)
 }
@@ -43,7 +43,9 @@ It is a comma-separated list of name=val pairs setting these named variables:
 	cpu.extension=off disables use of instructions from the specified instruction set extension.
 	extension is the lower case name for the instruction set extension such as sse41 or avx
 	as listed in internal/cpu package. As an example cpu.avx=off disables runtime detection
-	and thereby use of AVX instructions.
+	and thereby use of AVX instructions. For some extensions that detection can miss,
+	such as in virtual machines that hide them, cpu.extension=on forces their use; the
+	program crashes if the processor does not in fact implement them.
 
 	cgocheck: setting cgocheck=0 disables all checks for packages
 	using cgo to incorrectly pass Go pointers to non-Go code.
@@ -59,6 +61,12 @@ It is a comma-separated list of name=val pairs setting these named variables:
 	removed in a future release, so operators should tweak their Linux configuration to suit
 	their needs before then. See https://go.dev/doc/gc-guide#Linux_transparent_huge_pages.
 
+	ditgoroutine: setting ditgoroutine=1 makes the runtime track the data
+	independent timing mode requested by crypto/subtle.WithDataIndependentTiming
+	per goroutine, setting and clearing it on whichever thread the goroutine
+	runs, instead of locking the goroutine to its thread. It currently only
+	affects arm64 processors that support DIT.
+
 	dontfreezetheworld: by default, the start of a fatal panic or throw
 	"freezes the world", preempting all threads to stop all running
 	goroutines, which makes it possible to traceback all goroutines, and
@@ -73,6 +81,13 @@ It is a comma-separated list of name=val pairs setting these named variables:
 	where each object is allocated on a unique page and addresses are
 	never recycled.
 
+	exithooktimeout: setting exithooktimeout=duration, such as exithooktimeout=5s,
+	limits how long each exit hook added by AddExitHook may run, unless the hook
+	sets its own timeout with AddExitHookWithOptions. The duration is an integer
+	followed by one of the units ns, us, ms, s, m or h. A hook that overruns the
+	timeout is reported, with its stack, on standard error, and the remaining hooks
+	are run. By default, there is no timeout.
+
 	gccheckmark: setting gccheckmark=1 enables verification of the
 	garbage collector's concurrent mark phase by performing a
 	second mark pass while the world is stopped.  If the second
@@ -138,6 +153,13 @@ It is a comma-separated list of name=val pairs setting these named variables:
 	of MADV_FREE. This is less efficient, but causes RSS numbers to drop
 	more quickly.
 
+	mapiterseed: setting mapiterseed=N, for a non-zero N, makes each iteration
+	over a map start at a position derived from N rather than at a random one,
+	so that iterations over a map that has not been modified in between visit
+	its entries in the same order. This is useful when debugging code that
+	ranges over maps. It does not change the hash seed of maps, so the order
+	still differs between maps with the same contents and between runs.
+
 	memprofilerate: setting memprofilerate=X will update the value of runtime.MemProfileRate.
 	When set to 0 memory profiling is disabled.  Refer to the description of
 	MemProfileRate for the default value.
@@ -200,6 +222,13 @@ It is a comma-separated list of name=val pairs setting these named variables:
 	schedtrace: setting schedtrace=X causes the scheduler to emit a single line to standard
 	error every X milliseconds, summarizing the scheduler state.
 
+	swissmapcheck: setting swissmapcheck=1 makes every change to a map check the
+	invariants of the changed table, or of the map if it is small: the counts of
+	used, deleted and free slots, that each control byte matches its key, and that
+	each key is found by a lookup. A failed check prints the map and crashes the
+	program. This is very slow, and is meant for tracking down memory corruption
+	that shows up in maps. It has no effect when swiss maps are disabled.
+
 	tracebackancestors: setting tracebackancestors=N extends tracebacks with the stacks at
 	which goroutines were created, where N limits the number of ancestor goroutines to
 	report. This also extends the information returned by runtime.Stack.
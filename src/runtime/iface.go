@@ -418,7 +418,7 @@ func convT64(val uint64) (x unsafe.Pointer) {
 //go:linkname convTstring
 func convTstring(val string) (x unsafe.Pointer) {
 	if val == "" {
-		x = unsafe.Pointer(&zeroVal[0])
+		x = unsafe.Pointer(&abi.ZeroVal[0])
 	} else {
 		x = mallocgc(unsafe.Sizeof(val), stringType, true)
 		*(*string)(x) = val
@@ -438,7 +438,7 @@ func convTstring(val string) (x unsafe.Pointer) {
 func convTslice(val []byte) (x unsafe.Pointer) {
 	// Note: this must work for any element type, not just byte.
 	if (*slice)(unsafe.Pointer(&val)).array == nil {
-		x = unsafe.Pointer(&zeroVal[0])
+		x = unsafe.Pointer(&abi.ZeroVal[0])
 	} else {
 		x = mallocgc(unsafe.Sizeof(val), sliceType, true)
 		*(*[]byte)(x) = val
@@ -31,6 +31,10 @@ func acquireLockRankAndM(rank lockRank) {
 	acquirem()
 }
 
+// printHeldLockRanks prints nothing, as lock ranks are not tracked.
+func printHeldLockRanks(mp *m) {
+}
+
 func unlockWithRank(l *mutex) {
 	unlock2(l)
 }
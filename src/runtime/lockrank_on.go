@@ -104,6 +104,21 @@ func printHeldLocks(gp *g) {
 	}
 }
 
+// printHeldLockRanks prints the ranks of the locks held by mp on one
+// line, for crash output.
+//
+//go:nowritebarrierrec
+func printHeldLockRanks(mp *m) {
+	print("locks held by m", mp.id, ":")
+	if mp.locksHeldLen == 0 {
+		print(" none")
+	}
+	for _, held := range mp.locksHeld[:mp.locksHeldLen] {
+		print(" ", held.rank.String())
+	}
+	print("\n")
+}
+
 // acquireLockRankAndM acquires a rank which is not associated with a mutex
 // lock. To maintain the invariant that an M with m.locks==0 does not hold any
 // lock-like resources, it also acquires the M.
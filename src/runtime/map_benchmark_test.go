@@ -8,8 +8,11 @@ import (
 	"encoding/binary"
 	"flag"
 	"fmt"
+	"internal/abi"
+	"internal/goexperiment"
 	"math/rand"
 	"runtime"
+	"runtime/metrics"
 	"slices"
 	"strconv"
 	"strings"
@@ -790,6 +793,71 @@ func BenchmarkMapIterLowLoad(b *testing.B) {
 	b.Run("Key=int32/Elem=*int32", benchSizes(benchmarkMapIterLowLoad[int32, *int32]))
 }
 
+// benchmarkMapIterGrow ranges over a map of n entries that doubles in
+// size at the start of the iteration, so that the iteration looks up each
+// entry again in the grown tables. With keys set, the range loop has no
+// element variable.
+func benchmarkMapIterGrow[K mapBenchmarkKeyType, E mapBenchmarkElemType](keys bool) func(b *testing.B, n int) {
+	return func(b *testing.B, n int) {
+		checkAllocSize[K, E](b, 2*n)
+		k := genValues[K](0, 2*n)
+		e := genValues[E](0, 2*n)
+
+		iterations := iterCount(b, n)
+		sinkK := newSink[K]()
+		sinkE := newSink[E]()
+		b.ResetTimer()
+
+		for i := 0; i < iterations; i++ {
+			b.StopTimer()
+			m := fillMap(k[:n], e[:n])
+			b.StartTimer()
+
+			grown := false
+			grow := func() {
+				b.StopTimer()
+				for j := n; j < 2*n; j++ {
+					m[k[j]] = e[j]
+				}
+				grown = true
+				b.StartTimer()
+			}
+			if keys {
+				for k := range m {
+					if !grown {
+						grow()
+					}
+					*sinkK = k
+				}
+			} else {
+				for k, e := range m {
+					if !grown {
+						grow()
+					}
+					*sinkK = k
+					*sinkE = e
+				}
+			}
+		}
+	}
+}
+
+func BenchmarkMapIterGrow(b *testing.B) {
+	sizes := func(f func(b *testing.B, n int)) func(*testing.B) {
+		return func(b *testing.B) {
+			for _, n := range []int{1 << 10, 1 << 16} {
+				b.Run("len="+strconv.Itoa(n), func(b *testing.B) { f(b, n) })
+			}
+		}
+	}
+	b.Run("Key=int64/Elem=int64/keys", sizes(benchmarkMapIterGrow[int64, int64](true)))
+	b.Run("Key=int64/Elem=int64/keys+elems", sizes(benchmarkMapIterGrow[int64, int64](false)))
+	b.Run("Key=string/Elem=string/keys", sizes(benchmarkMapIterGrow[string, string](true)))
+	b.Run("Key=string/Elem=string/keys+elems", sizes(benchmarkMapIterGrow[string, string](false)))
+	b.Run("Key=int32/Elem=mediumType/keys", sizes(benchmarkMapIterGrow[int32, mediumType](true)))
+	b.Run("Key=int32/Elem=mediumType/keys+elems", sizes(benchmarkMapIterGrow[int32, mediumType](false)))
+}
+
 func benchmarkMapAccessHit[K mapBenchmarkKeyType, E mapBenchmarkElemType](b *testing.B, n int) {
 	if n == 0 {
 		b.Skip("can't access empty map")
@@ -853,6 +921,73 @@ func BenchmarkMapAccessMiss(b *testing.B) {
 	b.Run("Key=int32/Elem=*int32", benchSizes(benchmarkMapAccessMiss[int32, *int32]))
 }
 
+// largeBenchSizes is like benchSizes, for maps of 10k to 10M entries, where
+// lookups are dominated by probing and cache misses rather than by hashing.
+func largeBenchSizes(f func(b *testing.B, n int)) func(*testing.B) {
+	cases := []int{1e4, 1e5, 1e6, 1e7}
+
+	return func(b *testing.B) {
+		for _, n := range cases {
+			b.Run("len="+strconv.Itoa(n), func(b *testing.B) {
+				if !*mapbench && n > 1e4 {
+					b.Skip("Skipped because -mapbench=false")
+				}
+
+				f(b, n)
+			})
+		}
+	}
+}
+
+func BenchmarkMapAccessHitLarge(b *testing.B) {
+	b.Run("Key=int64/Elem=int64", largeBenchSizes(benchmarkMapAccessHit[int64, int64]))
+	b.Run("Key=string/Elem=string", largeBenchSizes(benchmarkMapAccessHit[string, string]))
+	b.Run("Key=smallType/Elem=int32", largeBenchSizes(benchmarkMapAccessHit[smallType, int32]))
+}
+
+func BenchmarkMapAccessMissLarge(b *testing.B) {
+	b.Run("Key=int64/Elem=int64", largeBenchSizes(benchmarkMapAccessMiss[int64, int64]))
+	b.Run("Key=string/Elem=string", largeBenchSizes(benchmarkMapAccessMiss[string, string]))
+	b.Run("Key=smallType/Elem=int32", largeBenchSizes(benchmarkMapAccessMiss[smallType, int32]))
+}
+
+// singleTableBenchSizes is like benchSizes, for maps that fit in a single
+// table, from the first table a small map grows into to a full table of
+// maximum capacity. Lookups in these maps probe groups that the map
+// header caches, without loading the directory and the table.
+func singleTableBenchSizes(f func(b *testing.B, n int)) func(*testing.B) {
+	cases := []int{
+		abi.SwissMapGroupSlots + 1, // no longer fits in a group
+		64,
+		256,
+		896, // 7/8 of 1024 slots, the maximum load of a table
+	}
+
+	return func(b *testing.B) {
+		for _, n := range cases {
+			b.Run("len="+strconv.Itoa(n), func(b *testing.B) {
+				f(b, n)
+			})
+		}
+	}
+}
+
+func BenchmarkMapAccessHitSingleTable(b *testing.B) {
+	b.Run("Key=int64/Elem=int64", singleTableBenchSizes(benchmarkMapAccessHit[int64, int64]))
+	b.Run("Key=string/Elem=string", singleTableBenchSizes(benchmarkMapAccessHit[string, string]))
+	b.Run("Key=smallType/Elem=int32", singleTableBenchSizes(benchmarkMapAccessHit[smallType, int32]))
+}
+
+func BenchmarkMapAccessMissSingleTable(b *testing.B) {
+	b.Run("Key=int64/Elem=int64", singleTableBenchSizes(benchmarkMapAccessMiss[int64, int64]))
+	b.Run("Key=string/Elem=string", singleTableBenchSizes(benchmarkMapAccessMiss[string, string]))
+	b.Run("Key=smallType/Elem=int32", singleTableBenchSizes(benchmarkMapAccessMiss[smallType, int32]))
+}
+
+func BenchmarkMapAssignExistsLarge(b *testing.B) {
+	b.Run("Key=smallType/Elem=int32", largeBenchSizes(benchmarkMapAssignExists[smallType, int32]))
+}
+
 // Assign to a key that already exists.
 func benchmarkMapAssignExists[K mapBenchmarkKeyType, E mapBenchmarkElemType](b *testing.B, n int) {
 	if n == 0 {
@@ -917,6 +1052,49 @@ func BenchmarkMapAssignFillNoHint(b *testing.B) {
 	b.Run("Key=int32/Elem=bigType", benchSizes(benchmarkMapAssignFillNoHint[int32, bigType]))
 	b.Run("Key=*int32/Elem=int32", benchSizes(benchmarkMapAssignFillNoHint[*int32, int32]))
 	b.Run("Key=int32/Elem=*int32", benchSizes(benchmarkMapAssignFillNoHint[int32, *int32]))
+	b.Run("Key=string/Elem=*int32", benchSizes(benchmarkMapAssignFillNoHint[string, *int32]))
+}
+
+// Build many maps of n entries without a hint, as a service that keeps
+// many mid-sized maps does, and report the memory of their groups, and
+// the fraction of it lost to rounding the allocations up to a size class.
+func benchmarkMapGroupUnused[K mapBenchmarkKeyType, E mapBenchmarkElemType](b *testing.B, n int) {
+	if !goexperiment.SwissMap {
+		b.Skip("map groups only exist with swiss maps")
+	}
+	k := genValues[K](0, n)
+	e := genValues[E](0, n)
+	s := []metrics.Sample{
+		{Name: "/maps/groups:bytes"},
+		{Name: "/maps/groups/unused:bytes"},
+	}
+	metrics.Read(s)
+	bytes, unused := s[0].Value.Uint64(), s[1].Value.Uint64()
+
+	b.ResetTimer()
+
+	for range b.N {
+		m := make(map[K]E)
+		for i := range n {
+			m[k[i]] = e[i]
+		}
+	}
+
+	b.StopTimer()
+
+	metrics.Read(s)
+	bytes, unused = s[0].Value.Uint64()-bytes, s[1].Value.Uint64()-unused
+	b.ReportMetric(float64(bytes)/float64(b.N), "group-B/op")
+	b.ReportMetric(100*float64(unused)/float64(bytes+unused), "unused-%")
+}
+
+func BenchmarkMapGroupUnused(b *testing.B) {
+	for _, n := range []int{64, 200, 500, 1000} {
+		b.Run(fmt.Sprintf("Key=int64/Elem=int64/len=%d", n), func(b *testing.B) { benchmarkMapGroupUnused[int64, int64](b, n) })
+		b.Run(fmt.Sprintf("Key=int32/Elem=int32/len=%d", n), func(b *testing.B) { benchmarkMapGroupUnused[int32, int32](b, n) })
+		b.Run(fmt.Sprintf("Key=string/Elem=int64/len=%d", n), func(b *testing.B) { benchmarkMapGroupUnused[string, int64](b, n) })
+		b.Run(fmt.Sprintf("Key=string/Elem=string/len=%d", n), func(b *testing.B) { benchmarkMapGroupUnused[string, string](b, n) })
+	}
 }
 
 // Identical to benchmarkMapAssignFillNoHint, but additionally measures the
@@ -1008,6 +1186,24 @@ func BenchmarkMapAssignFillHint(b *testing.B) {
 	b.Run("Key=int32/Elem=*int32", benchSizes(benchmarkMapAssignFillHint[int32, *int32]))
 }
 
+// BenchmarkMapFillLarge builds maps of 1M entries, with and without a
+// size hint. Time and allocations are per map.
+func BenchmarkMapFillLarge(b *testing.B) {
+	const n = 1 << 20
+	k := genValues[int64](0, n)
+	for _, hint := range []int{0, n} {
+		b.Run("hint="+strconv.Itoa(hint), func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				m := make(map[int64]int64, hint)
+				for _, k := range k {
+					m[k] = k
+				}
+			}
+		})
+	}
+}
+
 // Fill a map of size n, reusing the same map. Time is per-key. The map is
 // cleared every n assignments.
 //
@@ -1182,9 +1378,11 @@ func BenchmarkMapSmallAccessHit(b *testing.B) {
 	b.Run("Key=int32/Elem=int32", smallBenchSizes(benchmarkMapAccessHit[int32, int32]))
 	b.Run("Key=int64/Elem=int64", smallBenchSizes(benchmarkMapAccessHit[int64, int64]))
 	b.Run("Key=string/Elem=string", smallBenchSizes(benchmarkMapAccessHit[string, string]))
+	b.Run("Key=smallType/Elem=int32", smallBenchSizes(benchmarkMapAccessHit[smallType, int32]))
 }
 func BenchmarkMapSmallAccessMiss(b *testing.B) {
 	b.Run("Key=int32/Elem=int32", smallBenchSizes(benchmarkMapAccessMiss[int32, int32]))
 	b.Run("Key=int64/Elem=int64", smallBenchSizes(benchmarkMapAccessMiss[int64, int64]))
 	b.Run("Key=string/Elem=string", smallBenchSizes(benchmarkMapAccessMiss[string, string]))
+	b.Run("Key=smallType/Elem=int32", smallBenchSizes(benchmarkMapAccessMiss[smallType, int32]))
 }
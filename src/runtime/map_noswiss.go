@@ -429,7 +429,7 @@ func mapaccess1(t *maptype, h *hmap, key unsafe.Pointer) unsafe.Pointer {
 		if err := mapKeyError(t, key); err != nil {
 			panic(err) // see issue 23734
 		}
-		return unsafe.Pointer(&zeroVal[0])
+		return unsafe.Pointer(&abi.ZeroVal[0])
 	}
 	if h.flags&hashWriting != 0 {
 		fatal("concurrent map read and map write")
@@ -470,7 +470,7 @@ bucketloop:
 			}
 		}
 	}
-	return unsafe.Pointer(&zeroVal[0])
+	return unsafe.Pointer(&abi.ZeroVal[0])
 }
 
 // mapaccess2 should be an internal detail,
@@ -499,7 +499,7 @@ func mapaccess2(t *maptype, h *hmap, key unsafe.Pointer) (unsafe.Pointer, bool)
 		if err := mapKeyError(t, key); err != nil {
 			panic(err) // see issue 23734
 		}
-		return unsafe.Pointer(&zeroVal[0]), false
+		return unsafe.Pointer(&abi.ZeroVal[0]), false
 	}
 	if h.flags&hashWriting != 0 {
 		fatal("concurrent map read and map write")
@@ -540,7 +540,7 @@ bucketloop:
 			}
 		}
 	}
-	return unsafe.Pointer(&zeroVal[0]), false
+	return unsafe.Pointer(&abi.ZeroVal[0]), false
 }
 
 // returns both key and elem. Used by map iterator.
@@ -589,7 +589,7 @@ bucketloop:
 
 func mapaccess1_fat(t *maptype, h *hmap, key, zero unsafe.Pointer) unsafe.Pointer {
 	e := mapaccess1(t, h, key)
-	if e == unsafe.Pointer(&zeroVal[0]) {
+	if e == unsafe.Pointer(&abi.ZeroVal[0]) {
 		return zero
 	}
 	return e
@@ -597,7 +597,7 @@ func mapaccess1_fat(t *maptype, h *hmap, key, zero unsafe.Pointer) unsafe.Pointe
 
 func mapaccess2_fat(t *maptype, h *hmap, key, zero unsafe.Pointer) (unsafe.Pointer, bool) {
 	e := mapaccess1(t, h, key)
-	if e == unsafe.Pointer(&zeroVal[0]) {
+	if e == unsafe.Pointer(&abi.ZeroVal[0]) {
 		return zero, false
 	}
 	return e, true
@@ -756,11 +756,39 @@ func mapdelete(t *maptype, h *hmap, key unsafe.Pointer) {
 	if asanenabled && h != nil {
 		asanread(key, t.Key.Size_)
 	}
+
+	h.delete(t, key)
+}
+
+// mapdelete2 is like mapdelete, but reports whether h contained key,
+// saving callers that need to know a lookup before the delete. Plain
+// delete(m, k) still calls mapdelete.
+//
+//go:linkname mapdelete2
+func mapdelete2(t *maptype, h *hmap, key unsafe.Pointer) bool {
+	if raceenabled && h != nil {
+		callerpc := sys.GetCallerPC()
+		pc := abi.FuncPCABIInternal(mapdelete2)
+		racewritepc(unsafe.Pointer(h), callerpc, pc)
+		raceReadObjectPC(t.Key, key, callerpc, pc)
+	}
+	if msanenabled && h != nil {
+		msanread(key, t.Key.Size_)
+	}
+	if asanenabled && h != nil {
+		asanread(key, t.Key.Size_)
+	}
+
+	return h.delete(t, key)
+}
+
+// delete deletes key from h, if present, and reports whether it was.
+func (h *hmap) delete(t *maptype, key unsafe.Pointer) bool {
 	if h == nil || h.count == 0 {
 		if err := mapKeyError(t, key); err != nil {
 			panic(err) // see issue 23734
 		}
-		return
+		return false
 	}
 	if h.flags&hashWriting != 0 {
 		fatal("concurrent map writes")
@@ -772,6 +800,7 @@ func mapdelete(t *maptype, h *hmap, key unsafe.Pointer) {
 	// in which case we have not actually done a write (delete).
 	h.flags ^= hashWriting
 
+	found := false
 	bucket := hash & bucketMask(h.B)
 	if h.growing() {
 		growWork(t, h, bucket)
@@ -849,6 +878,7 @@ search:
 			if h.count == 0 {
 				h.hash0 = uint32(rand())
 			}
+			found = true
 			break search
 		}
 	}
@@ -857,6 +887,7 @@ search:
 		fatal("concurrent map writes")
 	}
 	h.flags &^= hashWriting
+	return found
 }
 
 // mapiterinit initializes the hiter struct used for ranging over maps.
@@ -910,6 +941,10 @@ func mapiterinit(t *maptype, h *hmap, it *hiter) {
 
 	// decide where to start
 	r := uintptr(rand())
+	if seed := debug.mapiterseed.Load(); seed != 0 {
+		// GODEBUG=mapiterseed: start at a fixed position.
+		r = uintptr(seed)
+	}
 	it.startBucket = r & bucketMask(h.B)
 	it.offset = uint8(r >> h.B & (abi.OldMapBucketCount - 1))
 
@@ -101,7 +101,7 @@ func mapaccess2(t *abi.SwissMapType, m *maps.Map, key unsafe.Pointer) (unsafe.Po
 
 func mapaccess1_fat(t *abi.SwissMapType, m *maps.Map, key, zero unsafe.Pointer) unsafe.Pointer {
 	e := mapaccess1(t, m, key)
-	if e == unsafe.Pointer(&zeroVal[0]) {
+	if e == unsafe.Pointer(&abi.ZeroVal[0]) {
 		return zero
 	}
 	return e
@@ -109,7 +109,7 @@ func mapaccess1_fat(t *abi.SwissMapType, m *maps.Map, key, zero unsafe.Pointer)
 
 func mapaccess2_fat(t *abi.SwissMapType, m *maps.Map, key, zero unsafe.Pointer) (unsafe.Pointer, bool) {
 	e := mapaccess1(t, m, key)
-	if e == unsafe.Pointer(&zeroVal[0]) {
+	if e == unsafe.Pointer(&abi.ZeroVal[0]) {
 		return zero, false
 	}
 	return e, true
@@ -158,6 +158,28 @@ func mapdelete(t *abi.SwissMapType, m *maps.Map, key unsafe.Pointer) {
 	m.Delete(t, key)
 }
 
+// mapdelete2 is like mapdelete, but reports whether m contained key,
+// saving callers that need to know a lookup before the delete. Plain
+// delete(m, k) still calls mapdelete.
+//
+//go:linkname mapdelete2
+func mapdelete2(t *abi.SwissMapType, m *maps.Map, key unsafe.Pointer) bool {
+	if raceenabled && m != nil {
+		callerpc := sys.GetCallerPC()
+		pc := abi.FuncPCABIInternal(mapdelete2)
+		racewritepc(unsafe.Pointer(m), callerpc, pc)
+		raceReadObjectPC(t.Key, key, callerpc, pc)
+	}
+	if msanenabled && m != nil {
+		msanread(key, t.Key.Size_)
+	}
+	if asanenabled && m != nil {
+		asanread(key, t.Key.Size_)
+	}
+
+	return m.Delete(t, key)
+}
+
 // mapiterinit initializes the Iter struct used for ranging over maps.
 // The Iter struct pointed to by 'it' is allocated on the stack
 // by the compilers order pass or on the heap by reflect_mapiterinit.
@@ -187,6 +209,18 @@ func mapiterinit(t *abi.SwissMapType, m *maps.Map, it *maps.Iter) {
 	it.Next()
 }
 
+// mapiterinitkeys is like mapiterinit, for range loops without an
+// element variable. The iteration leaves it.elem nil.
+func mapiterinitkeys(t *abi.SwissMapType, m *maps.Map, it *maps.Iter) {
+	if raceenabled && m != nil {
+		callerpc := sys.GetCallerPC()
+		racereadpc(unsafe.Pointer(m), callerpc, abi.FuncPCABIInternal(mapiterinitkeys))
+	}
+
+	it.InitKeys(t, m)
+	it.Next()
+}
+
 // mapiternext should be an internal detail,
 // but widely used packages access it using linkname.
 // Notable members of the hall of shame include:
@@ -422,15 +456,11 @@ func mapclone(m any) any {
 }
 
 func mapclone2(t *abi.SwissMapType, src *maps.Map) *maps.Map {
-	dst := makemap(t, int(src.Used()), nil)
-
-	var iter maps.Iter
-	iter.Init(t, src)
-	for iter.Next(); iter.Key() != nil; iter.Next() {
-		dst.Put(t, iter.Key(), iter.Elem())
+	if raceenabled {
+		callerpc := sys.GetCallerPC()
+		racereadpc(unsafe.Pointer(src), callerpc, abi.FuncPCABIInternal(mapclone2))
 	}
-
-	return dst
+	return src.Clone(t)
 }
 
 // keys for implementing maps.keys
@@ -10,7 +10,9 @@ import (
 	"internal/abi"
 	"internal/goarch"
 	"internal/runtime/maps"
+	"runtime"
 	"slices"
+	"strconv"
 	"testing"
 	"unsafe"
 )
@@ -18,8 +20,8 @@ import (
 func TestHmapSize(t *testing.T) {
 	// The structure of Map is defined in internal/runtime/maps/map.go
 	// and in cmd/compile/internal/reflectdata/map_swiss.go and must be in sync.
-	// The size of Map should be 48 bytes on 64 bit and 32 bytes on 32 bit platforms.
-	wantSize := uintptr(2*8 + 4*goarch.PtrSize)
+	// The size of Map should be 64 bytes on 64 bit and 44 bytes on 32 bit platforms.
+	wantSize := uintptr(3*8 + 5*goarch.PtrSize)
 	gotSize := unsafe.Sizeof(maps.Map{})
 	if gotSize != wantSize {
 		t.Errorf("sizeof(maps.Map{})==%d, want %d", gotSize, wantSize)
@@ -28,16 +30,61 @@ func TestHmapSize(t *testing.T) {
 
 // See also reflect_test.TestGroupSizeZero.
 func TestGroupSizeZero(t *testing.T) {
-	var m map[struct{}]struct{}
-	mTyp := abi.TypeOf(m)
-	mt := (*abi.SwissMapType)(unsafe.Pointer(mTyp))
+	ctrlSize := uintptr(8 * abi.SwissMapCtrlWords)
+	for _, tc := range []struct {
+		name     string
+		m        any
+		slotSize uintptr
+		elemOff  uintptr
+	}{
+		// Zero-size elems and slots take no space, and need no
+		// padding to keep pointers to them within the group.
+		{"map[struct{}]struct{}", map[struct{}]struct{}(nil), 0, 0},
+		{"map[int]struct{}", map[int]struct{}(nil), goarch.PtrSize, 0},
+		{"map[string]struct{}", map[string]struct{}(nil), 2 * goarch.PtrSize, 0},
+		{"map[struct{}]int", map[struct{}]int(nil), goarch.PtrSize, 0},
+		{"map[int32]int32", map[int32]int32(nil), 8, 4},
+	} {
+		mt := (*abi.SwissMapType)(unsafe.Pointer(abi.TypeOf(tc.m)))
+		if mt.SlotSize != tc.slotSize || mt.ElemOff != tc.elemOff {
+			t.Errorf("%s: SlotSize, ElemOff got %d, %d want %d, %d", tc.name, mt.SlotSize, mt.ElemOff, tc.slotSize, tc.elemOff)
+		}
+		want := ctrlSize + abi.SwissMapGroupSlots*tc.slotSize
+		if mt.GroupSize != want || mt.Group.Size() != want {
+			t.Errorf("%s: GroupSize %d, Group.Size() %d want %d", tc.name, mt.GroupSize, mt.Group.Size(), want)
+		}
+	}
+}
+
+// TestMapHintAllocs checks that a presized map allocates the tables of
+// its directory, and their groups, as one object each, whatever the
+// number of tables, and that the collector still finds the pointers in
+// the groups.
+func TestMapHintAllocs(t *testing.T) {
+	for _, hint := range []int{1 << 12, 1 << 16} {
+		allocs := testing.AllocsPerRun(10, func() {
+			mapGrowSink = make(map[int64]*int64, hint)
+		})
+		// The Map, the directory, the tables and the groups.
+		if allocs > 4 {
+			t.Errorf("make(map[int64]*int64, %d) got %v allocs want at most 4", hint, allocs)
+		}
 
-	// internal/runtime/maps when create pointers to slots, even if slots
-	// are size 0. The compiler should have reserved an extra word to
-	// ensure that pointers to the zero-size type at the end of group are
-	// valid.
-	if mt.Group.Size() <= 8 {
-		t.Errorf("Group size got %d want >8", mt.Group.Size())
+		m := make(map[int64]*int64, hint)
+		for i := range int64(hint) {
+			p := new(int64)
+			*p = i
+			m[i] = p
+		}
+		runtime.GC()
+		for range 1000 {
+			mapIndirectSink = make([]int, 16)
+		}
+		for i := range int64(hint) {
+			if p := m[i]; p == nil || *p != i {
+				t.Fatalf("hint %d: m[%d] = %v, want pointer to %d", hint, i, p, i)
+			}
+		}
 	}
 }
 
@@ -73,3 +120,48 @@ func TestMapIterOrder(t *testing.T) {
 		}
 	}
 }
+
+// Lookups and assignments in a map with a single table probe the groups
+// that the map caches. Grow maps one key at a time past the first split
+// of their table, which drops the cache, and check every key after each
+// insertion, through each of the lookup and assignment paths.
+func TestMapSingleTableGrow(t *testing.T) {
+	t.Run("Key=uint32", func(t *testing.T) {
+		testMapSingleTableGrow(t, func(i int) uint32 { return uint32(i) })
+	})
+	t.Run("Key=int64", func(t *testing.T) {
+		testMapSingleTableGrow(t, func(i int) int64 { return int64(i) })
+	})
+	t.Run("Key=string", func(t *testing.T) {
+		testMapSingleTableGrow(t, strconv.Itoa)
+	})
+	type key struct {
+		s string
+		i int64
+	}
+	t.Run("Key=struct", func(t *testing.T) {
+		testMapSingleTableGrow(t, func(i int) key { return key{strconv.Itoa(i), int64(i)} })
+	})
+}
+
+func testMapSingleTableGrow[K comparable](t *testing.T, key func(int) K) {
+	// More than the 896 entries of a table of maximum capacity.
+	const n = 2048
+	m := make(map[K]int)
+	for i := range n {
+		m[key(i)] = i
+		for j := 0; j <= i; j++ {
+			if got, ok := m[key(j)]; !ok || got != j {
+				t.Fatalf("after %d insertions: m[%v] got %d, %v want %d, true", i+1, key(j), got, ok, j)
+			}
+		}
+		if got := m[key(i+1)]; got != 0 {
+			t.Fatalf("after %d insertions: m[%v] got %d want 0", i+1, key(i+1), got)
+		}
+		// Assign to a present key, which must not insert another.
+		m[key(i/2)] = i / 2
+		if len(m) != i+1 {
+			t.Fatalf("after %d insertions: len(m) got %d want %d", i+1, len(m), i+1)
+		}
+	}
+}
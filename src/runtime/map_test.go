@@ -6,16 +6,24 @@ package runtime_test
 
 import (
 	"fmt"
+	"internal/abi"
+	"internal/asan"
 	"internal/goexperiment"
+	"internal/msan"
+	"internal/race"
 	"internal/testenv"
+	"iter"
+	"maps"
 	"math"
 	"os"
 	"reflect"
 	"runtime"
+	"runtime/debug"
 	"slices"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"unsafe"
 )
@@ -388,6 +396,152 @@ func TestBigItems(t *testing.T) {
 	}
 }
 
+// Keys and elements larger than abi.SwissMapMaxKeyBytes and
+// abi.SwissMapMaxElemBytes are stored outside the map's groups.
+type indirectKey struct {
+	p   *int
+	pad [128]byte
+}
+
+type indirectElem struct {
+	p   *int
+	pad [128]byte
+}
+
+// TestMapIndirectGC checks that the garbage collector keeps alive the
+// values that keys and elements stored outside the map point to, as the
+// map grows and shrinks.
+func TestMapIndirectGC(t *testing.T) {
+	for _, n := range []int{1, abi.SwissMapGroupSlots, 100, 2000} {
+		t.Run(strconv.Itoa(n), func(t *testing.T) {
+			var collected atomic.Int64
+			newInt := func(v int) *int {
+				p := new(int)
+				*p = v
+				runtime.SetFinalizer(p, func(*int) { collected.Add(1) })
+				return p
+			}
+
+			m := make(map[indirectKey]indirectElem)
+			var keys []indirectKey
+			for i := range n {
+				k := indirectKey{p: newInt(i)}
+				m[k] = indirectElem{p: newInt(-i)}
+				// Drop every other key from the map, to exercise
+				// delete and the reuse of slots.
+				if i%2 == 1 {
+					delete(m, keys[len(keys)-1])
+					keys = keys[:len(keys)-1]
+				}
+				keys = append(keys, k)
+			}
+
+			runtime.GC()
+			runtime.GC()
+			// Allocate enough to reuse any memory that the GC
+			// wrongly freed.
+			for range 1000 {
+				mapIndirectSink = make([]int, 16)
+			}
+
+			if len(m) != len(keys) {
+				t.Fatalf("len(m) = %d, want %d", len(m), len(keys))
+			}
+			for k, e := range m {
+				if *e.p != -*k.p {
+					t.Errorf("m[%d] = %d, want %d", *k.p, *e.p, -*k.p)
+				}
+			}
+			for _, k := range keys {
+				if e, ok := m[k]; !ok || *e.p != -*k.p {
+					t.Errorf("m[%d] = %v, %v, want %d", *k.p, e.p, ok, -*k.p)
+				}
+			}
+			// Only the keys and elements deleted from the map may
+			// have been collected.
+			if c, max := collected.Load(), int64(2*(n-len(keys))); c > max {
+				t.Errorf("%d values collected, want at most %d", c, max)
+			}
+			runtime.KeepAlive(keys)
+		})
+	}
+}
+
+var mapIndirectSink []int
+
+// TestMapDelete2 checks that mapdelete2 deletes like delete, and reports
+// whether the map contained the key.
+func TestMapDelete2(t *testing.T) {
+	for _, n := range []int{0, 1, 5, 1000} {
+		t.Run(strconv.Itoa(n), func(t *testing.T) {
+			m := make(map[int]int)
+			for i := range n {
+				m[i] = i
+			}
+			for i := range n {
+				if !runtime.MapDelete2(m, i) {
+					t.Errorf("MapDelete2(m, %d) = false, want true", i)
+				}
+				if _, ok := m[i]; ok {
+					t.Errorf("m[%d] still present after MapDelete2", i)
+				}
+				if runtime.MapDelete2(m, i) {
+					t.Errorf("MapDelete2(m, %d) again = true, want false", i)
+				}
+				if runtime.MapDelete2(m, n+i) {
+					t.Errorf("MapDelete2(m, %d) of missing key = true, want false", n+i)
+				}
+			}
+			if len(m) != 0 {
+				t.Errorf("len(m) = %d, want 0", len(m))
+			}
+		})
+	}
+
+	if runtime.MapDelete2(map[string]int(nil), "a") {
+		t.Errorf("MapDelete2 from nil map = true, want false")
+	}
+	// A NaN key can't be found, so it isn't deleted.
+	m := map[float64]int{math.NaN(): 1}
+	if runtime.MapDelete2(m, math.NaN()) || len(m) != 1 {
+		t.Errorf("MapDelete2(m, NaN) = true or deleted, want false and len(m) = 1")
+	}
+}
+
+// TestMapIndirectKeyUpdate checks that assigning to an existing key
+// stored outside the map's groups overwrites the stored key with the new
+// one, as for keys stored in the groups (see TestNegativeZero).
+func TestMapIndirectKeyUpdate(t *testing.T) {
+	type key struct {
+		f   float64
+		pad [128]byte
+	}
+	negZero := math.Copysign(0, -1)
+
+	for _, n := range []int{1, abi.SwissMapGroupSlots, 100, 2000} {
+		t.Run(strconv.Itoa(n), func(t *testing.T) {
+			m := make(map[key]int)
+			for i := 1; i < n; i++ {
+				m[key{f: float64(i)}] = i
+			}
+			m[key{f: 0}] = 0
+			m[key{f: negZero}] = -1
+
+			if len(m) != n {
+				t.Fatalf("len(m) = %d, want %d", len(m), n)
+			}
+			for k, v := range m {
+				if k.f != 0 {
+					continue
+				}
+				if v != -1 || !math.Signbit(k.f) {
+					t.Errorf("m has key %v elem %d, want key %v elem -1", k.f, v, negZero)
+				}
+			}
+		})
+	}
+}
+
 func TestMapHugeZero(t *testing.T) {
 	type T [4000]byte
 	m := map[int]T{}
@@ -404,6 +558,54 @@ func TestMapHugeZero(t *testing.T) {
 	}
 }
 
+// TestMapAccessZero checks the lookups that may return the zero value,
+// for elements that fit in abi.ZeroVal and for larger ones, which are
+// looked up with the _fat variants.
+func TestMapAccessZero(t *testing.T) {
+	t.Run("small", testMapAccessZero[[8]byte])
+	t.Run("fits", testMapAccessZero[[abi.ZeroValSize]byte])
+	t.Run("fat", testMapAccessZero[[abi.ZeroValSize + 1]byte])
+}
+
+func testMapAccessZero[E [8]byte | [abi.ZeroValSize]byte | [abi.ZeroValSize + 1]byte](t *testing.T) {
+	var zero, nonzero E
+	nonzero[len(nonzero)-1] = 1
+
+	var nilMap map[int]E
+	if v := nilMap[0]; v != zero {
+		t.Errorf("nil map: m[0] = %v, want zero", v)
+	}
+	if v, ok := nilMap[0]; ok || v != zero {
+		t.Errorf("nil map: m[0] = %v, %v, want zero, false", v, ok)
+	}
+
+	// Key 0 has the zero value as element, and key 2 is missing.
+	m := map[int]E{0: zero, 1: nonzero}
+	ms := map[string]E{"": zero, "1": nonzero}
+	for k, want := range []E{zero, nonzero, zero} {
+		found := k < 2
+		if v := m[k]; v != want {
+			t.Errorf("m[%d] = %v, want %v", k, v, want)
+		}
+		if v, ok := m[k]; ok != found || v != want {
+			t.Errorf("m[%d] = %v, %v, want %v, %v", k, v, ok, want, found)
+		}
+		sk := strconv.Itoa(k)
+		if k == 0 {
+			sk = ""
+		}
+		if v, ok := ms[sk]; ok != found || v != want {
+			t.Errorf("ms[%q] = %v, %v, want %v, %v", sk, v, ok, want, found)
+		}
+	}
+
+	for _, k := range []int{0, 1, 2} {
+		if n := testing.AllocsPerRun(10, func() { _, sinkOK = m[k] }); n != 0 {
+			t.Errorf("m[%d] allocates %v times", k, n)
+		}
+	}
+}
+
 type empty struct {
 }
 
@@ -539,6 +741,43 @@ NextRound:
 	}
 }
 
+// With GODEBUG=mapiterseed, iterations over an unmodified map return its
+// entries in the same order.
+func TestMapIterSeed(t *testing.T) {
+	for _, n := range []int{5, 1000} {
+		m := make(map[int]bool)
+		for i := range n {
+			m[i] = true
+		}
+		order := func() []int {
+			var keys []int
+			for k := range m {
+				keys = append(keys, k)
+			}
+			return keys
+		}
+
+		t.Run(fmt.Sprintf("default/len=%d", n), func(t *testing.T) {
+			first := order()
+			for range 100 {
+				if !slices.Equal(order(), first) {
+					return
+				}
+			}
+			t.Errorf("constant iteration order: %v", first)
+		})
+		t.Run(fmt.Sprintf("GODEBUG=mapiterseed=1/len=%d", n), func(t *testing.T) {
+			t.Setenv("GODEBUG", "mapiterseed=1")
+			first := order()
+			for range 100 {
+				if got := order(); !slices.Equal(got, first) {
+					t.Fatalf("iteration order changed:\n%v\nthen\n%v", first, got)
+				}
+			}
+		})
+	}
+}
+
 // Map iteration must not return duplicate entries.
 func TestMapIterDuplicate(t *testing.T) {
 	// Run several rounds to increase the probability
@@ -663,6 +902,47 @@ func TestMapLargeValNoPointer(t *testing.T) {
 	}
 }
 
+var mapGrowSink any
+
+// Test that pointers moved into new tables during grow and split stay
+// reachable when the grow races with concurrent marking. Grow copies
+// slots into freshly allocated groups without barriers on the (nil)
+// destination contents, so a missed shade of the source pointers would
+// show up here as corrupted elements.
+func TestMapGrowConcurrentGC(t *testing.T) {
+	type T struct {
+		n int
+		s string
+	}
+	n := 100000
+	if testing.Short() {
+		n = 10000
+	}
+	defer debug.SetGCPercent(debug.SetGCPercent(1))
+	for round := 0; round < 4; round++ {
+		m := make(map[string]*T)
+		for i := 0; i < n; i++ {
+			k := strconv.Itoa(i)
+			m[k] = &T{n: i, s: k}
+		}
+		runtime.GC()
+		// Allocate garbage to encourage reuse of anything freed
+		// too early.
+		for i := 0; i < n; i++ {
+			mapGrowSink = &T{n: -1, s: "garbage"}
+		}
+		runtime.GC()
+		if len(m) != n {
+			t.Fatalf("len(m) = %d, want %d", len(m), n)
+		}
+		for k, v := range m {
+			if v.s != k || strconv.Itoa(v.n) != k {
+				t.Fatalf("corrupted map: m[%q] = %+v", k, *v)
+			}
+		}
+	}
+}
+
 // Test that making a map with a large or invalid hint
 // doesn't panic. (Issue 19926).
 func TestIgnoreBogusMapHint(t *testing.T) {
@@ -702,7 +982,65 @@ func TestNonEscapingMap(t *testing.T) {
 	if n != 0 {
 		t.Errorf("with variable hint: want 0 allocs, got %v", n)
 	}
+	n = testing.AllocsPerRun(1000, func() {
+		m := map[string]int{"a": 1, "b": 2, "c": 3}
+		m["d"] = 4
+		m["e"] = 5
+		sum := 0
+		for _, k := range []string{"a", "b", "c", "d", "e", "f"} {
+			sum += m[k]
+		}
+		if _, ok := m["f"]; ok || sum != 15 || len(m) != 5 {
+			panic("bad map contents")
+		}
+	})
+	if n != 0 {
+		t.Errorf("five entries: want 0 allocs, got %v", n)
+	}
+}
 
+// A non-escaping map that outgrows the group allocated with it on the
+// stack moves its entries to the heap.
+func TestNonEscapingMapGrow(t *testing.T) {
+	m := make(map[int]int)
+	for i := range 100 {
+		m[i] = i * 10
+		for j := range i + 1 {
+			if got, ok := m[j]; !ok || got != j*10 {
+				t.Fatalf("after %d inserts: m[%d] = %d, %v want %d, true", i+1, j, got, ok, j*10)
+			}
+		}
+	}
+	if len(m) != 100 {
+		t.Errorf("len(m) = %d want 100", len(m))
+	}
+}
+
+// A range loop over a map allocates nothing, whatever the size of the map.
+func TestMapRangeAllocs(t *testing.T) {
+	for _, n := range []int{0, 1, 8, 100, 1e5} {
+		m := make(map[int]int)
+		big := make(map[int][200]byte)
+		for i := range n {
+			m[i] = i
+			big[i] = [200]byte{byte(i)}
+		}
+		var sum int
+		if allocs := testing.AllocsPerRun(10, func() {
+			for k, v := range m {
+				sum += k + v
+			}
+		}); allocs != 0 {
+			t.Errorf("range over map[int]int of %d entries: got %v allocs want 0", n, allocs)
+		}
+		if allocs := testing.AllocsPerRun(10, func() {
+			for k, v := range big {
+				sum += k + int(v[0])
+			}
+		}); allocs != 0 {
+			t.Errorf("range over map[int][200]byte of %d entries: got %v allocs want 0", n, allocs)
+		}
+	}
 }
 
 func TestDeferDeleteSlow(t *testing.T) {
@@ -726,6 +1064,48 @@ func TestDeferDeleteSlow(t *testing.T) {
 	}
 }
 
+// TestDeleteFastKeys deletes keys of the types with specialized map
+// functions, from both small and large maps.
+func TestDeleteFastKeys(t *testing.T) {
+	ptrs := make([]*int, 1001)
+	for i := range ptrs {
+		ptrs[i] = new(int)
+	}
+	t.Run("int32", func(t *testing.T) { testDeleteFastKeys(t, func(i int) int32 { return int32(i) }) })
+	t.Run("int64", func(t *testing.T) { testDeleteFastKeys(t, func(i int) int64 { return int64(i) }) })
+	t.Run("ptr", func(t *testing.T) { testDeleteFastKeys(t, func(i int) *int { return ptrs[i] }) })
+	t.Run("string", func(t *testing.T) { testDeleteFastKeys(t, strconv.Itoa) })
+}
+
+func testDeleteFastKeys[K comparable](t *testing.T, key func(int) K) {
+	for _, n := range []int{1, 8, 9, 16, 17, 100, 1000} {
+		m := make(map[K]int)
+		for i := range n {
+			m[key(i)] = i
+		}
+		// Delete the even keys and a missing one.
+		for i := 0; i < n; i += 2 {
+			delete(m, key(i))
+		}
+		delete(m, key(n))
+		if len(m) != n/2 {
+			t.Errorf("n=%d: len = %d after deleting even keys, want %d", n, len(m), n/2)
+		}
+		for i := range n + 1 {
+			v, ok := m[key(i)]
+			if want := i%2 == 1 && i < n; ok != want || ok && v != i {
+				t.Errorf("n=%d: m[%v] = %d, %v, want %d, %v", n, key(i), v, ok, i, want)
+			}
+		}
+		for i := 1; i < n; i += 2 {
+			delete(m, key(i))
+		}
+		if len(m) != 0 {
+			t.Errorf("n=%d: len = %d after deleting all keys, want 0", n, len(m))
+		}
+	}
+}
+
 // TestIncrementAfterDeleteValueInt and other test Issue 25936.
 // Value types int, int32, int64 are affected. Value type string
 // works as expected.
@@ -1060,6 +1440,94 @@ func TestEmptyMapWithInterfaceKey(t *testing.T) {
 	})
 }
 
+// Every map operation with an unhashable key panics with the error of
+// mapKeyError, whatever the size of the map, and leaves the map unchanged.
+func TestMapUnhashableKey(t *testing.T) {
+	keys := []struct {
+		key  any
+		want string
+	}{
+		{[]int{1}, "runtime error: hash of unhashable type []int"},
+		{func() {}, "runtime error: hash of unhashable type func()"},
+		{map[int]int{}, "runtime error: hash of unhashable type map[int]int"},
+		{struct{ a any }{[]byte{}}, "runtime error: hash of unhashable type []uint8"},
+		{[1]any{func() {}}, "runtime error: hash of unhashable type func()"},
+	}
+	ops := []struct {
+		name string
+		f    func(m map[any]int, k any)
+	}{
+		{"access1", func(m map[any]int, k any) { _ = m[k] }},
+		{"access2", func(m map[any]int, k any) { _, _ = m[k] }},
+		{"assign", func(m map[any]int, k any) { m[k] = 1 }},
+		{"opassign", func(m map[any]int, k any) { m[k]++ }},
+		{"delete", func(m map[any]int, k any) { delete(m, k) }},
+		{"iterate", func(m map[any]int, k any) {
+			for range m {
+				m[k] = 1
+			}
+			// An empty map has nothing to iterate over.
+			m[k] = 1
+		}},
+	}
+	for _, size := range []int{-1, 0, 3, abi.SwissMapGroupSlots, 100} {
+		for _, op := range ops {
+			for _, k := range keys {
+				var m map[any]int
+				if size >= 0 {
+					m = make(map[any]int)
+					for i := range size {
+						m[i] = i
+					}
+				}
+
+				want := k.want
+				if m == nil && (op.name == "assign" || op.name == "opassign" || op.name == "iterate") {
+					want = "assignment to entry in nil map"
+				}
+				if m == nil && op.name == "opassign" && (race.Enabled || msan.Enabled || asan.Enabled) {
+					// When instrumenting, the compiler rewrites m[k]++
+					// to m[k] = m[k] + 1, so the lookup panics first.
+					want = k.want
+				}
+
+				func() {
+					defer func() {
+						r := recover()
+						err, ok := r.(error)
+						if !ok {
+							t.Errorf("size %d: %s(%T): got panic %v, want error", size, op.name, k.key, r)
+							return
+						}
+						if err.Error() != want {
+							t.Errorf("size %d: %s(%T): got panic %q, want %q", size, op.name, k.key, err, want)
+						}
+						if _, ok := err.(runtime.Error); !ok {
+							t.Errorf("size %d: %s(%T): panic value %T is not a runtime.Error", size, op.name, k.key, err)
+						}
+					}()
+					op.f(m, k.key)
+				}()
+
+				// The map is unchanged, and still usable.
+				if len(m) != max(size, 0) {
+					t.Errorf("size %d: %s(%T): len(m) = %d after panic", size, op.name, k.key, len(m))
+				}
+				if m == nil {
+					continue
+				}
+				for i := range size {
+					if v, ok := m[i]; !ok || v != i {
+						t.Errorf("size %d: %s(%T): m[%d] = %d, %v after panic, want %d, true", size, op.name, k.key, i, v, ok, i)
+					}
+				}
+				m[size] = size
+				delete(m, size)
+			}
+		}
+	}
+}
+
 func TestMapKeys(t *testing.T) {
 	if goexperiment.SwissMap {
 		t.Skip("mapkeys not implemented for swissmaps")
@@ -1202,3 +1670,403 @@ func TestMapIterDeleteReplace(t *testing.T) {
 		})
 	}
 }
+
+// Clearing a large map releases its tables, rather than keeping them for
+// a handful of later entries.
+func TestMapClearReleasesMemory(t *testing.T) {
+	if !goexperiment.SwissMap {
+		t.Skip("clear keeps the buckets of old maps")
+	}
+
+	const n = 1 << 19
+	m := make(map[int]int)
+	for i := range n {
+		m[i] = i
+	}
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+
+	clear(m)
+	for i := range 10 {
+		m[i] = i
+	}
+
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+	runtime.KeepAlive(m)
+
+	// Each entry takes at least 16 bytes in its table.
+	if freed := int64(before.HeapInuse) - int64(after.HeapInuse); freed < n*16/2 {
+		t.Errorf("clear freed %d bytes of heap, want at least %d", freed, n*16/2)
+	}
+	if len(m) != 10 {
+		t.Errorf("len(m) = %d after clear and 10 inserts, want 10", len(m))
+	}
+}
+
+// An iteration in progress when its map is cleared yields none of the
+// entries from before the clear.
+func TestMapClearDuringIteration(t *testing.T) {
+	for _, n := range []int{5, 1000, 100000} {
+		t.Run(strconv.Itoa(n), func(t *testing.T) {
+			m := make(map[int]int)
+			for i := range n {
+				m[i] = i
+			}
+
+			cleared := false
+			for k := range m {
+				if cleared && k < n {
+					t.Fatalf("iteration yielded key %d from before clear", k)
+				}
+				if !cleared {
+					clear(m)
+					cleared = true
+					for i := n; i < n+100; i++ {
+						m[i] = i
+					}
+				}
+			}
+		})
+	}
+}
+
+// Incrementing the entries of a map with a full group of entries doesn't
+// allocate.
+func TestMapFullSmallUpdateAllocs(t *testing.T) {
+	// Exactly fill the group of a small map, or the first bucket.
+	n := abi.SwissMapGroupSlots
+	if !goexperiment.SwissMap {
+		n = abi.OldMapBucketCount
+	}
+	t.Run("int32", func(t *testing.T) {
+		testMapFullSmallUpdateAllocs(t, n, func(i int) int32 { return int32(i) })
+	})
+	t.Run("int64", func(t *testing.T) {
+		testMapFullSmallUpdateAllocs(t, n, func(i int) int64 { return int64(i) })
+	})
+	t.Run("string", func(t *testing.T) {
+		testMapFullSmallUpdateAllocs(t, n, strconv.Itoa)
+	})
+	t.Run("pointer", func(t *testing.T) {
+		ps := make([]int, n)
+		testMapFullSmallUpdateAllocs(t, n, func(i int) *int { return &ps[i] })
+	})
+	t.Run("float64", func(t *testing.T) {
+		testMapFullSmallUpdateAllocs(t, n, func(i int) float64 { return float64(i) + 0.5 })
+	})
+}
+
+func testMapFullSmallUpdateAllocs[K comparable](t *testing.T, n int, key func(int) K) {
+	keys := make([]K, n)
+	for i := range keys {
+		keys[i] = key(i)
+	}
+
+	// Each run updates a fresh map, including the warm-up run of
+	// AllocsPerRun, as growing a map happens only once.
+	const runs = 100
+	ms := make([]map[K]int, runs+1)
+	for i := range ms {
+		ms[i] = make(map[K]int)
+		for _, k := range keys {
+			ms[i][k] = 0
+		}
+	}
+	next := 0
+	allocs := testing.AllocsPerRun(runs, func() {
+		m := ms[next]
+		next++
+		for _, k := range keys {
+			m[k]++
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("updating a full map of %d entries: %v allocs, want 0", n, allocs)
+	}
+	for _, m := range ms {
+		for _, k := range keys {
+			if m[k] != 1 {
+				t.Errorf("m[%v] = %d, want 1", k, m[k])
+			}
+		}
+	}
+}
+
+// fuzzMapFillSizes are the sizes FuzzMapOps fills a map to, on either side
+// of the size of a small map and of the load of a full table of
+// internal/runtime/maps.maxTableCapacity (1024) slots, and a size that
+// needs a directory of depth 3 or more.
+var fuzzMapFillSizes = []int{
+	abi.SwissMapGroupSlots - 1,
+	abi.SwissMapGroupSlots,
+	abi.SwissMapGroupSlots + 1,
+	1024*7/8 - 1,
+	1024 * 7 / 8,
+	1024*7/8 + 1,
+	12000,
+}
+
+// FuzzMapOps interprets its input as operations on a map[uint32]uint32,
+// three bytes each: an op, followed by a little-endian key. It mirrors the
+// operations onto a reference kept in slices, and compares the two after
+// every operation, including the entries returned by an iteration that
+// is interleaved with the other operations.
+func FuzzMapOps(f *testing.F) {
+	const (
+		opGet = iota
+		opPut
+		opDelete
+		opClear
+		opIterStart
+		opIterNext
+		opFill
+		opSnapshot
+		numOps
+	)
+	f.Add([]byte{
+		opFill, 2, 0,
+		opIterStart, 0, 0,
+		opIterNext, 0, 0,
+		opPut, 0, 1,
+		opIterNext | 0xf0, 0, 0,
+		opSnapshot, 0, 0,
+	})
+	f.Add([]byte{
+		opFill, 4, 0,
+		opIterStart, 0, 0,
+		opIterNext | 0xf0, 0, 0,
+		opFill, 6, 0x10,
+		opDelete, 1, 0,
+		opIterNext | 0xf0, 0, 0,
+		opClear, 0, 0,
+		opIterNext, 0, 0,
+		opSnapshot, 0, 0,
+	})
+
+	f.Fuzz(func(t *testing.T, in []byte) {
+		m := make(map[uint32]uint32)
+		present := make([]bool, 1<<16)
+		elems := make([]uint32, 1<<16)
+		n := 0
+		put := func(k, e uint32) {
+			m[k] = e
+			if !present[k] {
+				present[k] = true
+				n++
+			}
+			elems[k] = e
+		}
+		del := func(k uint32) {
+			delete(m, k)
+			if present[k] {
+				present[k] = false
+				n--
+			}
+		}
+
+		// State of the interleaved iteration. An iteration must
+		// return the keys present when it started that are not
+		// deleted before it reaches them. It may return keys added,
+		// or deleted and added again, after it started, and may
+		// return those more than once.
+		var (
+			next    func() (uint32, uint32, bool)
+			stop    func()
+			mustSee []bool
+			seen    []bool
+			renewed []bool
+		)
+		defer func() {
+			if stop != nil {
+				stop()
+			}
+		}()
+
+		var counter uint32
+		for ; len(in) >= 3; in = in[3:] {
+			op := in[0] % numOps
+			k := uint32(in[1]) | uint32(in[2])<<8
+			counter++
+			switch op {
+			case opGet:
+				want := uint32(0)
+				if present[k] {
+					want = elems[k]
+				}
+				if e, ok := m[k]; e != want || ok != present[k] {
+					t.Fatalf("m[%d] = %d, %v, want %d, %v", k, e, ok, want, present[k])
+				}
+			case opPut:
+				if next != nil && !present[k] {
+					renewed[k] = true
+				}
+				put(k, counter)
+			case opDelete:
+				if next != nil && present[k] {
+					mustSee[k] = false
+					renewed[k] = true
+				}
+				del(k)
+			case opClear:
+				clear(m)
+				for k := range present {
+					if present[k] && next != nil {
+						mustSee[k] = false
+						renewed[k] = true
+					}
+					present[k] = false
+				}
+				n = 0
+			case opIterStart:
+				if stop != nil {
+					stop()
+				}
+				next, stop = iter.Pull2(maps.All(m))
+				mustSee = slices.Clone(present)
+				seen = make([]bool, 1<<16)
+				renewed = make([]bool, 1<<16)
+			case opIterNext:
+				if next == nil {
+					break
+				}
+				for range in[0]>>4 + 1 {
+					k, e, ok := next()
+					if !ok {
+						for k := range mustSee {
+							if mustSee[k] && !seen[k] {
+								t.Fatalf("iteration ended without returning key %d", k)
+							}
+						}
+						next, stop = nil, nil
+						break
+					}
+					if !present[k] || e != elems[k] {
+						t.Fatalf("iteration returned %d: %d, want present %v elem %d", k, e, present[k], elems[k])
+					}
+					if seen[k] && !renewed[k] {
+						t.Fatalf("iteration returned key %d twice", k)
+					}
+					seen[k] = true
+				}
+			case opFill:
+				size := fuzzMapFillSizes[int(k)%len(fuzzMapFillSizes)]
+				for i := uint32(0); n < size && i < 1<<16; i++ {
+					k := (k + i) & (1<<16 - 1)
+					if !present[k] {
+						if next != nil {
+							renewed[k] = true
+						}
+						put(k, counter)
+					}
+				}
+			case opSnapshot:
+				got := 0
+				for k, e := range m {
+					if k >= 1<<16 || !present[k] || e != elems[k] {
+						t.Fatalf("range returned %d: %d, which is not in the reference", k, e)
+					}
+					got++
+				}
+				if got != n {
+					t.Fatalf("range returned %d entries, want %d", got, n)
+				}
+			}
+			if len(m) != n {
+				t.Fatalf("len(m) = %d, want %d", len(m), n)
+			}
+		}
+	})
+}
+
+// TestMapZeroSize checks maps with zero-size keys, elements or both,
+// whose slots take less space than their fields would with padding.
+func TestMapZeroSize(t *testing.T) {
+	t.Run("map[struct{}]struct{}", func(t *testing.T) {
+		m := map[struct{}]struct{}{}
+		m[struct{}{}] = struct{}{}
+		m[struct{}{}] = struct{}{}
+		if len(m) != 1 {
+			t.Fatalf("len(m) = %d, want 1", len(m))
+		}
+		n := 0
+		for range m {
+			n++
+		}
+		if n != 1 {
+			t.Errorf("iteration returned %d entries, want 1", n)
+		}
+		if _, ok := m[struct{}{}]; !ok {
+			t.Errorf("m[struct{}{}] missing")
+		}
+		delete(m, struct{}{})
+		if _, ok := m[struct{}{}]; ok || len(m) != 0 {
+			t.Errorf("m[struct{}{}] present after delete, len(m) = %d", len(m))
+		}
+		for range m {
+			t.Errorf("iteration of empty map returned an entry")
+		}
+	})
+
+	t.Run("map[int]struct{}", func(t *testing.T) {
+		for _, n := range []int{1, abi.SwissMapGroupSlots, 100, 2000} {
+			m := map[int]struct{}{}
+			for i := range n {
+				m[i] = struct{}{}
+			}
+			for i := 0; i < n; i += 2 {
+				delete(m, i)
+			}
+			seen := make([]bool, n)
+			for k := range m {
+				if k%2 == 0 || seen[k] {
+					t.Fatalf("n=%d: iteration returned key %d", n, k)
+				}
+				seen[k] = true
+			}
+			for i := range n {
+				if _, ok := m[i]; ok != (i%2 == 1) || seen[i] != ok {
+					t.Fatalf("n=%d: m[%d] present %v, seen %v", n, i, ok, seen[i])
+				}
+			}
+		}
+	})
+
+	t.Run("map[string]struct{}", func(t *testing.T) {
+		m := map[string]struct{}{}
+		for i := range 100 {
+			m[strconv.Itoa(i)] = struct{}{}
+		}
+		for i := 0; i < 100; i += 2 {
+			delete(m, strconv.Itoa(i))
+		}
+		for k := range m {
+			if i, _ := strconv.Atoi(k); i%2 == 0 {
+				t.Errorf("iteration returned deleted key %q", k)
+			}
+		}
+		if len(m) != 50 {
+			t.Errorf("len(m) = %d, want 50", len(m))
+		}
+	})
+
+	t.Run("map[struct{}]int", func(t *testing.T) {
+		m := map[struct{}]int{}
+		m[struct{}{}] = 1
+		m[struct{}{}]++
+		if v := m[struct{}{}]; v != 2 || len(m) != 1 {
+			t.Fatalf("m[struct{}{}] = %d, len(m) = %d, want 2, 1", v, len(m))
+		}
+		for k, v := range m {
+			if k != (struct{}{}) || v != 2 {
+				t.Errorf("iteration returned %v: %d, want {}: 2", k, v)
+			}
+		}
+		delete(m, struct{}{})
+		if v, ok := m[struct{}{}]; ok || v != 0 || len(m) != 0 {
+			t.Errorf("m[struct{}{}] = %d, %v after delete, len(m) = %d", v, ok, len(m))
+		}
+	})
+}
@@ -0,0 +1,92 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package runtime
+
+import (
+	"internal/runtime/atomic"
+	"internal/runtime/maps"
+	_ "unsafe" // for go:linkname
+)
+
+// mapStats are cumulative map statistics, see maps.Stats.
+type mapStats struct {
+	groups      atomic.Uint64
+	groupBytes  atomic.Uint64
+	groupUnused atomic.Uint64
+	entries     atomic.Uint64
+	tombstones  atomic.Uint64
+	directory   atomic.Uint64
+}
+
+// add adds s to the statistics of a P. Only the P's owner writes them,
+// so this is a load and a store of each counter, not an atomic add.
+func (ms *mapStats) add(s *maps.Stats) {
+	ms.groups.Store(ms.groups.Load() + s.Groups)
+	ms.groupBytes.Store(ms.groupBytes.Load() + s.GroupBytes)
+	ms.groupUnused.Store(ms.groupUnused.Load() + s.GroupUnused)
+	ms.entries.Store(ms.entries.Load() + s.Entries)
+	ms.tombstones.Store(ms.tombstones.Load() + s.Tombstones)
+	ms.directory.Store(ms.directory.Load() + s.Directory)
+}
+
+// addAtomic adds s to statistics that may be written concurrently.
+func (ms *mapStats) addAtomic(s *maps.Stats) {
+	ms.groups.Add(int64(s.Groups))
+	ms.groupBytes.Add(int64(s.GroupBytes))
+	ms.groupUnused.Add(int64(s.GroupUnused))
+	ms.entries.Add(int64(s.Entries))
+	ms.tombstones.Add(int64(s.Tombstones))
+	ms.directory.Add(int64(s.Directory))
+}
+
+// read adds the statistics in ms to s.
+func (ms *mapStats) read(s *maps.Stats) {
+	s.Groups += ms.groups.Load()
+	s.GroupBytes += ms.groupBytes.Load()
+	s.GroupUnused += ms.groupUnused.Load()
+	s.Entries += ms.entries.Load()
+	s.Tombstones += ms.tombstones.Load()
+	s.Directory += ms.directory.Load()
+}
+
+// mapStatsNoP are the map statistics of allocations without a P.
+var mapStatsNoP mapStats
+
+//go:linkname maps_addStats internal/runtime/maps.addStats
+func maps_addStats(s maps.Stats) {
+	mp := acquirem()
+	if pp := mp.p.ptr(); pp != nil {
+		pp.mapStats.add(&s)
+	} else {
+		mapStatsNoP.addAtomic(&s)
+	}
+	releasem(mp)
+}
+
+// maps_roundupsize returns the size of the allocation of size bytes, for
+// the unused memory of groups in maps.Stats.
+//
+//go:linkname maps_roundupsize internal/runtime/maps.roundupsize
+func maps_roundupsize(size uintptr, noscan bool) uintptr {
+	return roundupsize(size, noscan)
+}
+
+// readMapStats returns the map statistics of the process.
+func readMapStats() maps.Stats {
+	var s maps.Stats
+	mapStatsNoP.read(&s)
+
+	// A P that is destroyed when GOMAXPROCS shrinks keeps its
+	// statistics, and stays in allp beyond its length, to be reused
+	// if GOMAXPROCS grows again.
+	lock(&allpLock)
+	for _, pp := range allp[:cap(allp)] {
+		if pp != nil {
+			pp.mapStats.read(&s)
+		}
+	}
+	unlock(&allpLock)
+	return s
+}
@@ -236,6 +236,22 @@ func maps_typedmemmove(typ *_type, dst, src unsafe.Pointer) {
 	typedmemmove(typ, dst, src)
 }
 
+// maps_memmoveFresh copies size bytes from src to dst, where dst lies
+// within a newly allocated map group array that has not been written
+// since allocation. Because dst is known to hold only nil pointers,
+// there are no old values to shade and only the pointers in src need
+// barriers, just as in growslice. pointers reports whether the copied
+// region may contain pointers.
+//
+//go:linkname maps_memmoveFresh internal/runtime/maps.memmoveFresh
+//go:nosplit
+func maps_memmoveFresh(dst, src unsafe.Pointer, size uintptr, pointers bool) {
+	if writeBarrier.enabled && pointers {
+		bulkBarrierPreWriteSrcOnly(uintptr(dst), uintptr(src), size, nil)
+	}
+	memmove(dst, src, size)
+}
+
 // reflectcallmove is invoked by reflectcall to copy the return values
 // out of the stack and into the heap, invoking the necessary write
 // barriers. dst, src, and size describe the return value area to
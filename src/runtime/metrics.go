@@ -7,7 +7,9 @@ package runtime
 // Metrics implementation exported to runtime/metrics.
 
 import (
+	"internal/abi"
 	"internal/godebugs"
+	"internal/runtime/maps"
 	"unsafe"
 )
 
@@ -309,12 +311,24 @@ func initMetrics() {
 				out.scalar = in.heapStats.tinyAllocCount
 			},
 		},
+		"/gc/limiter/enabled:seconds": {
+			compute: func(_ *statAggregate, out *metricValue) {
+				out.kind = metricKindFloat64
+				out.scalar = float64bits(gcCPULimiter.enabledSeconds())
+			},
+		},
 		"/gc/limiter/last-enabled:gc-cycle": {
 			compute: func(_ *statAggregate, out *metricValue) {
 				out.kind = metricKindUint64
 				out.scalar = uint64(gcCPULimiter.lastEnabledCycle.Load())
 			},
 		},
+		"/gc/limiter/overflow:cpu-seconds": {
+			compute: func(_ *statAggregate, out *metricValue) {
+				out.kind = metricKindFloat64
+				out.scalar = float64bits(gcCPULimiter.overflowSeconds())
+			},
+		},
 		"/gc/pauses:seconds": {
 			compute: func(_ *statAggregate, out *metricValue) {
 				// N.B. this is identical to /sched/pauses/total/gc:seconds.
@@ -327,6 +341,55 @@ func initMetrics() {
 				out.scalar = uint64(startingStackSize)
 			},
 		},
+		"/maps/directory:entries": {
+			deps: makeStatDepSet(mapStatsDep),
+			compute: func(in *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = in.mapStats.Directory
+			},
+		},
+		"/maps/groups:groups": {
+			deps: makeStatDepSet(mapStatsDep),
+			compute: func(in *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = in.mapStats.Groups
+			},
+		},
+		"/maps/groups:bytes": {
+			deps: makeStatDepSet(mapStatsDep),
+			compute: func(in *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = in.mapStats.GroupBytes
+			},
+		},
+		"/maps/groups/unused:bytes": {
+			deps: makeStatDepSet(mapStatsDep),
+			compute: func(in *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = in.mapStats.GroupUnused
+			},
+		},
+		"/maps/rehash/entries:entries": {
+			deps: makeStatDepSet(mapStatsDep),
+			compute: func(in *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = in.mapStats.Entries
+			},
+		},
+		"/maps/rehash/tombstones:slots": {
+			deps: makeStatDepSet(mapStatsDep),
+			compute: func(in *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = in.mapStats.Tombstones
+			},
+		},
+		"/maps/slots:slots": {
+			deps: makeStatDepSet(mapStatsDep),
+			compute: func(in *statAggregate, out *metricValue) {
+				out.kind = metricKindUint64
+				out.scalar = in.mapStats.Groups * abi.SwissMapGroupSlots
+			},
+		},
 		"/memory/classes/heap/free:bytes": {
 			deps: makeStatDepSet(heapStatsDep),
 			compute: func(in *statAggregate, out *metricValue) {
@@ -518,6 +581,7 @@ const (
 	sysStatsDep                 // corresponds to sysStatsAggregate
 	cpuStatsDep                 // corresponds to cpuStatsAggregate
 	gcStatsDep                  // corresponds to gcStatsAggregate
+	mapStatsDep                 // corresponds to mapStatsAggregate
 	numStatsDeps
 )
 
@@ -696,6 +760,16 @@ func (a *gcStatsAggregate) compute() {
 	a.totalScan = a.heapScan + a.stackScan + a.globalsScan
 }
 
+// mapStatsAggregate represents the statistics of maps, see maps.Stats.
+type mapStatsAggregate struct {
+	maps.Stats
+}
+
+// compute populates the mapStatsAggregate with values from the runtime.
+func (a *mapStatsAggregate) compute() {
+	a.Stats = readMapStats()
+}
+
 // nsToSec takes a duration in nanoseconds and converts it to seconds as
 // a float64.
 func nsToSec(ns int64) float64 {
@@ -713,6 +787,7 @@ type statAggregate struct {
 	sysStats  sysStatsAggregate
 	cpuStats  cpuStatsAggregate
 	gcStats   gcStatsAggregate
+	mapStats  mapStatsAggregate
 }
 
 // ensure populates statistics aggregates determined by deps if they
@@ -735,6 +810,8 @@ func (a *statAggregate) ensure(deps *statDepSet) {
 			a.cpuStats.compute()
 		case gcStatsDep:
 			a.gcStats.compute()
+		case mapStatsDep:
+			a.mapStats.compute()
 		}
 	}
 	a.ensured = a.ensured.union(missing)
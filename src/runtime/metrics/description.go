@@ -278,6 +278,15 @@ var allDesc = []Description{
 		Kind:       KindUint64,
 		Cumulative: true,
 	},
+	{
+		Name: "/gc/limiter/enabled:seconds",
+		Description: "Estimated total wall-clock time during which the GC CPU limiter " +
+			"was enabled. The limiter is enabled and disabled at updates, which happen " +
+			"about every 10 milliseconds while the GC is running, so this metric is only " +
+			"accurate to that granularity.",
+		Kind:       KindFloat64,
+		Cumulative: true,
+	},
 	{
 		Name: "/gc/limiter/last-enabled:gc-cycle",
 		Description: "GC cycle the last time the GC CPU limiter was enabled. " +
@@ -287,6 +296,16 @@ var allDesc = []Description{
 			"The first GC cycle is cycle 1, so a value of 0 indicates that it was never enabled.",
 		Kind: KindUint64,
 	},
+	{
+		Name: "/gc/limiter/overflow:cpu-seconds",
+		Description: "Estimated total GC CPU time in excess of what the GC CPU limiter " +
+			"allows. This approximates how much GC work the limiter dropped, trading " +
+			"memory for CPU time. This metric is an overestimate, and not directly " +
+			"comparable to system CPU time measurements. Compare only with " +
+			"/cpu/classes metrics.",
+		Kind:       KindFloat64,
+		Cumulative: true,
+	},
 	{
 		Name:        "/gc/pauses:seconds",
 		Description: "Deprecated. Prefer the identical /sched/pauses/total/gc:seconds.",
@@ -319,6 +338,70 @@ var allDesc = []Description{
 		Kind:        KindUint64,
 		Cumulative:  false,
 	},
+	{
+		Name: "/maps/directory:entries",
+		Description: "Count of directory entries allocated for maps. A map whose entries " +
+			"do not fit in a single table has a directory of tables, which doubles in " +
+			"size when a table that fills it splits. " +
+			"Zero if the program is built with GOEXPERIMENT=noswissmap.",
+		Kind:       KindUint64,
+		Cumulative: true,
+	},
+	{
+		Name: "/maps/groups/unused:bytes",
+		Description: "Memory allocated for map groups beyond their size, because the " +
+			"allocation was rounded up to a size class, or to whole pages for large " +
+			"allocations. The ratio to /maps/groups:bytes is the internal fragmentation " +
+			"of map groups. " +
+			"Zero if the program is built with GOEXPERIMENT=noswissmap.",
+		Kind:       KindUint64,
+		Cumulative: true,
+	},
+	{
+		Name: "/maps/groups:bytes",
+		Description: "Memory of the groups of slots allocated for maps, that is the " +
+			"groups in /maps/groups:groups times their size, which depends on the " +
+			"key and element types of the map. " +
+			"Zero if the program is built with GOEXPERIMENT=noswissmap.",
+		Kind:       KindUint64,
+		Cumulative: true,
+	},
+	{
+		Name: "/maps/groups:groups",
+		Description: "Count of groups of slots allocated for maps, including the single " +
+			"group of small maps. The memory of a group is freed by the garbage " +
+			"collector once its map has grown or is unreachable. " +
+			"Zero if the program is built with GOEXPERIMENT=noswissmap.",
+		Kind:       KindUint64,
+		Cumulative: true,
+	},
+	{
+		Name: "/maps/rehash/entries:entries",
+		Description: "Count of map entries moved into new groups by growing a map, " +
+			"splitting a table, or rehashing a table to discard tombstones. " +
+			"This is a measure of rehashing work, not of the entries in live maps, " +
+			"which the runtime does not track. " +
+			"Zero if the program is built with GOEXPERIMENT=noswissmap.",
+		Kind:       KindUint64,
+		Cumulative: true,
+	},
+	{
+		Name: "/maps/rehash/tombstones:slots",
+		Description: "Count of tombstones, the slots of deleted map entries that " +
+			"could not be reused in place, discarded by growing, splitting, or " +
+			"rehashing tables. Tombstones still present in live maps are not counted. " +
+			"Zero if the program is built with GOEXPERIMENT=noswissmap.",
+		Kind:       KindUint64,
+		Cumulative: true,
+	},
+	{
+		Name: "/maps/slots:slots",
+		Description: "Count of slots allocated for maps, that is /maps/groups:groups " +
+			"times the number of slots in a group. " +
+			"Zero if the program is built with GOEXPERIMENT=noswissmap.",
+		Kind:       KindUint64,
+		Cumulative: true,
+	},
 	{
 		Name: "/memory/classes/heap/free:bytes",
 		Description: "Memory that is completely free and eligible to be returned to the underlying system, " +
@@ -203,6 +203,12 @@ Below is the full list of supported metrics, ordered lexicographically.
 		runtime, only their block. Each block is already accounted for
 		in allocs-by-size and frees-by-size.
 
+	/gc/limiter/enabled:seconds
+		Estimated total wall-clock time during which the GC CPU limiter
+		was enabled. The limiter is enabled and disabled at updates,
+		which happen about every 10 milliseconds while the GC is
+		running, so this metric is only accurate to that granularity.
+
 	/gc/limiter/last-enabled:gc-cycle
 		GC cycle the last time the GC CPU limiter was enabled.
 		This metric is useful for diagnosing the root cause of an
@@ -211,6 +217,13 @@ Below is the full list of supported metrics, ordered lexicographically.
 		to occur with use of SetMemoryLimit. The first GC cycle is cycle
 		1, so a value of 0 indicates that it was never enabled.
 
+	/gc/limiter/overflow:cpu-seconds
+		Estimated total GC CPU time in excess of what the GC CPU limiter
+		allows. This approximates how much GC work the limiter dropped,
+		trading memory for CPU time. This metric is an overestimate,
+		and not directly comparable to system CPU time measurements.
+		Compare only with /cpu/classes metrics.
+
 	/gc/pauses:seconds
 		Deprecated. Prefer the identical /sched/pauses/total/gc:seconds.
 
@@ -344,6 +357,11 @@ Below is the full list of supported metrics, ordered lexicographically.
 		The number of non-default behaviors executed by the crypto/tls
 		package due to a non-default GODEBUG=tlsunsafeekm=... setting.
 
+	/godebug/non-default-behavior/urlhostnamezone:events
+		The number of non-default behaviors executed by the net/url
+		package due to a non-default GODEBUG=urlhostnamezone=...
+		setting.
+
 	/godebug/non-default-behavior/winreadlinkvolume:events
 		The number of non-default behaviors executed by the os package
 		due to a non-default GODEBUG=winreadlinkvolume=... setting.
@@ -381,6 +399,50 @@ Below is the full list of supported metrics, ordered lexicographically.
 		package due to a non-default GODEBUG=zipinsecurepath=...
 		setting.
 
+	/maps/directory:entries
+		Count of directory entries allocated for maps. A map whose
+		entries do not fit in a single table has a directory of tables,
+		which doubles in size when a table that fills it splits. Zero if
+		the program is built with GOEXPERIMENT=noswissmap.
+
+	/maps/groups/unused:bytes
+		Memory allocated for map groups beyond their size, because the
+		allocation was rounded up to a size class, or to whole pages
+		for large allocations. The ratio to /maps/groups:bytes is the
+		internal fragmentation of map groups. Zero if the program is
+		built with GOEXPERIMENT=noswissmap.
+
+	/maps/groups:bytes
+		Memory of the groups of slots allocated for maps, that is the
+		groups in /maps/groups:groups times their size, which depends
+		on the key and element types of the map. Zero if the program is
+		built with GOEXPERIMENT=noswissmap.
+
+	/maps/groups:groups
+		Count of groups of slots allocated for maps, including the
+		single group of small maps. The memory of a group is freed by
+		the garbage collector once its map has grown or is unreachable.
+		Zero if the program is built with GOEXPERIMENT=noswissmap.
+
+	/maps/rehash/entries:entries
+		Count of map entries moved into new groups by growing a map,
+		splitting a table, or rehashing a table to discard tombstones.
+		This is a measure of rehashing work, not of the entries in live
+		maps, which the runtime does not track. Zero if the program is
+		built with GOEXPERIMENT=noswissmap.
+
+	/maps/rehash/tombstones:slots
+		Count of tombstones, the slots of deleted map entries
+		that could not be reused in place, discarded by growing,
+		splitting, or rehashing tables. Tombstones still present in
+		live maps are not counted. Zero if the program is built with
+		GOEXPERIMENT=noswissmap.
+
+	/maps/slots:slots
+		Count of slots allocated for maps, that is /maps/groups:groups
+		times the number of slots in a group. Zero if the program is
+		built with GOEXPERIMENT=noswissmap.
+
 	/memory/classes/heap/free:bytes
 		Memory that is completely free and eligible to be returned to
 		the underlying system, but has not been. This metric is the
@@ -1380,3 +1380,88 @@ func TestMetricHeapUnusedLargeObjectOverflow(t *testing.T) {
 	done <- struct{}{}
 	wg.Wait()
 }
+
+func TestMapMetrics(t *testing.T) {
+	if !goexperiment.SwissMap {
+		t.Skip("map metrics are only maintained for swiss maps")
+	}
+
+	type mapStats struct {
+		directory, groups, groupBytes, groupUnused, entries, tombstones, slots uint64
+	}
+	read := func() mapStats {
+		s := []metrics.Sample{
+			{Name: "/maps/directory:entries"},
+			{Name: "/maps/groups:groups"},
+			{Name: "/maps/groups:bytes"},
+			{Name: "/maps/groups/unused:bytes"},
+			{Name: "/maps/rehash/entries:entries"},
+			{Name: "/maps/rehash/tombstones:slots"},
+			{Name: "/maps/slots:slots"},
+		}
+		metrics.Read(s)
+		return mapStats{
+			directory:   s[0].Value.Uint64(),
+			groups:      s[1].Value.Uint64(),
+			groupBytes:  s[2].Value.Uint64(),
+			groupUnused: s[3].Value.Uint64(),
+			entries:     s[4].Value.Uint64(),
+			tombstones:  s[5].Value.Uint64(),
+			slots:       s[6].Value.Uint64(),
+		}
+	}
+
+	// Other goroutines may use maps too, so the statistics may only
+	// be checked for lower bounds.
+	const n = 10000
+
+	// A presized map allocates its groups and directory up front,
+	// and moves no entries.
+	before := read()
+	m := make(map[int]int, n)
+	after := read()
+	if got, want := after.groups-before.groups, uint64(n/abi.SwissMapGroupSlots); got < want {
+		t.Errorf("make with hint %d allocated %d groups, want at least %d", n, got, want)
+	}
+	if got := after.directory - before.directory; got < 1 {
+		t.Errorf("make with hint %d allocated %d directory entries, want at least 1", n, got)
+	}
+	if after.slots != after.groups*abi.SwissMapGroupSlots {
+		t.Errorf("%d slots, want %d groups times %d", after.slots, after.groups, abi.SwissMapGroupSlots)
+	}
+	// A group of a map[int]int is a control word and slots of two ints.
+	const groupSize = 8 + abi.SwissMapGroupSlots*2*unsafe.Sizeof(int(0))
+	if got, want := after.groupBytes-before.groupBytes, uint64(n/abi.SwissMapGroupSlots*groupSize); got < want {
+		t.Errorf("make with hint %d allocated %d bytes of groups, want at least %d", n, got, want)
+	}
+	if after.groupUnused >= after.groupBytes {
+		t.Errorf("%d unused bytes of groups, want less than the %d bytes of groups", after.groupUnused, after.groupBytes)
+	}
+
+	// A map that grows from empty moves at least the entries that
+	// filled it before its last growth.
+	before = read()
+	g := make(map[int]int)
+	for i := range n {
+		g[i] = i
+	}
+	after = read()
+	if got, want := after.entries-before.entries, uint64(n/2); got < want {
+		t.Errorf("growing a map to %d entries moved %d entries, want at least %d", n, got, want)
+	}
+
+	// Deleting and inserting at a fixed size leaves tombstones, which
+	// are discarded by rehashing.
+	before = read()
+	for i := range 10 * n {
+		delete(g, i)
+		g[n+i] = i
+	}
+	after = read()
+	if after.tombstones == before.tombstones {
+		t.Errorf("churn of a map of %d entries discarded no tombstones", n)
+	}
+
+	runtime.KeepAlive(m)
+	runtime.KeepAlive(g)
+}
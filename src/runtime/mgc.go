@@ -1469,10 +1469,11 @@ func gcBgMarkWorker(ready chan struct{}) {
 
 		startTime := nanotime()
 		pp.gcMarkWorkerStartTime = startTime
-		var trackLimiterEvent bool
+		limiterEvent := limiterEventMarkWork
 		if pp.gcMarkWorkerMode == gcMarkWorkerIdleMode {
-			trackLimiterEvent = pp.limiterEvent.start(limiterEventIdleMarkWork, startTime)
+			limiterEvent = limiterEventIdleMarkWork
 		}
+		trackLimiterEvent := pp.limiterEvent.start(limiterEvent, startTime)
 
 		decnwait := atomic.Xadd(&work.nwait, -1)
 		if decnwait == work.nproc {
@@ -1526,7 +1527,7 @@ func gcBgMarkWorker(ready chan struct{}) {
 		duration := now - startTime
 		gcController.markWorkerStop(pp.gcMarkWorkerMode, duration)
 		if trackLimiterEvent {
-			pp.limiterEvent.stop(limiterEventIdleMarkWork, now)
+			pp.limiterEvent.stop(limiterEvent, now)
 		}
 		if pp.gcMarkWorkerMode == gcMarkWorkerFractionalMode {
 			atomic.Xaddint64(&pp.gcFractionalMarkTime, duration)
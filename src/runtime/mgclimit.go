@@ -34,8 +34,8 @@ type gcCPULimiterState struct {
 
 	enabled atomic.Bool
 
-	// gcEnabled is an internal copy of gcBlackenEnabled that determines
-	// whether the limiter tracks total assist time.
+	// gcEnabled is an internal copy of gcBlackenEnabled, used to check
+	// GC transitions.
 	//
 	// gcBlackenEnabled isn't used directly so as to keep this structure
 	// unit-testable.
@@ -57,11 +57,17 @@ type gcCPULimiterState struct {
 	}
 	// overflow is the cumulative amount of GC CPU time that we tried to fill the
 	// bucket with but exceeded its capacity.
-	overflow uint64
+	//
+	// Updated under lock, but may be read concurrently.
+	overflow atomic.Uint64
 
 	// assistTimePool is the accumulated assist time since the last update.
 	assistTimePool atomic.Int64
 
+	// markWorkerTimePool is the accumulated dedicated and fractional mark
+	// worker time since the last update.
+	markWorkerTimePool atomic.Int64
+
 	// idleMarkTimePool is the accumulated idle mark time since the last update.
 	idleMarkTimePool atomic.Int64
 
@@ -76,6 +82,13 @@ type gcCPULimiterState struct {
 	// lastEnabledCycle is the GC cycle that last had the limiter enabled.
 	lastEnabledCycle atomic.Uint32
 
+	// enabledTime is the cumulative wall-clock time in nanoseconds during
+	// which the limiter was enabled. The limiter is only enabled or disabled
+	// when it's updated, so it's accurate to within an update period.
+	//
+	// Updated under lock, but may be read concurrently.
+	enabledTime atomic.Int64
+
 	// nprocs is an internal copy of gomaxprocs, used to determine total available
 	// CPU time.
 	//
@@ -91,6 +104,36 @@ func (l *gcCPULimiterState) limiting() bool {
 	return l.enabled.Load()
 }
 
+// overflowSeconds returns the cumulative GC CPU time, in CPU seconds, that
+// didn't fit in the bucket. It estimates how much GC work the limiter
+// dropped on the floor.
+//
+// It is safe to call concurrently with other operations, and never
+// blocks on the limiter's lock.
+func (l *gcCPULimiterState) overflowSeconds() float64 {
+	return nsToSec(int64(l.overflow.Load()))
+}
+
+// enabledSeconds returns the cumulative wall-clock time, in seconds, during
+// which the limiter was enabled.
+//
+// It is safe to call concurrently with other operations, and never
+// blocks on the limiter's lock.
+func (l *gcCPULimiterState) enabledSeconds() float64 {
+	return nsToSec(l.enabledTime.Load())
+}
+
+// printState prints a one-line summary of the limiter's state, for
+// crash output. It does not take the limiter's lock, so the summary
+// may be inconsistent if the limiter is being updated concurrently.
+//
+//go:nowritebarrierrec
+func (l *gcCPULimiterState) printState() {
+	print("gc cpu limiter: enabled=", l.enabled.Load(),
+		" fill=", l.bucket.fill, "/", l.bucket.capacity,
+		" overflow=", l.overflow.Load(), "\n")
+}
+
 // startGCTransition notifies the limiter of a GC transition.
 //
 // This call takes ownership of the limiter and disables all other means of
@@ -128,6 +171,9 @@ func (l *gcCPULimiterState) finishGCTransition(now int64) {
 	// isn't running on all CPUs, it is preventing user code from doing so,
 	// so it might as well be.
 	if lastUpdate := l.lastUpdate.Load(); now >= lastUpdate {
+		if l.enabled.Load() {
+			l.enabledTime.Add(now - lastUpdate)
+		}
 		l.accumulate(0, (now-lastUpdate)*int64(l.nprocs))
 	}
 	l.lastUpdate.Store(now)
@@ -151,6 +197,18 @@ func (l *gcCPULimiterState) addAssistTime(t int64) {
 	l.assistTimePool.Add(t)
 }
 
+// addMarkWorkerTime notifies the limiter of additional time dedicated and
+// fractional mark workers spent marking. It will be included in the next update.
+func (l *gcCPULimiterState) addMarkWorkerTime(t int64) {
+	l.markWorkerTimePool.Add(t)
+}
+
+// addIdleMarkTime notifies the limiter of additional time idle priority mark
+// workers spent marking. It will be counted as mutator time in the next update.
+func (l *gcCPULimiterState) addIdleMarkTime(t int64) {
+	l.idleMarkTimePool.Add(t)
+}
+
 // addIdleTime notifies the limiter of additional time a P spent on the idle list. It will be
 // subtracted from the total CPU time in the next update.
 func (l *gcCPULimiterState) addIdleTime(t int64) {
@@ -185,12 +243,30 @@ func (l *gcCPULimiterState) updateLocked(now int64) {
 	windowTotalTime := (now - lastUpdate) * int64(l.nprocs)
 	l.lastUpdate.Store(now)
 
+	// Attribute the window to the enabled state if the limiter was enabled
+	// for it, even if it stays enabled afterward.
+	if l.enabled.Load() {
+		l.enabledTime.Add(now - lastUpdate)
+	}
+
 	// Drain the pool of assist time.
 	assistTime := l.assistTimePool.Load()
 	if assistTime != 0 {
 		l.assistTimePool.Add(-assistTime)
 	}
 
+	// Drain the pool of mark worker time.
+	markWorkerTime := l.markWorkerTimePool.Load()
+	if markWorkerTime != 0 {
+		l.markWorkerTimePool.Add(-markWorkerTime)
+	}
+
+	// Drain the pool of idle mark time.
+	idleMarkTime := l.idleMarkTimePool.Load()
+	if idleMarkTime != 0 {
+		l.idleMarkTimePool.Add(-idleMarkTime)
+	}
+
 	// Drain the pool of idle time.
 	idleTime := l.idleTimePool.Load()
 	if idleTime != 0 {
@@ -209,8 +285,11 @@ func (l *gcCPULimiterState) updateLocked(now int64) {
 		for _, pp := range allp {
 			typ, duration := pp.limiterEvent.consume(now)
 			switch typ {
+			case limiterEventMarkWork:
+				markWorkerTime += duration
 			case limiterEventIdleMarkWork:
-				fallthrough
+				idleMarkTime += duration
+				sched.idleTime.Add(duration)
 			case limiterEventIdle:
 				idleTime += duration
 				sched.idleTime.Add(duration)
@@ -227,18 +306,16 @@ func (l *gcCPULimiterState) updateLocked(now int64) {
 		releasem(mp)
 	}
 
-	// Compute total GC time.
-	windowGCTime := assistTime
-	if l.gcEnabled {
-		windowGCTime += int64(float64(windowTotalTime) * gcBackgroundUtilization)
-	}
+	// Compute total GC time. Dedicated and fractional mark workers usually add
+	// up to gcBackgroundUtilization of the total time, but the OS may deschedule
+	// them, and fractional workers may run for more or less than their goal, so
+	// use the time they were measured to run for.
+	windowGCTime := assistTime + markWorkerTime
 
-	// Subtract out all idle time from the total time. Do this after computing
-	// GC time, because the background utilization is dependent on the *real*
-	// total time, not the total time after idle time is subtracted.
+	// Subtract out all idle time from the total time.
 	//
-	// Idle time is counted as any time that a P is on the P idle list plus idle mark
-	// time. Idle mark workers soak up time that the application spends idle.
+	// Idle time is counted as any time that a P is on the P idle list. Idle mark
+	// time is subtracted too, but only to be counted as mutator time below.
 	//
 	// On a heavily undersubscribed system, any additional idle time can skew GC CPU
 	// utilization, because the GC might be executing continuously and thrashing,
@@ -254,9 +331,16 @@ func (l *gcCPULimiterState) updateLocked(now int64) {
 	// be 8/(8+2) = 80%. Even though the limiter turns on, though, assist should be
 	// unnecessary, as the GC has way more CPU time to outpace the 1 goroutine that's
 	// running.
-	windowTotalTime -= idleTime
-
-	l.accumulate(windowTotalTime-windowGCTime, windowGCTime)
+	windowTotalTime -= idleTime + idleMarkTime
+
+	// Idle mark workers only run on Ps that have no goroutines to run, so they
+	// can't be starving the application. They're not GC time, or the limiter
+	// would turn on for a GC that's merely making use of a quiet machine. They're
+	// not idle time either, because subtracting them from the total would leave
+	// just the dedicated workers and the few running goroutines, with the same
+	// effect. Count them as mutator time instead, which matches the pacer, whose
+	// GC CPU utilization leaves out idle mark work.
+	l.accumulate(windowTotalTime-windowGCTime+idleMarkTime, windowGCTime)
 }
 
 // accumulate adds time to the bucket and signals whether the limiter is enabled.
@@ -276,7 +360,7 @@ func (l *gcCPULimiterState) accumulate(mutatorTime, gcTime int64) {
 
 	// Handle limiting case.
 	if change > 0 && headroom <= uint64(change) {
-		l.overflow += uint64(change) - headroom
+		l.overflow.Add(change - int64(headroom))
 		l.bucket.fill = l.bucket.capacity
 		if !enabled {
 			l.enabled.Store(true)
@@ -353,6 +437,7 @@ const (
 	limiterEventMarkAssist                             // Refers to mark assist (see gcAssistAlloc).
 	limiterEventScavengeAssist                         // Refers to a scavenge assist (see allocSpan).
 	limiterEventIdle                                   // Refers to time a P spent on the idle list.
+	limiterEventMarkWork                               // Refers to a dedicated or fractional mark worker (see gcMarkWorkerMode).
 
 	limiterEventBits = 3
 )
@@ -470,8 +555,10 @@ func (e *limiterEvent) stop(typ limiterEventType, now int64) {
 	}
 	// Account for the event.
 	switch typ {
+	case limiterEventMarkWork:
+		gcCPULimiter.addMarkWorkerTime(duration)
 	case limiterEventIdleMarkWork:
-		gcCPULimiter.addIdleTime(duration)
+		gcCPULimiter.addIdleMarkTime(duration)
 	case limiterEventIdle:
 		gcCPULimiter.addIdleTime(duration)
 		sched.idleTime.Add(duration)
@@ -28,6 +28,13 @@ func TestGCCPULimiter(t *testing.T) {
 		return int64(frac * float64(d) * procs)
 	}
 
+	// markWorkerTime computes the CPU time for dedicated mark workers running
+	// on GCBackgroundUtilization of GOMAXPROCS over the wall-clock duration d.
+	markWorkerTime := func(d time.Duration) int64 {
+		t.Helper()
+		return int64(GCBackgroundUtilization * float64(d) * procs)
+	}
+
 	l := NewGCCPULimiter(ticks, procs)
 
 	// Do the whole test twice to make sure state doesn't leak across.
@@ -87,12 +94,15 @@ func TestGCCPULimiter(t *testing.T) {
 		// And here we want n=procs:
 		factor := (1 / (1 - 2*GCBackgroundUtilization))
 		fill := (2*time.Millisecond + 1*time.Microsecond) * procs
-		l.Update(advance(time.Duration(factor * float64(fill-procs) / procs)))
+		window := time.Duration(factor * float64(fill-procs) / procs)
+		l.AddMarkWorkerTime(markWorkerTime(window))
+		l.Update(advance(window))
 		if l.Fill() != procs {
 			t.Fatalf("expected fill %d cpu-ns from draining after a GC started, got fill of %d cpu-ns", procs, l.Fill())
 		}
 
 		// Drain to zero for the rest of the test.
+		l.AddMarkWorkerTime(markWorkerTime(2 * procs * CapacityPerProc))
 		l.Update(advance(2 * procs * CapacityPerProc))
 		if l.Fill() != 0 {
 			t.Fatalf("expected empty bucket from draining, got fill of %d cpu-ns", l.Fill())
@@ -100,6 +110,7 @@ func TestGCCPULimiter(t *testing.T) {
 
 		// Test filling up the bucket with 50% total GC work (so, not moving the bucket at all).
 		l.AddAssistTime(assistTime(10*time.Millisecond, 0.5-GCBackgroundUtilization))
+		l.AddMarkWorkerTime(markWorkerTime(10 * time.Millisecond))
 		l.Update(advance(10 * time.Millisecond))
 		if l.Fill() != 0 {
 			t.Fatalf("expected empty bucket from 50%% GC work, got fill of %d cpu-ns", l.Fill())
@@ -107,6 +118,7 @@ func TestGCCPULimiter(t *testing.T) {
 
 		// Test adding to the bucket overall with 100% GC work.
 		l.AddAssistTime(assistTime(time.Millisecond, 1.0-GCBackgroundUtilization))
+		l.AddMarkWorkerTime(markWorkerTime(time.Millisecond))
 		l.Update(advance(time.Millisecond))
 		if expect := uint64(procs * time.Millisecond); l.Fill() != expect {
 			t.Errorf("expected %d fill from 100%% GC CPU, got fill of %d cpu-ns", expect, l.Fill())
@@ -120,6 +132,7 @@ func TestGCCPULimiter(t *testing.T) {
 
 		// Test filling the bucket exactly full.
 		l.AddAssistTime(assistTime(CapacityPerProc-time.Millisecond, 1.0-GCBackgroundUtilization))
+		l.AddMarkWorkerTime(markWorkerTime(CapacityPerProc - time.Millisecond))
 		l.Update(advance(CapacityPerProc - time.Millisecond))
 		if l.Fill() != l.Capacity() {
 			t.Errorf("expected bucket filled to capacity %d, got %d", l.Capacity(), l.Fill())
@@ -137,6 +150,7 @@ func TestGCCPULimiter(t *testing.T) {
 		// Test adding with a delta of exactly zero. That is, GC work is exactly 50% of all resources.
 		// Specifically, the limiter should still be on, and no overflow should accumulate.
 		l.AddAssistTime(assistTime(1*time.Second, 0.5-GCBackgroundUtilization))
+		l.AddMarkWorkerTime(markWorkerTime(1 * time.Second))
 		l.Update(advance(1 * time.Second))
 		if l.Fill() != l.Capacity() {
 			t.Errorf("expected bucket filled to capacity %d, got %d", l.Capacity(), l.Fill())
@@ -153,6 +167,7 @@ func TestGCCPULimiter(t *testing.T) {
 
 		// Drain the bucket by half.
 		l.AddAssistTime(assistTime(CapacityPerProc, 0))
+		l.AddMarkWorkerTime(markWorkerTime(CapacityPerProc))
 		l.Update(advance(CapacityPerProc))
 		if expect := l.Capacity() / 2; l.Fill() != expect {
 			t.Errorf("failed to drain to %d, got fill %d", expect, l.Fill())
@@ -165,7 +180,9 @@ func TestGCCPULimiter(t *testing.T) {
 		}
 
 		// Test overfilling the bucket.
+		overflowSeconds := l.OverflowSeconds()
 		l.AddAssistTime(assistTime(CapacityPerProc, 1.0-GCBackgroundUtilization))
+		l.AddMarkWorkerTime(markWorkerTime(CapacityPerProc))
 		l.Update(advance(CapacityPerProc))
 		if l.Fill() != l.Capacity() {
 			t.Errorf("failed to fill to capacity %d, got fill %d", l.Capacity(), l.Fill())
@@ -176,12 +193,16 @@ func TestGCCPULimiter(t *testing.T) {
 		if expect := uint64(CapacityPerProc * procs / 2); l.Overflow() != expect+baseOverflow {
 			t.Errorf("bucket overfilled should have overflow %d, found %d", expect, l.Overflow())
 		}
+		if l.OverflowSeconds() <= overflowSeconds {
+			t.Errorf("overflow metric did not increase after overfill: was %f, got %f", overflowSeconds, l.OverflowSeconds())
+		}
 		if t.Failed() {
 			t.FailNow()
 		}
 
 		// Test ending the cycle with some assists left over.
 		l.AddAssistTime(assistTime(1*time.Millisecond, 1.0-GCBackgroundUtilization))
+		l.AddMarkWorkerTime(markWorkerTime(1 * time.Millisecond))
 		l.StartGCTransition(false, advance(1*time.Millisecond))
 		if l.Fill() != l.Capacity() {
 			t.Errorf("failed to maintain fill to capacity %d, got fill %d", l.Capacity(), l.Fill())
@@ -253,3 +274,200 @@ func TestGCCPULimiter(t *testing.T) {
 		baseOverflow += uint64((CapacityPerProc/2 + 6*time.Millisecond) * procs)
 	}
 }
+
+func TestGCCPULimiterIdleMark(t *testing.T) {
+	const procs = 8
+	const d = 10 * time.Millisecond
+
+	// window describes CPU time spent over d of wall-clock time while the GC
+	// is running, in units of d on one P. The dedicated workers make up
+	// GCBackgroundUtilization of the total, and the mutator the rest.
+	const markWorker = GCBackgroundUtilization * procs
+	// fill is the expected fill of the bucket afterward, in the same units.
+	type window struct {
+		assist, idle, idleMark int64
+		fill                   uint64
+	}
+	for _, test := range []struct {
+		name    string
+		windows []window
+	}{
+		{"Mutator", []window{{fill: 0}}},
+		// Idle mark workers on otherwise idle Ps don't starve the mutator.
+		{"IdleMark", []window{{idleMark: 5, fill: 0}}},
+		// Ps on the idle list shrink the total, leaving the dedicated
+		// workers with more CPU time than the mutator.
+		{"Idle", []window{{idle: 5, fill: 1}}},
+		{"IdleAndIdleMark", []window{{idle: 3, idleMark: 2, fill: 0}}},
+		{"Assist", []window{{assist: 3, fill: 2}}},
+		// Idle mark time counts as mutator time, so the bucket fills as
+		// much as without any idle Ps.
+		{"AssistAndIdleMark", []window{{assist: 3, idleMark: 3, fill: 2}}},
+		{"AssistAndIdle", []window{{assist: 3, idle: 3, fill: 5}}},
+		{"Trajectory", []window{
+			{assist: 3, fill: 2},
+			{assist: 3, fill: 4},
+			{idleMark: 5, fill: 0},
+			{assist: 1, idleMark: 4, fill: 0},
+			{idle: 5, fill: 1},
+			{assist: 2, idle: 2, idleMark: 2, fill: 3},
+		}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			ticks := int64(0)
+			l := NewGCCPULimiter(ticks, procs)
+			l.StartGCTransition(true, ticks)
+			l.FinishGCTransition(ticks)
+
+			for i, w := range test.windows {
+				l.AddMarkWorkerTime(markWorker * int64(d))
+				l.AddAssistTime(w.assist * int64(d))
+				l.AddIdleTime(w.idle * int64(d))
+				l.AddIdleMarkTime(w.idleMark * int64(d))
+				ticks += int64(d)
+				l.Update(ticks)
+				if want := w.fill * uint64(d); l.Fill() != want {
+					t.Errorf("window %d: got fill %d, want %d", i, l.Fill(), want)
+				}
+				if l.Limiting() {
+					t.Errorf("window %d: limiter is enabled with fill %d of capacity %d", i, l.Fill(), l.Capacity())
+				}
+			}
+		})
+	}
+}
+
+func TestGCCPULimiterEnabledTime(t *testing.T) {
+	const procs = 4
+	const period = GCCPULimiterUpdatePeriod
+
+	// Create mock time.
+	ticks := int64(0)
+	advance := func(d time.Duration) int64 {
+		t.Helper()
+		ticks += int64(d)
+		return ticks
+	}
+
+	// gcTime computes the CPU time for GC work that takes up all of
+	// GOMAXPROCS over the wall-clock duration d.
+	gcTime := func(d time.Duration) int64 {
+		return int64(d) * procs
+	}
+
+	l := NewGCCPULimiter(ticks, procs)
+	l.StartGCTransition(true, advance(0))
+	l.FinishGCTransition(advance(0))
+
+	// Fill the bucket, which enables the limiter.
+	l.AddAssistTime(gcTime(CapacityPerProc))
+	l.Update(advance(CapacityPerProc))
+	if !l.Limiting() {
+		t.Fatalf("limiter is not enabled after filling the bucket")
+	}
+	if l.EnabledTime() != 0 {
+		t.Fatalf("limiter was enabled for %d ns before it was enabled", l.EnabledTime())
+	}
+	enabledAt := ticks
+
+	// Keep the limiter enabled for a while with regular updates, as
+	// during a GC cycle, then across the transition to the sweep phase.
+	for ticks-enabledAt < int64(2*time.Second) {
+		l.AddMarkWorkerTime(gcTime(period))
+		l.Update(advance(period))
+	}
+	l.StartGCTransition(false, advance(0))
+	l.FinishGCTransition(advance(time.Millisecond))
+	if !l.Limiting() {
+		t.Fatalf("limiter is not enabled after a period of limiting")
+	}
+	limitedFor := time.Duration(ticks - enabledAt)
+	if got := time.Duration(l.EnabledTime()); got != limitedFor {
+		t.Errorf("limiter enabled for %v while limiting, want %v", got, limitedFor)
+	}
+
+	// Drain the bucket with mutator time. The limiter is disabled at the
+	// first update, which accounts for the update period before it.
+	l.Update(advance(period))
+	if l.Limiting() {
+		t.Fatalf("limiter is enabled after draining the bucket")
+	}
+	disabledAt := ticks
+	for range 10 {
+		l.Update(advance(period))
+	}
+	if got, want := l.EnabledTime(), disabledAt-enabledAt; got != want {
+		t.Errorf("limiter enabled for %d ns, want %d", got, want)
+	}
+	if got := time.Duration(l.EnabledTime()); got < limitedFor || got > limitedFor+period {
+		t.Errorf("limiter enabled for %v, want %v within %v", got, limitedFor, time.Duration(period))
+	}
+	if got, want := l.EnabledSeconds(), time.Duration(l.EnabledTime()).Seconds(); got != want {
+		t.Errorf("enabled time metric is %f seconds, want %f", got, want)
+	}
+}
+
+func TestGCCPULimiterMarkWorkers(t *testing.T) {
+	const procs = 8
+	const d = 10 * time.Millisecond
+
+	// window describes CPU time spent over d of wall-clock time while the GC
+	// is running, in units of d on one P, and the expected fill of the bucket
+	// afterward. The mutator gets all the time the GC doesn't.
+	type window struct {
+		markWorker, assist int64
+		fill               uint64
+	}
+	for _, test := range []struct {
+		name    string
+		windows []window
+	}{
+		// Mark workers on GCBackgroundUtilization of GOMAXPROCS.
+		{"Background", []window{{markWorker: 2, fill: 0}}},
+		{"BackgroundAndAssist", []window{{markWorker: 2, assist: 3, fill: 2}}},
+		// Descheduled mark workers leave more time to the mutator.
+		{"Descheduled", []window{
+			{markWorker: 2, assist: 3, fill: 2},
+			{markWorker: 0, assist: 3, fill: 0},
+		}},
+		// Mark workers running past their goal take time from the mutator.
+		{"Busy", []window{
+			{markWorker: 6, fill: 4},
+			{markWorker: 4, assist: 2, fill: 8},
+			{markWorker: 5, assist: 1, fill: 12},
+			{markWorker: 1, fill: 6},
+		}},
+		{"All", []window{{markWorker: 8, fill: 8}}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			ticks := int64(0)
+			l := NewGCCPULimiter(ticks, procs)
+			l.StartGCTransition(true, ticks)
+			l.FinishGCTransition(ticks)
+
+			for i, w := range test.windows {
+				l.AddMarkWorkerTime(w.markWorker * int64(d))
+				l.AddAssistTime(w.assist * int64(d))
+				ticks += int64(d)
+				l.Update(ticks)
+				if want := w.fill * uint64(d); l.Fill() != want {
+					t.Errorf("window %d: got fill %d, want %d", i, l.Fill(), want)
+				}
+			}
+		})
+	}
+
+	// Mark workers that take up all of GOMAXPROCS fill the bucket and
+	// enable the limiter, even without assists.
+	l := NewGCCPULimiter(0, procs)
+	l.StartGCTransition(true, 0)
+	l.FinishGCTransition(0)
+	l.AddMarkWorkerTime(procs * int64(CapacityPerProc))
+	l.Update(int64(CapacityPerProc))
+	if l.Fill() != l.Capacity() {
+		t.Errorf("got fill %d from mark workers alone, want capacity %d", l.Fill(), l.Capacity())
+	}
+	if !l.Limiting() {
+		t.Errorf("limiter is not enabled after mark workers filled the bucket")
+	}
+}
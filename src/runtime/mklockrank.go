@@ -101,6 +101,9 @@ hchan, root, timers, timer, notifyList, reflectOffs < synctest;
 # User arena state
 NONE < userArenaState;
 
+# Exit hooks
+NONE < exitHooks;
+
 # Tracing without a P uses a global trace buffer.
 scavenge
 # Above TRACEGLOBAL can emit a trace event without a P.
@@ -118,6 +121,7 @@ allg,
   allp, # procresize
   execR, # May grow stack
   execW, # May allocate after BeforeFork
+  exitHooks,
   hchan,
   notifyList,
   reflectOffs,
@@ -146,6 +146,7 @@ func osinit() {
 
 	ncpu = getncpu()
 	physPageSize = getPageSize()
+	osCacheLineSize = getCacheLineSize()
 
 	osinit_hack()
 }
@@ -163,9 +164,10 @@ func internal_cpu_getsysctlbyname(name []byte) (int32, int32) {
 }
 
 const (
-	_CTL_HW      = 6
-	_HW_NCPU     = 3
-	_HW_PAGESIZE = 7
+	_CTL_HW       = 6
+	_HW_NCPU      = 3
+	_HW_PAGESIZE  = 7
+	_HW_CACHELINE = 10
 )
 
 func getncpu() int32 {
@@ -192,6 +194,18 @@ func getPageSize() uintptr {
 	return 0
 }
 
+func getCacheLineSize() uintptr {
+	// Use sysctl to fetch hw.cachelinesize.
+	mib := [2]uint32{_CTL_HW, _HW_CACHELINE}
+	out := uint32(0)
+	nout := unsafe.Sizeof(out)
+	ret := sysctl(&mib[0], 2, (*byte)(unsafe.Pointer(&out)), &nout, nil, 0)
+	if ret >= 0 && int32(out) > 0 {
+		return uintptr(out)
+	}
+	return 0
+}
+
 //go:nosplit
 func readRandom(r []byte) int {
 	arc4random_buf(unsafe.Pointer(&r[0]), int32(len(r)))
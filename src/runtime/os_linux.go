@@ -329,8 +329,23 @@ func sysauxv(auxv []uintptr) (pairs int) {
 var sysTHPSizePath = []byte("/sys/kernel/mm/transparent_hugepage/hpage_pmd_size\x00")
 
 func getHugePageSize() uintptr {
+	return readPowerOfTwo(sysTHPSizePath)
+}
+
+// sysCacheLineSizePath is the line size of the first cache of CPU 0,
+// which Linux lists as the L1 data cache.
+var sysCacheLineSizePath = []byte("/sys/devices/system/cpu/cpu0/cache/index0/coherency_line_size\x00")
+
+func getCacheLineSize() uintptr {
+	return readPowerOfTwo(sysCacheLineSizePath)
+}
+
+// readPowerOfTwo returns the number in the file at path, a
+// NUL-terminated byte slice, or 0 if the file cannot be read or does not
+// hold a power of two.
+func readPowerOfTwo(path []byte) uintptr {
 	var numbuf [20]byte
-	fd := open(&sysTHPSizePath[0], 0 /* O_RDONLY */, 0)
+	fd := open(&path[0], 0 /* O_RDONLY */, 0)
 	if fd < 0 {
 		return 0
 	}
@@ -355,6 +370,7 @@ func getHugePageSize() uintptr {
 func osinit() {
 	ncpu = getproccount()
 	physHugePageSize = getHugePageSize()
+	osCacheLineSize = getCacheLineSize()
 	osArchInit()
 	vgetrandomInit()
 }
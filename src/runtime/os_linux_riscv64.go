@@ -4,4 +4,16 @@
 
 package runtime
 
-func osArchInit() {}
+import (
+	"internal/cpu"
+	"internal/runtime/syscall"
+	"unsafe"
+)
+
+func osArchInit() {
+	// Ask the kernel which extensions may be used in user space, for
+	// internal/cpu to pick up in cpuinit. riscv_hwprobe was added in
+	// Linux 6.4; on older kernels the call fails and internal/cpu
+	// reports no extensions.
+	syscall.Syscall6(syscall.SYS_RISCV_HWPROBE, uintptr(unsafe.Pointer(&cpu.HWProbe[0])), uintptr(len(cpu.HWProbe)), 0, 0, 0, 0)
+}
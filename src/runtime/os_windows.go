@@ -491,6 +491,8 @@ func osinit() {
 
 	physPageSize = getPageSize()
 
+	osArchInit()
+
 	// Windows dynamic priority boosting assumes that a process has different types
 	// of dedicated threads -- GUI, IO, computational, etc. Go processes use
 	// equivalent threads that all do a mix of GUI, IO, computations, etc.
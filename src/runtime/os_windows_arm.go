@@ -20,3 +20,5 @@ func checkgoarm() {
 		exit(1)
 	}
 }
+
+func osArchInit() {}
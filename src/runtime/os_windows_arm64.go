@@ -4,7 +4,22 @@
 
 package runtime
 
-import "unsafe"
+import (
+	"internal/cpu"
+	"unsafe"
+)
+
+//go:cgo_import_dynamic runtime._IsProcessorFeaturePresent IsProcessorFeaturePresent%1 "kernel32.dll"
+
+var _IsProcessorFeaturePresent stdFunction
+
+var advapi32dll = [...]uint16{'a', 'd', 'v', 'a', 'p', 'i', '3', '2', '.', 'd', 'l', 'l', 0}
+
+const (
+	_HKEY_LOCAL_MACHINE = 0x80000002
+	_KEY_QUERY_VALUE    = 0x0001
+	_REG_QWORD          = 11
+)
 
 //go:nosplit
 func cputicks() int64 {
@@ -12,3 +27,62 @@ func cputicks() int64 {
 	stdcall1(_QueryPerformanceCounter, uintptr(unsafe.Pointer(&counter)))
 	return counter
 }
+
+// osArchInit collects CPU feature information for internal/cpu, which
+// cannot read the ID registers from user mode on Windows.
+func osArchInit() {
+	for pf := uintptr(0); pf < 64; pf++ {
+		if stdcall1(_IsProcessorFeaturePresent, pf)&0xff != 0 {
+			cpu.WindowsProcessorFeatures |= 1 << pf
+		}
+	}
+
+	// Windows mirrors some of the ID registers of each processor in
+	// the registry as "CP <encoding>" values. They are not documented,
+	// so treat them as optional.
+	advapi32 := windowsLoadSystemLib(advapi32dll[:])
+	if advapi32 == 0 {
+		return
+	}
+	regOpenKeyExW := windowsFindfunc(advapi32, []byte("RegOpenKeyExW\000"))
+	regQueryValueExW := windowsFindfunc(advapi32, []byte("RegQueryValueExW\000"))
+	regCloseKey := windowsFindfunc(advapi32, []byte("RegCloseKey\000"))
+	if regOpenKeyExW == nil || regQueryValueExW == nil || regCloseKey == nil {
+		return
+	}
+
+	var buf [64]uint16
+	var key uintptr
+	path := asciiToUTF16(buf[:], `HARDWARE\DESCRIPTION\System\CentralProcessor\0`)
+	if stdcall5(regOpenKeyExW, _HKEY_LOCAL_MACHINE, uintptr(unsafe.Pointer(&path[0])), 0, _KEY_QUERY_VALUE, uintptr(unsafe.Pointer(&key))) != 0 {
+		return
+	}
+	read := func(name string) (uint64, bool) {
+		var val uint64
+		var typ uint32
+		size := uint32(unsafe.Sizeof(val))
+		n := asciiToUTF16(buf[:], name)
+		r := stdcall6(regQueryValueExW, key, uintptr(unsafe.Pointer(&n[0])), 0,
+			uintptr(unsafe.Pointer(&typ)), uintptr(unsafe.Pointer(&val)), uintptr(unsafe.Pointer(&size)))
+		return val, r == 0 && typ == _REG_QWORD && size == uint32(unsafe.Sizeof(val))
+	}
+	isar0, ok0 := read("CP 4030") // ID_AA64ISAR0_EL1
+	pfr0, ok1 := read("CP 4020")  // ID_AA64PFR0_EL1
+	zfr0, _ := read("CP 4024")    // ID_AA64ZFR0_EL1
+	stdcall1(regCloseKey, key)
+
+	if ok0 && ok1 {
+		cpu.WindowsISAR0, cpu.WindowsPFR0, cpu.WindowsZFR0 = isar0, pfr0, zfr0
+		cpu.WindowsIDRegisters = true
+	}
+}
+
+// asciiToUTF16 stores s, which must be ASCII, as a NUL-terminated
+// UTF-16 string in buf and returns that part of buf.
+func asciiToUTF16(buf []uint16, s string) []uint16 {
+	for i := 0; i < len(s); i++ {
+		buf[i] = uint16(s[i])
+	}
+	buf[len(s)] = 0
+	return buf[:len(s)+1]
+}
@@ -1408,6 +1408,11 @@ func dopanic_m(gp *g, pc, sp uintptr) bool {
 	}
 
 	level, all, docrash := gotraceback()
+	if level >= 2 {
+		printCPUFeatures()
+		gcCPULimiter.printState()
+		printHeldLockRanks(gp.m)
+	}
 	if level > 0 {
 		if gp != gp.m.curg {
 			all = true
@@ -28,6 +28,7 @@ func writeHeapProto(w io.Writer, p []profilerecord.MemProfileRecord, rate int64,
 	values := []int64{0, 0, 0, 0}
 	var locs []uint64
 	for _, r := range p {
+		mapGroups := allocatesMapGroups(r.Stack)
 		hideRuntime := true
 		for tries := 0; tries < 2; tries++ {
 			stk := r.Stack
@@ -61,12 +62,39 @@ func writeHeapProto(w io.Writer, p []profilerecord.MemProfileRecord, rate int64,
 			if blockSize != 0 {
 				b.pbLabel(tagSample_Label, "bytes", "", blockSize)
 			}
+			if mapGroups {
+				b.pbLabel(tagSample_Label, "object", "map groups", 0)
+			}
 		})
 	}
 	b.build()
 	return nil
 }
 
+// allocatesMapGroups reports whether stk is the stack of an allocation of
+// the groups of a map. The runtime frames of such a stack are hidden like
+// those of any allocation, so that the memory is attributed to the
+// function that uses the map; the sample is labeled object=map groups
+// instead, so that it can still be told apart, for example with
+// pprof -tagfocus.
+func allocatesMapGroups(stk []uintptr) bool {
+	for _, addr := range stk {
+		f := runtime.FuncForPC(addr)
+		if f == nil {
+			continue
+		}
+		switch name := f.Name(); {
+		case name == "internal/runtime/maps.newarray":
+			// All groups, and nothing else, are allocated
+			// through it.
+			return true
+		case !strings.HasPrefix(name, "runtime.") && !strings.HasPrefix(name, "internal/runtime/"):
+			return false
+		}
+	}
+	return false
+}
+
 // scaleHeapSample adjusts the data from a heap Sample to
 // account for its probability of appearing in the collected
 // data. heap profiles are a sampling of the memory allocations
@@ -10,7 +10,6 @@ import (
 	"internal/goarch"
 	"internal/goos"
 	"internal/runtime/atomic"
-	"internal/runtime/exithook"
 	"internal/runtime/sys"
 	"internal/stringslite"
 	"unsafe"
@@ -282,7 +281,10 @@ func main() {
 	fn := main_main // make an indirect call, as the linker doesn't know the address of the main package when laying down the runtime
 	fn()
 	if raceenabled {
-		runExitHooks(0) // run hooks now, since racefini does not return
+		// Run hooks now, since racefini does not return.
+		if code := runExitHooks(0); code != 0 {
+			exit(int32(code))
+		}
 		racefini()
 	}
 
@@ -302,33 +304,23 @@ func main() {
 	if panicking.Load() != 0 {
 		gopark(nil, nil, waitReasonPanicWait, traceBlockForever, 1)
 	}
-	runExitHooks(0)
-
-	exit(0)
+	exit(int32(runExitHooks(0)))
 	for {
 		var x *int32
 		*x = 0
 	}
 }
 
-// os_beforeExit is called from os.Exit(0).
+// os_beforeExit is called from os.Exit. It returns the code to exit
+// with, which the exit hooks may have changed.
 //
 //go:linkname os_beforeExit os.runtime_beforeExit
-func os_beforeExit(exitCode int) {
-	runExitHooks(exitCode)
+func os_beforeExit(exitCode int) int {
+	exitCode = runExitHooks(exitCode)
 	if exitCode == 0 && raceenabled {
 		racefini()
 	}
-}
-
-func init() {
-	exithook.Gosched = Gosched
-	exithook.Goid = func() uint64 { return getg().goid }
-	exithook.Throw = throw
-}
-
-func runExitHooks(code int) {
-	exithook.Run(code)
+	return exitCode
 }
 
 // start forcegc helper goroutine
@@ -730,12 +722,19 @@ const (
 
 // cpuinit sets up CPU feature flags and calls internal/cpu.Initialize. env should be the complete
 // value of the GODEBUG environment variable.
+// osCacheLineSize is the size in bytes of the data cache lines as
+// reported by the OS, or 0 if it is not known. If set, it must be set by
+// the OS init code (typically in osinit) before cpuinit, which uses it
+// if the processor does not report the line size itself.
+var osCacheLineSize uintptr
+
 func cpuinit(env string) {
 	switch GOOS {
 	case "aix", "darwin", "ios", "dragonfly", "freebsd", "netbsd", "openbsd", "illumos", "solaris", "linux":
 		cpu.DebugOptions = true
 	}
 	cpu.Initialize(env)
+	cpu.SetOSCacheLineSize(osCacheLineSize)
 
 	// Support cpu feature variables are used in code generated by the compiler
 	// to guard execution of instructions that can not be assumed to be always supported.
@@ -804,6 +803,7 @@ func schedinit() {
 	lockInit(&paniclk, lockRankPanic)
 	lockInit(&allglock, lockRankAllg)
 	lockInit(&allpLock, lockRankAllp)
+	lockInit(&exitHooksLock, lockRankExitHooks)
 	lockInit(&reflectOffs.lock, lockRankReflectOffs)
 	lockInit(&finlock, lockRankFin)
 	lockInit(&cpuprof.lock, lockRankCpuprof)
@@ -1377,6 +1377,7 @@ const (
 	stwForTestReadMemStatsSlow                      // "ReadMemStatsSlow (test)"
 	stwForTestPageCachePagesLeaked                  // "PageCachePagesLeaked (test)"
 	stwForTestResetDebugLog                         // "ResetDebugLog (test)"
+	stwExitHookTimeout                              // "exit hook timeout"
 )
 
 func (r stwReason) String() string {
@@ -1408,6 +1409,7 @@ var stwReasonStrings = [...]string{
 	stwForTestReadMemStatsSlow:     "ReadMemStatsSlow (test)",
 	stwForTestPageCachePagesLeaked: "PageCachePagesLeaked (test)",
 	stwForTestResetDebugLog:        "ResetDebugLog (test)",
+	stwExitHookTimeout:             "exit hook timeout",
 }
 
 // worldStop provides context from the stop-the-world required by the
@@ -1849,6 +1851,11 @@ func mstart1() {
 
 	if debug.dataindependenttiming == 1 {
 		sys.EnableDIT()
+	} else if sys.DITSupported {
+		// New threads inherit PSTATE.DIT from the thread that
+		// created them, which may have been running a goroutine
+		// with ditWanted set.
+		ditSync(gp.m, false)
 	}
 
 	if fn := gp.m.mstartfn; fn != nil {
@@ -3255,6 +3262,11 @@ func execute(gp *g, inheritTime bool) {
 		setThreadCPUProfiler(hz)
 	}
 
+	// Carry gp's data independent timing mode to this thread.
+	if gp.ditWanted != mp.ditEnabled {
+		ditSync(mp, gp.ditWanted)
+	}
+
 	trace := traceAcquire()
 	if trace.ok() {
 		trace.GoStart()
@@ -4325,6 +4337,7 @@ func gdestroy(gp *g) {
 	mp.lockedg = 0
 	gp.preemptStop = false
 	gp.paniconfault = false
+	gp.ditWanted = false
 	gp._defer = nil // should be true already but just in case.
 	gp._panic = nil // non-nil for Goexit during panic. points at stack-allocated data.
 	gp.writebuf = nil
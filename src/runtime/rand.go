@@ -184,6 +184,11 @@ func maps_rand() uint64 {
 	return rand()
 }
 
+//go:linkname maps_iterSeed internal/runtime/maps.iterSeed
+func maps_iterSeed() uint64 {
+	return uint64(debug.mapiterseed.Load())
+}
+
 // mrandinit initializes the random state of an m.
 func mrandinit(mp *m) {
 	var seed [4]uint64
@@ -167,16 +167,17 @@ class MapTypePrinter:
 			yield from self.swiss_map_children()
 
 	def swiss_map_children(self):
-		SwissMapGroupSlots = 8 # see internal/abi:SwissMapGroupSlots
-
 		cnt = 0
 		# Yield keys and elements in group.
 		# group is a value of type *group[K,V]
 		def group_slots(group):
 			ctrl = group['ctrl']
 
-			for i in xrange(SwissMapGroupSlots):
-				c = (ctrl >> (8*i)) & 0xff
+			# The number of slots depends on GOARCH, see
+			# internal/abi:SwissMapGroupSlots.
+			lo, hi = group['slots'].type.range()
+			for i in xrange(hi - lo + 1):
+				c = (ctrl[i // 8] >> (8*(i % 8))) & 0xff
 				if (c & 0x80) != 0:
 					# Empty or deleted
 					continue
@@ -311,7 +311,8 @@ var auxv []uintptr
 //go:linkname getAuxv
 func getAuxv() []uintptr { return auxv }
 
-// zeroVal is used by reflect via linkname.
+// zeroVal is abi.ZeroVal, under the name by which packages
+// outside the standard library refer to it.
 //
 // zeroVal should be an internal detail,
 // but widely used packages access it using linkname.
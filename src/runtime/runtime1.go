@@ -8,6 +8,7 @@ import (
 	"internal/bytealg"
 	"internal/goarch"
 	"internal/runtime/atomic"
+	"internal/runtime/maps"
 	"unsafe"
 )
 
@@ -332,6 +333,8 @@ var debug struct {
 	traceCheckStackOwnership int32
 	profstackdepth           int32
 	dataindependenttiming    int32
+	ditgoroutine             int32
+	swissmapcheck            int32
 
 	// debug.malloc is used as a combined debug check
 	// in the malloc function and should be set
@@ -360,6 +363,11 @@ var debug struct {
 	// but allowing it is convenient for testing and for programs
 	// that do an os.Setenv in main.init or main.main.
 	asynctimerchan atomic.Int32
+
+	// mapiterseed, if non-zero, replaces the random starting position
+	// of map iterations. It may change at any time, and affects the
+	// iterations started after the change.
+	mapiterseed atomic.Int32
 }
 
 var dbgvars = []*dbgVar{
@@ -370,6 +378,7 @@ var dbgvars = []*dbgVar{
 	{name: "clobberfree", value: &debug.clobberfree},
 	{name: "dataindependenttiming", value: &debug.dataindependenttiming},
 	{name: "disablethp", value: &debug.disablethp},
+	{name: "ditgoroutine", value: &debug.ditgoroutine},
 	{name: "dontfreezetheworld", value: &debug.dontfreezetheworld},
 	{name: "efence", value: &debug.efence},
 	{name: "gccheckmark", value: &debug.gccheckmark},
@@ -381,6 +390,7 @@ var dbgvars = []*dbgVar{
 	{name: "inittrace", value: &debug.inittrace},
 	{name: "invalidptr", value: &debug.invalidptr},
 	{name: "madvdontneed", value: &debug.madvdontneed},
+	{name: "mapiterseed", atomic: &debug.mapiterseed},
 	{name: "panicnil", atomic: &debug.panicnil},
 	{name: "profstackdepth", value: &debug.profstackdepth, def: 128},
 	{name: "runtimecontentionstacks", atomic: &debug.runtimeContentionStacks},
@@ -388,6 +398,7 @@ var dbgvars = []*dbgVar{
 	{name: "scavtrace", value: &debug.scavtrace},
 	{name: "scheddetail", value: &debug.scheddetail},
 	{name: "schedtrace", value: &debug.schedtrace},
+	{name: "swissmapcheck", value: &debug.swissmapcheck},
 	{name: "traceadvanceperiod", value: &debug.traceadvanceperiod},
 	{name: "traceallocfree", atomic: &debug.traceallocfree},
 	{name: "tracecheckstackownership", value: &debug.traceCheckStackOwnership},
@@ -439,6 +450,7 @@ func parsedebugvars() {
 
 	debug.malloc = (debug.inittrace | debug.sbrk) != 0
 	debug.profstackdepth = min(debug.profstackdepth, maxProfStackDepth)
+	maps.SetDebugCheck(debug.swissmapcheck != 0)
 
 	setTraceback(gogetenv("GOTRACEBACK"))
 	traceback_env = traceback_cache
@@ -512,6 +524,11 @@ func parsegodebug(godebug string, seen map[string]bool) {
 			if n, ok := atoi(value); ok {
 				MemProfileRate = n
 			}
+		} else if key == "exithooktimeout" {
+			// The timeout is a duration, not an int32.
+			if n, ok := parseDuration(value); ok {
+				exitHookTimeout.Store(n)
+			}
 		} else {
 			for _, v := range dbgvars {
 				if v.name == key {
@@ -468,6 +468,7 @@ type g struct {
 	runnableTime  int64 // the amount of time spent runnable, cleared when running, only used when tracking
 	lockedm       muintptr
 	fipsIndicator uint8
+	ditWanted     bool // g wants data independent timing while running; see dit.go
 	sig           uint32
 	writebuf      []byte
 	sigcode0      uintptr
@@ -560,6 +561,7 @@ type m struct {
 	isExtraInSig    bool          // m is an extra m in a signal handler
 	freeWait        atomic.Uint32 // Whether it is safe to free g0 and delete m (one of freeMRef, freeMStack, freeMWait)
 	needextram      bool
+	ditEnabled      bool // PSTATE.DIT is set on this thread for a g with ditWanted
 	g0StackAccurate bool // whether the g0 stack has accurate bounds
 	traceback       uint8
 	ncgocall        uint64        // number of cgo calls in total
@@ -744,6 +746,10 @@ type p struct {
 	scannedStackSize uint64 // stack size of goroutines scanned by this P
 	scannedStacks    uint64 // number of goroutines scanned by this P
 
+	// mapStats are the cumulative statistics of the maps that
+	// allocated on this P, for runtime/metrics.
+	mapStats mapStats
+
 	// preempt is set to indicate that this P should be enter the
 	// scheduler ASAP (regardless of what G is running on it).
 	preempt bool
@@ -741,6 +741,9 @@ func sighandler(sig uint32, info *siginfo, ctxt unsafe.Pointer, gp *g) {
 	}
 
 	if flags&_SigKill != 0 {
+		if sigExitHooks(sig) {
+			return
+		}
 		dieFromSignal(sig)
 	}
 
@@ -858,6 +861,7 @@ func fatalsignal(sig uint32, c *sigctxt, gp *g, mp *m) *g {
 		// leading up to the cgocall, which switched from curg to g0.
 		gp = mp.curg
 	}
+	printRunningExitHook()
 	if sig == _SIGILL || sig == _SIGFPE {
 		// It would be nice to know how long the instruction is.
 		// Unfortunately, that's complicated to do in general (mostly for x86
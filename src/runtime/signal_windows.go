@@ -370,6 +370,7 @@ func winthrow(info *exceptionrecord, r *context, gp *g) {
 		}
 		gp = g0.m.curg
 	}
+	printRunningExitHook()
 	print("\n")
 
 	g0.m.throwing = throwTypeRuntime
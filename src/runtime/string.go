@@ -540,6 +540,38 @@ func parseByteCount(s string) (int64, bool) {
 	return int64(un), true
 }
 
+// parseDuration parses a duration in nanoseconds from s, which must
+// be a non-negative integer followed by one of the units ns, us, ms,
+// s, m or h, as in "500ms" or "10s".
+func parseDuration(s string) (int64, bool) {
+	i := 0
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+	var unit int64
+	switch s[i:] {
+	case "ns":
+		unit = 1
+	case "us":
+		unit = 1e3
+	case "ms":
+		unit = 1e6
+	case "s":
+		unit = 1e9
+	case "m":
+		unit = 60e9
+	case "h":
+		unit = 3600e9
+	default:
+		return 0, false
+	}
+	n, ok := atoi64(s[:i])
+	if !ok || n < 0 || n > maxInt64/unit {
+		return 0, false
+	}
+	return n * unit, true
+}
+
 //go:nosplit
 func findnull(s *byte) int {
 	if s == nil {
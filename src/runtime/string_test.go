@@ -604,3 +604,35 @@ func TestParseByteCount(t *testing.T) {
 		}
 	}
 }
+
+func TestParseDuration(t *testing.T) {
+	for _, test := range []struct {
+		in  string
+		out int64
+		ok  bool
+	}{
+		{"0s", 0, true},
+		{"1ns", 1, true},
+		{"15us", 15e3, true},
+		{"100ms", 100e6, true},
+		{"5s", 5e9, true},
+		{"2m", 120e9, true},
+		{"1h", 3600e9, true},
+		{"9223372036854775807ns", 1<<63 - 1, true},
+
+		{"", 0, false},
+		{"5", 0, false},
+		{"s", 0, false},
+		{"-1s", 0, false},
+		{"1.5s", 0, false},
+		{"5 s", 0, false},
+		{"5sec", 0, false},
+		{"3000000000h", 0, false},
+	} {
+		out, ok := runtime.ParseDuration(test.in)
+		if test.out != out || test.ok != ok {
+			t.Errorf("parseDuration(%q) = (%v, %v) want (%v, %v)",
+				test.in, out, ok, test.out, test.ok)
+		}
+	}
+}
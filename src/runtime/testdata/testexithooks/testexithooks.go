@@ -8,6 +8,12 @@ import (
 	"flag"
 	"internal/runtime/exithook"
 	"os"
+	"os/signal"
+	"runtime"
+	"runtime/debug"
+	"runtime/trace"
+	"sync"
+	"sync/atomic"
 	"time"
 	_ "unsafe"
 )
@@ -29,6 +35,64 @@ func main() {
 		testHookCallsExit()
 	case "exit2":
 		testExit2()
+	case "public":
+		testPublic()
+	case "concurrent":
+		testConcurrent()
+	case "addfromhook":
+		testAddFromHook()
+	case "goexit":
+		testHookCallsGoexit()
+	case "remove":
+		testRemove()
+	case "removeduringexit":
+		testRemoveDuringExit()
+	case "removerace":
+		testRemoveRace()
+	case "panicsrecover":
+		testPanicsRecover()
+	case "panicspublic":
+		testPanicsPublic()
+	case "panicsinternal":
+		testPanicsInternal()
+	case "setexitcode":
+		testSetExitCode()
+	case "setexitcodemain":
+		testSetExitCodeMain()
+	case "setexitcodeoutside":
+		testSetExitCodeOutside()
+	case "setexitcodeexit":
+		testSetExitCodeThenExit()
+	case "timeout":
+		testTimeout()
+	case "timeoutexit":
+		testTimeoutExit()
+	case "timeoutgodebug":
+		testTimeoutGODEBUG()
+	case "exitrace":
+		testExitRace()
+	case "exitcodefirst":
+		testExitCodeFirst()
+	case "twohooks":
+		testTwoHooks()
+	case "phases":
+		testPhases()
+	case "signal":
+		testSignal()
+	case "signalblock":
+		testSignalBlock()
+	case "signalnotify":
+		testSignalNotify()
+	case "allocate":
+		testAllocate()
+	case "channel":
+		testChannel()
+	case "spawn":
+		testSpawn()
+	case "trace":
+		testTrace()
+	case "crash":
+		testCrash()
 	default:
 		panic("unknown mode")
 	}
@@ -37,6 +101,8 @@ func main() {
 func testSimple() {
 	f1 := func() { println("foo") }
 	f2 := func() { println("bar") }
+	f3 := func() { println("baz") }
+	exithook.Add(exithook.Hook{F: f3, Phase: exithook.PhaseLast})
 	exithook.Add(exithook.Hook{F: f1})
 	exithook.Add(exithook.Hook{F: f2})
 	// no explicit call to os.Exit
@@ -45,7 +111,9 @@ func testSimple() {
 func testGoodExit() {
 	f1 := func() { println("apple") }
 	f2 := func() { println("orange") }
+	f3 := func() { println("pear") }
 	exithook.Add(exithook.Hook{F: f1})
+	exithook.Add(exithook.Hook{F: f3, Phase: exithook.PhaseFirst})
 	exithook.Add(exithook.Hook{F: f2})
 	// explicit call to os.Exit
 	os.Exit(0)
@@ -93,3 +161,355 @@ func testExit2() {
 	}
 	os.Exit(0)
 }
+
+func testPublic() {
+	f1 := func() { println("one") }
+	f2 := func() { println("two") }
+	runtime.AddExitHook(f1)
+	runtime.AddExitHook(f2)
+	// public hooks run on failure too
+	os.Exit(3)
+}
+
+func testConcurrent() {
+	var n atomic.Int32
+	runtime.AddExitHook(func() { println("ran", n.Load()) })
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 10 {
+				runtime.AddExitHook(func() { n.Add(1) })
+			}
+		}()
+	}
+	wg.Wait()
+	os.Exit(0)
+}
+
+func testAddFromHook() {
+	runtime.AddExitHook(func() { println("last") })
+	runtime.AddExitHook(func() {
+		println("first")
+		runtime.AddExitHook(func() { println("second") })
+	})
+}
+
+func testHookCallsGoexit() {
+	runtime.AddExitHook(func() { println("ok") })
+	runtime.AddExitHook(func() { runtime.Goexit() })
+	os.Exit(0)
+}
+
+func testRemove() {
+	runtime.AddExitHook(func() { println("one") })
+	h := runtime.AddExitHook(func() { println("two") })
+	runtime.AddExitHook(func() { println("three") })
+	if !h.Remove() {
+		panic("Remove failed")
+	}
+	if h.Remove() {
+		panic("second Remove succeeded")
+	}
+	if (runtime.ExitHook{}).Remove() {
+		panic("Remove of zero ExitHook succeeded")
+	}
+}
+
+func testRemoveDuringExit() {
+	runtime.AddExitHook(func() { println("one") })
+	h2 := runtime.AddExitHook(func() { println("two") })
+	var h3 runtime.ExitHook
+	h3 = runtime.AddExitHook(func() {
+		// h3 is running, so it can no longer be removed.
+		println("three", h2.Remove(), h3.Remove())
+	})
+	os.Exit(0)
+}
+
+func testRemoveRace() {
+	runtime.AddExitHook(func() { println("ok") })
+	var hs []runtime.ExitHook
+	for range 1000 {
+		hs = append(hs, runtime.AddExitHook(func() {}))
+	}
+	started := make(chan bool)
+	go func() {
+		close(started)
+		for _, h := range hs {
+			h.Remove()
+		}
+	}()
+	<-started
+	os.Exit(0)
+}
+
+func testPanicsRecover() {
+	debug.SetRecoverExitHookPanics(true)
+	runtime.AddExitHook(func() { println("ok") })
+	runtime.AddExitHook(func() { panic("BADBADBAD") })
+	runtime.AddExitHook(func() { println("good") })
+	os.Exit(0)
+}
+
+func testPanicsPublic() {
+	runtime.AddExitHook(func() { println("ok") })
+	runtime.AddExitHook(func() { panic("BADBADBAD") })
+	runtime.AddExitHook(func() { println("good") })
+	os.Exit(0)
+}
+
+func testPanicsInternal() {
+	// Runtime-internal hooks are not recoverable.
+	debug.SetRecoverExitHookPanics(true)
+	f1 := func() { println("ok") }
+	f2 := func() { panic("BADBADBAD") }
+	f3 := func() { println("good") }
+	exithook.Add(exithook.Hook{F: f1, RunOnFailure: true})
+	exithook.Add(exithook.Hook{F: f2, RunOnFailure: true})
+	exithook.Add(exithook.Hook{F: f3, RunOnFailure: true})
+	os.Exit(0)
+}
+
+func testSetExitCode() {
+	runtime.AddExitHook(func() { println("after") })
+	exithook.Add(exithook.Hook{F: func() { println("skipped") }})
+	runtime.AddExitHook(func() {
+		println("setting")
+		runtime.SetExitCode(7)
+	})
+	os.Exit(0)
+}
+
+func testSetExitCodeMain() {
+	runtime.AddExitHook(func() { runtime.SetExitCode(9) })
+	// no explicit call to os.Exit
+}
+
+func testSetExitCodeOutside() {
+	defer func() {
+		println("recovered", recover().(error).Error())
+	}()
+	runtime.SetExitCode(1)
+}
+
+func testSetExitCodeThenExit() {
+	runtime.AddExitHook(func() {
+		runtime.SetExitCode(3)
+		os.Exit(4)
+	})
+	os.Exit(0)
+}
+
+func sleepForever() {
+	time.Sleep(time.Hour)
+}
+
+func testTimeout() {
+	runtime.AddExitHook(func() { println("first") })
+	runtime.AddExitHookWithOptions(sleepForever, runtime.ExitHookOptions{
+		Timeout: int64(100 * time.Millisecond),
+	})
+	runtime.AddExitHook(func() { println("before") })
+	os.Exit(0)
+}
+
+func testTimeoutExit() {
+	runtime.AddExitHook(func() { println("first") })
+	runtime.AddExitHookWithOptions(sleepForever, runtime.ExitHookOptions{
+		Timeout:       int64(100 * time.Millisecond),
+		ExitOnTimeout: true,
+	})
+	runtime.AddExitHook(func() { println("before") })
+	os.Exit(0)
+}
+
+func testTimeoutGODEBUG() {
+	// Run with GODEBUG=exithooktimeout=100ms.
+	runtime.AddExitHook(func() { println("first") })
+	runtime.AddExitHook(sleepForever)
+	runtime.AddExitHookWithOptions(func() {
+		// Hooks with a timeout may still set the exit code.
+		time.Sleep(10 * time.Millisecond)
+		runtime.SetExitCode(5)
+		println("second")
+	}, runtime.ExitHookOptions{Timeout: -1})
+}
+
+func testExitRace() {
+	runtime.AddExitHook(func() {
+		println("hook")
+		time.Sleep(50 * time.Millisecond)
+	})
+	start := make(chan bool)
+	for range 100 {
+		go func() {
+			<-start
+			os.Exit(3)
+		}()
+	}
+	close(start)
+	select {}
+}
+
+func testExitCodeFirst() {
+	runtime.AddExitHook(func() {
+		println("hook")
+		// This exit waits for the hooks and uses their exit code.
+		go os.Exit(7)
+		time.Sleep(100 * time.Millisecond)
+	})
+	os.Exit(4)
+}
+
+func addGoodHook() {
+	exithook.Add(exithook.Hook{F: func() { println("good") }})
+}
+
+func addBadHook() {
+	exithook.Add(exithook.Hook{F: func() { panic("BADBADBAD") }})
+}
+
+func testTwoHooks() {
+	addGoodHook()
+	addBadHook()
+	os.Exit(0)
+}
+
+func testPhases() {
+	add := func(s string, phase runtime.ExitHookPhase) {
+		runtime.AddExitHookWithOptions(func() { println(s) }, runtime.ExitHookOptions{Phase: phase})
+	}
+	add("L1", runtime.ExitHookLast)
+	add("N1", runtime.ExitHookNormal)
+	add("F1", runtime.ExitHookFirst)
+	add("L2", runtime.ExitHookLast)
+	runtime.AddExitHook(func() {
+		println("N2")
+		// Hooks added during the exit run as soon as their phase
+		// allows: F3 runs next, as it is in an earlier phase.
+		add("N3", runtime.ExitHookNormal)
+		add("F3", runtime.ExitHookFirst)
+	})
+	add("F2", runtime.ExitHookFirst)
+	os.Exit(0)
+}
+
+// The signal modes print "ready" once they are prepared to receive
+// a signal, and then wait for it.
+
+func testSignal() {
+	debug.SetExitHooksOnSignal(int64(10 * time.Second))
+	runtime.AddExitHook(func() { println("flushed") })
+	println("ready")
+	time.Sleep(time.Hour)
+}
+
+func testSignalBlock() {
+	debug.SetExitHooksOnSignal(int64(100 * time.Millisecond))
+	runtime.AddExitHook(func() { println("flushed") })
+	runtime.AddExitHook(func() {
+		println("blocking")
+		time.Sleep(time.Hour)
+	})
+	println("ready")
+	time.Sleep(time.Hour)
+}
+
+func testSignalNotify() {
+	debug.SetExitHooksOnSignal(int64(10 * time.Second))
+	runtime.AddExitHook(func() { println("flushed") })
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt)
+	println("ready")
+	<-c
+	println("notified")
+	os.Exit(0)
+}
+
+// The following modes check that exit hooks run on an ordinary
+// goroutine, with a live scheduler and garbage collector, whichever
+// way the program exits.
+
+var sink []byte
+
+func testAllocate() {
+	runtime.AddExitHook(func() {
+		for range 100 {
+			sink = make([]byte, 1<<20)
+			sink[len(sink)-1] = 1
+		}
+		runtime.GC()
+		println("allocated")
+	})
+	// no explicit call to os.Exit
+}
+
+func testChannel() {
+	req, resp := make(chan int), make(chan int)
+	go func() {
+		for v := range req {
+			resp <- v * 2
+		}
+	}()
+	runtime.AddExitHook(func() {
+		req <- 21
+		println("received", <-resp)
+	})
+	// Exit while panicking, from a deferred call.
+	defer os.Exit(3)
+	panic("exiting")
+}
+
+func testSpawn() {
+	runtime.AddExitHookWithOptions(func() {
+		var wg sync.WaitGroup
+		var n atomic.Int32
+		for range 10 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				growStack(100)
+				n.Add(1)
+			}()
+		}
+		wg.Wait()
+		println("spawned", n.Load())
+	}, runtime.ExitHookOptions{Timeout: int64(time.Minute)})
+	os.Exit(1)
+}
+
+// growStack recurses n times with a large frame, to grow the stack.
+func growStack(n int) byte {
+	var buf [1024]byte
+	buf[n%len(buf)] = byte(n)
+	if n == 0 {
+		return buf[0]
+	}
+	return growStack(n-1) + buf[n%len(buf)]
+}
+
+// testTrace traces the exit hooks, writing the trace to stdout.
+// The trace is stopped by a hook in the last phase, after the other
+// hooks have run.
+func testTrace() {
+	if err := trace.Start(os.Stdout); err != nil {
+		panic(err)
+	}
+	runtime.AddExitHookWithOptions(trace.Stop, runtime.ExitHookOptions{
+		Phase: runtime.ExitHookLast,
+	})
+	runtime.AddExitHook(func() { time.Sleep(10 * time.Millisecond) })
+	os.Exit(0)
+}
+
+// testCrash blocks in an exit hook, so that the test can send a
+// SIGQUIT while the hook is running.
+func testCrash() {
+	runtime.AddExitHook(func() {
+		println("ready")
+		time.Sleep(time.Hour)
+	})
+	os.Exit(0)
+}
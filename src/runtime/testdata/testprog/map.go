@@ -10,6 +10,8 @@ func init() {
 	register("concurrentMapWrites", concurrentMapWrites)
 	register("concurrentMapReadWrite", concurrentMapReadWrite)
 	register("concurrentMapIterateWrite", concurrentMapIterateWrite)
+	register("concurrentMapDeleteWrite", concurrentMapDeleteWrite)
+	register("concurrentMapClearWrite", concurrentMapClearWrite)
 }
 
 func concurrentMapWrites() {
@@ -75,3 +77,45 @@ func concurrentMapIterateWrite() {
 	<-c
 	<-c
 }
+
+func concurrentMapDeleteWrite() {
+	m := map[int]int{}
+	c := make(chan struct{})
+	go func() {
+		for i := 0; i < 10000; i++ {
+			m[5] = 0
+			runtime.Gosched()
+		}
+		c <- struct{}{}
+	}()
+	go func() {
+		for i := 0; i < 10000; i++ {
+			delete(m, 5)
+			runtime.Gosched()
+		}
+		c <- struct{}{}
+	}()
+	<-c
+	<-c
+}
+
+func concurrentMapClearWrite() {
+	m := map[int]int{}
+	c := make(chan struct{})
+	go func() {
+		for i := 0; i < 10000; i++ {
+			m[5] = 0
+			runtime.Gosched()
+		}
+		c <- struct{}{}
+	}()
+	go func() {
+		for i := 0; i < 10000; i++ {
+			clear(m)
+			runtime.Gosched()
+		}
+		c <- struct{}{}
+	}()
+	<-c
+	<-c
+}
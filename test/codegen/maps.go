@@ -66,6 +66,39 @@ func LookupStringConversionKeyedArrayLit(m map[[2]string]int, bytes []byte) int
 	return m[[2]string{0: string(bytes)}]
 }
 
+// ------------------- //
+//     Map Length      //
+// ------------------- //
+
+// The length of a map is loaded from its first word, without a call.
+
+func MapLen(m map[int]int) int {
+	// amd64:`MOVQ\t\(`,-"CALL"
+	// arm64:`MOVD\t\(`,-"CALL"
+	return len(m)
+}
+
+func MapEmpty(m map[string]int) bool {
+	// amd64:`MOVQ\t\(`,-"CALL"
+	// arm64:`MOVD\t\(`,-"CALL"
+	return len(m) == 0
+}
+
+func MapNonEmpty(m map[string]int) bool {
+	// amd64:`MOVQ\t\(`,-"CALL"
+	// arm64:`MOVD\t\(`,-"CALL"
+	return len(m) != 0
+}
+
+func MapLenGuard(m map[int]int, k int) int {
+	// amd64:`MOVQ\t\(`,-"CALL"
+	// arm64:`MOVD\t\(`,-"CALL"
+	if len(m) == 0 {
+		return -1
+	}
+	return m[k]
+}
+
 // ------------------- //
 //     Map Clear       //
 // ------------------- //
@@ -6,6 +6,8 @@
 
 package codegen
 
+import "math/bits"
+
 // ------------------ //
 //   constant shifts  //
 // ------------------ //
@@ -531,3 +533,25 @@ func checkLeftShiftWithAddition(a int64, b int64) int64 {
 	a = a + b<<3
 	return a
 }
+
+//
+// Bounds checks on directory indexes taken from the top bits of a hash.
+//
+
+func checkDirIndexDepth(dir []*int, hash uint64, depth uint8) *int {
+	if depth >= 64 || len(dir) != 1<<depth {
+		return nil
+	}
+	// amd64:-".*panicIndex"
+	// arm64:-".*panicIndex"
+	return dir[hash>>(64-depth)]
+}
+
+func checkDirIndexTrailingZeros(dir []*int, hash uint64) *int {
+	if len(dir) == 0 {
+		return nil
+	}
+	// amd64:-".*panicIndex"
+	// arm64:-".*panicIndex"
+	return dir[hash>>(64-bits.TrailingZeros64(uint64(len(dir))))]
+}
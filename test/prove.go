@@ -1712,6 +1712,50 @@ func clampedIdx2(x []int, i int) int {
 	return x[max(min(i, len(x)-1), 0)] // TODO: can't get rid of this bounds check yet
 }
 
+func rshuLimit(x uint64, s uint) int {
+	if s < 60 {
+		return 0
+	}
+	if x>>s < 16 { // ERROR "Proved Less64U$"
+		return 1
+	}
+	return 0
+}
+
+// dirIndexShift mimics an extendible hashing directory whose global
+// shift is known to select at most the top 4 bits of the hash.
+func dirIndexShift(dir []int, hash uint64, shift uint) int {
+	if len(dir) < 16 || shift < 60 {
+		return 0
+	}
+	return dir[hash>>shift] // ERROR "Proved IsInBounds$"
+}
+
+func dirIndexDepth(dir []int, hash uint64, depth uint8) int {
+	if depth >= 64 || len(dir) != 1<<depth { // ERROR "Proved Lsh64x8 bounded$"
+		return 0
+	}
+	return dir[hash>>(64-depth)] // ERROR "Proved IsInBounds$"
+}
+
+func dirIndexTrailingZeros(dir []int, hash uint64) int {
+	if len(dir) == 0 {
+		return 0
+	}
+	return dir[hash>>(64-bits.TrailingZeros64(uint64(len(dir))))] // ERROR "Proved IsInBounds$" "Proved Ctz64 non-zero$" "Proved Leq64$"
+}
+
+func dirIndexUnbounded(dir []int, hash uint64, depth uint8) int {
+	if len(dir) != 1<<depth {
+		return 0
+	}
+	return dir[hash>>(64-depth)] // depth may be >= 64; must keep the bounds check.
+}
+
+func dirIndexEmpty(dir []int, hash uint64) int {
+	return dir[hash>>(64-bits.TrailingZeros64(uint64(len(dir))))] // ERROR "Proved Leq64$"
+}
+
 //go:noinline
 func useInt(a int) {
 }